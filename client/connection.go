@@ -36,6 +36,15 @@ type ConnectionArgs struct {
 	// Authentication interactor
 	AuthInteractor []httpbakery.Interactor
 
+	// API token sent as bearer token, as issued by POST /1.0/auth/tokens
+	AuthToken string
+
+	// File storing the OpenID Connect tokens (for the "oidc" authentication type)
+	OIDCTokensFile string
+
+	// Function showing the user how to complete an OpenID Connect login
+	OIDCLoginPrompt func(verificationURL string, userCode string)
+
 	// Custom proxy
 	Proxy func(*http.Request) (*url.URL, error)
 
@@ -219,10 +228,11 @@ func httpsLXD(url string, args *ConnectionArgs) (InstanceServer, error) {
 		httpProtocol:     "https",
 		httpUserAgent:    args.UserAgent,
 		bakeryInteractor: args.AuthInteractor,
+		authToken:        args.AuthToken,
 		chConnected:      make(chan struct{}, 1),
 	}
 
-	if args.AuthType == "candid" {
+	if args.AuthType == "candid" || args.AuthType == "oidc" || args.AuthToken != "" {
 		server.RequireAuthenticated(true)
 	}
 
@@ -239,6 +249,8 @@ func httpsLXD(url string, args *ConnectionArgs) (InstanceServer, error) {
 	server.http = httpClient
 	if args.AuthType == "candid" {
 		server.setupBakeryClient()
+	} else if args.AuthType == "oidc" {
+		server.oidcClient = newOIDCClient(args.OIDCTokensFile, args.OIDCLoginPrompt, args.Proxy)
 	}
 
 	// Test the connection and seed the server information
@@ -52,6 +52,8 @@ type ImageServer interface {
 	GetPrivateImage(fingerprint string, secret string) (image *api.Image, ETag string, err error)
 	GetPrivateImageFile(fingerprint string, secret string, req ImageFileRequest) (resp *ImageFileResponse, err error)
 
+	GetImageSignatures(fingerprint string, secret string) (signatures *ImageSignatures, err error)
+
 	GetImageAliases() (aliases []api.ImageAliasesEntry, err error)
 	GetImageAliasNames() (names []string, err error)
 
@@ -66,6 +68,8 @@ type InstanceServer interface {
 	// Server functions
 	GetServer() (server *api.Server, ETag string, err error)
 	GetServerResources() (resources *api.Resources, err error)
+	GetMetrics() (metrics string, err error)
+	GetAuditEntries() (entries []api.AuditEntry, err error)
 	UpdateServer(server api.ServerPut, ETag string) (err error)
 	HasExtension(extension string) (exists bool)
 	RequireAuthenticated(authenticated bool)
@@ -73,6 +77,21 @@ type InstanceServer interface {
 	UseTarget(name string) (client InstanceServer)
 	UseProject(name string) (client InstanceServer)
 
+	// API token functions
+	GetAuthTokenIDs() (ids []string, err error)
+	GetAuthTokens() (tokens []api.AuthToken, err error)
+	GetAuthToken(id string) (token *api.AuthToken, err error)
+	CreateAuthToken(token api.AuthTokensPost) (result *api.AuthToken, err error)
+	DeleteAuthToken(id string) (err error)
+
+	// Local RBAC group functions
+	GetAuthGroupNames() (names []string, err error)
+	GetAuthGroups() (groups []api.AuthGroup, err error)
+	GetAuthGroup(name string) (group *api.AuthGroup, ETag string, err error)
+	CreateAuthGroup(group api.AuthGroupsPost) (err error)
+	UpdateAuthGroup(name string, group api.AuthGroupPut, ETag string) (err error)
+	DeleteAuthGroup(name string) (err error)
+
 	// Certificate functions
 	GetCertificateFingerprints() (fingerprints []string, err error)
 	GetCertificates() (certificates []api.Certificate, err error)
@@ -142,6 +161,8 @@ type InstanceServer interface {
 	GetInstanceNames(instanceType api.InstanceType) (names []string, err error)
 	GetInstances(instanceType api.InstanceType) (instances []api.Instance, err error)
 	GetInstancesFull(instanceType api.InstanceType) (instances []api.InstanceFull, err error)
+	GetInstancesWithArgs(instanceType api.InstanceType, args ListArgs) (instances []api.Instance, nextPageToken string, err error)
+	GetInstancesFullWithArgs(instanceType api.InstanceType, args ListArgs) (instances []api.InstanceFull, nextPageToken string, err error)
 	GetInstance(name string) (instance *api.Instance, ETag string, err error)
 	CreateInstance(instance api.InstancesPost) (op Operation, err error)
 	CreateInstanceFromImage(source ImageServer, image api.Image, req api.InstancesPost) (op RemoteOperation, err error)
@@ -197,12 +218,15 @@ type InstanceServer interface {
 
 	// Event handling functions
 	GetEvents() (listener *EventListener, err error)
+	GetEventsSince(since map[string]string) (listener *EventListener, err error)
 
 	// Image functions
+	GetImagesWithArgs(args ListArgs) (images []api.Image, nextPageToken string, err error)
 	CreateImage(image api.ImagesPost, args *ImageCreateArgs) (op Operation, err error)
 	CopyImage(source ImageServer, image api.Image, args *ImageCopyArgs) (op RemoteOperation, err error)
 	UpdateImage(fingerprint string, image api.ImagePut, ETag string) (err error)
 	DeleteImage(fingerprint string) (op Operation, err error)
+	DeleteImageWithArgs(fingerprint string, args *ImageDeleteArgs) (op Operation, err error)
 	RefreshImage(fingerprint string) (op Operation, err error)
 	CreateImageSecret(fingerprint string) (op Operation, err error)
 	CreateImageAlias(alias api.ImageAliasesPost) (err error)
@@ -224,6 +248,8 @@ type InstanceServer interface {
 	// Operation functions
 	GetOperationUUIDs() (uuids []string, err error)
 	GetOperations() (operations []api.Operation, err error)
+	GetOperationsAllHistory() (operations []api.Operation, err error)
+	GetOperationsWithArgs(args ListArgs) (operations []api.Operation, nextPageToken string, err error)
 	GetOperation(uuid string) (op *api.Operation, ETag string, err error)
 	GetOperationWait(uuid string, timeout int) (op *api.Operation, ETag string, err error)
 	GetOperationWebsocket(uuid string, secret string) (conn *websocket.Conn, err error)
@@ -259,6 +285,7 @@ type InstanceServer interface {
 	// Storage volume functions ("storage" API extension)
 	GetStoragePoolVolumeNames(pool string) (names []string, err error)
 	GetStoragePoolVolumes(pool string) (volumes []api.StorageVolume, err error)
+	GetStoragePoolVolumesWithArgs(pool string, args ListArgs) (volumes []api.StorageVolume, nextPageToken string, err error)
 	GetStoragePoolVolume(pool string, volType string, name string) (volume *api.StorageVolume, ETag string, err error)
 	CreateStoragePoolVolume(pool string, volume api.StorageVolumesPost) (err error)
 	UpdateStoragePoolVolume(pool string, volType string, name string, volume api.StorageVolumePut, ETag string) (err error)
@@ -280,12 +307,22 @@ type InstanceServer interface {
 	// Cluster functions ("cluster" API extensions)
 	GetCluster() (cluster *api.Cluster, ETag string, err error)
 	UpdateCluster(cluster api.ClusterPut, ETag string) (op Operation, err error)
+	UpdateClusterCertificate(certs api.ClusterCertificatePut, ETag string) (err error)
+	CreateClusterMember(member api.ClusterMembersPost) (token *api.ClusterMemberJoinToken, err error)
 	DeleteClusterMember(name string, force bool) (err error)
 	GetClusterMemberNames() (names []string, err error)
 	GetClusterMembers() (members []api.ClusterMember, err error)
 	GetClusterMember(name string) (member *api.ClusterMember, ETag string, err error)
 	RenameClusterMember(name string, member api.ClusterMemberPost) (err error)
 
+	// Webhook functions ("webhooks" API extension)
+	GetWebhookNames() (names []string, err error)
+	GetWebhooks() (webhooks []api.Webhook, err error)
+	GetWebhook(name string) (webhook *api.Webhook, ETag string, err error)
+	CreateWebhook(webhook api.WebhooksPost) (err error)
+	UpdateWebhook(name string, webhook api.WebhookPut, ETag string) (err error)
+	DeleteWebhook(name string) (err error)
+
 	// Internal functions (for internal use)
 	RawQuery(method string, path string, data interface{}, queryETag string) (resp *api.Response, ETag string, err error)
 	RawWebsocket(path string) (conn *websocket.Conn, err error)
@@ -320,6 +357,24 @@ type BackupFileResponse struct {
 	Size int64
 }
 
+// The ListArgs struct is used to filter, project and paginate the lists of
+// instances, images, storage volumes and operations.
+//
+// API extension: list_filters
+type ListArgs struct {
+	// Filter expression (e.g. "status eq Running and config.user.team eq web")
+	Filter string
+
+	// Fields to return (all if empty)
+	Fields []string
+
+	// Maximum number of entries to return (0 for no limit)
+	Limit int
+
+	// Token of the page to return, as returned for the previous page
+	PageToken string
+}
+
 // The ImageCreateArgs struct is used for direct image upload.
 type ImageCreateArgs struct {
 	// Reader for the meta file
@@ -375,6 +430,16 @@ type ImageFileResponse struct {
 	RootfsSize int64
 }
 
+// The ImageSignatures struct holds the detached signatures of the image files.
+// Either field is empty when the matching file isn't signed.
+type ImageSignatures struct {
+	// Signature of the metadata (or unified) file
+	Meta []byte
+
+	// Signature of the rootfs file
+	Rootfs []byte
+}
+
 // The ImageCopyArgs struct is used to pass additional options during image copy.
 type ImageCopyArgs struct {
 	// Aliases to add to the copied image.
@@ -391,6 +456,16 @@ type ImageCopyArgs struct {
 
 	// The image type to use for resolution
 	Type string
+
+	// Whether to reject the image unless it's signed by a trusted key
+	RequireSignature bool
+}
+
+// The ImageDeleteArgs struct is used to pass additional options during image
+// deletion.
+type ImageDeleteArgs struct {
+	// Whether to delete the image even if instances were created from it
+	Force bool
 }
 
 // The StoragePoolVolumeCopyArgs struct is used to pass additional options
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"gopkg.in/macaroon-bakery.v2/bakery"
@@ -39,6 +41,9 @@ type ProtocolLXD struct {
 	bakeryInteractor     []httpbakery.Interactor
 	requireAuthenticated bool
 
+	oidcClient *oidcClient
+	authToken  string
+
 	clusterTarget string
 	project       string
 }
@@ -93,13 +98,66 @@ func (r *ProtocolLXD) GetHTTPClient() (*http.Client, error) {
 	return r.http, nil
 }
 
-// Do performs a Request, using macaroon authentication if set.
+// Maximum number of retries of the requests rejected with a 429 status code,
+// and maximum delay between two retries.
+const (
+	tooManyRequestsRetries  = 5
+	tooManyRequestsMaxDelay = 30 * time.Second
+)
+
+// Do performs a Request, using macaroon, API token or OpenID Connect authentication if set.
 func (r *ProtocolLXD) do(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := r.doOnce(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt == tooManyRequestsRetries {
+			return resp, err
+		}
+
+		// Only retry if the body can be sent again.
+		if req.Body != nil && req.GetBody == nil {
+			return resp, nil
+		}
+
+		// Wait for as long as the server asked, backing off exponentially
+		// if it didn't say.
+		delay := time.Duration(1<<uint(attempt)) * time.Second
+		seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+		if err == nil && seconds > 0 {
+			delay = time.Duration(seconds) * time.Second
+		}
+
+		if delay > tooManyRequestsMaxDelay {
+			delay = tooManyRequestsMaxDelay
+		}
+
+		resp.Body.Close()
+
+		logger.Debug("Retrying rate limited request", "url", req.URL.String(), "delay", delay)
+		time.Sleep(delay)
+
+		if req.Body != nil {
+			req.Body, err = req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (r *ProtocolLXD) doOnce(req *http.Request) (*http.Response, error) {
+	if r.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+r.authToken)
+	}
+
 	if r.bakeryClient != nil {
 		r.addMacaroonHeaders(req)
 		return r.bakeryClient.Do(req)
 	}
 
+	if r.oidcClient != nil {
+		return r.oidcClient.do(r.http, req)
+	}
+
 	return r.http.Do(req)
 }
 
@@ -167,6 +225,15 @@ func lxdParseResponse(resp *http.Response) (*api.Response, string, error) {
 }
 
 func (r *ProtocolLXD) rawQuery(method string, url string, data interface{}, ETag string) (*api.Response, string, error) {
+	response, headers, err := r.rawQueryWithHeaders(method, url, data, ETag)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return response, headers.Get("ETag"), nil
+}
+
+func (r *ProtocolLXD) rawQueryWithHeaders(method string, url string, data interface{}, ETag string) (*api.Response, http.Header, error) {
 	var req *http.Request
 	var err error
 
@@ -184,7 +251,7 @@ func (r *ProtocolLXD) rawQuery(method string, url string, data interface{}, ETag
 			// Some data to be sent along with the request
 			req, err = http.NewRequest(method, url, data.(io.Reader))
 			if err != nil {
-				return nil, "", err
+				return nil, nil, err
 			}
 
 			// Set the encoding accordingly
@@ -194,14 +261,14 @@ func (r *ProtocolLXD) rawQuery(method string, url string, data interface{}, ETag
 			buf := bytes.Buffer{}
 			err := json.NewEncoder(&buf).Encode(data)
 			if err != nil {
-				return nil, "", err
+				return nil, nil, err
 			}
 
 			// Some data to be sent along with the request
 			// Use a reader since the request body needs to be seekable
 			req, err = http.NewRequest(method, url, bytes.NewReader(buf.Bytes()))
 			if err != nil {
-				return nil, "", err
+				return nil, nil, err
 			}
 
 			// Set the encoding accordingly
@@ -214,7 +281,7 @@ func (r *ProtocolLXD) rawQuery(method string, url string, data interface{}, ETag
 		// No data to be sent along with the request
 		req, err = http.NewRequest(method, url, nil)
 		if err != nil {
-			return nil, "", err
+			return nil, nil, err
 		}
 	}
 
@@ -236,11 +303,16 @@ func (r *ProtocolLXD) rawQuery(method string, url string, data interface{}, ETag
 	// Send the request
 	resp, err := r.do(req)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
-	return lxdParseResponse(resp)
+	response, _, err := lxdParseResponse(resp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return response, resp.Header, nil
 }
 
 func (r *ProtocolLXD) setQueryAttributes(uri string) (string, error) {
@@ -300,6 +372,55 @@ func (r *ProtocolLXD) queryStruct(method string, path string, data interface{},
 	return etag, nil
 }
 
+// Query a list endpoint with the given filtering, field selection and
+// pagination arguments, returning the token of the next page if any.
+func (r *ProtocolLXD) queryList(path string, v neturl.Values, args ListArgs, target interface{}) (string, error) {
+	if !r.HasExtension("list_filters") {
+		return "", fmt.Errorf("The server is missing the required \"list_filters\" API extension")
+	}
+
+	if args.Filter != "" {
+		v.Set("filter", args.Filter)
+	}
+
+	if len(args.Fields) > 0 {
+		v.Set("fields", strings.Join(args.Fields, ","))
+	}
+
+	if args.Limit > 0 {
+		v.Set("limit", fmt.Sprintf("%d", args.Limit))
+	}
+
+	if args.PageToken != "" {
+		v.Set("page_token", args.PageToken)
+	}
+
+	// Generate the URL
+	url := fmt.Sprintf("%s/1.0%s?%s", r.httpHost, path, v.Encode())
+
+	// Add project/target
+	url, err := r.setQueryAttributes(url)
+	if err != nil {
+		return "", err
+	}
+
+	resp, headers, err := r.rawQueryWithHeaders("GET", url, nil, "")
+	if err != nil {
+		return "", err
+	}
+
+	err = resp.MetadataAsStruct(&target)
+	if err != nil {
+		return "", err
+	}
+
+	// Log the data
+	logger.Debugf("Got response struct from LXD")
+	logger.Debugf(logger.Pretty(target))
+
+	return headers.Get("X-LXD-Next-Page-Token"), nil
+}
+
 func (r *ProtocolLXD) queryOperation(method string, path string, data interface{}, ETag string) (Operation, string, error) {
 	// Attempt to setup an early event listener
 	listener, err := r.GetEvents()
@@ -373,6 +494,16 @@ func (r *ProtocolLXD) rawWebsocket(url string) (*websocket.Conn, error) {
 		r.addMacaroonHeaders(req)
 	}
 
+	// Set the API token if any
+	if r.authToken != "" {
+		headers.Set("Authorization", "Bearer "+r.authToken)
+	}
+
+	// Set the OpenID Connect access token if any
+	if r.oidcClient != nil && r.oidcClient.accessToken() != "" {
+		headers.Set("Authorization", "Bearer "+r.oidcClient.accessToken())
+	}
+
 	// Establish the connection
 	conn, _, err := dialer.Dial(url, headers)
 	if err != nil {
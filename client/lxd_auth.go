@@ -0,0 +1,207 @@
+package lxd
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// API token handling functions
+
+// GetAuthTokenIDs returns a list of API token IDs
+func (r *ProtocolLXD) GetAuthTokenIDs() ([]string, error) {
+	if !r.HasExtension("auth_tokens") {
+		return nil, fmt.Errorf("The server is missing the required \"auth_tokens\" API extension")
+	}
+
+	urls := []string{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", "/auth/tokens", nil, "", &urls)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse it
+	ids := []string{}
+	for _, url := range urls {
+		fields := strings.Split(url, "/auth/tokens/")
+		ids = append(ids, fields[len(fields)-1])
+	}
+
+	return ids, nil
+}
+
+// GetAuthTokens returns a list of API tokens
+func (r *ProtocolLXD) GetAuthTokens() ([]api.AuthToken, error) {
+	if !r.HasExtension("auth_tokens") {
+		return nil, fmt.Errorf("The server is missing the required \"auth_tokens\" API extension")
+	}
+
+	tokens := []api.AuthToken{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", "/auth/tokens?recursion=1", nil, "", &tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// GetAuthToken returns the API token with the given ID
+func (r *ProtocolLXD) GetAuthToken(id string) (*api.AuthToken, error) {
+	if !r.HasExtension("auth_tokens") {
+		return nil, fmt.Errorf("The server is missing the required \"auth_tokens\" API extension")
+	}
+
+	token := api.AuthToken{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", fmt.Sprintf("/auth/tokens/%s", url.PathEscape(id)), nil, "", &token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// CreateAuthToken issues a new API token, the returned struct being the only
+// place the bearer token can be found
+func (r *ProtocolLXD) CreateAuthToken(token api.AuthTokensPost) (*api.AuthToken, error) {
+	if !r.HasExtension("auth_tokens") {
+		return nil, fmt.Errorf("The server is missing the required \"auth_tokens\" API extension")
+	}
+
+	result := api.AuthToken{}
+
+	// Send the request
+	_, err := r.queryStruct("POST", "/auth/tokens", token, "", &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// DeleteAuthToken revokes the API token with the given ID
+func (r *ProtocolLXD) DeleteAuthToken(id string) error {
+	if !r.HasExtension("auth_tokens") {
+		return fmt.Errorf("The server is missing the required \"auth_tokens\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("DELETE", fmt.Sprintf("/auth/tokens/%s", url.PathEscape(id)), nil, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Local RBAC group handling functions
+
+// GetAuthGroupNames returns a list of local RBAC group names
+func (r *ProtocolLXD) GetAuthGroupNames() ([]string, error) {
+	if !r.HasExtension("auth_groups") {
+		return nil, fmt.Errorf("The server is missing the required \"auth_groups\" API extension")
+	}
+
+	urls := []string{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", "/auth/groups", nil, "", &urls)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse it
+	names := []string{}
+	for _, url := range urls {
+		fields := strings.Split(url, "/auth/groups/")
+		names = append(names, fields[len(fields)-1])
+	}
+
+	return names, nil
+}
+
+// GetAuthGroups returns a list of local RBAC groups
+func (r *ProtocolLXD) GetAuthGroups() ([]api.AuthGroup, error) {
+	if !r.HasExtension("auth_groups") {
+		return nil, fmt.Errorf("The server is missing the required \"auth_groups\" API extension")
+	}
+
+	groups := []api.AuthGroup{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", "/auth/groups?recursion=1", nil, "", &groups)
+	if err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// GetAuthGroup returns the local RBAC group with the given name
+func (r *ProtocolLXD) GetAuthGroup(name string) (*api.AuthGroup, string, error) {
+	if !r.HasExtension("auth_groups") {
+		return nil, "", fmt.Errorf("The server is missing the required \"auth_groups\" API extension")
+	}
+
+	group := api.AuthGroup{}
+
+	// Fetch the raw value
+	etag, err := r.queryStruct("GET", fmt.Sprintf("/auth/groups/%s", url.PathEscape(name)), nil, "", &group)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &group, etag, nil
+}
+
+// CreateAuthGroup defines a new local RBAC group
+func (r *ProtocolLXD) CreateAuthGroup(group api.AuthGroupsPost) error {
+	if !r.HasExtension("auth_groups") {
+		return fmt.Errorf("The server is missing the required \"auth_groups\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("POST", "/auth/groups", group, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateAuthGroup updates the local RBAC group to match the provided struct
+func (r *ProtocolLXD) UpdateAuthGroup(name string, group api.AuthGroupPut, ETag string) error {
+	if !r.HasExtension("auth_groups") {
+		return fmt.Errorf("The server is missing the required \"auth_groups\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("PUT", fmt.Sprintf("/auth/groups/%s", url.PathEscape(name)), group, ETag)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteAuthGroup deletes the local RBAC group with the given name
+func (r *ProtocolLXD) DeleteAuthGroup(name string) error {
+	if !r.HasExtension("auth_groups") {
+		return fmt.Errorf("The server is missing the required \"auth_groups\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("DELETE", fmt.Sprintf("/auth/groups/%s", url.PathEscape(name)), nil, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -58,6 +58,14 @@ func (r *ProtocolLXD) GetCertificate(fingerprint string) (*api.Certificate, stri
 
 // CreateCertificate adds a new certificate to the LXD trust store
 func (r *ProtocolLXD) CreateCertificate(certificate api.CertificatesPost) error {
+	if (certificate.Restricted || len(certificate.Projects) > 0) && !r.HasExtension("certificate_project") {
+		return fmt.Errorf("The server is missing the required \"certificate_project\" API extension")
+	}
+
+	if certificate.Type == "metrics" && !r.HasExtension("metrics") {
+		return fmt.Errorf("The server is missing the required \"metrics\" API extension")
+	}
+
 	// Send the request
 	_, _, err := r.query("POST", "/certificates", certificate, "")
 	if err != nil {
@@ -73,6 +81,10 @@ func (r *ProtocolLXD) UpdateCertificate(fingerprint string, certificate api.Cert
 		return fmt.Errorf("The server is missing the required \"certificate_update\" API extension")
 	}
 
+	if (certificate.Restricted || len(certificate.Projects) > 0) && !r.HasExtension("certificate_project") {
+		return fmt.Errorf("The server is missing the required \"certificate_project\" API extension")
+	}
+
 	// Send the request
 	_, _, err := r.query("PUT", fmt.Sprintf("/certificates/%s", url.PathEscape(fingerprint)), certificate, ETag)
 	if err != nil {
@@ -35,6 +35,12 @@ func (r *ProtocolLXD) UpdateCluster(cluster api.ClusterPut, ETag string) (Operat
 		}
 	}
 
+	if cluster.ClusterToken != "" {
+		if !r.HasExtension("clustering_join_token") {
+			return nil, fmt.Errorf("The server is missing the required \"clustering_join_token\" API extension")
+		}
+	}
+
 	op, _, err := r.queryOperation("PUT", "/cluster", cluster, "")
 	if err != nil {
 		return nil, err
@@ -43,6 +49,20 @@ func (r *ProtocolLXD) UpdateCluster(cluster api.ClusterPut, ETag string) (Operat
 	return op, nil
 }
 
+// UpdateClusterCertificate updates the cluster certificate for every node in the cluster
+func (r *ProtocolLXD) UpdateClusterCertificate(certs api.ClusterCertificatePut, ETag string) error {
+	if !r.HasExtension("clustering_update_cert") {
+		return fmt.Errorf("The server is missing the required \"clustering_update_cert\" API extension")
+	}
+
+	_, _, err := r.query("PUT", "/cluster/certificate", certs, ETag)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // DeleteClusterMember makes the given member leave the cluster (gracefully or not,
 // depending on the force flag)
 func (r *ProtocolLXD) DeleteClusterMember(name string, force bool) error {
@@ -63,6 +83,21 @@ func (r *ProtocolLXD) DeleteClusterMember(name string, force bool) error {
 	return nil
 }
 
+// CreateClusterMember generates a join token for a new member of the cluster
+func (r *ProtocolLXD) CreateClusterMember(member api.ClusterMembersPost) (*api.ClusterMemberJoinToken, error) {
+	if !r.HasExtension("clustering_join_token") {
+		return nil, fmt.Errorf("The server is missing the required \"clustering_join_token\" API extension")
+	}
+
+	token := &api.ClusterMemberJoinToken{}
+	_, err := r.queryStruct("POST", "/cluster/members", member, "", &token)
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
 // GetClusterMemberNames returns the URLs of the current members in the cluster
 func (r *ProtocolLXD) GetClusterMemberNames() ([]string, error) {
 	if !r.HasExtension("clustering") {
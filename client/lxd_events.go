@@ -2,6 +2,9 @@ package lxd
 
 import (
 	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/lxc/lxd/shared"
@@ -12,6 +15,38 @@ import (
 
 // GetEvents connects to the LXD monitoring interface
 func (r *ProtocolLXD) GetEvents() (*EventListener, error) {
+	return r.getEvents("/events", true)
+}
+
+// GetEventsSince connects to the LXD monitoring interface, first replaying
+// the recent events which follow the given position in the events of the
+// cluster member they originate from. Positions are given as
+// <epoch>:<sequence>, from the epoch and sequence of the last event received
+// from each member, and are rejected if the member restarted since.
+//
+// This always requires a new connection to the monitoring interface, so it
+// fails if there are other event listeners on this client.
+func (r *ProtocolLXD) GetEventsSince(since map[string]string) (*EventListener, error) {
+	if !r.HasExtension("event_sequence") {
+		return nil, fmt.Errorf("The server is missing the required \"event_sequence\" API extension")
+	}
+
+	// Sort the members to get a stable URL.
+	names := make([]string, 0, len(since))
+	for name := range since {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := make([]string, len(names))
+	for i, name := range names {
+		fields[i] = fmt.Sprintf("%s:%s", name, since[name])
+	}
+
+	return r.getEvents(fmt.Sprintf("/events?since=%s", strings.Join(fields, ",")), false)
+}
+
+func (r *ProtocolLXD) getEvents(path string, reuse bool) (*EventListener, error) {
 	// Prevent anything else from interacting with the listeners
 	r.eventListenersLock.Lock()
 	defer r.eventListenersLock.Unlock()
@@ -23,13 +58,17 @@ func (r *ProtocolLXD) GetEvents() (*EventListener, error) {
 	}
 
 	if r.eventListeners != nil {
+		if !reuse {
+			return nil, fmt.Errorf("Can't replay events on an existing events connection")
+		}
+
 		// There is an existing Go routine setup, so just add another target
 		r.eventListeners = append(r.eventListeners, &listener)
 		return &listener, nil
 	}
 
 	// Setup a new connection with LXD
-	url, err := r.setQueryAttributes("/events")
+	url, err := r.setQueryAttributes(path)
 	if err != nil {
 		return nil, err
 	}
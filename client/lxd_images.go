@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"os/exec"
 	"strings"
 
 	"github.com/lxc/lxd/shared"
@@ -33,6 +34,22 @@ func (r *ProtocolLXD) GetImages() ([]api.Image, error) {
 	return images, nil
 }
 
+// GetImagesWithArgs returns the images matching the given filter, along with
+// the token of the next page if any.
+func (r *ProtocolLXD) GetImagesWithArgs(args ListArgs) ([]api.Image, string, error) {
+	images := []api.Image{}
+
+	v := url.Values{}
+	v.Set("recursion", "1")
+
+	next, err := r.queryList("/images", v, args, &images)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return images, next, nil
+}
+
 // GetImageFingerprints returns a list of available image fingerprints
 func (r *ProtocolLXD) GetImageFingerprints() ([]string, error) {
 	urls := []string{}
@@ -140,9 +157,102 @@ func (r *ProtocolLXD) GetPrivateImageFile(fingerprint string, secret string, req
 		}
 	}
 
+	// Attempt to download a delta from an image we already have
+	if secret == "" && req.DeltaSourceRetriever != nil && req.MetaFile != nil && req.RootfsFile != nil && r.HasExtension("image_deltas") {
+		resp, err := r.getImageFileDelta(fingerprint, uri, req)
+		if err == nil {
+			return resp, nil
+		}
+
+		// Rewind the targets for the full download
+		_, err = req.MetaFile.Seek(0, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = req.RootfsFile.Seek(0, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return lxdDownloadImage(fingerprint, uri, r.httpUserAgent, r.http, req)
 }
 
+// getImageFileDelta downloads the metadata of a split image along with the
+// delta to its rootfs from a locally available image, and applies it.
+func (r *ProtocolLXD) getImageFileDelta(fingerprint string, uri string, req ImageFileRequest) (*ImageFileResponse, error) {
+	// Applying deltas requires xdelta3
+	_, err := exec.LookPath("xdelta3")
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the available deltas
+	sources := []string{}
+	_, err = r.queryStruct("GET", fmt.Sprintf("/images/%s/export?deltas=true", url.PathEscape(fingerprint)), nil, "", &sources)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, source := range sources {
+		srcPath := req.DeltaSourceRetriever(source, "rootfs")
+		if srcPath == "" {
+			continue
+		}
+
+		deltaURI, err := setQueryParam(uri, "delta", source)
+		if err != nil {
+			return nil, err
+		}
+
+		// Download the metadata and the delta
+		deltaFile, err := ioutil.TempFile("", "lxc_image_")
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(deltaFile.Name())
+		defer deltaFile.Close()
+
+		sha256 := sha256.New()
+		resp, err := lxdDownloadImageDelta(deltaURI, r.httpUserAgent, r.http, req, io.MultiWriter(req.MetaFile, sha256), deltaFile)
+		if err != nil {
+			return nil, err
+		}
+
+		// Apply it
+		patchedFile, err := ioutil.TempFile("", "lxc_image_")
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(patchedFile.Name())
+		defer patchedFile.Close()
+
+		_, err = shared.RunCommand("xdelta3", "-f", "-d", "-s", srcPath, deltaFile.Name(), patchedFile.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		// Copy to the target
+		size, err := io.Copy(io.MultiWriter(req.RootfsFile, sha256), patchedFile)
+		if err != nil {
+			return nil, err
+		}
+		resp.RootfsSize = size
+		resp.RootfsName = fingerprint
+
+		// Check the hash
+		hash := fmt.Sprintf("%x", sha256.Sum(nil))
+		if !strings.HasPrefix(hash, fingerprint) {
+			return nil, fmt.Errorf("Image fingerprint doesn't match. Got %s expected %s", hash, fingerprint)
+		}
+
+		return resp, nil
+	}
+
+	return nil, fmt.Errorf("No usable image delta")
+}
+
 func lxdDownloadImage(fingerprint string, uri string, userAgent string, client *http.Client, req ImageFileRequest) (*ImageFileResponse, error) {
 	// Prepare the response
 	resp := ImageFileResponse{}
@@ -273,6 +383,186 @@ func lxdDownloadImage(fingerprint string, uri string, userAgent string, client *
 	return &resp, nil
 }
 
+func lxdDownloadImageDelta(uri string, userAgent string, client *http.Client, req ImageFileRequest, metaFile io.Writer, deltaFile io.Writer) (*ImageFileResponse, error) {
+	resp := ImageFileResponse{}
+
+	// Prepare the download request
+	request, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if userAgent != "" {
+		request.Header.Set("User-Agent", userAgent)
+	}
+
+	// Start the request
+	response, doneCh, err := cancel.CancelableDownload(req.Canceler, client, request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	defer close(doneCh)
+
+	if response.StatusCode != http.StatusOK {
+		_, _, err := lxdParseResponse(response)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ctype, ctypeParams, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	if err != nil || ctype != "multipart/form-data" {
+		return nil, fmt.Errorf("Invalid image delta")
+	}
+
+	// Handle the data
+	body := response.Body
+	if req.ProgressHandler != nil {
+		body = &ioprogress.ProgressReader{
+			ReadCloser: response.Body,
+			Tracker: &ioprogress.ProgressTracker{
+				Length: response.ContentLength,
+				Handler: func(percent int64, speed int64) {
+					req.ProgressHandler(ioprogress.ProgressData{Text: fmt.Sprintf("%d%% (%s/s)", percent, units.GetByteSizeString(speed, 2))})
+				},
+			},
+		}
+	}
+
+	mr := multipart.NewReader(body, ctypeParams["boundary"])
+
+	// Get the metadata tarball
+	part, err := mr.NextPart()
+	if err != nil {
+		return nil, err
+	}
+
+	if part.FormName() != "metadata" {
+		return nil, fmt.Errorf("Invalid image delta")
+	}
+
+	size, err := io.Copy(metaFile, part)
+	if err != nil {
+		return nil, err
+	}
+	resp.MetaSize = size
+	resp.MetaName = part.FileName()
+
+	// Get the rootfs delta
+	part, err = mr.NextPart()
+	if err != nil {
+		return nil, err
+	}
+
+	if part.FormName() != "rootfs.delta" {
+		return nil, fmt.Errorf("Invalid image delta")
+	}
+
+	_, err = io.Copy(deltaFile, part)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// GetImageSignatures returns the detached signatures of the image files, if any
+func (r *ProtocolLXD) GetImageSignatures(fingerprint string, secret string) (*ImageSignatures, error) {
+	signatures := ImageSignatures{}
+
+	// Older servers don't sign images
+	if !r.HasExtension("image_signatures") {
+		return &signatures, nil
+	}
+
+	uri := fmt.Sprintf("/1.0/images/%s/export?signature=true", url.PathEscape(fingerprint))
+
+	var err error
+	uri, err = r.setQueryAttributes(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build the URL
+	uri = fmt.Sprintf("%s%s", r.httpHost, uri)
+	if secret != "" {
+		uri, err = setQueryParam(uri, "secret", secret)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	request, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.httpUserAgent != "" {
+		request.Header.Set("User-Agent", r.httpUserAgent)
+	}
+
+	response, err := r.do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	// Unsigned image
+	if response.StatusCode == http.StatusNotFound {
+		return &signatures, nil
+	}
+
+	if response.StatusCode != http.StatusOK {
+		_, _, err := lxdParseResponse(response)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ctype, ctypeParams, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	if err != nil {
+		ctype = "application/octet-stream"
+	}
+
+	// Unified images only have one signature
+	if ctype != "multipart/form-data" {
+		signatures.Meta, err = ioutil.ReadAll(response.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		return &signatures, nil
+	}
+
+	mr := multipart.NewReader(response.Body, ctypeParams["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := ioutil.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+
+		switch part.FormName() {
+		case "metadata":
+			signatures.Meta = content
+		case "rootfs":
+			signatures.Rootfs = content
+		default:
+			return nil, fmt.Errorf("Invalid multipart image signature")
+		}
+	}
+
+	return &signatures, nil
+}
+
 // GetImageAliases returns the list of available aliases as ImageAliasesEntry structs
 func (r *ProtocolLXD) GetImageAliases() ([]api.ImageAliasesEntry, error) {
 	aliases := []api.ImageAliasesEntry{}
@@ -341,6 +631,12 @@ func (r *ProtocolLXD) CreateImage(image api.ImagesPost, args *ImageCreateArgs) (
 		}
 	}
 
+	if !image.CreatedAt.IsZero() {
+		if !r.HasExtension("image_reproducible_publish") {
+			return nil, fmt.Errorf("The server is missing the required \"image_reproducible_publish\" API extension")
+		}
+	}
+
 	// Send the JSON based request
 	if args == nil {
 		op, _, err := r.queryOperation("POST", "/images", image, "")
@@ -356,6 +652,28 @@ func (r *ProtocolLXD) CreateImage(image api.ImagesPost, args *ImageCreateArgs) (
 		return nil, fmt.Errorf("Metadata file is required")
 	}
 
+	ociImage := image.Source != nil && image.Source.Type == "oci"
+	if ociImage {
+		if !r.HasExtension("image_import_oci") {
+			return nil, fmt.Errorf("The server is missing the required \"image_import_oci\" API extension")
+		}
+
+		if args.RootfsFile != nil {
+			return nil, fmt.Errorf("OCI images must be a single tarball")
+		}
+	}
+
+	diskImage := image.Source != nil && image.Source.Type == "disk"
+	if diskImage {
+		if !r.HasExtension("image_import_disk") {
+			return nil, fmt.Errorf("The server is missing the required \"image_import_disk\" API extension")
+		}
+
+		if args.RootfsFile != nil {
+			return nil, fmt.Errorf("Disk images must be a single file")
+		}
+	}
+
 	// Prepare the body
 	var body io.Reader
 	var contentType string
@@ -454,6 +772,14 @@ func (r *ProtocolLXD) CreateImage(image api.ImagesPost, args *ImageCreateArgs) (
 		req.Header.Set("X-LXD-filename", image.Filename)
 	}
 
+	if ociImage {
+		req.Header.Set("X-LXD-source-type", "oci")
+	}
+
+	if diskImage {
+		req.Header.Set("X-LXD-source-type", "disk")
+	}
+
 	if len(image.Properties) > 0 {
 		imgProps := url.Values{}
 
@@ -608,6 +934,14 @@ func (r *ProtocolLXD) CopyImage(source ImageServer, image api.Image, args *Image
 
 	if args != nil {
 		req.Source.ImageType = args.Type
+
+		if args.RequireSignature {
+			if !r.HasExtension("image_signatures") {
+				return nil, fmt.Errorf("The server is missing the required \"image_signatures\" API extension")
+			}
+
+			req.Source.RequireSignature = true
+		}
 	}
 
 	// Generate secret token if needed
@@ -659,6 +993,26 @@ func (r *ProtocolLXD) DeleteImage(fingerprint string) (Operation, error) {
 	return op, nil
 }
 
+// DeleteImageWithArgs requests that LXD removes an image from the store, with
+// additional options
+func (r *ProtocolLXD) DeleteImageWithArgs(fingerprint string, args *ImageDeleteArgs) (Operation, error) {
+	if args == nil || !args.Force {
+		return r.DeleteImage(fingerprint)
+	}
+
+	if !r.HasExtension("image_used_by") {
+		return nil, fmt.Errorf("The server is missing the required \"image_used_by\" API extension")
+	}
+
+	// Send the request
+	op, _, err := r.queryOperation("DELETE", fmt.Sprintf("/images/%s?force=1", url.PathEscape(fingerprint)), nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
 // RefreshImage requests that LXD issues an image refresh
 func (r *ProtocolLXD) RefreshImage(fingerprint string) (Operation, error) {
 	if !r.HasExtension("image_force_refresh") {
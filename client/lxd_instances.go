@@ -115,6 +115,49 @@ func (r *ProtocolLXD) GetInstancesFull(instanceType api.InstanceType) ([]api.Ins
 	return instances, nil
 }
 
+// GetInstancesWithArgs returns the instances matching the given filter,
+// along with the token of the next page if any.
+func (r *ProtocolLXD) GetInstancesWithArgs(instanceType api.InstanceType, args ListArgs) ([]api.Instance, string, error) {
+	instances := []api.Instance{}
+
+	path, v, err := r.instanceTypeToPath(instanceType)
+	if err != nil {
+		return nil, "", err
+	}
+
+	v.Set("recursion", "1")
+
+	// Fetch the raw value
+	next, err := r.queryList(path, v, args, &instances)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return instances, next, nil
+}
+
+// GetInstancesFullWithArgs returns the instances matching the given filter,
+// including snapshots, backups and state, along with the token of the next
+// page if any.
+func (r *ProtocolLXD) GetInstancesFullWithArgs(instanceType api.InstanceType, args ListArgs) ([]api.InstanceFull, string, error) {
+	instances := []api.InstanceFull{}
+
+	path, v, err := r.instanceTypeToPath(instanceType)
+	if err != nil {
+		return nil, "", err
+	}
+
+	v.Set("recursion", "2")
+
+	// Fetch the raw value
+	next, err := r.queryList(path, v, args, &instances)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return instances, next, nil
+}
+
 // GetInstance returns the instance entry for the provided name.
 func (r *ProtocolLXD) GetInstance(name string) (*api.Instance, string, error) {
 	instance := api.Instance{}
@@ -220,6 +263,12 @@ func (r *ProtocolLXD) CreateInstance(instance api.InstancesPost) (Operation, err
 		}
 	}
 
+	if instance.Source.Type == "disk" {
+		if !r.HasExtension("image_import_disk") {
+			return nil, fmt.Errorf("The server is missing the required \"image_import_disk\" API extension")
+		}
+	}
+
 	// Send the request
 	op, _, err := r.queryOperation("POST", path, instance, "")
 	if err != nil {
@@ -316,6 +365,10 @@ func (r *ProtocolLXD) CreateInstanceFromImage(source ImageServer, image api.Imag
 	// Minimal source fields for remote image
 	req.Source.Mode = "pull"
 
+	if req.Source.RequireSignature && !r.HasExtension("image_signatures") {
+		return nil, fmt.Errorf("The server is missing the required \"image_signatures\" API extension")
+	}
+
 	// If we have an alias and the image is public, use that
 	if req.Source.Alias != "" && image.Public {
 		req.Source.Fingerprint = ""
@@ -106,6 +106,10 @@ func (r *ProtocolLXD) CreateNetwork(network api.NetworksPost) error {
 		return fmt.Errorf("The server is missing the required \"network\" API extension")
 	}
 
+	if len(network.Members) > 0 && !r.HasExtension("clustering_member_config") {
+		return fmt.Errorf("The server is missing the required \"clustering_member_config\" API extension")
+	}
+
 	// Send the request
 	_, _, err := r.query("POST", "/networks", network, "")
 	if err != nil {
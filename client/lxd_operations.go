@@ -51,6 +51,57 @@ func (r *ProtocolLXD) GetOperations() ([]api.Operation, error) {
 	return operations, nil
 }
 
+// GetOperationsAllHistory returns a list of Operation struct, including the
+// completed operations recorded by the server
+func (r *ProtocolLXD) GetOperationsAllHistory() ([]api.Operation, error) {
+	if !r.HasExtension("operations_history") {
+		return nil, fmt.Errorf("The server is missing the required \"operations_history\" API extension")
+	}
+
+	apiOperations := map[string][]api.Operation{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", "/operations?recursion=1&all-history=true", nil, "", &apiOperations)
+	if err != nil {
+		return nil, err
+	}
+
+	// Turn it into just a list of operations
+	operations := []api.Operation{}
+	for _, v := range apiOperations {
+		for _, operation := range v {
+			operations = append(operations, operation)
+		}
+	}
+
+	return operations, nil
+}
+
+// GetOperationsWithArgs returns the operations matching the given filter,
+// along with the token of the next page if any.
+func (r *ProtocolLXD) GetOperationsWithArgs(args ListArgs) ([]api.Operation, string, error) {
+	apiOperations := map[string][]api.Operation{}
+
+	v := url.Values{}
+	v.Set("recursion", "1")
+
+	// Fetch the raw value
+	next, err := r.queryList("/operations", v, args, &apiOperations)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Turn it into just a list of operations
+	operations := []api.Operation{}
+	for _, v := range apiOperations {
+		for _, operation := range v {
+			operations = append(operations, operation)
+		}
+	}
+
+	return operations, next, nil
+}
+
 // GetOperation returns an Operation entry for the provided uuid
 func (r *ProtocolLXD) GetOperation(uuid string) (*api.Operation, string, error) {
 	op := api.Operation{}
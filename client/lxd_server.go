@@ -2,6 +2,8 @@ package lxd
 
 import (
 	"fmt"
+	"io/ioutil"
+	"net/http"
 
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
@@ -101,6 +103,8 @@ func (r *ProtocolLXD) UseProject(name string) InstanceServer {
 		bakeryClient:         r.bakeryClient,
 		bakeryInteractor:     r.bakeryInteractor,
 		requireAuthenticated: r.requireAuthenticated,
+		oidcClient:           r.oidcClient,
+		authToken:            r.authToken,
 		clusterTarget:        r.clusterTarget,
 		project:              name,
 	}
@@ -120,6 +124,8 @@ func (r *ProtocolLXD) UseTarget(name string) InstanceServer {
 		bakeryClient:         r.bakeryClient,
 		bakeryInteractor:     r.bakeryInteractor,
 		requireAuthenticated: r.requireAuthenticated,
+		oidcClient:           r.oidcClient,
+		authToken:            r.authToken,
 		project:              r.project,
 		clusterTarget:        name,
 	}
@@ -129,3 +135,61 @@ func (r *ProtocolLXD) UseTarget(name string) InstanceServer {
 func (r *ProtocolLXD) IsAgent() bool {
 	return r.server != nil && r.server.Environment.Server == "lxd-agent"
 }
+
+// GetAuditEntries returns the recent entries of the audit log
+func (r *ProtocolLXD) GetAuditEntries() ([]api.AuditEntry, error) {
+	if !r.HasExtension("audit") {
+		return nil, fmt.Errorf("The server is missing the required \"audit\" API extension")
+	}
+
+	entries := []api.AuditEntry{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", "/audit", nil, "", &entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// GetMetrics returns the text OpenMetrics data of the server
+func (r *ProtocolLXD) GetMetrics() (string, error) {
+	if !r.HasExtension("metrics") {
+		return "", fmt.Errorf("The server is missing the required \"metrics\" API extension")
+	}
+
+	// Prepare the request
+	requestURL, err := r.setQueryAttributes(fmt.Sprintf("%s/1.0/metrics", r.httpHost))
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest("GET", requestURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	// Send the request
+	resp, err := r.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _, err := lxdParseResponse(resp)
+		if err != nil {
+			return "", err
+		}
+
+		return "", fmt.Errorf("Bad HTTP status: %d", resp.StatusCode)
+	}
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
@@ -78,6 +78,10 @@ func (r *ProtocolLXD) CreateStoragePool(pool api.StoragePoolsPost) error {
 		return fmt.Errorf("The server is missing the required \"storage_driver_ceph\" API extension")
 	}
 
+	if len(pool.Members) > 0 && !r.HasExtension("clustering_member_config") {
+		return fmt.Errorf("The server is missing the required \"clustering_member_config\" API extension")
+	}
+
 	// Send the request
 	_, _, err := r.query("POST", "/storage-pools", pool, "")
 	if err != nil {
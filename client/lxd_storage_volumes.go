@@ -51,6 +51,23 @@ func (r *ProtocolLXD) GetStoragePoolVolumes(pool string) ([]api.StorageVolume, e
 	return volumes, nil
 }
 
+// GetStoragePoolVolumesWithArgs returns the storage volumes matching the
+// given filter, along with the token of the next page if any.
+func (r *ProtocolLXD) GetStoragePoolVolumesWithArgs(pool string, args ListArgs) ([]api.StorageVolume, string, error) {
+	volumes := []api.StorageVolume{}
+
+	v := url.Values{}
+	v.Set("recursion", "1")
+
+	// Fetch the raw value
+	next, err := r.queryList(fmt.Sprintf("/storage-pools/%s/volumes", url.PathEscape(pool)), v, args, &volumes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return volumes, next, nil
+}
+
 // GetStoragePoolVolume returns a StorageVolume entry for the provided pool and volume name
 func (r *ProtocolLXD) GetStoragePoolVolume(pool string, volType string, name string) (*api.StorageVolume, string, error) {
 	if !r.HasExtension("storage") {
@@ -0,0 +1,114 @@
+package lxd
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// Webhook handling functions
+
+// GetWebhookNames returns a list of webhook names
+func (r *ProtocolLXD) GetWebhookNames() ([]string, error) {
+	if !r.HasExtension("webhooks") {
+		return nil, fmt.Errorf("The server is missing the required \"webhooks\" API extension")
+	}
+
+	urls := []string{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", "/webhooks", nil, "", &urls)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse it
+	names := []string{}
+	for _, url := range urls {
+		fields := strings.Split(url, "/webhooks/")
+		names = append(names, fields[len(fields)-1])
+	}
+
+	return names, nil
+}
+
+// GetWebhooks returns a list of webhooks
+func (r *ProtocolLXD) GetWebhooks() ([]api.Webhook, error) {
+	if !r.HasExtension("webhooks") {
+		return nil, fmt.Errorf("The server is missing the required \"webhooks\" API extension")
+	}
+
+	webhooks := []api.Webhook{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", "/webhooks?recursion=1", nil, "", &webhooks)
+	if err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// GetWebhook returns the webhook with the given name
+func (r *ProtocolLXD) GetWebhook(name string) (*api.Webhook, string, error) {
+	if !r.HasExtension("webhooks") {
+		return nil, "", fmt.Errorf("The server is missing the required \"webhooks\" API extension")
+	}
+
+	webhook := api.Webhook{}
+
+	// Fetch the raw value
+	etag, err := r.queryStruct("GET", fmt.Sprintf("/webhooks/%s", url.PathEscape(name)), nil, "", &webhook)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &webhook, etag, nil
+}
+
+// CreateWebhook defines a new webhook
+func (r *ProtocolLXD) CreateWebhook(webhook api.WebhooksPost) error {
+	if !r.HasExtension("webhooks") {
+		return fmt.Errorf("The server is missing the required \"webhooks\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("POST", "/webhooks", webhook, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateWebhook updates the webhook to match the provided struct
+func (r *ProtocolLXD) UpdateWebhook(name string, webhook api.WebhookPut, ETag string) error {
+	if !r.HasExtension("webhooks") {
+		return fmt.Errorf("The server is missing the required \"webhooks\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("PUT", fmt.Sprintf("/webhooks/%s", url.PathEscape(name)), webhook, ETag)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteWebhook deletes the webhook with the given name
+func (r *ProtocolLXD) DeleteWebhook(name string) error {
+	if !r.HasExtension("webhooks") {
+		return fmt.Errorf("The server is missing the required \"webhooks\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("DELETE", fmt.Sprintf("/webhooks/%s", url.PathEscape(name)), nil, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,311 @@
+package lxd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OIDCTokens represents the tokens obtained from an OpenID Connect provider
+type OIDCTokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+type oidcProvider struct {
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+	TokenEndpoint               string `json:"token_endpoint"`
+}
+
+type oidcTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	Error        string `json:"error"`
+}
+
+// oidcClient obtains, refreshes and stores the access tokens sent to the
+// server, logging in with the device authorization flow when needed.
+type oidcClient struct {
+	http       *http.Client
+	tokensFile string
+	prompt     func(verificationURL string, userCode string)
+
+	mu     sync.Mutex
+	tokens *OIDCTokens
+}
+
+func newOIDCClient(tokensFile string, prompt func(string, string), proxy func(*http.Request) (*neturl.URL, error)) *oidcClient {
+	if proxy == nil {
+		proxy = http.ProxyFromEnvironment
+	}
+
+	o := &oidcClient{
+		// The provider isn't reached with the TLS settings of the server.
+		http:       &http.Client{Transport: &http.Transport{Proxy: proxy}, Timeout: 30 * time.Second},
+		tokensFile: tokensFile,
+		prompt:     prompt,
+	}
+
+	if tokensFile != "" {
+		content, err := ioutil.ReadFile(tokensFile)
+		if err == nil {
+			tokens := OIDCTokens{}
+			if json.Unmarshal(content, &tokens) == nil {
+				o.tokens = &tokens
+			}
+		}
+	}
+
+	return o
+}
+
+func (o *oidcClient) accessToken() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.tokens == nil {
+		return ""
+	}
+
+	return o.tokens.AccessToken
+}
+
+// Send the request with the current access token, getting a new one and
+// retrying once if the server rejected it.
+func (o *oidcClient) do(client *http.Client, req *http.Request) (*http.Response, error) {
+	token := o.accessToken()
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	issuer := resp.Header.Get("X-LXD-OIDC-issuer")
+	if issuer == "" || (resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden) {
+		return resp, nil
+	}
+
+	resp.Body.Close()
+
+	err = o.authenticate(token, issuer, resp.Header.Get("X-LXD-OIDC-clientid"), resp.Header.Get("X-LXD-OIDC-audience"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Rewind the body for the retry
+	if req.Body != nil {
+		if req.GetBody == nil {
+			return nil, fmt.Errorf("OIDC authentication completed, please retry the request")
+		}
+
+		req.Body, err = req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req.Header.Set("Authorization", "Bearer "+o.accessToken())
+
+	return client.Do(req)
+}
+
+// Get a new access token, replacing the given rejected one.
+func (o *oidcClient) authenticate(rejected string, issuer string, clientID string, audience string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	// Another request already replaced the token.
+	if o.tokens != nil && o.tokens.AccessToken != rejected {
+		return nil
+	}
+
+	provider := oidcProvider{}
+	err := o.getJSON(strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", &provider)
+	if err != nil {
+		return fmt.Errorf("Failed to fetch OIDC provider configuration: %v", err)
+	}
+
+	// Fallback to a new login if the token can't be refreshed.
+	var tokens *OIDCTokens
+	if o.tokens != nil && o.tokens.RefreshToken != "" {
+		tokens, _ = o.refresh(provider, clientID)
+	}
+
+	if tokens == nil {
+		tokens, err = o.deviceLogin(provider, clientID, audience)
+		if err != nil {
+			return err
+		}
+	}
+
+	o.tokens = tokens
+
+	return o.save()
+}
+
+func (o *oidcClient) refresh(provider oidcProvider, clientID string) (*OIDCTokens, error) {
+	token := oidcTokenResponse{}
+	err := o.postForm(provider.TokenEndpoint, neturl.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {o.tokens.RefreshToken},
+		"client_id":     {clientID},
+	}, &token)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("Failed to refresh OIDC token: %s", token.Error)
+	}
+
+	tokens := &OIDCTokens{AccessToken: token.AccessToken, RefreshToken: token.RefreshToken}
+	if tokens.RefreshToken == "" {
+		tokens.RefreshToken = o.tokens.RefreshToken
+	}
+
+	return tokens, nil
+}
+
+// Log in with the OAuth 2.0 device authorization grant (RFC 8628).
+func (o *oidcClient) deviceLogin(provider oidcProvider, clientID string, audience string) (*OIDCTokens, error) {
+	if o.prompt == nil {
+		return nil, fmt.Errorf("OIDC login required but no way to prompt the user")
+	}
+
+	if provider.DeviceAuthorizationEndpoint == "" {
+		return nil, fmt.Errorf("The OIDC provider doesn't support the device authorization flow")
+	}
+
+	values := neturl.Values{
+		"client_id": {clientID},
+		"scope":     {"openid offline_access"},
+	}
+
+	if audience != "" {
+		values.Set("audience", audience)
+	}
+
+	device := struct {
+		DeviceCode              string `json:"device_code"`
+		UserCode                string `json:"user_code"`
+		VerificationURI         string `json:"verification_uri"`
+		VerificationURIComplete string `json:"verification_uri_complete"`
+		ExpiresIn               int    `json:"expires_in"`
+		Interval                int    `json:"interval"`
+	}{}
+
+	err := o.postForm(provider.DeviceAuthorizationEndpoint, values, &device)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to start OIDC device login: %v", err)
+	}
+
+	if device.DeviceCode == "" {
+		return nil, fmt.Errorf("Failed to start OIDC device login: no device code returned")
+	}
+
+	verificationURL := device.VerificationURIComplete
+	if verificationURL == "" {
+		verificationURL = device.VerificationURI
+	}
+
+	o.prompt(verificationURL, device.UserCode)
+
+	interval := time.Duration(device.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	expiry := time.Now().Add(time.Duration(device.ExpiresIn) * time.Second)
+	if device.ExpiresIn <= 0 {
+		expiry = time.Now().Add(5 * time.Minute)
+	}
+
+	for time.Now().Before(expiry) {
+		time.Sleep(interval)
+
+		token := oidcTokenResponse{}
+		err := o.postForm(provider.TokenEndpoint, neturl.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"device_code": {device.DeviceCode},
+			"client_id":   {clientID},
+		}, &token)
+		if err != nil {
+			return nil, err
+		}
+
+		switch token.Error {
+		case "":
+			if token.AccessToken == "" {
+				return nil, fmt.Errorf("No access token returned by the OIDC provider")
+			}
+
+			return &OIDCTokens{AccessToken: token.AccessToken, RefreshToken: token.RefreshToken}, nil
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += 5 * time.Second
+		default:
+			return nil, fmt.Errorf("OIDC device login failed: %s", token.Error)
+		}
+	}
+
+	return nil, fmt.Errorf("OIDC device login timed out")
+}
+
+func (o *oidcClient) getJSON(url string, target interface{}) error {
+	resp, err := o.http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Bad HTTP status: %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+// Post the form and decode the JSON reply. Error replies of the token
+// endpoint are decoded too, as they carry the state of the device login.
+func (o *oidcClient) postForm(url string, values neturl.Values, target interface{}) error {
+	resp, err := o.http.PostForm(url, values)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest && resp.StatusCode != http.StatusUnauthorized {
+		return fmt.Errorf("Bad HTTP status: %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+func (o *oidcClient) save() error {
+	if o.tokensFile == "" {
+		return nil
+	}
+
+	content, err := json.Marshal(o.tokens)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(o.tokensFile), 0700)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(o.tokensFile, content, 0600)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
@@ -190,6 +191,65 @@ func (r *ProtocolSimpleStreams) GetImageFile(fingerprint string, req ImageFileRe
 	return &resp, nil
 }
 
+// GetImageSignatures returns the detached signatures of the image files, if any
+func (r *ProtocolSimpleStreams) GetImageSignatures(fingerprint string, secret string) (*ImageSignatures, error) {
+	// Get the file list
+	files, err := r.ssClient.GetFiles(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	// Signatures are stored alongside the files they sign
+	download := func(path string) ([]byte, error) {
+		uri := fmt.Sprintf("%s/%s.sig", r.httpHost, path)
+
+		request, err := http.NewRequest("GET", uri, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if r.httpUserAgent != "" {
+			request.Header.Set("User-Agent", r.httpUserAgent)
+		}
+
+		response, err := r.http.Do(request)
+		if err != nil {
+			return nil, err
+		}
+		defer response.Body.Close()
+
+		if response.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+
+		if response.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("Unable to fetch %s: %s", uri, response.Status)
+		}
+
+		return ioutil.ReadAll(response.Body)
+	}
+
+	signatures := ImageSignatures{}
+
+	meta, ok := files["meta"]
+	if ok {
+		signatures.Meta, err = download(meta.Path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rootfs, ok := files["root"]
+	if ok {
+		signatures.Rootfs, err = download(rootfs.Path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &signatures, nil
+}
+
 // GetImageSecret isn't relevant for the simplestreams protocol
 func (r *ProtocolSimpleStreams) GetImageSecret(fingerprint string) (string, error) {
 	return "", fmt.Errorf("Private images aren't supported by the simplestreams protocol")
@@ -0,0 +1,1002 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+	"github.com/lxc/lxd/shared/termios"
+)
+
+type cmdAuth struct {
+	global *cmdGlobal
+}
+
+func (c *cmdAuth) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("auth")
+	cmd.Short = i18n.G("Manage authentication")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage authentication`))
+
+	// Group
+	authGroupCmd := cmdAuthGroup{global: c.global, auth: c}
+	cmd.AddCommand(authGroupCmd.Command())
+
+	// Token
+	authTokenCmd := cmdAuthToken{global: c.global, auth: c}
+	cmd.AddCommand(authTokenCmd.Command())
+
+	return cmd
+}
+
+// Group
+type cmdAuthGroup struct {
+	global *cmdGlobal
+	auth   *cmdAuth
+}
+
+func (c *cmdAuthGroup) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("group")
+	cmd.Short = i18n.G("Manage RBAC groups")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage the groups of the built-in RBAC backend
+
+The built-in RBAC backend is enabled through the rbac.local server
+configuration key. Members of a group are identified by their certificate
+fingerprint or username.
+
+The role is one of:
+ - viewer: Read-only access
+ - operator: Manage instances, images and profiles
+ - admin: Full access`))
+
+	// Add identity
+	authGroupAddIdentityCmd := cmdAuthGroupAddIdentity{global: c.global, authGroup: c}
+	cmd.AddCommand(authGroupAddIdentityCmd.Command())
+
+	// Create
+	authGroupCreateCmd := cmdAuthGroupCreate{global: c.global, authGroup: c}
+	cmd.AddCommand(authGroupCreateCmd.Command())
+
+	// Delete
+	authGroupDeleteCmd := cmdAuthGroupDelete{global: c.global, authGroup: c}
+	cmd.AddCommand(authGroupDeleteCmd.Command())
+
+	// Edit
+	authGroupEditCmd := cmdAuthGroupEdit{global: c.global, authGroup: c}
+	cmd.AddCommand(authGroupEditCmd.Command())
+
+	// Grant
+	authGroupGrantCmd := cmdAuthGroupGrant{global: c.global, authGroup: c}
+	cmd.AddCommand(authGroupGrantCmd.Command())
+
+	// List
+	authGroupListCmd := cmdAuthGroupList{global: c.global, authGroup: c}
+	cmd.AddCommand(authGroupListCmd.Command())
+
+	// Remove identity
+	authGroupRemoveIdentityCmd := cmdAuthGroupRemoveIdentity{global: c.global, authGroup: c}
+	cmd.AddCommand(authGroupRemoveIdentityCmd.Command())
+
+	// Revoke
+	authGroupRevokeCmd := cmdAuthGroupRevoke{global: c.global, authGroup: c}
+	cmd.AddCommand(authGroupRevokeCmd.Command())
+
+	// Show
+	authGroupShowCmd := cmdAuthGroupShow{global: c.global, authGroup: c}
+	cmd.AddCommand(authGroupShowCmd.Command())
+
+	return cmd
+}
+
+// Add identity
+type cmdAuthGroupAddIdentity struct {
+	global    *cmdGlobal
+	authGroup *cmdAuthGroup
+}
+
+func (c *cmdAuthGroupAddIdentity) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("add-identity [<remote>:]<group> <identity>")
+	cmd.Short = i18n.G("Add members to RBAC groups")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Add members to RBAC groups
+
+The identity is one of tls:<certificate fingerprint>, oidc:<subject> or
+candid:<username>, depending on how the member authenticates.`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthGroupAddIdentity) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing group name"))
+	}
+
+	group, etag, err := resource.server.GetAuthGroup(resource.name)
+	if err != nil {
+		return err
+	}
+
+	if shared.StringInSlice(args[1], group.Identities) {
+		return fmt.Errorf(i18n.G("Identity %s is already a member of group %s"), args[1], resource.name)
+	}
+
+	group.Identities = append(group.Identities, args[1])
+
+	return resource.server.UpdateAuthGroup(resource.name, group.Writable(), etag)
+}
+
+// Create
+type cmdAuthGroupCreate struct {
+	global    *cmdGlobal
+	authGroup *cmdAuthGroup
+
+	flagDescription string
+}
+
+func (c *cmdAuthGroupCreate) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("create [<remote>:]<group>")
+	cmd.Short = i18n.G("Create RBAC groups")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Create RBAC groups`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc auth group create ci
+lxc auth group grant ci operator ci
+lxc auth group add-identity ci tls:<fingerprint>
+    Let the client using the given certificate manage the instances of the "ci" project.`))
+
+	cmd.Flags().StringVar(&c.flagDescription, "description", "", i18n.G("Group description")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthGroupCreate) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing group name"))
+	}
+
+	group := api.AuthGroupsPost{Name: resource.name}
+	group.Description = c.flagDescription
+	group.Identities = []string{}
+	group.Roles = []api.AuthGroupRole{}
+
+	err = resource.server.CreateAuthGroup(group)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Group %s created")+"\n", resource.name)
+	}
+
+	return nil
+}
+
+// Delete
+type cmdAuthGroupDelete struct {
+	global    *cmdGlobal
+	authGroup *cmdAuthGroup
+}
+
+func (c *cmdAuthGroupDelete) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("delete [<remote>:]<group>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Delete RBAC groups")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Delete RBAC groups`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthGroupDelete) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing group name"))
+	}
+
+	err = resource.server.DeleteAuthGroup(resource.name)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Group %s deleted")+"\n", resource.name)
+	}
+
+	return nil
+}
+
+// Edit
+type cmdAuthGroupEdit struct {
+	global    *cmdGlobal
+	authGroup *cmdAuthGroup
+}
+
+func (c *cmdAuthGroupEdit) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("edit [<remote>:]<group>")
+	cmd.Short = i18n.G("Edit RBAC groups as YAML")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Edit RBAC groups as YAML`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc auth group edit <group> < group.yaml
+    Update a group using the content of group.yaml`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthGroupEdit) helpTemplate() string {
+	return i18n.G(
+		`### This is a yaml representation of the group.
+### Any line starting with a '# will be ignored.
+###
+### A group consists of a set of identities (tls:<certificate fingerprint>,
+### oidc:<subject> or candid:<username>) followed by a set of roles, granted
+### on all projects unless a project is given.
+###
+### An example would look like:
+### name: ci
+### description: CI runners
+### identities:
+### - tls:0a1b2c3d4e5f
+### roles:
+### - role: operator
+###   project: ci
+### - role: viewer
+###   project: ""
+###
+### Note that the name is shown but cannot be changed`)
+}
+
+func (c *cmdAuthGroupEdit) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing group name"))
+	}
+
+	// If stdin isn't a terminal, read text from it
+	if !termios.IsTerminal(getStdinFd()) {
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		newdata := api.AuthGroupPut{}
+		err = yaml.Unmarshal(contents, &newdata)
+		if err != nil {
+			return err
+		}
+
+		return resource.server.UpdateAuthGroup(resource.name, newdata, "")
+	}
+
+	// Extract the current value
+	group, etag, err := resource.server.GetAuthGroup(resource.name)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&group)
+	if err != nil {
+		return err
+	}
+
+	// Spawn the editor
+	content, err := shared.TextEditor("", []byte(c.helpTemplate()+"\n\n"+string(data)))
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Parse the text received from the editor
+		newdata := api.AuthGroupPut{}
+		err = yaml.Unmarshal(content, &newdata)
+		if err == nil {
+			err = resource.server.UpdateAuthGroup(resource.name, newdata, etag)
+		}
+
+		// Respawn the editor
+		if err != nil {
+			fmt.Fprintf(os.Stderr, i18n.G("Config parsing error: %s")+"\n", err)
+			fmt.Println(i18n.G("Press enter to open the editor again"))
+
+			_, err := os.Stdin.Read(make([]byte, 1))
+			if err != nil {
+				return err
+			}
+
+			content, err = shared.TextEditor("", content)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		break
+	}
+
+	return nil
+}
+
+// Grant
+type cmdAuthGroupGrant struct {
+	global    *cmdGlobal
+	authGroup *cmdAuthGroup
+}
+
+func (c *cmdAuthGroupGrant) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("grant [<remote>:]<group> <role> [<project>]")
+	cmd.Short = i18n.G("Grant roles to RBAC groups")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Grant roles to RBAC groups
+
+The role is granted on all projects unless a project is given.`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthGroupGrant) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 3)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing group name"))
+	}
+
+	role := api.AuthGroupRole{Role: args[1]}
+	if len(args) > 2 {
+		role.Project = args[2]
+	}
+
+	group, etag, err := resource.server.GetAuthGroup(resource.name)
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range group.Roles {
+		if existing == role {
+			return fmt.Errorf(i18n.G("Group %s already has this role"), resource.name)
+		}
+	}
+
+	group.Roles = append(group.Roles, role)
+
+	return resource.server.UpdateAuthGroup(resource.name, group.Writable(), etag)
+}
+
+// List
+type cmdAuthGroupList struct {
+	global    *cmdGlobal
+	authGroup *cmdAuthGroup
+
+	flagFormat string
+}
+
+func (c *cmdAuthGroupList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list [<remote>:]")
+	cmd.Aliases = []string{"ls"}
+	cmd.Short = i18n.G("List RBAC groups")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List RBAC groups`))
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthGroupList) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) > 0 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	groups, err := resource.server.GetAuthGroups()
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, group := range groups {
+		roles := []string{}
+		for _, role := range group.Roles {
+			if role.Project == "" {
+				roles = append(roles, role.Role)
+			} else {
+				roles = append(roles, fmt.Sprintf("%s (%s)", role.Role, role.Project))
+			}
+		}
+
+		data = append(data, []string{
+			group.Name,
+			group.Description,
+			strings.Join(roles, "\n"),
+			fmt.Sprintf("%d", len(group.Identities)),
+		})
+	}
+	sort.Sort(stringList(data))
+
+	header := []string{
+		i18n.G("NAME"),
+		i18n.G("DESCRIPTION"),
+		i18n.G("ROLES"),
+		i18n.G("MEMBERS"),
+	}
+
+	return utils.RenderTable(c.flagFormat, header, data, groups)
+}
+
+// Remove identity
+type cmdAuthGroupRemoveIdentity struct {
+	global    *cmdGlobal
+	authGroup *cmdAuthGroup
+}
+
+func (c *cmdAuthGroupRemoveIdentity) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("remove-identity [<remote>:]<group> <identity>")
+	cmd.Short = i18n.G("Remove members from RBAC groups")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Remove members from RBAC groups`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthGroupRemoveIdentity) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing group name"))
+	}
+
+	group, etag, err := resource.server.GetAuthGroup(resource.name)
+	if err != nil {
+		return err
+	}
+
+	identities := []string{}
+	for _, identity := range group.Identities {
+		if identity != args[1] {
+			identities = append(identities, identity)
+		}
+	}
+
+	if len(identities) == len(group.Identities) {
+		return fmt.Errorf(i18n.G("Identity %s isn't a member of group %s"), args[1], resource.name)
+	}
+
+	group.Identities = identities
+
+	return resource.server.UpdateAuthGroup(resource.name, group.Writable(), etag)
+}
+
+// Revoke
+type cmdAuthGroupRevoke struct {
+	global    *cmdGlobal
+	authGroup *cmdAuthGroup
+}
+
+func (c *cmdAuthGroupRevoke) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("revoke [<remote>:]<group> <role> [<project>]")
+	cmd.Short = i18n.G("Revoke roles from RBAC groups")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Revoke roles from RBAC groups`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthGroupRevoke) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 3)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing group name"))
+	}
+
+	role := api.AuthGroupRole{Role: args[1]}
+	if len(args) > 2 {
+		role.Project = args[2]
+	}
+
+	group, etag, err := resource.server.GetAuthGroup(resource.name)
+	if err != nil {
+		return err
+	}
+
+	roles := []api.AuthGroupRole{}
+	for _, existing := range group.Roles {
+		if existing != role {
+			roles = append(roles, existing)
+		}
+	}
+
+	if len(roles) == len(group.Roles) {
+		return fmt.Errorf(i18n.G("Group %s doesn't have this role"), resource.name)
+	}
+
+	group.Roles = roles
+
+	return resource.server.UpdateAuthGroup(resource.name, group.Writable(), etag)
+}
+
+// Show
+type cmdAuthGroupShow struct {
+	global    *cmdGlobal
+	authGroup *cmdAuthGroup
+}
+
+func (c *cmdAuthGroupShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("show [<remote>:]<group>")
+	cmd.Short = i18n.G("Show RBAC group details")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show RBAC group details`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthGroupShow) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing group name"))
+	}
+
+	group, _, err := resource.server.GetAuthGroup(resource.name)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&group)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
+
+// Token
+type cmdAuthToken struct {
+	global *cmdGlobal
+	auth   *cmdAuth
+}
+
+func (c *cmdAuthToken) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("token")
+	cmd.Short = i18n.G("Manage API tokens")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage API tokens`))
+
+	// Add
+	authTokenAddCmd := cmdAuthTokenAdd{global: c.global, authToken: c}
+	cmd.AddCommand(authTokenAddCmd.Command())
+
+	// List
+	authTokenListCmd := cmdAuthTokenList{global: c.global, authToken: c}
+	cmd.AddCommand(authTokenListCmd.Command())
+
+	// Revoke
+	authTokenRevokeCmd := cmdAuthTokenRevoke{global: c.global, authToken: c}
+	cmd.AddCommand(authTokenRevokeCmd.Command())
+
+	// Show
+	authTokenShowCmd := cmdAuthTokenShow{global: c.global, authToken: c}
+	cmd.AddCommand(authTokenShowCmd.Command())
+
+	return cmd
+}
+
+// Add
+type cmdAuthTokenAdd struct {
+	global    *cmdGlobal
+	authToken *cmdAuthToken
+
+	flagDescription string
+	flagScope       string
+	flagProjects    string
+	flagExpiry      string
+}
+
+func (c *cmdAuthTokenAdd) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("add [<remote>:]")
+	cmd.Short = i18n.G("Issue new API tokens")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Issue new API tokens
+
+The token is only displayed once and can't be retrieved afterwards.
+
+The scope is one of:
+ - read-only: Read-only access
+ - operate: Read-only access plus instance lifecycle operations
+ - manage: Full access`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc auth token add --scope=operate --projects=ci --expiry=24h
+    Issue a token able to start and stop the instances of the "ci" project for a day.`))
+
+	cmd.Flags().StringVar(&c.flagDescription, "description", "", i18n.G("Token description")+"``")
+	cmd.Flags().StringVar(&c.flagScope, "scope", "read-only", i18n.G("Token scope (read-only|operate|manage)")+"``")
+	cmd.Flags().StringVar(&c.flagProjects, "projects", "", i18n.G("Projects the token is restricted to (comma separated)")+"``")
+	cmd.Flags().StringVar(&c.flagExpiry, "expiry", "", i18n.G("Validity period of the token (e.g. 24h)")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthTokenAdd) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) > 0 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	req := api.AuthTokensPost{
+		Description: c.flagDescription,
+		Scope:       c.flagScope,
+		Projects:    []string{},
+	}
+
+	if c.flagProjects != "" {
+		req.Projects = strings.Split(c.flagProjects, ",")
+	}
+
+	if c.flagExpiry != "" {
+		expiry, err := time.ParseDuration(c.flagExpiry)
+		if err != nil {
+			return fmt.Errorf(i18n.G("Invalid expiry: %v"), err)
+		}
+
+		req.ExpiresAt = time.Now().Add(expiry)
+	}
+
+	token, err := resource.server.CreateAuthToken(req)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("API token %s issued, expiring on %s:")+"\n", token.ID, token.ExpiresAt.Local().Format(time.RFC3339))
+	}
+
+	fmt.Println(token.Token)
+
+	return nil
+}
+
+// List
+type cmdAuthTokenList struct {
+	global    *cmdGlobal
+	authToken *cmdAuthToken
+
+	flagFormat string
+}
+
+func (c *cmdAuthTokenList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list [<remote>:]")
+	cmd.Aliases = []string{"ls"}
+	cmd.Short = i18n.G("List API tokens")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List API tokens`))
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthTokenList) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) > 0 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	tokens, err := resource.server.GetAuthTokens()
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, token := range tokens {
+		const layout = "Jan 2, 2006 at 3:04pm (MST)"
+		data = append(data, []string{
+			token.ID,
+			token.Description,
+			token.Scope,
+			strings.Join(token.Projects, ", "),
+			token.ExpiresAt.Local().Format(layout),
+		})
+	}
+	sort.Sort(stringList(data))
+
+	header := []string{
+		i18n.G("ID"),
+		i18n.G("DESCRIPTION"),
+		i18n.G("SCOPE"),
+		i18n.G("PROJECTS"),
+		i18n.G("EXPIRY DATE"),
+	}
+
+	return utils.RenderTable(c.flagFormat, header, data, tokens)
+}
+
+// Revoke
+type cmdAuthTokenRevoke struct {
+	global    *cmdGlobal
+	authToken *cmdAuthToken
+}
+
+func (c *cmdAuthTokenRevoke) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("revoke [<remote>:]<id>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Revoke API tokens")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Revoke API tokens`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthTokenRevoke) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing token ID"))
+	}
+
+	err = resource.server.DeleteAuthToken(resource.name)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("API token %s revoked")+"\n", resource.name)
+	}
+
+	return nil
+}
+
+// Show
+type cmdAuthTokenShow struct {
+	global    *cmdGlobal
+	authToken *cmdAuthToken
+}
+
+func (c *cmdAuthTokenShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("show [<remote>:]<id>")
+	cmd.Short = i18n.G("Show API token details")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show API token details`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthTokenShow) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing token ID"))
+	}
+
+	token, err := resource.server.GetAuthToken(resource.name)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&token)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"sort"
 	"strings"
@@ -49,6 +50,14 @@ func (c *cmdCluster) Command() *cobra.Command {
 	clusterEnableCmd := cmdClusterEnable{global: c.global, cluster: c}
 	cmd.AddCommand(clusterEnableCmd.Command())
 
+	// Add token
+	clusterAddCmd := cmdClusterAdd{global: c.global, cluster: c}
+	cmd.AddCommand(clusterAddCmd.Command())
+
+	// Update certificate
+	clusterUpdateCertificateCmd := cmdClusterUpdateCertificate{global: c.global, cluster: c}
+	cmd.AddCommand(clusterUpdateCertificateCmd.Command())
+
 	return cmd
 }
 
@@ -402,3 +411,144 @@ func (c *cmdClusterEnable) Run(cmd *cobra.Command, args []string) error {
 	fmt.Println(i18n.G("Clustering enabled"))
 	return nil
 }
+
+// Add
+type cmdClusterAdd struct {
+	global  *cmdGlobal
+	cluster *cmdCluster
+}
+
+func (c *cmdClusterAdd) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("add [<remote>:]<name>")
+	cmd.Short = i18n.G("Request a join token for adding a cluster member")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Request a join token for adding a cluster member
+
+The returned token can be used with "lxd init" on the new member to join the cluster.`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterAdd) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing cluster member name"))
+	}
+
+	// Request the join token
+	token, err := resource.server.CreateClusterMember(api.ClusterMembersPost{ServerName: resource.name})
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Member %s join token:")+"\n", resource.name)
+	}
+
+	fmt.Println(token.String())
+
+	return nil
+}
+
+// Update certificate
+type cmdClusterUpdateCertificate struct {
+	global  *cmdGlobal
+	cluster *cmdCluster
+}
+
+func (c *cmdClusterUpdateCertificate) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("update-certificate [<remote>:] <cert.crt> <cert.key>")
+	cmd.Short = i18n.G("Update cluster certificate")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Update cluster certificate
+
+The new certificate and key are distributed to all cluster members.`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterUpdateCertificate) Run(cmd *cobra.Command, args []string) error {
+	conf := c.global.conf
+
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 3)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := ""
+	certFile := args[0]
+	keyFile := args[1]
+	if len(args) == 3 {
+		remote = args[0]
+		certFile = args[1]
+		keyFile = args[2]
+	}
+
+	remoteName, _, err := conf.ParseRemote(remote)
+	if err != nil {
+		return err
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	cert, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return errors.Wrapf(err, i18n.G("Failed to read certificate file %q"), certFile)
+	}
+
+	key, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return errors.Wrapf(err, i18n.G("Failed to read key file %q"), keyFile)
+	}
+
+	req := api.ClusterCertificatePut{
+		ClusterCertificate:    string(cert),
+		ClusterCertificateKey: string(key),
+	}
+
+	err = resource.server.UpdateClusterCertificate(req, "")
+	if err != nil {
+		return err
+	}
+
+	// Update the certificate stored for the remote, if any.
+	certPath := conf.ServerCertPath(remoteName)
+	if shared.PathExists(certPath) {
+		err = ioutil.WriteFile(certPath, cert, 0644)
+		if err != nil {
+			return errors.Wrapf(err, i18n.G("Could not write new remote certificate for remote '%s'"), remoteName)
+		}
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Println(i18n.G("Successfully updated cluster certificates"))
+	}
+
+	return nil
+}
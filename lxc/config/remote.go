@@ -21,13 +21,14 @@ import (
 
 // Remote holds details for communication with a remote daemon
 type Remote struct {
-	Addr     string `yaml:"addr"`
-	AuthType string `yaml:"auth_type,omitempty"`
-	Domain   string `yaml:"domain,omitempty"`
-	Project  string `yaml:"project,omitempty"`
-	Protocol string `yaml:"protocol,omitempty"`
-	Public   bool   `yaml:"public"`
-	Static   bool   `yaml:"-"`
+	Addr             string `yaml:"addr"`
+	AuthType         string `yaml:"auth_type,omitempty"`
+	Domain           string `yaml:"domain,omitempty"`
+	Project          string `yaml:"project,omitempty"`
+	Protocol         string `yaml:"protocol,omitempty"`
+	Public           bool   `yaml:"public"`
+	RequireSignature bool   `yaml:"require_signature,omitempty"`
+	Static           bool   `yaml:"-"`
 }
 
 // ParseRemote splits remote and object
@@ -93,7 +94,7 @@ func (c *Config) GetInstanceServer(name string) (lxd.InstanceServer, error) {
 	}
 
 	// HTTPs
-	if remote.AuthType != "candid" && (args.TLSClientCert == "" || args.TLSClientKey == "") {
+	if remote.AuthType != "candid" && remote.AuthType != "oidc" && (args.TLSClientCert == "" || args.TLSClientKey == "") {
 		return nil, fmt.Errorf("Missing TLS client certificate and key")
 	}
 
@@ -242,6 +243,11 @@ func (c *Config) getConnectionArgs(name string) (*lxd.ConnectionArgs, error) {
 		}
 
 		args.CookieJar = c.cookieJars[name]
+	} else if args.AuthType == "oidc" {
+		args.OIDCTokensFile = c.OIDCTokensPath(name)
+		args.OIDCLoginPrompt = func(verificationURL string, userCode string) {
+			fmt.Fprintf(os.Stderr, "To log in, open %s in a browser and confirm the code %s\n", verificationURL, userCode)
+		}
 	}
 
 	// Stop here if no TLS involved
@@ -260,7 +266,7 @@ func (c *Config) getConnectionArgs(name string) (*lxd.ConnectionArgs, error) {
 	}
 
 	// Stop here if no client certificate involved
-	if remote.Protocol == "simplestreams" || remote.AuthType == "candid" {
+	if remote.Protocol == "simplestreams" || remote.AuthType == "candid" || remote.AuthType == "oidc" {
 		return &args, nil
 	}
 
@@ -6,6 +6,7 @@ import (
 	"encoding/pem"
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -49,6 +50,10 @@ type cmdConfigTrustAdd struct {
 	global      *cmdGlobal
 	config      *cmdConfig
 	configTrust *cmdConfigTrust
+
+	flagRestricted bool
+	flagProjects   string
+	flagType       string
 }
 
 func (c *cmdConfigTrustAdd) Command() *cobra.Command {
@@ -56,7 +61,20 @@ func (c *cmdConfigTrustAdd) Command() *cobra.Command {
 	cmd.Use = i18n.G("add [<remote>:] <cert>")
 	cmd.Short = i18n.G("Add new trusted clients")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
-		`Add new trusted clients`))
+		`Add new trusted clients
+
+Restricted clients only get access to the listed projects and can't
+change the server configuration, the cluster or the trusted clients.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc config trust add ci.crt --restricted --projects=ci,staging
+    Trust ci.crt, only giving it access to the "ci" and "staging" projects.
+
+lxc config trust add prometheus.crt --type=metrics
+    Trust prometheus.crt for scraping the metrics only.`))
+
+	cmd.Flags().StringVar(&c.flagType, "type", "client", i18n.G("Type of certificate (client|metrics)")+"``")
+	cmd.Flags().BoolVar(&c.flagRestricted, "restricted", false, i18n.G("Restrict the certificate to the given projects"))
+	cmd.Flags().StringVar(&c.flagProjects, "projects", "", i18n.G("Projects the restricted certificate can access (comma separated)")+"``")
 
 	cmd.RunE = c.Run
 
@@ -94,7 +112,19 @@ func (c *cmdConfigTrustAdd) Run(cmd *cobra.Command, args []string) error {
 	cert := api.CertificatesPost{}
 	cert.Certificate = base64.StdEncoding.EncodeToString(x509Cert.Raw)
 	cert.Name = name
-	cert.Type = "client"
+	cert.Type = c.flagType
+
+	if c.flagProjects != "" && !c.flagRestricted {
+		return fmt.Errorf(i18n.G("--projects can only be used with --restricted"))
+	}
+
+	if c.flagRestricted {
+		cert.Restricted = true
+		cert.Projects = []string{}
+		if c.flagProjects != "" {
+			cert.Projects = strings.Split(c.flagProjects, ",")
+		}
+	}
 
 	return resource.server.CreateCertificate(cert)
 }
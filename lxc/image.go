@@ -56,6 +56,10 @@ hash or alias name (if one is set).`))
 	imageAliasCmd := cmdImageAlias{global: c.global, image: c}
 	cmd.AddCommand(imageAliasCmd.Command())
 
+	// Build
+	imageBuildCmd := cmdImageBuild{global: c.global, image: c}
+	cmd.AddCommand(imageBuildCmd.Command())
+
 	// Copy
 	imageCopyCmd := cmdImageCopy{global: c.global, image: c}
 	cmd.AddCommand(imageCopyCmd.Command())
@@ -84,6 +88,14 @@ hash or alias name (if one is set).`))
 	imageListCmd := cmdImageList{global: c.global, image: c}
 	cmd.AddCommand(imageListCmd.Command())
 
+	// Mirror
+	imageMirrorCmd := cmdImageMirror{global: c.global, image: c}
+	cmd.AddCommand(imageMirrorCmd.Command())
+
+	// Prune
+	imagePruneCmd := cmdImagePrune{global: c.global, image: c}
+	cmd.AddCommand(imagePruneCmd.Command())
+
 	// Refresh
 	imageRefreshCmd := cmdImageRefresh{global: c.global, image: c}
 	cmd.AddCommand(imageRefreshCmd.Command())
@@ -207,9 +219,10 @@ func (c *cmdImageCopy) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	copyArgs := lxd.ImageCopyArgs{
-		AutoUpdate: c.flagAutoUpdate,
-		Public:     c.flagPublic,
-		Type:       imageType,
+		AutoUpdate:       c.flagAutoUpdate,
+		Public:           c.flagPublic,
+		Type:             imageType,
+		RequireSignature: conf.Remotes[remoteName].RequireSignature,
 	}
 
 	// Do the copy
@@ -260,6 +273,8 @@ func (c *cmdImageCopy) Run(cmd *cobra.Command, args []string) error {
 type cmdImageDelete struct {
 	global *cmdGlobal
 	image  *cmdImage
+
+	flagForce bool
 }
 
 func (c *cmdImageDelete) Command() *cobra.Command {
@@ -270,6 +285,7 @@ func (c *cmdImageDelete) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Delete images`))
 
+	cmd.Flags().BoolVarP(&c.flagForce, "force", "f", false, i18n.G("Delete images even if instances were created from them"))
 	cmd.RunE = c.Run
 
 	return cmd
@@ -294,7 +310,7 @@ func (c *cmdImageDelete) Run(cmd *cobra.Command, args []string) error {
 		}
 
 		image := c.image.dereferenceAlias(resource.server, "", resource.name)
-		op, err := resource.server.DeleteImage(image)
+		op, err := resource.server.DeleteImageWithArgs(image, &lxd.ImageDeleteArgs{Force: c.flagForce})
 		if err != nil {
 			return err
 		}
@@ -596,6 +612,8 @@ type cmdImageImport struct {
 
 	flagPublic  bool
 	flagAliases []string
+	flagOCI     bool
+	flagType    string
 }
 
 func (c *cmdImageImport) Command() *cobra.Command {
@@ -605,10 +623,22 @@ func (c *cmdImageImport) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Import image into the image store
 
-Directory import is only available on Linux and must be performed as root.`))
+Directory import is only available on Linux and must be performed as root.
+
+With --oci, the tarball or directory is an OCI image layout or the output of
+"docker save", which gets converted into a container image by the server.
+
+With --type=virtual-machine and a single file, the file is a qcow2, VMDK or
+raw disk image, which gets converted into a virtual machine image by the server.
+The "architecture" property selects the architecture of the image.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc image import --type=virtual-machine disk.qcow2 --alias my-vm
+    Import a raw disk image as a virtual machine image.`))
 
 	cmd.Flags().BoolVar(&c.flagPublic, "public", false, i18n.G("Make image public"))
 	cmd.Flags().StringArrayVar(&c.flagAliases, "alias", nil, i18n.G("New aliases to add to the image")+"``")
+	cmd.Flags().BoolVar(&c.flagOCI, "oci", false, i18n.G("Import an OCI image layout or Docker image archive"))
+	cmd.Flags().StringVar(&c.flagType, "type", "", i18n.G("Image type (container or virtual-machine)")+"``")
 	cmd.RunE = c.Run
 
 	return cmd
@@ -634,6 +664,27 @@ func (c *cmdImageImport) packImageDir(path string) (string, error) {
 	return outFileName, nil
 }
 
+func (c *cmdImageImport) packOCIDir(path string) (string, error) {
+	if !shared.PathExists(filepath.Join(path, "oci-layout")) {
+		return "", fmt.Errorf(i18n.G("Directory isn't an OCI image layout"))
+	}
+
+	outFile, err := ioutil.TempFile("", "lxd_image_")
+	if err != nil {
+		return "", err
+	}
+	defer outFile.Close()
+
+	outFileName := outFile.Name()
+	_, err = shared.RunCommand("tar", "-C", path, "-cf", outFileName, ".")
+	if err != nil {
+		os.Remove(outFileName)
+		return "", err
+	}
+
+	return outFileName, nil
+}
+
 func (c *cmdImageImport) Run(cmd *cobra.Command, args []string) error {
 	conf := c.global.conf
 
@@ -727,6 +778,20 @@ func (c *cmdImageImport) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	imageType := "container"
+	if c.flagOCI && (strings.HasPrefix(imageFile, "https://") || rootfsFile != "") {
+		return fmt.Errorf(i18n.G("OCI images can only be imported from a single local tarball or directory"))
+	}
+
+	if !shared.StringInSlice(c.flagType, []string{"", "container", "virtual-machine"}) {
+		return fmt.Errorf(i18n.G("Invalid image type %q"), c.flagType)
+	}
+
+	// A single file imported as a virtual machine is a raw disk image.
+	diskImage := c.flagType == "virtual-machine" && rootfsFile == "" && !strings.HasPrefix(imageFile, "https://") && !shared.IsDir(imageFile)
+	if diskImage && c.flagOCI {
+		return fmt.Errorf(i18n.G("OCI images can't be imported as virtual machines"))
+	}
+
 	if strings.HasPrefix(imageFile, "https://") {
 		image.Source = &api.ImagesPostSource{}
 		image.Source.Type = "url"
@@ -739,7 +804,25 @@ func (c *cmdImageImport) Run(cmd *cobra.Command, args []string) error {
 		var rootfs io.ReadCloser
 
 		// Open meta
-		if shared.IsDir(imageFile) {
+		if c.flagOCI {
+			image.Source = &api.ImagesPostSource{}
+			image.Source.Type = "oci"
+		}
+
+		if diskImage {
+			image.Source = &api.ImagesPostSource{}
+			image.Source.Type = "disk"
+			imageType = "virtual-machine"
+		}
+
+		if shared.IsDir(imageFile) && c.flagOCI {
+			imageFile, err = c.packOCIDir(imageFile)
+			if err != nil {
+				return err
+			}
+			// remove temp file
+			defer os.Remove(imageFile)
+		} else if shared.IsDir(imageFile) {
 			imageFile, err = c.packImageDir(imageFile)
 			if err != nil {
 				return err
@@ -939,6 +1022,33 @@ func (c *cmdImageInfo) Run(cmd *cobra.Command, args []string) error {
 		fmt.Printf("    Alias: %s\n", info.UpdateSource.Alias)
 	}
 
+	if len(info.UsedBy) > 0 {
+		fmt.Println(i18n.G("Used by:"))
+		for _, entry := range info.UsedBy {
+			fmt.Printf("    %s\n", entry)
+		}
+	}
+
+	policy := info.AutoUpdatePolicy
+	if policy != (api.ImageAutoUpdatePolicy{}) {
+		fmt.Println(i18n.G("Auto update policy:"))
+		if policy.Window != "" {
+			fmt.Printf("    Window: %s\n", policy.Window)
+		}
+
+		if policy.Pin != "" {
+			fmt.Printf("    Pin: %s\n", policy.Pin)
+		}
+
+		if policy.MaxAge != "" {
+			fmt.Printf("    Max age: %s\n", policy.MaxAge)
+		}
+
+		if policy.NotifyOnly {
+			fmt.Printf("    Notify only: %v\n", policy.NotifyOnly)
+		}
+	}
+
 	return nil
 }
 
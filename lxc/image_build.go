@@ -0,0 +1,580 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+)
+
+// imageBuildRecipe is the declarative description of an image build.
+type imageBuildRecipe struct {
+	// Image to build from, as [<remote>:]<image>
+	Base string `yaml:"base"`
+
+	// Instance configuration
+	Profiles []string          `yaml:"profiles"`
+	Config   map[string]string `yaml:"config"`
+
+	// Environment of the build commands
+	Environment map[string]string `yaml:"environment"`
+
+	// Files to push, in order
+	Files []imageBuildFile `yaml:"files"`
+
+	// Commands to run, in order, through "sh -c"
+	Commands []string `yaml:"commands"`
+
+	// Templates to add to the image metadata, indexed by target path
+	Templates map[string]*api.ImageMetadataTemplate `yaml:"templates"`
+
+	// Resulting image
+	Aliases     []string          `yaml:"aliases"`
+	Properties  map[string]string `yaml:"properties"`
+	Public      bool              `yaml:"public"`
+	Compression string            `yaml:"compression"`
+}
+
+// imageBuildFile is a file pushed into the build instance.
+type imageBuildFile struct {
+	Path    string `yaml:"path"`
+	Source  string `yaml:"source"`
+	Content string `yaml:"content"`
+	Mode    string `yaml:"mode"`
+	UID     int64  `yaml:"uid"`
+	GID     int64  `yaml:"gid"`
+}
+
+// imageBuildLog discards Close calls so that it can be handed out as the
+// output of the build commands.
+type imageBuildLog struct {
+	io.Writer
+}
+
+func (l imageBuildLog) Close() error {
+	return nil
+}
+
+// Build
+type cmdImageBuild struct {
+	global *cmdGlobal
+	image  *cmdImage
+
+	flagLog string
+
+	log io.Writer
+}
+
+func (c *cmdImageBuild) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("build <recipe> [<remote>:]")
+	cmd.Short = i18n.G("Build images from recipes")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Build images from recipes
+
+An ephemeral container is created from the base image of the recipe. Its
+files are then pushed and its commands run in order, after which the
+container is published with the templates, aliases and properties of the
+recipe, and deleted.
+
+Paths of the files and templates in the recipe are relative to the recipe.
+
+The files of the image get the creation date of the base image as their
+modification time, or the one given by SOURCE_DATE_EPOCH, so that building
+the same recipe twice gives the same image as long as its commands do the
+same.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc image build recipe.yaml --log build.log
+    Build the image described by recipe.yaml, such as:
+
+    base: images:alpine/3.11
+    environment:
+      LANG: C
+    files:
+    - path: /etc/motd
+      source: motd
+      mode: "0644"
+    commands:
+    - apk add --no-cache nginx
+    templates:
+      /etc/hostname:
+        when: [create, copy]
+        template: hostname.tpl
+    aliases:
+    - nginx
+    properties:
+      description: Alpine with nginx`))
+
+	cmd.Flags().StringVar(&c.flagLog, "log", "", i18n.G("Also write the build log to the given file")+"``")
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+// Write a build step to the log.
+func (c *cmdImageBuild) step(format string, args ...interface{}) {
+	fmt.Fprintf(c.log, "==> "+format+"\n", args...)
+}
+
+func (c *cmdImageBuild) Run(cmd *cobra.Command, args []string) error {
+	conf := c.global.conf
+
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 2)
+	if exit {
+		return err
+	}
+
+	// Parse the recipe
+	content, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	recipe := imageBuildRecipe{}
+	err = yaml.UnmarshalStrict(content, &recipe)
+	if err != nil {
+		return fmt.Errorf(i18n.G("Failed to parse the recipe: %v"), err)
+	}
+
+	if recipe.Base == "" {
+		return fmt.Errorf(i18n.G("The recipe must have a base image"))
+	}
+
+	recipeDir := filepath.Dir(args[0])
+
+	// Setup the build log
+	c.log = os.Stdout
+	if c.global.flagQuiet {
+		c.log = ioutil.Discard
+	}
+
+	if c.flagLog != "" {
+		logFile, err := os.Create(c.flagLog)
+		if err != nil {
+			return err
+		}
+		defer logFile.Close()
+
+		c.log = io.MultiWriter(c.log, logFile)
+	}
+
+	// Connect to the servers
+	remote := ""
+	if len(args) > 1 {
+		remote = args[1]
+	}
+
+	remote, name, err := conf.ParseRemote(remote)
+	if err != nil {
+		return err
+	}
+
+	if name != "" {
+		return fmt.Errorf(i18n.G("Only a remote can be given as the target of the build"))
+	}
+
+	d, err := conf.GetInstanceServer(remote)
+	if err != nil {
+		return err
+	}
+
+	iremote, image, err := conf.ParseRemote(recipe.Base)
+	if err != nil {
+		return err
+	}
+
+	var imgRemote lxd.ImageServer
+	if iremote == remote {
+		imgRemote = d
+	} else {
+		imgRemote, err = conf.GetImageServer(iremote)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Pin the base image to a fingerprint so that all the builds from the
+	// recipe are logged against the exact image they started from.
+	alias, _, err := imgRemote.GetImageAlias(image)
+	if err == nil {
+		image = alias.Target
+	}
+
+	imgInfo, _, err := imgRemote.GetImage(image)
+	if err != nil {
+		return err
+	}
+
+	c.step(i18n.G("Using base image %s (%s)"), recipe.Base, imgInfo.Fingerprint)
+
+	// The image is published with a fixed date for it to be reproducible.
+	createdAt := imgInfo.CreatedAt
+	sourceDate := os.Getenv("SOURCE_DATE_EPOCH")
+	if sourceDate != "" {
+		seconds, err := strconv.ParseInt(sourceDate, 10, 64)
+		if err != nil {
+			return fmt.Errorf(i18n.G("Invalid SOURCE_DATE_EPOCH %q"), sourceDate)
+		}
+
+		createdAt = time.Unix(seconds, 0)
+	}
+
+	if createdAt.Unix() <= 0 {
+		return fmt.Errorf(i18n.G("The base image has no creation date, SOURCE_DATE_EPOCH must be set"))
+	}
+
+	// Create the build instance, named after the recipe as its name ends
+	// up in the image through the templates.
+	req := api.InstancesPost{
+		Name: fmt.Sprintf("build-%x", sha256.Sum256(content))[:18],
+		Source: api.InstanceSource{
+			Type:             "image",
+			RequireSignature: conf.Remotes[iremote].RequireSignature,
+		},
+		Type: api.InstanceTypeContainer,
+	}
+	req.Ephemeral = true
+	req.Profiles = recipe.Profiles
+	req.Config = recipe.Config
+
+	op, err := d.CreateInstanceFromImage(imgRemote, *imgInfo, req)
+	if err != nil {
+		return err
+	}
+
+	progress := utils.ProgressRenderer{
+		Format: i18n.G("Retrieving image: %s"),
+		Quiet:  c.global.flagQuiet,
+	}
+
+	_, err = op.AddHandler(progress.UpdateOp)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	err = utils.CancelableWait(op, &progress)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+	progress.Done("")
+
+	opInfo, err := op.GetTarget()
+	if err != nil {
+		return err
+	}
+
+	instances, ok := opInfo.Resources["instances"]
+	if !ok || len(instances) == 0 {
+		instances, ok = opInfo.Resources["containers"]
+	}
+
+	if !ok || len(instances) == 0 {
+		return fmt.Errorf(i18n.G("Didn't get any affected image, instance or snapshot from server"))
+	}
+
+	name = filepath.Base(instances[0])
+	c.step(i18n.G("Created build container %s"), name)
+
+	// Always get rid of the build instance, stopping it is enough for it
+	// to be deleted as long as it's still ephemeral.
+	revert := true
+	defer func() {
+		if !revert {
+			return
+		}
+
+		c.step(i18n.G("Deleting build container %s"), name)
+		c.stop(d, name)
+		op, err := d.DeleteInstance(name)
+		if err == nil {
+			op.Wait()
+		}
+	}()
+
+	err = c.build(d, name, recipe, recipeDir)
+	if err != nil {
+		return err
+	}
+
+	// Turn it into a regular instance so that it survives being stopped
+	c.step(i18n.G("Stopping build container %s"), name)
+	inst, etag, err := d.GetInstance(name)
+	if err != nil {
+		return err
+	}
+
+	inst.Ephemeral = false
+	op2, err := d.UpdateInstance(name, inst.Writable(), etag)
+	if err != nil {
+		return err
+	}
+
+	err = op2.Wait()
+	if err != nil {
+		return err
+	}
+
+	err = c.stop(d, name)
+	if err != nil {
+		return err
+	}
+
+	// Publish it
+	c.step(i18n.G("Publishing build container %s"), name)
+	imgReq := api.ImagesPost{
+		Source: &api.ImagesPostSource{
+			Type: "container",
+			Name: name,
+		},
+		CompressionAlgorithm: recipe.Compression,
+		CreatedAt:            createdAt.UTC(),
+	}
+	imgReq.Public = recipe.Public
+	if len(recipe.Properties) > 0 {
+		imgReq.Properties = recipe.Properties
+	}
+
+	op2, err = d.CreateImage(imgReq, nil)
+	if err != nil {
+		return err
+	}
+
+	progress = utils.ProgressRenderer{
+		Format: i18n.G("Publishing container: %s"),
+		Quiet:  c.global.flagQuiet,
+	}
+
+	_, err = op2.AddHandler(progress.UpdateOp)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	// Rebuilding the same image is fine.
+	fingerprint := ""
+	err = utils.CancelableWait(op2, &progress)
+	if err != nil {
+		progress.Done("")
+
+		fields := strings.SplitN(err.Error(), "The image already exists: ", 2)
+		if len(fields) != 2 {
+			return err
+		}
+
+		fingerprint = fields[1]
+	} else {
+		progress.Done("")
+		fingerprint = op2.Get().Metadata["fingerprint"].(string)
+	}
+
+	aliases := []api.ImageAlias{}
+	for _, entry := range recipe.Aliases {
+		aliases = append(aliases, api.ImageAlias{Name: entry})
+	}
+
+	err = ensureImageAliases(d, aliases, fingerprint)
+	if err != nil {
+		return err
+	}
+
+	c.step(i18n.G("Deleting build container %s"), name)
+	revert = false
+	op2, err = d.DeleteInstance(name)
+	if err != nil {
+		return err
+	}
+
+	err = op2.Wait()
+	if err != nil {
+		return err
+	}
+
+	c.step(i18n.G("Image built with fingerprint: %s"), fingerprint)
+
+	return nil
+}
+
+// Start the build instance and apply the recipe to it.
+func (c *cmdImageBuild) build(d lxd.InstanceServer, name string, recipe imageBuildRecipe, recipeDir string) error {
+	// Templates are part of the instance metadata rather than its rootfs,
+	// so they can be added before it is even started.
+	if len(recipe.Templates) > 0 {
+		metadata, etag, err := d.GetInstanceMetadata(name)
+		if err != nil {
+			return err
+		}
+
+		if metadata.Templates == nil {
+			metadata.Templates = map[string]*api.ImageMetadataTemplate{}
+		}
+
+		targets := []string{}
+		for target := range recipe.Templates {
+			targets = append(targets, target)
+		}
+		sort.Strings(targets)
+
+		for _, target := range targets {
+			template := recipe.Templates[target]
+			if template == nil || template.Template == "" {
+				return fmt.Errorf(i18n.G("Missing template file for %s"), target)
+			}
+
+			c.step(i18n.G("Adding template %s for %s"), template.Template, target)
+
+			content, err := ioutil.ReadFile(filepath.Join(recipeDir, template.Template))
+			if err != nil {
+				return err
+			}
+
+			templateName := filepath.Base(template.Template)
+			err = d.CreateInstanceTemplateFile(name, templateName, bytes.NewReader(content))
+			if err != nil {
+				return err
+			}
+
+			entry := *template
+			entry.Template = templateName
+			metadata.Templates[target] = &entry
+		}
+
+		err = d.SetInstanceMetadata(name, *metadata, etag)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.step(i18n.G("Starting build container %s"), name)
+	op, err := d.UpdateInstanceState(name, api.InstanceStatePut{Action: string(shared.Start), Timeout: -1}, "")
+	if err != nil {
+		return err
+	}
+
+	err = op.Wait()
+	if err != nil {
+		return err
+	}
+
+	// Push the files
+	fileCmd := cmdFile{global: c.global}
+	for _, file := range recipe.Files {
+		if file.Path == "" {
+			return fmt.Errorf(i18n.G("Missing path for file"))
+		}
+
+		c.step(i18n.G("Pushing %s"), file.Path)
+
+		content := []byte(file.Content)
+		if file.Source != "" {
+			content, err = ioutil.ReadFile(filepath.Join(recipeDir, file.Source))
+			if err != nil {
+				return err
+			}
+		}
+
+		mode := int64(0644)
+		if file.Mode != "" {
+			mode, err = strconv.ParseInt(file.Mode, 8, 0)
+			if err != nil {
+				return fmt.Errorf(i18n.G("Invalid mode %q for %s"), file.Mode, file.Path)
+			}
+		}
+
+		err = fileCmd.recursiveMkdir(d, name, filepath.Dir(file.Path), nil, 0, 0)
+		if err != nil {
+			return err
+		}
+
+		args := lxd.InstanceFileArgs{
+			Content:   bytes.NewReader(content),
+			UID:       file.UID,
+			GID:       file.GID,
+			Mode:      int(mode),
+			Type:      "file",
+			WriteMode: "overwrite",
+		}
+
+		err = d.CreateInstanceFile(name, file.Path, args)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Run the commands
+	for _, command := range recipe.Commands {
+		c.step(i18n.G("Running %s"), command)
+
+		req := api.InstanceExecPost{
+			Command:     []string{"sh", "-c", command},
+			WaitForWS:   true,
+			Interactive: false,
+			Environment: recipe.Environment,
+		}
+
+		execArgs := lxd.InstanceExecArgs{
+			Stdin:    ioutil.NopCloser(bytes.NewReader(nil)),
+			Stdout:   imageBuildLog{c.log},
+			Stderr:   imageBuildLog{c.log},
+			DataDone: make(chan bool),
+		}
+
+		op, err := d.ExecInstance(name, req, &execArgs)
+		if err != nil {
+			return err
+		}
+
+		err = op.Wait()
+		if err != nil {
+			return err
+		}
+
+		// Wait for any remaining I/O to be flushed
+		<-execArgs.DataDone
+
+		ret := int(op.Get().Metadata["return"].(float64))
+		if ret != 0 {
+			return fmt.Errorf(i18n.G("Command %q failed with exit code %d"), command, ret)
+		}
+	}
+
+	return nil
+}
+
+// Stop the build instance if it's running.
+func (c *cmdImageBuild) stop(d lxd.InstanceServer, name string) error {
+	state, _, err := d.GetInstanceState(name)
+	if err != nil {
+		return err
+	}
+
+	if state.StatusCode == api.Stopped {
+		return nil
+	}
+
+	op, err := d.UpdateInstanceState(name, api.InstanceStatePut{Action: string(shared.Stop), Timeout: -1, Force: true}, "")
+	if err != nil {
+		return err
+	}
+
+	return op.Wait()
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+)
+
+// Mirror
+type cmdImageMirror struct {
+	global *cmdGlobal
+	image  *cmdImage
+
+	flagPublic     bool
+	flagAutoUpdate bool
+	flagPrune      bool
+	flagDryRun     bool
+}
+
+func (c *cmdImageMirror) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("mirror <remote>: <remote>: [<filter>...]")
+	cmd.Short = i18n.G("Synchronize images between servers")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Synchronize images between servers
+
+Images of the source server which match all the filters and are missing on
+the target server are copied to it, along with their aliases. Aliases of the
+target server are moved to the images they point to on the source server.
+
+The filters are:
+ - "alias=<glob>" or "<glob>", for images with a matching alias
+ - "architecture=<name>", for images of the given architecture
+ - "type=container" or "type=virtual-machine", for images of the given type
+ - "<property>=<glob>", for images with a matching property
+
+With --prune, images of the target server which match the filters but
+aren't on the source server anymore are deleted. At least one filter must
+then be given, as all the images of the target server would match otherwise.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc image mirror images: local: alias=alpine/3.11* type=container architecture=x86_64
+    Mirror the Alpine 3.11 container images for x86_64 from the images: remote.
+
+lxc image mirror images: local: "alpine/*" --prune --dry-run
+    Show the changes needed to mirror all the Alpine images.`))
+
+	cmd.Flags().BoolVar(&c.flagPublic, "public", false, i18n.G("Make the copied images public"))
+	cmd.Flags().BoolVar(&c.flagAutoUpdate, "auto-update", false, i18n.G("Keep the copied images up to date"))
+	cmd.Flags().BoolVar(&c.flagPrune, "prune", false, i18n.G("Delete the images which aren't on the source server anymore"))
+	cmd.Flags().BoolVar(&c.flagDryRun, "dry-run", false, i18n.G("Only show the changes which would be made"))
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+// imageMirrorEntry is an image along with the aliases pointing to it.
+type imageMirrorEntry struct {
+	image   api.Image
+	aliases []string
+}
+
+// Return the images of the server, along with the aliases pointing to them,
+// indexed by fingerprint.
+func (c *cmdImageMirror) getImages(server lxd.ImageServer) (map[string]*imageMirrorEntry, error) {
+	images, err := server.GetImages()
+	if err != nil {
+		return nil, err
+	}
+
+	aliases, err := server.GetImageAliases()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := map[string]*imageMirrorEntry{}
+	for _, image := range images {
+		entries[image.Fingerprint] = &imageMirrorEntry{image: image, aliases: []string{}}
+	}
+
+	// Simplestreams servers list all the aliases of a product on each of
+	// its images, so only rely on the alias targets.
+	for _, alias := range aliases {
+		entry, ok := entries[alias.Target]
+		if !ok {
+			continue
+		}
+
+		entry.aliases = append(entry.aliases, alias.Name)
+	}
+
+	for _, entry := range entries {
+		sort.Strings(entry.aliases)
+	}
+
+	return entries, nil
+}
+
+// Check that the image matches all the filters.
+func (c *cmdImageMirror) imageMatches(filters []string, entry *imageMirrorEntry) (bool, error) {
+	for _, filter := range filters {
+		key := "alias"
+		value := filter
+		if strings.Contains(filter, "=") {
+			fields := strings.SplitN(filter, "=", 2)
+			key = fields[0]
+			value = fields[1]
+		}
+
+		candidates := []string{}
+		switch key {
+		case "alias":
+			candidates = entry.aliases
+		case "architecture":
+			candidates = []string{entry.image.Architecture}
+		case "type":
+			imageType := entry.image.Type
+			if imageType == "" {
+				imageType = "container"
+			}
+
+			candidates = []string{imageType}
+		default:
+			property, ok := entry.image.Properties[key]
+			if ok {
+				candidates = []string{property}
+			}
+		}
+
+		found := false
+		for _, candidate := range candidates {
+			match, err := filepath.Match(value, candidate)
+			if err != nil {
+				return false, fmt.Errorf(i18n.G("Bad filter %q: %v"), filter, err)
+			}
+
+			if match {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// Return the matching images, indexed by fingerprint.
+func (c *cmdImageMirror) filterImages(filters []string, entries map[string]*imageMirrorEntry) (map[string]*imageMirrorEntry, error) {
+	matching := map[string]*imageMirrorEntry{}
+
+	for fingerprint, entry := range entries {
+		match, err := c.imageMatches(filters, entry)
+		if err != nil {
+			return nil, err
+		}
+
+		if match {
+			matching[fingerprint] = entry
+		}
+	}
+
+	return matching, nil
+}
+
+// Return the fingerprints of the images, sorted.
+func (c *cmdImageMirror) sortedFingerprints(entries map[string]*imageMirrorEntry) []string {
+	fingerprints := []string{}
+	for fingerprint := range entries {
+		fingerprints = append(fingerprints, fingerprint)
+	}
+	sort.Strings(fingerprints)
+
+	return fingerprints
+}
+
+// Return a one line description of the image.
+func (c *cmdImageMirror) describe(entry *imageMirrorEntry) string {
+	description := entry.image.Fingerprint[0:12]
+
+	if len(entry.aliases) > 0 {
+		description += fmt.Sprintf(" (%s)", strings.Join(entry.aliases, ", "))
+	}
+
+	if entry.image.Properties["description"] != "" {
+		description += fmt.Sprintf(": %s", entry.image.Properties["description"])
+	}
+
+	return description
+}
+
+func (c *cmdImageMirror) Run(cmd *cobra.Command, args []string) error {
+	conf := c.global.conf
+
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, -1)
+	if exit {
+		return err
+	}
+
+	// Parse source remote
+	sourceName, name, err := conf.ParseRemote(args[0])
+	if err != nil {
+		return err
+	}
+
+	if name != "" {
+		return fmt.Errorf(i18n.G("Filters must be passed as separate arguments"))
+	}
+
+	sourceServer, err := conf.GetImageServer(sourceName)
+	if err != nil {
+		return err
+	}
+
+	// Parse destination remote
+	resources, err := c.global.ParseServers(args[1])
+	if err != nil {
+		return err
+	}
+
+	if resources[0].name != "" {
+		return fmt.Errorf(i18n.G("Filters must be passed as separate arguments"))
+	}
+
+	destinationServer := resources[0].server
+	filters := args[2:]
+
+	if c.flagPrune && len(filters) == 0 {
+		return fmt.Errorf(i18n.G("At least one filter is required with --prune"))
+	}
+
+	// Find the images to mirror
+	sourceImages, err := c.getImages(sourceServer)
+	if err != nil {
+		return err
+	}
+
+	sourceImages, err = c.filterImages(filters, sourceImages)
+	if err != nil {
+		return err
+	}
+
+	destinationImages, err := c.getImages(destinationServer)
+	if err != nil {
+		return err
+	}
+
+	// Copy the missing images
+	for _, fingerprint := range c.sortedFingerprints(sourceImages) {
+		entry := sourceImages[fingerprint]
+
+		_, ok := destinationImages[fingerprint]
+		if ok {
+			continue
+		}
+
+		fmt.Printf("+ %s\n", c.describe(entry))
+		if c.flagDryRun {
+			continue
+		}
+
+		copyArgs := lxd.ImageCopyArgs{
+			AutoUpdate:       c.flagAutoUpdate,
+			Public:           c.flagPublic,
+			Type:             entry.image.Type,
+			RequireSignature: conf.Remotes[sourceName].RequireSignature,
+		}
+
+		op, err := destinationServer.CopyImage(sourceServer, entry.image, &copyArgs)
+		if err != nil {
+			return err
+		}
+
+		progress := utils.ProgressRenderer{
+			Format: i18n.G("Copying the image: %s"),
+			Quiet:  c.global.flagQuiet,
+		}
+
+		_, err = op.AddHandler(progress.UpdateOp)
+		if err != nil {
+			progress.Done("")
+			return err
+		}
+
+		err = utils.CancelableWait(op, &progress)
+		if err != nil {
+			progress.Done("")
+			return err
+		}
+
+		progress.Done("")
+	}
+
+	// Point the aliases to the mirrored images
+	destinationAliases := map[string]string{}
+	for fingerprint, entry := range destinationImages {
+		for _, alias := range entry.aliases {
+			destinationAliases[alias] = fingerprint
+		}
+	}
+
+	for _, fingerprint := range c.sortedFingerprints(sourceImages) {
+		entry := sourceImages[fingerprint]
+
+		aliases := []api.ImageAlias{}
+		for _, alias := range entry.aliases {
+			current, ok := destinationAliases[alias]
+			if ok && current == fingerprint {
+				continue
+			}
+
+			if ok {
+				fmt.Printf("~ %s: %s -> %s\n", alias, current[0:12], fingerprint[0:12])
+			} else if destinationImages[fingerprint] != nil {
+				fmt.Printf("~ %s: -> %s\n", alias, fingerprint[0:12])
+			}
+
+			aliases = append(aliases, api.ImageAlias{Name: alias})
+		}
+
+		if c.flagDryRun || len(aliases) == 0 {
+			continue
+		}
+
+		err = ensureImageAliases(destinationServer, aliases, fingerprint)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Delete the images which aren't on the source server anymore
+	if !c.flagPrune {
+		return nil
+	}
+
+	// Match the filters against the aliases the images had before this run,
+	// so that images whose aliases were just moved away are pruned too.
+	destinationImages, err = c.filterImages(filters, destinationImages)
+	if err != nil {
+		return err
+	}
+
+	for _, fingerprint := range c.sortedFingerprints(destinationImages) {
+		entry := destinationImages[fingerprint]
+
+		// Cached images are managed by the server itself.
+		if entry.image.Cached {
+			continue
+		}
+
+		_, ok := sourceImages[fingerprint]
+		if ok {
+			continue
+		}
+
+		fmt.Printf("- %s\n", c.describe(entry))
+		if c.flagDryRun {
+			continue
+		}
+
+		op, err := destinationServer.DeleteImage(fingerprint)
+		if err != nil {
+			return err
+		}
+
+		err = op.Wait()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+)
+
+// Prune
+type cmdImagePrune struct {
+	global *cmdGlobal
+	image  *cmdImage
+
+	flagUnusedFor      string
+	flagCached         bool
+	flagIncludeAliased bool
+	flagDryRun         bool
+}
+
+func (c *cmdImagePrune) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("prune [<remote>:]")
+	cmd.Short = i18n.G("Delete unused images")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Delete unused images
+
+Images which no instance was created from are deleted, except for those with
+aliases unless --include-aliased is passed. The server keeps the images which
+instances in projects the client can't see were created from.
+
+The --unused-for flag restricts this to the images which haven't been used
+for a given time, expressed as in snapshots.expiry (e.g. 2w or 1m).`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc image prune --cached --unused-for 2w --dry-run
+    Show the cached images which haven't been used for two weeks.`))
+
+	cmd.Flags().StringVar(&c.flagUnusedFor, "unused-for", "", i18n.G("Only delete the images which haven't been used for the given time")+"``")
+	cmd.Flags().BoolVar(&c.flagCached, "cached", false, i18n.G("Only delete cached images"))
+	cmd.Flags().BoolVar(&c.flagIncludeAliased, "include-aliased", false, i18n.G("Also delete images with aliases"))
+	cmd.Flags().BoolVar(&c.flagDryRun, "dry-run", false, i18n.G("Only show the images which would be deleted"))
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+// Check whether the image is unused as per the flags.
+func (c *cmdImagePrune) unused(image api.Image, now time.Time) (bool, error) {
+	// Storage pools holding an optimized volume don't count as users.
+	for _, entry := range image.UsedBy {
+		if !strings.HasPrefix(entry, "/1.0/storage-pools/") {
+			return false, nil
+		}
+	}
+
+	if c.flagCached && !image.Cached {
+		return false, nil
+	}
+
+	if !c.flagIncludeAliased && len(image.Aliases) > 0 {
+		return false, nil
+	}
+
+	if c.flagUnusedFor != "" {
+		lastUsed := image.LastUsedAt
+		if lastUsed.IsZero() || lastUsed.Unix() <= 0 {
+			lastUsed = image.UploadedAt
+		}
+
+		expiry, err := shared.GetSnapshotExpiry(lastUsed, c.flagUnusedFor)
+		if err != nil {
+			return false, err
+		}
+
+		if expiry.After(now) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func (c *cmdImagePrune) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	remote := ""
+	if len(args) > 0 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	if resources[0].name != "" {
+		return fmt.Errorf(i18n.G("Only a remote can be given to prune images from"))
+	}
+
+	d := resources[0].server
+	if !d.HasExtension("image_used_by") {
+		return fmt.Errorf(i18n.G("The server doesn't track image usage"))
+	}
+
+	_, err = shared.GetSnapshotExpiry(time.Now(), c.flagUnusedFor)
+	if err != nil {
+		return fmt.Errorf(i18n.G("Invalid --unused-for value %q: %v"), c.flagUnusedFor, err)
+	}
+
+	images, err := d.GetImages()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].Fingerprint < images[j].Fingerprint
+	})
+
+	now := time.Now()
+	for _, image := range images {
+		unused, err := c.unused(image, now)
+		if err != nil {
+			return err
+		}
+
+		if !unused {
+			continue
+		}
+
+		description := image.Properties["description"]
+		if description == "" {
+			description = image.Filename
+		}
+
+		fmt.Printf("- %s: %s\n", image.Fingerprint[0:12], description)
+		if c.flagDryRun {
+			continue
+		}
+
+		// The server refuses to delete the images which instances the
+		// client can't see were created from.
+		op, err := d.DeleteImage(image.Fingerprint)
+		if err != nil {
+			if err.Error() == "The image is still used by instances" {
+				fmt.Printf("  %s\n", i18n.G("Kept as still used by instances"))
+				continue
+			}
+
+			return err
+		}
+
+		err = op.Wait()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -274,6 +274,9 @@ func (c *cmdInit) create(conf *config.Config, args []string) (lxd.InstanceServer
 			}
 		}
 
+		// Only accept signed images if the remote requires it
+		req.Source.RequireSignature = conf.Remotes[iremote].RequireSignature
+
 		// Create the instance
 		op, err := d.CreateInstanceFromImage(imgRemote, *imgInfo, req)
 		if err != nil {
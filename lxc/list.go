@@ -34,6 +34,7 @@ type cmdList struct {
 	flagColumns string
 	flagFast    bool
 	flagFormat  string
+	flagFilter  string
 }
 
 func (c *cmdList) Command() *cobra.Command {
@@ -64,6 +65,11 @@ A regular expression matching a configuration item or its value. (e.g. volatile.
 When multiple filters are passed, they are added one on top of the other,
 selecting containers which satisfy them all.
 
+The --filter option takes an expression evaluated by the server, such as
+"status eq Running and config.user.team eq web". Fields are the keys of the
+API representation of the containers, compared using eq or ne and combined
+using and, or, not and parentheses.
+
 == Columns ==
 The -c option takes a comma separated list of arguments that control
 which container attributes to output when displaying in table or csv
@@ -108,12 +114,16 @@ Custom columns are defined with "[config:|devices:]key[:name][:maxWidth]":
   "ETHP" is a custom column generated from a device key.
 
 lxc list -c ns,user.comment:comment
-  List images with their running state and user comment.`))
+  List images with their running state and user comment.
+
+lxc list --filter "status eq Running and config.user.team eq web"
+  List the running containers of the web team, filtered by the server.`))
 
 	cmd.RunE = c.Run
 	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", defaultColumns, i18n.G("Columns")+"``")
 	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
 	cmd.Flags().BoolVar(&c.flagFast, "fast", false, i18n.G("Fast mode (same as --columns=nsacPt)"))
+	cmd.Flags().StringVar(&c.flagFilter, "filter", "", i18n.G("Filter expression evaluated by the server")+"``")
 
 	return cmd
 }
@@ -386,9 +396,20 @@ func (c *cmdList) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// Server-side filter
+	listArgs := lxd.ListArgs{Filter: c.flagFilter}
+	if c.flagFilter != "" && !d.HasExtension("list_filters") {
+		return fmt.Errorf(i18n.G("The server doesn't support server-side filtering"))
+	}
+
 	if len(filters) == 0 && needsData && d.HasExtension("container_full") {
 		// Using the GetInstancesFull shortcut
-		cts, err := d.GetInstancesFull(api.InstanceTypeAny)
+		var cts []api.InstanceFull
+		if c.flagFilter != "" {
+			cts, _, err = d.GetInstancesFullWithArgs(api.InstanceTypeAny, listArgs)
+		} else {
+			cts, err = d.GetInstancesFull(api.InstanceTypeAny)
+		}
 		if err != nil {
 			return err
 		}
@@ -398,7 +419,12 @@ func (c *cmdList) Run(cmd *cobra.Command, args []string) error {
 
 	// Get the list of containers
 	var cts []api.Instance
-	ctslist, err := d.GetInstances(api.InstanceTypeAny)
+	var ctslist []api.Instance
+	if c.flagFilter != "" {
+		ctslist, _, err = d.GetInstancesWithArgs(api.InstanceTypeAny, listArgs)
+	} else {
+		ctslist, err = d.GetInstances(api.InstanceTypeAny)
+	}
 	if err != nil {
 		return err
 	}
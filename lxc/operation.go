@@ -9,6 +9,7 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared/api"
 	cli "github.com/lxc/lxd/shared/cmd"
 	"github.com/lxc/lxd/shared/i18n"
 )
@@ -91,7 +92,8 @@ type cmdOperationList struct {
 	global    *cmdGlobal
 	operation *cmdOperation
 
-	flagFormat string
+	flagFormat     string
+	flagAllHistory bool
 }
 
 func (c *cmdOperationList) Command() *cobra.Command {
@@ -102,6 +104,7 @@ func (c *cmdOperationList) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`List background operations`))
 	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+	cmd.Flags().BoolVar(&c.flagAllHistory, "all-history", false, i18n.G("Include the recorded completed operations"))
 
 	cmd.RunE = c.Run
 
@@ -132,7 +135,12 @@ func (c *cmdOperationList) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get operations
-	operations, err := resource.server.GetOperations()
+	var operations []api.Operation
+	if c.flagAllHistory {
+		operations, err = resource.server.GetOperationsAllHistory()
+	} else {
+		operations, err = resource.server.GetOperations()
+	}
 	if err != nil {
 		return err
 	}
@@ -76,6 +76,8 @@ type cmdRemoteAdd struct {
 	flagProtocol   string
 	flagAuthType   string
 	flagDomain     string
+
+	flagRequireSignature bool
 }
 
 func (c *cmdRemoteAdd) Command() *cobra.Command {
@@ -95,9 +97,10 @@ Basic authentication can be used when combined with the "simplestreams" protocol
 	cmd.Flags().BoolVar(&c.flagAcceptCert, "accept-certificate", false, i18n.G("Accept certificate"))
 	cmd.Flags().StringVar(&c.flagPassword, "password", "", i18n.G("Remote admin password")+"``")
 	cmd.Flags().StringVar(&c.flagProtocol, "protocol", "", i18n.G("Server protocol (lxd or simplestreams)")+"``")
-	cmd.Flags().StringVar(&c.flagAuthType, "auth-type", "", i18n.G("Server authentication type (tls or candid)")+"``")
+	cmd.Flags().StringVar(&c.flagAuthType, "auth-type", "", i18n.G("Server authentication type (tls, candid or oidc)")+"``")
 	cmd.Flags().BoolVar(&c.flagPublic, "public", false, i18n.G("Public image server"))
 	cmd.Flags().StringVar(&c.flagDomain, "domain", "", i18n.G("Candid domain to use")+"``")
+	cmd.Flags().BoolVar(&c.flagRequireSignature, "require-signature", false, i18n.G("Only accept images signed by a trusted key from this remote"))
 
 	return cmd
 }
@@ -150,7 +153,7 @@ func (c *cmdRemoteAdd) Run(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf(i18n.G("Only https URLs are supported for simplestreams"))
 		}
 
-		conf.Remotes[server] = config.Remote{Addr: addr, Public: true, Protocol: c.flagProtocol}
+		conf.Remotes[server] = config.Remote{Addr: addr, Public: true, Protocol: c.flagProtocol, RequireSignature: c.flagRequireSignature}
 		return conf.SaveConfig(c.global.confPath)
 	} else if c.flagProtocol != "lxd" {
 		return fmt.Errorf(i18n.G("Invalid protocol: %s"), c.flagProtocol)
@@ -219,7 +222,7 @@ func (c *cmdRemoteAdd) Run(cmd *cobra.Command, args []string) error {
 			}
 		}
 	}
-	conf.Remotes[server] = config.Remote{Addr: addr, Protocol: c.flagProtocol, AuthType: c.flagAuthType, Domain: c.flagDomain}
+	conf.Remotes[server] = config.Remote{Addr: addr, Protocol: c.flagProtocol, AuthType: c.flagAuthType, Domain: c.flagDomain, RequireSignature: c.flagRequireSignature}
 
 	// Attempt to connect
 	var d lxd.ImageServer
@@ -297,11 +300,11 @@ func (c *cmdRemoteAdd) Run(cmd *cobra.Command, args []string) error {
 
 	// Handle public remotes
 	if c.flagPublic {
-		conf.Remotes[server] = config.Remote{Addr: addr, Public: true}
+		conf.Remotes[server] = config.Remote{Addr: addr, Public: true, RequireSignature: c.flagRequireSignature}
 		return conf.SaveConfig(c.global.confPath)
 	}
 
-	if c.flagAuthType == "candid" {
+	if c.flagAuthType == "candid" || c.flagAuthType == "oidc" {
 		d.(lxd.InstanceServer).RequireAuthenticated(false)
 	}
 
@@ -349,7 +352,7 @@ func (c *cmdRemoteAdd) Run(cmd *cobra.Command, args []string) error {
 
 	// Detect public remotes
 	if srv.Public {
-		conf.Remotes[server] = config.Remote{Addr: addr, Public: true}
+		conf.Remotes[server] = config.Remote{Addr: addr, Public: true, RequireSignature: c.flagRequireSignature}
 		return conf.SaveConfig(c.global.confPath)
 	}
 
@@ -629,6 +632,7 @@ func (c *cmdRemoteRemove) Run(cmd *cobra.Command, args []string) error {
 
 	os.Remove(conf.ServerCertPath(args[0]))
 	os.Remove(conf.CookiesPath(args[0]))
+	os.Remove(conf.OIDCTokensPath(args[0]))
 
 	return conf.SaveConfig(c.global.confPath)
 }
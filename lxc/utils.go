@@ -183,15 +183,23 @@ func ensureImageAliases(client lxd.InstanceServer, aliases []api.ImageAlias, fin
 		return err
 	}
 
-	// Delete existing aliases that match provided ones
+	// Point existing aliases that match provided ones to the new image
+	existing := map[string]bool{}
 	for _, alias := range GetExistingAliases(names, resp) {
-		err := client.DeleteImageAlias(alias.Name)
+		existing[alias.Name] = true
+
+		err := client.UpdateImageAlias(alias.Name, api.ImageAliasesEntryPut{Description: alias.Description, Target: fingerprint}, "")
 		if err != nil {
-			fmt.Println(fmt.Sprintf(i18n.G("Failed to remove alias %s"), alias.Name))
+			fmt.Println(fmt.Sprintf(i18n.G("Failed to update alias %s"), alias.Name))
 		}
 	}
+
 	// Create new aliases
 	for _, alias := range aliases {
+		if existing[alias.Name] {
+			continue
+		}
+
 		aliasPost := api.ImageAliasesPost{}
 		aliasPost.Name = alias.Name
 		aliasPost.Target = fingerprint
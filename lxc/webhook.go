@@ -0,0 +1,435 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+	"github.com/lxc/lxd/shared/termios"
+)
+
+type cmdWebhook struct {
+	global *cmdGlobal
+}
+
+func (c *cmdWebhook) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("webhook")
+	cmd.Short = i18n.G("Manage webhooks")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage webhooks
+
+Lifecycle and operation events are sent to webhooks as POST requests.
+Events can be restricted to some types ("lifecycle" or "operation") or
+actions (e.g. "lifecycle/container-started" or "operation/failure"), and to
+some projects.`))
+
+	// Create
+	webhookCreateCmd := cmdWebhookCreate{global: c.global, webhook: c}
+	cmd.AddCommand(webhookCreateCmd.Command())
+
+	// Delete
+	webhookDeleteCmd := cmdWebhookDelete{global: c.global, webhook: c}
+	cmd.AddCommand(webhookDeleteCmd.Command())
+
+	// Edit
+	webhookEditCmd := cmdWebhookEdit{global: c.global, webhook: c}
+	cmd.AddCommand(webhookEditCmd.Command())
+
+	// List
+	webhookListCmd := cmdWebhookList{global: c.global, webhook: c}
+	cmd.AddCommand(webhookListCmd.Command())
+
+	// Show
+	webhookShowCmd := cmdWebhookShow{global: c.global, webhook: c}
+	cmd.AddCommand(webhookShowCmd.Command())
+
+	return cmd
+}
+
+// Create
+type cmdWebhookCreate struct {
+	global  *cmdGlobal
+	webhook *cmdWebhook
+
+	flagDescription string
+	flagSecret      string
+	flagEvents      []string
+	flagProjects    []string
+}
+
+func (c *cmdWebhookCreate) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("create [<remote>:]<webhook> <URL>")
+	cmd.Short = i18n.G("Create webhooks")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Create webhooks`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc webhook create chatops https://chat.example.com/hooks/lxd --event lifecycle --event operation/failure --secret s3cr3t
+    Send the lifecycle events and the operation failures to the given URL, signing them with the given secret.`))
+
+	cmd.Flags().StringVar(&c.flagDescription, "description", "", i18n.G("Webhook description")+"``")
+	cmd.Flags().StringVar(&c.flagSecret, "secret", "", i18n.G("Secret used to sign the requests")+"``")
+	cmd.Flags().StringSliceVar(&c.flagEvents, "event", nil, i18n.G("Event type or action to deliver (all if none)")+"``")
+	cmd.Flags().StringSliceVar(&c.flagProjects, "project", nil, i18n.G("Project whose events are delivered (all if none)")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdWebhookCreate) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing webhook name"))
+	}
+
+	webhook := api.WebhooksPost{Name: resource.name}
+	webhook.Description = c.flagDescription
+	webhook.URL = args[1]
+	webhook.Secret = c.flagSecret
+	webhook.Events = c.flagEvents
+	webhook.Projects = c.flagProjects
+	webhook.Config = map[string]string{}
+
+	if webhook.Events == nil {
+		webhook.Events = []string{}
+	}
+
+	if webhook.Projects == nil {
+		webhook.Projects = []string{}
+	}
+
+	err = resource.server.CreateWebhook(webhook)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Webhook %s created")+"\n", resource.name)
+	}
+
+	return nil
+}
+
+// Delete
+type cmdWebhookDelete struct {
+	global  *cmdGlobal
+	webhook *cmdWebhook
+}
+
+func (c *cmdWebhookDelete) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("delete [<remote>:]<webhook>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Delete webhooks")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Delete webhooks`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdWebhookDelete) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing webhook name"))
+	}
+
+	err = resource.server.DeleteWebhook(resource.name)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Webhook %s deleted")+"\n", resource.name)
+	}
+
+	return nil
+}
+
+// Edit
+type cmdWebhookEdit struct {
+	global  *cmdGlobal
+	webhook *cmdWebhook
+}
+
+func (c *cmdWebhookEdit) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("edit [<remote>:]<webhook>")
+	cmd.Short = i18n.G("Edit webhooks as YAML")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Edit webhooks as YAML`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc webhook edit <webhook> < webhook.yaml
+    Update a webhook using the content of webhook.yaml`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdWebhookEdit) helpTemplate() string {
+	return i18n.G(
+		`### This is a yaml representation of the webhook.
+### Any line starting with a '# will be ignored.
+###
+### A webhook consists of the URL events are sent to, along with the events
+### and projects it's restricted to, if any.
+###
+### An example would look like:
+### name: chatops
+### description: Chat notifications
+### url: https://chat.example.com/hooks/lxd
+### events:
+### - lifecycle
+### - operation/failure
+### projects:
+### - default
+### config:
+###   retry.count: "3"
+###
+### The secret isn't shown and is left unchanged unless a new one is set.
+### Note that the name is shown but cannot be changed`)
+}
+
+func (c *cmdWebhookEdit) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing webhook name"))
+	}
+
+	// If stdin isn't a terminal, read text from it
+	if !termios.IsTerminal(getStdinFd()) {
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		newdata := api.WebhookPut{}
+		err = yaml.Unmarshal(contents, &newdata)
+		if err != nil {
+			return err
+		}
+
+		return resource.server.UpdateWebhook(resource.name, newdata, "")
+	}
+
+	// Extract the current value
+	webhook, etag, err := resource.server.GetWebhook(resource.name)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&webhook)
+	if err != nil {
+		return err
+	}
+
+	// Spawn the editor
+	content, err := shared.TextEditor("", []byte(c.helpTemplate()+"\n\n"+string(data)))
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Parse the text received from the editor
+		newdata := api.WebhookPut{}
+		err = yaml.Unmarshal(content, &newdata)
+		if err == nil {
+			err = resource.server.UpdateWebhook(resource.name, newdata, etag)
+		}
+
+		// Respawn the editor
+		if err != nil {
+			fmt.Fprintf(os.Stderr, i18n.G("Config parsing error: %s")+"\n", err)
+			fmt.Println(i18n.G("Press enter to open the editor again"))
+
+			_, err := os.Stdin.Read(make([]byte, 1))
+			if err != nil {
+				return err
+			}
+
+			content, err = shared.TextEditor("", content)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		break
+	}
+
+	return nil
+}
+
+// List
+type cmdWebhookList struct {
+	global  *cmdGlobal
+	webhook *cmdWebhook
+
+	flagFormat string
+}
+
+func (c *cmdWebhookList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list [<remote>:]")
+	cmd.Aliases = []string{"ls"}
+	cmd.Short = i18n.G("List webhooks")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List webhooks`))
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdWebhookList) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) > 0 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	webhooks, err := resource.server.GetWebhooks()
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, webhook := range webhooks {
+		data = append(data, []string{
+			webhook.Name,
+			webhook.Description,
+			webhook.URL,
+			strings.Join(webhook.Events, "\n"),
+			strings.Join(webhook.Projects, "\n"),
+		})
+	}
+	sort.Sort(stringList(data))
+
+	header := []string{
+		i18n.G("NAME"),
+		i18n.G("DESCRIPTION"),
+		i18n.G("URL"),
+		i18n.G("EVENTS"),
+		i18n.G("PROJECTS"),
+	}
+
+	return utils.RenderTable(c.flagFormat, header, data, webhooks)
+}
+
+// Show
+type cmdWebhookShow struct {
+	global  *cmdGlobal
+	webhook *cmdWebhook
+}
+
+func (c *cmdWebhookShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("show [<remote>:]<webhook>")
+	cmd.Short = i18n.G("Show webhook details")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show webhook details`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdWebhookShow) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing webhook name"))
+	}
+
+	webhook, _, err := resource.server.GetWebhook(resource.name)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&webhook)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
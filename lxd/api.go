@@ -30,6 +30,10 @@ func RestServer(d *Daemon) *http.Server {
 		mux.HandleFunc(endpoint, f)
 	}
 
+	for endpoint, f := range simpleStreamsHandlers(d) {
+		mux.HandleFunc(endpoint, f)
+	}
+
 	for _, c := range api10 {
 		d.createCmd(mux, "1.0", c)
 
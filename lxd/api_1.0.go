@@ -32,9 +32,15 @@ var api10Cmd = APIEndpoint{
 var api10 = []APIEndpoint{
 	api10Cmd,
 	api10ResourcesCmd,
+	auditCmd,
+	authTokensCmd,
+	authTokenCmd,
+	authGroupsCmd,
+	authGroupCmd,
 	certificateCmd,
 	certificatesCmd,
 	clusterCmd,
+	clusterCertificateCmd,
 	clusterNodeCmd,
 	clusterNodesCmd,
 	instanceBackupCmd,
@@ -60,6 +66,7 @@ var api10 = []APIEndpoint{
 	imageRefreshCmd,
 	imagesCmd,
 	imageSecretCmd,
+	metricsCmd,
 	networkCmd,
 	networkLeasesCmd,
 	networksCmd,
@@ -83,6 +90,8 @@ var api10 = []APIEndpoint{
 	storagePoolVolumeTypeCustomCmd,
 	storagePoolVolumeTypeImageCmd,
 	storagePoolVolumeTypeVMCmd,
+	webhookCmd,
+	webhooksCmd,
 }
 
 func api10Get(d *Daemon, r *http.Request) response.Response {
@@ -99,6 +108,11 @@ func api10Get(d *Daemon, r *http.Request) response.Response {
 			authMethods = append(authMethods, "candid")
 		}
 
+		oidcIssuer, oidcClientID, _, _ := config.OIDCServer()
+		if oidcIssuer != "" && oidcClientID != "" {
+			authMethods = append(authMethods, "oidc")
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -244,6 +258,12 @@ func api10Get(d *Daemon, r *http.Request) response.Response {
 		return response.InternalError(err)
 	}
 
+	// Restricted certificates don't get to see the server configuration.
+	_, restricted := d.userRestrictedProjects(r)
+	if restricted {
+		fullSrv.Config = map[string]interface{}{}
+	}
+
 	return response.SyncResponseETag(true, fullSrv, fullSrv.Config)
 }
 
@@ -401,7 +421,7 @@ func doApi10Update(d *Daemon, req api.ServerPut, patch bool) response.Response {
 
 		if strings.HasPrefix(k, "candid.") {
 			hasCandid = true
-		} else if strings.HasPrefix(k, "rbac.") {
+		} else if strings.HasPrefix(k, "rbac.") && k != "rbac.local" {
 			hasRBAC = true
 		}
 
@@ -424,7 +444,16 @@ func doApi10Update(d *Daemon, req api.ServerPut, patch bool) response.Response {
 		} else {
 			clusterChanged, err = newClusterConfig.Replace(req.Config)
 		}
-		return err
+		if err != nil {
+			return err
+		}
+
+		rbacAPIURL, _, _, _, _, _, _ := newClusterConfig.RBACServer()
+		if newClusterConfig.LocalRBAC() && rbacAPIURL != "" {
+			return config.ErrorList{&config.Error{Name: "rbac.local", Value: true, Reason: "The built-in and external RBAC backends are mutually exclusive"}}
+		}
+
+		return nil
 	})
 	if err != nil {
 		switch err.(type) {
@@ -472,6 +501,8 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 	maasChanged := false
 	candidChanged := false
 	rbacChanged := false
+	oidcChanged := false
+	localRBACChanged := false
 
 	for key := range clusterChanged {
 		switch key {
@@ -493,6 +524,11 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 			fallthrough
 		case "candid.api.url":
 			candidChanged = true
+		case "core.audit_syslog":
+			err := d.audit.SetSyslog(clusterConfig.AuditSyslog())
+			if err != nil {
+				return err
+			}
 		case "images.auto_update_interval":
 			if !d.os.MockMode {
 				d.taskAutoUpdate.Reset()
@@ -501,6 +537,18 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 			if !d.os.MockMode {
 				d.taskPruneImages.Reset()
 			}
+		case "operations.history_expiry":
+			if !d.os.MockMode {
+				d.taskPruneOperationRecords.Reset()
+			}
+		case "oidc.audience":
+			fallthrough
+		case "oidc.claim":
+			fallthrough
+		case "oidc.client.id":
+			fallthrough
+		case "oidc.issuer":
+			oidcChanged = true
 		case "rbac.agent.url":
 			fallthrough
 		case "rbac.agent.username":
@@ -515,6 +563,12 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 			fallthrough
 		case "rbac.expiry":
 			rbacChanged = true
+		case "rbac.local":
+			localRBACChanged = true
+		case "core.rate_limit":
+			fallthrough
+		case "core.rate_limit_burst":
+			d.setupRateLimit(clusterConfig.RateLimit())
 		}
 	}
 
@@ -585,6 +639,14 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 		}
 	}
 
+	if oidcChanged {
+		d.setupOIDC(clusterConfig.OIDCServer())
+	}
+
+	if localRBACChanged {
+		d.setupLocalRBAC(clusterConfig.LocalRBAC())
+	}
+
 	if rbacChanged {
 		apiURL, apiKey, apiExpiry, agentURL, agentUsername, agentPrivateKey, agentPublicKey := clusterConfig.RBACServer()
 
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lxc/lxd/lxd/audit"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
+
+	log "github.com/lxc/lxd/shared/log15"
+)
+
+var auditCmd = APIEndpoint{
+	Path: "audit",
+
+	Get: APIEndpointAction{Handler: auditGet},
+}
+
+// Return the recent entries of the audit log of this member.
+func auditGet(d *Daemon, r *http.Request) response.Response {
+	// If a target was specified, forward the request to the relevant node.
+	resp := ForwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	filter := audit.Filter{
+		User:    r.FormValue("user"),
+		Project: r.FormValue("project"),
+		Limit:   100,
+	}
+
+	if r.FormValue("since") != "" {
+		since, err := time.Parse(time.RFC3339, r.FormValue("since"))
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid since timestamp: %v", err))
+		}
+
+		filter.Since = since
+	}
+
+	if r.FormValue("before") != "" {
+		before, err := time.Parse(time.RFC3339Nano, r.FormValue("before"))
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid before timestamp: %v", err))
+		}
+
+		filter.Before = before
+	}
+
+	if r.FormValue("limit") != "" {
+		limit, err := strconv.Atoi(r.FormValue("limit"))
+		if err != nil || limit < 0 {
+			return response.BadRequest(fmt.Errorf("Invalid limit %q", r.FormValue("limit")))
+		}
+
+		filter.Limit = limit
+	}
+
+	entries, err := d.audit.Entries(filter)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, entries)
+}
+
+// Body of a request, hashing what the handler reads.
+type auditBody struct {
+	io.ReadCloser
+	hash hash.Hash
+}
+
+func (b *auditBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.hash.Write(p[:n])
+	return n, err
+}
+
+// Response writer capturing the status code.
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *auditResponseWriter) Flush() {
+	flusher, ok := w.ResponseWriter.(http.Flusher)
+	if ok {
+		flusher.Flush()
+	}
+}
+
+func (w *auditResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("Response writer doesn't support hijacking")
+	}
+
+	// Switching protocols.
+	w.status = http.StatusSwitchingProtocols
+
+	return hijacker.Hijack()
+}
+
+// Wrap the given request and response writer for recording the request in
+// the audit log, once the returned function is called.
+func auditRequest(d *Daemon, w http.ResponseWriter, r *http.Request, username string, protocol string) (http.ResponseWriter, *http.Request, func()) {
+	body := &auditBody{ReadCloser: r.Body, hash: sha256.New()}
+	r.Body = body
+
+	recorder := &auditResponseWriter{ResponseWriter: w}
+
+	entry := api.AuditEntry{
+		Timestamp: time.Now().UTC(),
+		User:      username,
+		Protocol:  protocol,
+		Address:   r.RemoteAddr,
+		Method:    r.Method,
+		URL:       r.URL.RequestURI(),
+		Project:   projectParam(r),
+	}
+
+	done := func() {
+		entry.BodyDigest = fmt.Sprintf("%x", body.hash.Sum(nil))
+		entry.StatusCode = recorder.status
+
+		// Background operations are referred to by their location.
+		prefix := fmt.Sprintf("/%s/operations/", version.APIVersion)
+		location := recorder.Header().Get("Location")
+		if strings.HasPrefix(location, prefix) {
+			entry.Operation = strings.TrimPrefix(location, prefix)
+		}
+
+		err := d.audit.Log(entry)
+		if err != nil {
+			logger.Warn("Failed to record request in audit log", log.Ctx{"url": entry.URL, "err": err})
+		}
+	}
+
+	return recorder, r, done
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -37,10 +38,17 @@ var clusterCmd = APIEndpoint{
 	Put: APIEndpointAction{Handler: clusterPut},
 }
 
+var clusterCertificateCmd = APIEndpoint{
+	Path: "cluster/certificate",
+
+	Put: APIEndpointAction{Handler: clusterCertificatePut},
+}
+
 var clusterNodesCmd = APIEndpoint{
 	Path: "cluster/members",
 
-	Get: APIEndpointAction{Handler: clusterNodesGet, AccessHandler: AllowAuthenticated},
+	Get:  APIEndpointAction{Handler: clusterNodesGet, AccessHandler: AllowAuthenticated},
+	Post: APIEndpointAction{Handler: clusterNodesPost},
 }
 
 var clusterNodeCmd = APIEndpoint{
@@ -57,6 +65,14 @@ var internalClusterAcceptCmd = APIEndpoint{
 	Post: APIEndpointAction{Handler: internalClusterPostAccept},
 }
 
+var internalClusterCertificateCmd = APIEndpoint{
+	Path: "cluster/certificate",
+
+	Delete: APIEndpointAction{Handler: internalClusterCertificateDelete},
+	Post:   APIEndpointAction{Handler: internalClusterCertificatePost},
+	Put:    APIEndpointAction{Handler: internalClusterCertificatePut},
+}
+
 var internalClusterRebalanceCmd = APIEndpoint{
 	Path: "cluster/rebalance",
 
@@ -180,6 +196,24 @@ func clusterPut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	// If a join token was provided, the server name defaults to the one
+	// the token was issued for.
+	var token *api.ClusterMemberJoinToken
+	if req.ClusterToken != "" {
+		token, err = cluster.DecodeJoinToken(req.ClusterToken)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		if req.ServerName == "" {
+			req.ServerName = token.ServerName
+		}
+
+		if req.ServerName != token.ServerName {
+			return response.BadRequest(fmt.Errorf("The join token was issued for a member with name %q", token.ServerName))
+		}
+	}
+
 	// Sanity checks
 	if req.ServerName == "" && req.Enabled {
 		return response.BadRequest(fmt.Errorf("ServerName is required when enabling clustering"))
@@ -196,11 +230,11 @@ func clusterPut(d *Daemon, r *http.Request) response.Response {
 	// Depending on the provided parameters we either bootstrap a brand new
 	// cluster with this node as first node, or perform a request to join a
 	// given cluster.
-	if req.ClusterAddress == "" {
+	if req.ClusterAddress == "" && token == nil {
 		return clusterPutBootstrap(d, req)
 	}
 
-	return clusterPutJoin(d, req)
+	return clusterPutJoin(d, req, token)
 }
 
 func clusterPutBootstrap(d *Daemon, req api.ClusterPut) response.Response {
@@ -254,7 +288,7 @@ func clusterPutBootstrap(d *Daemon, req api.ClusterPut) response.Response {
 
 	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationClusterBootstrap, resources, nil, run, nil, nil)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	// Add the cluster flag from the agent
@@ -263,7 +297,20 @@ func clusterPutBootstrap(d *Daemon, req api.ClusterPut) response.Response {
 	return operations.OperationResponse(op)
 }
 
-func clusterPutJoin(d *Daemon, req api.ClusterPut) response.Response {
+func clusterPutJoin(d *Daemon, req api.ClusterPut, token *api.ClusterMemberJoinToken) response.Response {
+	// When joining with a token, find a reachable cluster member matching
+	// the token and use the token secret to authenticate.
+	if token != nil {
+		clusterAddress, clusterCert, err := cluster.JoinTokenTarget(token)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		req.ClusterAddress = clusterAddress
+		req.ClusterCertificate = clusterCert
+		req.ClusterPassword = token.Secret
+	}
+
 	// Make sure basic pre-conditions are met.
 	if len(req.ClusterCertificate) == 0 {
 		return response.BadRequest(fmt.Errorf("No target cluster member certificate provided"))
@@ -625,7 +672,7 @@ func clusterPutJoin(d *Daemon, req api.ClusterPut) response.Response {
 
 	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationClusterJoin, resources, nil, run, nil, nil)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
@@ -799,6 +846,165 @@ func clusterInitMember(d, client lxd.InstanceServer, memberConfig []api.ClusterM
 	return nil
 }
 
+// Replace the cluster certificate on all members.
+func clusterCertificatePut(d *Daemon, r *http.Request) response.Response {
+	req := api.ClusterCertificatePut{}
+
+	// Parse the request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	cert := []byte(req.ClusterCertificate)
+	key := []byte(req.ClusterCertificateKey)
+
+	// Sanity checks
+	_, err = tls.X509KeyPair(cert, key)
+	if err != nil {
+		return response.BadRequest(errors.Wrap(err, "Invalid certificate key pair"))
+	}
+
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !clustered {
+		return response.BadRequest(fmt.Errorf("This server is not clustered"))
+	}
+
+	notifier, err := cluster.NewNotifier(d.State(), d.endpoints.NetworkCert(), cluster.NotifyAll)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Stage the new certificate on all members first, so that it's only
+	// used once every member has it.
+	err = clusterCertificateStage(d, cert, key)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = notifier(func(client lxd.InstanceServer) error {
+		_, _, err := client.RawQuery("PUT", "/internal/cluster/certificate", req, "")
+		return err
+	})
+	if err != nil {
+		notifier(func(client lxd.InstanceServer) error {
+			_, _, err := client.RawQuery("DELETE", "/internal/cluster/certificate", nil, "")
+			return err
+		})
+		clusterCertificateUnstage(d)
+
+		return response.SmartError(errors.Wrap(err, "Failed to stage certificate on other members"))
+	}
+
+	// Then switch all the members to it, this one last as the other ones
+	// are reached with the current certificate.
+	err = notifier(func(client lxd.InstanceServer) error {
+		_, _, err := client.RawQuery("POST", "/internal/cluster/certificate", nil, "")
+		return err
+	})
+	if err != nil {
+		return response.SmartError(errors.Wrap(err, "Failed to update certificate on other members"))
+	}
+
+	err = clusterCertificateCommit(d)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// Write the given cluster certificate next to the current one, until it gets
+// committed.
+func clusterCertificateStage(d *Daemon, cert []byte, key []byte) error {
+	_, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		return errors.Wrap(err, "Invalid certificate key pair")
+	}
+
+	err = util.WriteCert(d.os.VarDir, "cluster.new", cert, key, nil)
+	if err != nil {
+		clusterCertificateUnstage(d)
+		return errors.Wrap(err, "Failed to stage cluster certificate")
+	}
+
+	return nil
+}
+
+// Remove the staged cluster certificate, if any.
+func clusterCertificateUnstage(d *Daemon) {
+	for _, name := range []string{"cluster.new.crt", "cluster.new.key"} {
+		err := os.Remove(filepath.Join(d.os.VarDir, name))
+		if err != nil && !os.IsNotExist(err) {
+			logger.Warnf("Failed to remove staged cluster certificate %s: %v", name, err)
+		}
+	}
+}
+
+// Replace the cluster certificate with the staged one and reload it.
+func clusterCertificateCommit(d *Daemon) error {
+	if !shared.PathExists(filepath.Join(d.os.VarDir, "cluster.new.crt")) {
+		return fmt.Errorf("No staged cluster certificate")
+	}
+
+	// The key goes first, as the certificate tells whether there's a
+	// staged keypair.
+	for _, ext := range []string{"key", "crt"} {
+		err := os.Rename(filepath.Join(d.os.VarDir, "cluster.new."+ext), filepath.Join(d.os.VarDir, "cluster."+ext))
+		if err != nil {
+			return errors.Wrap(err, "Failed to save cluster certificate")
+		}
+	}
+
+	info, err := util.LoadCert(d.os.VarDir)
+	if err != nil {
+		return errors.Wrap(err, "Failed to load cluster certificate")
+	}
+
+	d.endpoints.NetworkUpdateCert(info)
+	d.gateway.UpdateCert(info)
+
+	return nil
+}
+
+// Stage a new cluster certificate on this member.
+func internalClusterCertificatePut(d *Daemon, r *http.Request) response.Response {
+	req := api.ClusterCertificatePut{}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = clusterCertificateStage(d, []byte(req.ClusterCertificate), []byte(req.ClusterCertificateKey))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// Switch this member to the staged cluster certificate.
+func internalClusterCertificatePost(d *Daemon, r *http.Request) response.Response {
+	err := clusterCertificateCommit(d)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// Drop the staged cluster certificate of this member.
+func internalClusterCertificateDelete(d *Daemon, r *http.Request) response.Response {
+	clusterCertificateUnstage(d)
+
+	return response.EmptySyncResponse
+}
+
 // Perform a request to the /internal/cluster/accept endpoint to check if a new
 // mode can be accepted into the cluster and obtain joining information such as
 // the cluster private certificate.
@@ -860,6 +1066,49 @@ func clusterNodesGet(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponse(true, result)
 }
 
+// Issue a join token for a new cluster member.
+func clusterNodesPost(d *Daemon, r *http.Request) response.Response {
+	req := api.ClusterMembersPost{}
+
+	// Parse the request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Sanity checks
+	if req.ServerName == "" {
+		return response.BadRequest(fmt.Errorf("No server name provided"))
+	}
+
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !clustered {
+		return response.BadRequest(fmt.Errorf("This server is not clustered"))
+	}
+
+	fingerprint := d.endpoints.NetworkCert().Fingerprint()
+
+	var token *api.ClusterMemberJoinToken
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		config, err := cluster.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		token, err = cluster.JoinTokenCreate(tx, req.ServerName, fingerprint, config.JoinTokenExpiry())
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, token)
+}
+
 func clusterNodeGet(d *Daemon, r *http.Request) response.Response {
 	name := mux.Vars(r)["name"]
 
@@ -1041,7 +1290,19 @@ func internalClusterPostAccept(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	nodes, err := cluster.Accept(d.State(), d.gateway, req.Name, req.Address, req.Schema, req.API)
+	// If the joining node's certificate was trusted using a join token, the
+	// token must have been issued for that node's name.
+	fingerprint := ""
+	_, username, protocol, err := d.Authenticate(r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if protocol == "tls" {
+		fingerprint = username
+	}
+
+	nodes, err := cluster.Accept(d.State(), d.gateway, req.Name, req.Address, req.Schema, req.API, fingerprint)
 	if err != nil {
 		return response.BadRequest(err)
 	}
@@ -11,6 +11,7 @@ import (
 	runtimeDebug "runtime/debug"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
@@ -30,6 +31,7 @@ import (
 	log "github.com/lxc/lxd/shared/log15"
 	"github.com/lxc/lxd/shared/logger"
 	"github.com/lxc/lxd/shared/osarch"
+	"github.com/lxc/lxd/shared/version"
 )
 
 var apiInternal = []APIEndpoint{
@@ -40,12 +42,15 @@ var apiInternal = []APIEndpoint{
 	internalContainerOnStopCmd,
 	internalContainersCmd,
 	internalSQLCmd,
+	internalClusterDatabaseCmd,
 	internalClusterAcceptCmd,
+	internalClusterCertificateCmd,
 	internalClusterRebalanceCmd,
 	internalClusterPromoteCmd,
 	internalClusterContainerMovedCmd,
 	internalGarbageCollectorCmd,
 	internalRAFTSnapshotCmd,
+	internalImageChunksCmd,
 }
 
 var internalShutdownCmd = APIEndpoint{
@@ -85,6 +90,13 @@ var internalSQLCmd = APIEndpoint{
 	Post: APIEndpointAction{Handler: internalSQLPost},
 }
 
+var internalClusterDatabaseCmd = APIEndpoint{
+	Path: "cluster/database",
+
+	Get:  APIEndpointAction{Handler: internalClusterDatabaseGet},
+	Post: APIEndpointAction{Handler: internalClusterDatabasePost},
+}
+
 var internalContainersCmd = APIEndpoint{
 	Path: "containers",
 
@@ -261,6 +273,123 @@ func internalSQLGet(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponse(true, internalSQLDump{Text: dump})
 }
 
+// A backup of the global database, along with the versions it was taken at.
+type internalClusterDatabase struct {
+	Schema        int    `json:"schema" yaml:"schema"`
+	APIExtensions int    `json:"api_extensions" yaml:"api_extensions"`
+	Dump          string `json:"dump" yaml:"dump"`
+}
+
+// Perform a consistent backup of the global database.
+func internalClusterDatabaseGet(d *Daemon, r *http.Request) response.Response {
+	tx, err := d.cluster.Begin()
+	if err != nil {
+		return response.SmartError(errors.Wrap(err, "Failed to start transaction"))
+	}
+	defer tx.Rollback()
+
+	dump, err := query.Dump(tx, cluster.FreshSchema(), false)
+	if err != nil {
+		return response.SmartError(errors.Wrap(err, "Failed to dump global database"))
+	}
+
+	backup := internalClusterDatabase{
+		Schema:        cluster.SchemaVersion,
+		APIExtensions: version.APIExtensionsCount(),
+		Dump:          dump,
+	}
+
+	return response.SyncResponse(true, backup)
+}
+
+// Restore a backup of the global database onto a freshly bootstrapped cluster
+// member.
+//
+// The member takes over the identity of the member with the same name in the
+// backup, while all other members are kept in the database and will show up
+// as offline.
+func internalClusterDatabasePost(d *Daemon, r *http.Request) response.Response {
+	req := internalClusterDatabase{}
+
+	// Parse the request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Version checks
+	if req.Schema != cluster.SchemaVersion {
+		return response.BadRequest(fmt.Errorf("The backup has schema version %d while this member has %d", req.Schema, cluster.SchemaVersion))
+	}
+
+	if req.APIExtensions > version.APIExtensionsCount() {
+		return response.BadRequest(fmt.Errorf("The backup was taken by a member with more API extensions (%d) than this one (%d)", req.APIExtensions, version.APIExtensionsCount()))
+	}
+
+	// Only restore onto a freshly bootstrapped member.
+	var local db.NodeInfo
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		nodes, err := tx.Nodes()
+		if err != nil {
+			return err
+		}
+
+		if len(nodes) != 1 || nodes[0].Address == "0.0.0.0" {
+			return fmt.Errorf("The database can only be restored onto a freshly bootstrapped cluster member")
+		}
+
+		local = nodes[0]
+
+		instances, err := tx.ContainerNodeList()
+		if err != nil {
+			return err
+		}
+
+		if len(instances) > 0 {
+			return fmt.Errorf("The database can only be restored onto a member without instances")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	tx, err := d.cluster.Begin()
+	if err != nil {
+		return response.SmartError(errors.Wrap(err, "Failed to start transaction"))
+	}
+	defer tx.Rollback()
+
+	err = query.Restore(tx, req.Dump)
+	if err != nil {
+		return response.SmartError(errors.Wrap(err, "Failed to restore global database"))
+	}
+
+	ids, err := query.SelectIntegers(tx, "SELECT id FROM nodes WHERE name=?", local.Name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if len(ids) != 1 {
+		return response.BadRequest(fmt.Errorf("The backup has no member named %q", local.Name))
+	}
+
+	_, err = tx.Exec("UPDATE nodes SET address=?, heartbeat=?, pending=0 WHERE id=?", local.Address, time.Now().UTC(), ids[0])
+	if err != nil {
+		return response.SmartError(errors.Wrap(err, "Failed to update member address"))
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return response.SmartError(errors.Wrap(err, "Failed to commit restored database"))
+	}
+
+	d.cluster.NodeID(int64(ids[0]))
+
+	return response.EmptySyncResponse
+}
+
 // Execute queries.
 func internalSQLPost(d *Daemon, r *http.Request) response.Response {
 	req := &internalSQLQuery{}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/lxc/lxd/lxd/ratelimit"
+)
+
+// Setup the per-client rate limit of the API requests
+func (d *Daemon) setupRateLimit(rate int64, burst int64) {
+	var limiter *ratelimit.Limiter
+	if rate > 0 {
+		limiter = ratelimit.NewLimiter(float64(rate), int(burst))
+	}
+
+	d.rateLimiterLock.Lock()
+	d.rateLimiter = limiter
+	d.rateLimiterLock.Unlock()
+}
+
+// Check whether the client is over its rate limit, returning how long it
+// should wait before retrying if so.
+func rateLimitRequest(d *Daemon, r *http.Request, username string, protocol string) (bool, time.Duration) {
+	d.rateLimiterLock.Lock()
+	limiter := d.rateLimiter
+	d.rateLimiterLock.Unlock()
+
+	if limiter == nil || r.RemoteAddr == "@" || protocol == "cluster" {
+		return true, 0
+	}
+
+	// Untrusted clients are told apart by their address.
+	key := username
+	if key == "" {
+		key, _, _ = net.SplitHostPort(r.RemoteAddr)
+	}
+
+	return limiter.Allow(fmt.Sprintf("%s/%s", protocol, key))
+}
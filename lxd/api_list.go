@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lxc/lxd/lxd/filter"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared"
+)
+
+// Response header holding the token of the next page of a paginated list.
+const listNextPageTokenHeader = "X-LXD-Next-Page-Token"
+
+// Filtering, field selection and pagination parameters of the list
+// endpoints.
+type listParams struct {
+	filter *filter.Expression
+	fields []string
+
+	// Maximum number of entries to return (0 for no limit), and key of the
+	// last entry of the previous page.
+	limit int
+	after string
+}
+
+// An entry of a list endpoint.
+type listEntry struct {
+	key    string      // Sort and pagination key
+	url    string      // URL of the entry
+	object interface{} // API representation of the entry
+}
+
+// Parse the filter, fields, limit and page_token query parameters.
+func listParamsParse(r *http.Request) (*listParams, error) {
+	params := &listParams{}
+
+	s := queryParam(r, "filter")
+	if s != "" {
+		expr, err := filter.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+
+		params.filter = expr
+	}
+
+	s = queryParam(r, "fields")
+	if s != "" {
+		for _, field := range strings.Split(s, ",") {
+			field = strings.TrimSpace(field)
+			if field != "" {
+				params.fields = append(params.fields, field)
+			}
+		}
+	}
+
+	s = queryParam(r, "limit")
+	if s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("Invalid limit %q", s)
+		}
+
+		params.limit = limit
+	}
+
+	s = queryParam(r, "page_token")
+	if s != "" {
+		after, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid page token %q", s)
+		}
+
+		params.after = string(after)
+	}
+
+	return params, nil
+}
+
+// Whether any filtering, field selection or pagination was requested.
+func (p *listParams) enabled() bool {
+	return p.filter != nil || len(p.fields) > 0 || p.limit > 0 || p.after != ""
+}
+
+// Whether the filter only refers to the given fields.
+func (p *listParams) filterOnly(fields ...string) bool {
+	if p.filter == nil {
+		return true
+	}
+
+	for _, field := range p.filter.Fields() {
+		if !shared.StringInSlice(field, fields) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Whether any of the given fields is or is below one of the given top-level
+// fields.
+func listFieldsRefer(fields []string, topLevel ...string) bool {
+	for _, field := range fields {
+		if shared.StringInSlice(strings.SplitN(field, ".", 2)[0], topLevel) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Return the entries matching the filter, sorted by key and restricted to
+// the requested page, along with the token of the next page if any.
+func (p *listParams) apply(entries []listEntry) ([]listEntry, string, error) {
+	result := []listEntry{}
+	for _, entry := range entries {
+		if p.filter != nil {
+			match, err := p.filter.Match(entry.object)
+			if err != nil {
+				return nil, "", err
+			}
+
+			if !match {
+				continue
+			}
+		}
+
+		result = append(result, entry)
+	}
+
+	entries, next := p.paginate(result)
+
+	return entries, next, nil
+}
+
+// Sort the entries by key and restrict them to the requested page.
+func (p *listParams) paginate(entries []listEntry) ([]listEntry, string) {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	if p.after != "" {
+		start := sort.Search(len(entries), func(i int) bool { return entries[i].key > p.after })
+		entries = entries[start:]
+	}
+
+	next := ""
+	if p.limit > 0 && len(entries) > p.limit {
+		entries = entries[:p.limit]
+		next = base64.RawURLEncoding.EncodeToString([]byte(entries[len(entries)-1].key))
+	}
+
+	return entries, next
+}
+
+// Render the given entry, either as its URL or as its API representation
+// restricted to the requested fields.
+func (p *listParams) render(entry listEntry, recursion bool) (interface{}, error) {
+	if !recursion {
+		return entry.url, nil
+	}
+
+	if len(p.fields) == 0 {
+		return entry.object, nil
+	}
+
+	return filter.Select(entry.object, p.fields)
+}
+
+// Return the response of a list endpoint.
+func (p *listParams) response(entries []listEntry, next string, recursion bool) response.Response {
+	result := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		value, err := p.render(entry, recursion)
+		if err != nil {
+			return response.InternalError(err)
+		}
+
+		result[i] = value
+	}
+
+	if next == "" {
+		return response.SyncResponse(true, result)
+	}
+
+	return response.SyncResponseHeaders(true, result, map[string]string{listNextPageTokenHeader: next})
+}
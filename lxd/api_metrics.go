@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/metrics"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/logger"
+
+	log "github.com/lxc/lxd/shared/log15"
+)
+
+var metricsCmd = APIEndpoint{
+	Path: "metrics",
+
+	Get: APIEndpointAction{Handler: metricsGet, AccessHandler: AllowAuthenticated},
+}
+
+// Return the metrics of the instances running on this member and of the
+// daemon itself, in the OpenMetrics text format.
+func metricsGet(d *Daemon, r *http.Request) response.Response {
+	// If a target was specified, forward the request to the relevant node.
+	resp := ForwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	// Only report the given project, if any, otherwise all the projects
+	// the client has access to.
+	project := r.FormValue("project")
+	if project != "" && !d.userHasPermission(r, project, "view") {
+		return response.Forbidden(nil)
+	}
+
+	instances, err := instanceLoadNodeAll(d.State())
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	running := []instance.Instance{}
+	for _, inst := range instances {
+		if project != "" && inst.Project() != project {
+			continue
+		}
+
+		if !d.userHasPermission(r, inst.Project(), "view") || !inst.IsRunning() {
+			continue
+		}
+
+		running = append(running, inst)
+	}
+
+	set := metrics.NewMetricSet(nil)
+	lock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+
+	// Collect the metrics of the instances with a few workers.
+	threads := 4
+	if len(running) < threads {
+		threads = len(running)
+	}
+
+	queue := make(chan instance.Instance, threads)
+
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+
+		go func() {
+			for {
+				inst, more := <-queue
+				if !more {
+					break
+				}
+
+				instanceMetrics, err := inst.Metrics()
+				if err != nil {
+					logger.Warn("Failed to get instance metrics", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "err": err})
+					continue
+				}
+
+				labeled := metrics.NewMetricSet(map[string]string{
+					"name":    inst.Name(),
+					"project": inst.Project(),
+					"type":    inst.Type().String(),
+				})
+				labeled.Merge(instanceMetrics)
+
+				lock.Lock()
+				set.Merge(labeled)
+				lock.Unlock()
+			}
+
+			wg.Done()
+		}()
+	}
+
+	for _, inst := range running {
+		queue <- inst
+	}
+
+	close(queue)
+	wg.Wait()
+
+	// Daemon internals are only exposed to unrestricted clients.
+	_, restricted := d.userRestrictedProjects(r)
+	if !restricted && d.userIsAdmin(r) {
+		set.Merge(daemonMetrics())
+	}
+
+	return response.ManualResponse(func(w http.ResponseWriter) error {
+		w.Header().Set("Content-Type", metrics.ContentType)
+		w.WriteHeader(http.StatusOK)
+
+		_, err := io.WriteString(w, set.String())
+		return err
+	})
+}
+
+// Return the metrics of the daemon itself.
+func daemonMetrics() *metrics.MetricSet {
+	set := metrics.NewMetricSet(nil)
+
+	set.Add(metrics.GoGoroutines, float64(runtime.NumGoroutine()), nil)
+
+	// Count the operations by type and status.
+	operations.Lock()
+	ops := operations.Operations()
+	operations.Unlock()
+
+	counts := map[[2]string]int{}
+	for _, op := range ops {
+		_, info, err := op.Render()
+		if err != nil {
+			continue
+		}
+
+		counts[[2]string{info.Description, strings.ToLower(info.Status)}]++
+	}
+
+	for key, count := range counts {
+		set.Add(metrics.Operations, float64(count), map[string]string{"type": key[0], "status": key[1]})
+	}
+
+	metrics.APIRequestDurationHistogram.Collect(set)
+	metrics.ClusterTransactionTimingHistogram.Collect(set)
+	metrics.ClusterHeartbeatTimingHistogram.Collect(set)
+
+	return set
+}
+
+// Convert the given instance state into metrics.
+func instanceStateMetrics(state *api.InstanceState) *metrics.MetricSet {
+	set := metrics.NewMetricSet(nil)
+
+	if state.CPU.Usage >= 0 {
+		set.Add(metrics.CPUSeconds, float64(state.CPU.Usage)/1e9, nil)
+	}
+
+	set.Add(metrics.MemoryUsageBytes, float64(state.Memory.Usage), nil)
+	set.Add(metrics.MemoryUsagePeakBytes, float64(state.Memory.UsagePeak), nil)
+	set.Add(metrics.MemorySwapUsageBytes, float64(state.Memory.SwapUsage), nil)
+
+	for name, network := range state.Network {
+		labels := map[string]string{"device": name}
+		set.Add(metrics.NetworkReceiveBytes, float64(network.Counters.BytesReceived), labels)
+		set.Add(metrics.NetworkTransmitBytes, float64(network.Counters.BytesSent), labels)
+		set.Add(metrics.NetworkReceivePackets, float64(network.Counters.PacketsReceived), labels)
+		set.Add(metrics.NetworkTransmitPackets, float64(network.Counters.PacketsSent), labels)
+	}
+
+	if state.Processes >= 0 {
+		set.Add(metrics.Procs, float64(state.Processes), nil)
+	}
+
+	return set
+}
+
+// Disk I/O counters of a block device.
+type instanceDiskIO struct {
+	ReadBytes       int64
+	WrittenBytes    int64
+	ReadsCompleted  int64
+	WritesCompleted int64
+}
+
+// Add the given disk I/O counters, keyed by device, to the set.
+func instanceDiskIOMetrics(set *metrics.MetricSet, disks map[string]*instanceDiskIO) {
+	for device, disk := range disks {
+		labels := map[string]string{"device": device}
+		set.Add(metrics.DiskReadBytes, float64(disk.ReadBytes), labels)
+		set.Add(metrics.DiskWrittenBytes, float64(disk.WrittenBytes), labels)
+		set.Add(metrics.DiskReadsCompleted, float64(disk.ReadsCompleted), labels)
+		set.Add(metrics.DiskWritesCompleted, float64(disk.WritesCompleted), labels)
+	}
+}
+
+// Parse the content of the blkio.throttle.io_service_bytes (bytes) or
+// blkio.throttle.io_serviced (operations) cgroup files into the given disk
+// I/O counters, keyed by major:minor device number.
+func parseBlkioStats(value string, disks map[string]*instanceDiskIO, bytes bool) error {
+	scanner := bufio.NewScanner(strings.NewReader(value))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		// Skip the "Total" line.
+		if len(fields) != 3 {
+			continue
+		}
+
+		count, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("Invalid blkio statistics line %q", scanner.Text())
+		}
+
+		disk, ok := disks[fields[0]]
+		if !ok {
+			disk = &instanceDiskIO{}
+			disks[fields[0]] = disk
+		}
+
+		switch {
+		case fields[1] == "Read" && bytes:
+			disk.ReadBytes = count
+		case fields[1] == "Write" && bytes:
+			disk.WrittenBytes = count
+		case fields[1] == "Read":
+			disk.ReadsCompleted = count
+		case fields[1] == "Write":
+			disk.WritesCompleted = count
+		}
+	}
+
+	return scanner.Err()
+}
@@ -426,7 +426,7 @@ func projectPost(d *Daemon, r *http.Request) response.Response {
 
 	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationProjectRename, nil, nil, run, nil, nil)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
@@ -486,8 +486,10 @@ func projectIsEmpty(project *api.Project) bool {
 
 // Validate the project configuration
 var projectConfigKeys = map[string]func(value string) error{
-	"features.profiles": shared.IsBool,
-	"features.images":   shared.IsBool,
+	"features.profiles":    shared.IsBool,
+	"features.images":      shared.IsBool,
+	"images.simplestreams": shared.IsBool,
+	"limits.operations":    shared.IsUint32,
 }
 
 func projectValidateConfig(config map[string]string) error {
@@ -0,0 +1,301 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// Default rotation settings of the audit log.
+const (
+	DefaultMaxSize  = 10 * 1024 * 1024
+	DefaultMaxFiles = 5
+)
+
+// Logger records API requests to a rotating JSON-lines file, and optionally
+// to syslog.
+type Logger struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	syslog *syslog.Writer
+}
+
+// Filter selects the audit log entries to return.
+type Filter struct {
+	Since   time.Time
+	User    string
+	Project string
+
+	// Only return the entries older than this, to page through them.
+	Before time.Time
+
+	// Maximum number of entries, the most recent ones being kept.
+	Limit int
+}
+
+// NewLogger returns a logger appending to the file at the given path. Once
+// it grows past maxSize bytes, the file is rotated, keeping maxFiles old
+// files around.
+func NewLogger(path string, maxSize int64, maxFiles int) (*Logger, error) {
+	l := &Logger{
+		path:     path,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+	}
+
+	err := l.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (l *Logger) open() error {
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return errors.Wrap(err, "Failed to open audit log")
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	l.file = f
+	l.size = info.Size()
+
+	return nil
+}
+
+// Return the path of the given rotated file, 0 being the current one.
+func (l *Logger) rotatedPath(n int) string {
+	if n == 0 {
+		return l.path
+	}
+
+	return fmt.Sprintf("%s.%d", l.path, n)
+}
+
+func (l *Logger) rotate() error {
+	err := l.file.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(l.rotatedPath(l.maxFiles))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for n := l.maxFiles - 1; n >= 0; n-- {
+		err := os.Rename(l.rotatedPath(n), l.rotatedPath(n+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return l.open()
+}
+
+// SetSyslog enables or disables sending the entries to syslog as well.
+func (l *Logger) SetSyslog(enabled bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !enabled {
+		if l.syslog != nil {
+			l.syslog.Close()
+			l.syslog = nil
+		}
+
+		return nil
+	}
+
+	if l.syslog != nil {
+		return nil
+	}
+
+	writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_AUTH, "lxd-audit")
+	if err != nil {
+		return errors.Wrap(err, "Failed to connect to syslog")
+	}
+
+	l.syslog = writer
+
+	return nil
+}
+
+// Log records the given entry.
+func (l *Logger) Log(entry api.AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.size > 0 && l.size+int64(len(data))+1 > l.maxSize {
+		err := l.rotate()
+		if err != nil {
+			return errors.Wrap(err, "Failed to rotate audit log")
+		}
+	}
+
+	n, err := l.file.Write(append(data, '\n'))
+	l.size += int64(n)
+	if err != nil {
+		return err
+	}
+
+	if l.syslog != nil {
+		err := l.syslog.Info(string(data))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Entries returns the recorded entries matching the given filter, oldest
+// first.
+//
+// The files are read from the most recent one, skipping the ones which are
+// older than the filter's Since or more recent than its Before, and stopping
+// once Limit entries were found.
+func (l *Logger) Entries(filter Filter) ([]api.AuditEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := []api.AuditEntry{}
+
+	for n := 0; n <= l.maxFiles; n++ {
+		if filter.Limit > 0 && len(entries) >= filter.Limit {
+			break
+		}
+
+		path := l.rotatedPath(n)
+
+		// A file last written before Since only has older entries,
+		// and so do all the ones rotated before it.
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		if !filter.Since.IsZero() && info.ModTime().Before(filter.Since) {
+			break
+		}
+
+		fileEntries, err := l.fileEntries(path, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(fileEntries, entries...)
+	}
+
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+
+	return entries, nil
+}
+
+// Return the entries of the given file matching the filter, oldest first.
+func (l *Logger) fileEntries(path string, filter Filter) ([]api.AuditEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := []api.AuditEntry{}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		entry := api.AuditEntry{}
+		err := json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			// Skip truncated lines.
+			continue
+		}
+
+		// Entries are in chronological order, so the rest of the
+		// file is too recent.
+		if !filter.Before.IsZero() && !entry.Timestamp.Before(filter.Before) {
+			break
+		}
+
+		if !filter.match(entry) {
+			continue
+		}
+
+		entries = append(entries, entry)
+		if filter.Limit > 0 && len(entries) > filter.Limit {
+			entries = entries[1:]
+		}
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (f Filter) match(entry api.AuditEntry) bool {
+	if !f.Since.IsZero() && entry.Timestamp.Before(f.Since) {
+		return false
+	}
+
+	if f.User != "" && entry.User != f.User {
+		return false
+	}
+
+	if f.Project != "" && entry.Project != f.Project {
+		return false
+	}
+
+	return true
+}
+
+// Close closes the log file and the syslog connection.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.syslog != nil {
+		l.syslog.Close()
+		l.syslog = nil
+	}
+
+	return l.file.Close()
+}
@@ -0,0 +1,95 @@
+package audit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestLogger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-audit-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+
+	// Small enough for a few entries per file.
+	logger, err := NewLogger(path, 1024, 2)
+	require.NoError(t, err)
+	defer logger.Close()
+
+	start := time.Now().UTC().Add(-time.Minute)
+	for i := 0; i < 30; i++ {
+		project := "default"
+		if i%2 == 1 {
+			project = "foo"
+		}
+
+		err := logger.Log(api.AuditEntry{
+			Timestamp:  start.Add(time.Duration(i) * time.Second),
+			User:       "abc",
+			Method:     "POST",
+			URL:        fmt.Sprintf("/1.0/instances/c%d", i),
+			Project:    project,
+			StatusCode: 200,
+		})
+		require.NoError(t, err)
+	}
+
+	// Rotated files are kept up to the limit.
+	assert.FileExists(t, path+".1")
+	assert.FileExists(t, path+".2")
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+
+	entries, err := logger.Entries(Filter{})
+	require.NoError(t, err)
+	assert.True(t, len(entries) < 30)
+	assert.Equal(t, "/1.0/instances/c29", entries[len(entries)-1].URL)
+
+	entries, err = logger.Entries(Filter{Project: "foo", Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "/1.0/instances/c27", entries[0].URL)
+	assert.Equal(t, "/1.0/instances/c29", entries[1].URL)
+
+	entries, err = logger.Entries(Filter{Since: start.Add(28 * time.Second)})
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	entries, err = logger.Entries(Filter{User: "def"})
+	require.NoError(t, err)
+	assert.Len(t, entries, 0)
+
+	// Pages of entries are returned before the given time.
+	entries, err = logger.Entries(Filter{Before: start.Add(29 * time.Second), Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "/1.0/instances/c27", entries[0].URL)
+	assert.Equal(t, "/1.0/instances/c28", entries[1].URL)
+
+	entries, err = logger.Entries(Filter{Before: entries[0].Timestamp, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "/1.0/instances/c25", entries[0].URL)
+	assert.Equal(t, "/1.0/instances/c26", entries[1].URL)
+
+	// Files last written before Since aren't read.
+	old := start.Add(-time.Hour)
+	require.NoError(t, os.Chtimes(path+".1", old, old))
+
+	current, err := logger.fileEntries(path, Filter{})
+	require.NoError(t, err)
+
+	entries, err = logger.Entries(Filter{Since: start})
+	require.NoError(t, err)
+	assert.Equal(t, current, entries)
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/rbac"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var authGroupsCmd = APIEndpoint{
+	Path: "auth/groups",
+
+	Get:  APIEndpointAction{Handler: authGroupsGet},
+	Post: APIEndpointAction{Handler: authGroupsPost},
+}
+
+var authGroupCmd = APIEndpoint{
+	Path: "auth/groups/{name}",
+
+	Delete: APIEndpointAction{Handler: authGroupDelete},
+	Get:    APIEndpointAction{Handler: authGroupGet},
+	Put:    APIEndpointAction{Handler: authGroupPut},
+}
+
+func authGroupsGet(d *Daemon, r *http.Request) response.Response {
+	recursion := util.IsRecursionRequest(r)
+
+	var groups []db.AuthGroup
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		groups, err = tx.AuthGroups()
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if recursion {
+		result := []api.AuthGroup{}
+		for _, group := range groups {
+			result = append(result, authGroupToAPI(group))
+		}
+
+		return response.SyncResponse(true, result)
+	}
+
+	urls := []string{}
+	for _, group := range groups {
+		urls = append(urls, fmt.Sprintf("/%s/auth/groups/%s", version.APIVersion, group.Name))
+	}
+
+	return response.SyncResponse(true, urls)
+}
+
+func authGroupsPost(d *Daemon, r *http.Request) response.Response {
+	req := api.AuthGroupsPost{}
+	err := shared.ReadToJSON(r.Body, &req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.Name == "" {
+		return response.BadRequest(fmt.Errorf("No name provided"))
+	}
+
+	if strings.Contains(req.Name, "/") {
+		return response.BadRequest(fmt.Errorf("Group names may not contain slashes"))
+	}
+
+	group, err := authGroupFromAPI(d, req.AuthGroupPut)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	group.Name = req.Name
+
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		_, err := tx.AuthGroupByName(group.Name)
+		if err == nil {
+			return db.ErrAlreadyDefined
+		}
+
+		if err != db.ErrNoSuchObject {
+			return err
+		}
+
+		_, err = tx.AuthGroupAdd(group)
+		return err
+	})
+	if err != nil {
+		if err == db.ErrAlreadyDefined {
+			return response.Conflict(fmt.Errorf("Group %q already exists", group.Name))
+		}
+
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/auth/groups/%s", version.APIVersion, group.Name))
+}
+
+func authGroupGet(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	var group db.AuthGroup
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		group, err = tx.AuthGroupByName(name)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	result := authGroupToAPI(group)
+
+	return response.SyncResponseETag(true, result, result.Writable())
+}
+
+func authGroupPut(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	var current db.AuthGroup
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		current, err = tx.AuthGroupByName(name)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Validate ETag
+	etag := authGroupToAPI(current)
+	err = util.EtagCheck(r, etag.Writable())
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	req := api.AuthGroupPut{}
+	err = shared.ReadToJSON(r.Body, &req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	group, err := authGroupFromAPI(d, req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.AuthGroupUpdate(name, group)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func authGroupDelete(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.AuthGroupRemove(name)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func authGroupToAPI(group db.AuthGroup) api.AuthGroup {
+	result := api.AuthGroup{
+		Name: group.Name,
+	}
+
+	result.Description = group.Description
+	result.Identities = group.Identities
+	result.Roles = []api.AuthGroupRole{}
+	for _, role := range group.Roles {
+		result.Roles = append(result.Roles, api.AuthGroupRole{Role: role.Role, Project: role.Project})
+	}
+
+	return result
+}
+
+// Validate the given group fields and convert them to a database entry.
+func authGroupFromAPI(d *Daemon, req api.AuthGroupPut) (db.AuthGroup, error) {
+	group := db.AuthGroup{
+		Description: req.Description,
+		Identities:  []string{},
+		Roles:       []db.AuthGroupRole{},
+	}
+
+	for _, identity := range req.Identities {
+		err := rbac.ValidIdentity(identity)
+		if err != nil {
+			return group, err
+		}
+
+		if !shared.StringInSlice(identity, group.Identities) {
+			group.Identities = append(group.Identities, identity)
+		}
+	}
+
+	projects := []string{}
+	for _, role := range req.Roles {
+		if !rbac.ValidRole(role.Role) {
+			return group, fmt.Errorf("Invalid role %q", role.Role)
+		}
+
+		if role.Project != "" && !shared.StringInSlice(role.Project, projects) {
+			projects = append(projects, role.Project)
+		}
+
+		binding := db.AuthGroupRole{Role: role.Role, Project: role.Project}
+		duplicate := false
+		for _, existing := range group.Roles {
+			if existing == binding {
+				duplicate = true
+				break
+			}
+		}
+
+		if !duplicate {
+			group.Roles = append(group.Roles, binding)
+		}
+	}
+
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		for _, name := range projects {
+			exists, err := tx.ProjectExists(name)
+			if err != nil {
+				return err
+			}
+
+			if !exists {
+				return fmt.Errorf("Project %q doesn't exist", name)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return group, err
+	}
+
+	return group, nil
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+// Prefix of the bearer tokens issued by POST /1.0/auth/tokens, telling them
+// apart from OpenID Connect tokens.
+const authTokenPrefix = "lxd_"
+
+// Default validity of API tokens.
+const authTokenDefaultExpiry = 30 * 24 * time.Hour
+
+// Permission scopes of API tokens.
+const (
+	authTokenScopeReadOnly = "read-only"
+	authTokenScopeOperate  = "operate"
+	authTokenScopeManage   = "manage"
+)
+
+var authTokensCmd = APIEndpoint{
+	Path: "auth/tokens",
+
+	Get:  APIEndpointAction{Handler: authTokensGet, AccessHandler: allowAuthTokenManagement},
+	Post: APIEndpointAction{Handler: authTokensPost, AccessHandler: allowAuthTokenManagement},
+}
+
+var authTokenCmd = APIEndpoint{
+	Path: "auth/tokens/{id}",
+
+	Delete: APIEndpointAction{Handler: authTokenDelete, AccessHandler: allowAuthTokenManagement},
+	Get:    APIEndpointAction{Handler: authTokenGet, AccessHandler: allowAuthTokenManagement},
+}
+
+// allowAuthTokenManagement is an AccessHandler which only lets admins not
+// authenticated with an API token manage the API tokens, so that tokens can't
+// outlive their expiry by issuing new ones.
+func allowAuthTokenManagement(d *Daemon, r *http.Request) response.Response {
+	protocol, _ := r.Context().Value("protocol").(string)
+	if protocol == "token" || !d.userIsAdmin(r) {
+		return response.Forbidden(nil)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func authTokensGet(d *Daemon, r *http.Request) response.Response {
+	recursion := util.IsRecursionRequest(r)
+
+	var tokens []db.AuthToken
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		tokens, err = tx.AuthTokens()
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if recursion {
+		result := []api.AuthToken{}
+		for _, token := range tokens {
+			result = append(result, authTokenToAPI(token))
+		}
+
+		return response.SyncResponse(true, result)
+	}
+
+	urls := []string{}
+	for _, token := range tokens {
+		urls = append(urls, fmt.Sprintf("/%s/auth/tokens/%s", version.APIVersion, token.Name))
+	}
+
+	return response.SyncResponse(true, urls)
+}
+
+func authTokensPost(d *Daemon, r *http.Request) response.Response {
+	req := api.AuthTokensPost{}
+	err := shared.ReadToJSON(r.Body, &req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if !shared.StringInSlice(req.Scope, []string{authTokenScopeReadOnly, authTokenScopeOperate, authTokenScopeManage}) {
+		return response.BadRequest(fmt.Errorf("Invalid scope %q", req.Scope))
+	}
+
+	now := time.Now().UTC()
+	if req.ExpiresAt.IsZero() {
+		req.ExpiresAt = now.Add(authTokenDefaultExpiry)
+	} else if req.ExpiresAt.Before(now) {
+		return response.BadRequest(fmt.Errorf("The expiry date is in the past"))
+	}
+
+	id, err := shared.RandomCryptoString()
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	secret, err := shared.RandomCryptoString()
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	token := db.AuthToken{
+		Name:         id[:12],
+		Description:  req.Description,
+		Secret:       authTokenHash(authTokenPrefix + secret),
+		Scope:        req.Scope,
+		Projects:     req.Projects,
+		CreationDate: now,
+		ExpiryDate:   req.ExpiresAt.UTC(),
+	}
+
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		err := tx.AuthTokensPrune(now)
+		if err != nil {
+			return errors.Wrap(err, "Failed to remove expired API tokens")
+		}
+
+		for _, name := range token.Projects {
+			exists, err := tx.ProjectExists(name)
+			if err != nil {
+				return err
+			}
+
+			if !exists {
+				return fmt.Errorf("Project %q doesn't exist", name)
+			}
+		}
+
+		_, err = tx.AuthTokenAdd(token)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	result := authTokenToAPI(token)
+	result.Token = authTokenPrefix + secret
+
+	return response.SyncResponseLocation(true, result, fmt.Sprintf("/%s/auth/tokens/%s", version.APIVersion, token.Name))
+}
+
+func authTokenGet(d *Daemon, r *http.Request) response.Response {
+	id := mux.Vars(r)["id"]
+
+	var token db.AuthToken
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		token, err = tx.AuthTokenByName(id)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, authTokenToAPI(token))
+}
+
+func authTokenDelete(d *Daemon, r *http.Request) response.Response {
+	id := mux.Vars(r)["id"]
+
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.AuthTokenRemove(id)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func authTokenToAPI(token db.AuthToken) api.AuthToken {
+	result := api.AuthToken{
+		ID:        token.Name,
+		CreatedAt: token.CreationDate,
+	}
+
+	result.Description = token.Description
+	result.Scope = token.Scope
+	result.Projects = token.Projects
+	result.ExpiresAt = token.ExpiryDate
+
+	return result
+}
+
+// Return the API token matching the given bearer token, or nil if there's no
+// such token or if it expired.
+func authTokenValidate(d *Daemon, bearer string) (*db.AuthToken, error) {
+	if !strings.HasPrefix(bearer, authTokenPrefix) {
+		return nil, nil
+	}
+
+	var token db.AuthToken
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		token, err = tx.AuthTokenBySecret(authTokenHash(bearer))
+		return err
+	})
+	if err != nil {
+		if err == db.ErrNoSuchObject {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	if time.Now().After(token.ExpiryDate) {
+		return nil, nil
+	}
+
+	return &token, nil
+}
+
+// Check whether the given API token grants the permission on the project.
+func authTokenHasPermission(token *db.AuthToken, project string, permission string) bool {
+	if len(token.Projects) > 0 && !shared.StringInSlice(project, token.Projects) {
+		return false
+	}
+
+	switch token.Scope {
+	case authTokenScopeReadOnly:
+		return permission == "view"
+	case authTokenScopeOperate:
+		return shared.StringInSlice(permission, []string{"view", "operate-containers"})
+	case authTokenScopeManage:
+		// Tokens restricted to some projects can't change the projects
+		// themselves.
+		return len(token.Projects) == 0 || permission != "manage-projects"
+	}
+
+	return false
+}
+
+// API token secrets are only stored as hashes in the database.
+func authTokenHash(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
@@ -52,31 +52,76 @@ func certificatesGet(d *Daemon, r *http.Request) response.Response {
 			return response.SmartError(err)
 		}
 		for _, baseCert := range baseCerts {
+			if !certificateVisible(d, r, baseCert.Fingerprint) {
+				continue
+			}
+
 			resp := api.Certificate{}
 			resp.Fingerprint = baseCert.Fingerprint
 			resp.Certificate = baseCert.Certificate
 			resp.Name = baseCert.Name
-			if baseCert.Type == 1 {
-				resp.Type = "client"
-			} else {
-				resp.Type = "unknown"
-			}
+			resp.Type = certificateTypeName(baseCert.Type)
+			resp.Restricted = baseCert.Restricted
+			resp.Projects = baseCert.Projects
 			certResponses = append(certResponses, resp)
 		}
 		return response.SyncResponse(true, certResponses)
 	}
 
 	body := []string{}
-	for _, cert := range d.clientCerts {
-		fingerprint := fmt.Sprintf("/%s/certificates/%s", version.APIVersion, shared.CertFingerprint(&cert))
-		body = append(body, fingerprint)
+	for _, certs := range []map[string]x509.Certificate{d.clientCerts, d.metricsCerts} {
+		for _, cert := range certs {
+			if !certificateVisible(d, r, shared.CertFingerprint(&cert)) {
+				continue
+			}
+
+			fingerprint := fmt.Sprintf("/%s/certificates/%s", version.APIVersion, shared.CertFingerprint(&cert))
+			body = append(body, fingerprint)
+		}
 	}
 
 	return response.SyncResponse(true, body)
 }
 
+// Return the API name of the given database certificate type.
+func certificateTypeName(certType int) string {
+	switch certType {
+	case db.CertificateTypeClient:
+		return "client"
+	case db.CertificateTypeMetrics:
+		return "metrics"
+	}
+
+	return "unknown"
+}
+
+// Return the database type of the given API certificate type.
+func certificateTypeParse(name string) (int, error) {
+	switch name {
+	case "client":
+		return db.CertificateTypeClient, nil
+	case "metrics":
+		return db.CertificateTypeMetrics, nil
+	}
+
+	return -1, fmt.Errorf("Unknown request type %s", name)
+}
+
+// Restricted certificates only get to see themselves.
+func certificateVisible(d *Daemon, r *http.Request, fingerprint string) bool {
+	_, restricted := d.userRestrictedProjects(r)
+	if !restricted {
+		return true
+	}
+
+	username, _ := r.Context().Value("username").(string)
+	return username == fingerprint
+}
+
 func readSavedClientCAList(d *Daemon) {
 	d.clientCerts = map[string]x509.Certificate{}
+	d.metricsCerts = map[string]x509.Certificate{}
+	d.clientCertProjects = map[string][]string{}
 
 	dbCerts, err := d.cluster.CertificatesGet()
 	if err != nil {
@@ -97,7 +142,15 @@ func readSavedClientCAList(d *Daemon) {
 			continue
 		}
 
-		d.clientCerts[shared.CertFingerprint(cert)] = *cert
+		if dbCert.Type == db.CertificateTypeMetrics {
+			d.metricsCerts[shared.CertFingerprint(cert)] = *cert
+		} else {
+			d.clientCerts[shared.CertFingerprint(cert)] = *cert
+		}
+
+		if dbCert.Restricted {
+			d.clientCertProjects[shared.CertFingerprint(cert)] = dbCert.Projects
+		}
 	}
 }
 
@@ -119,15 +172,31 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	if (!trusted || (protocol == "candid" && !d.userIsAdmin(r))) && util.PasswordCheck(secret, req.Password) != nil {
-		if req.Password != "" {
-			logger.Warn("Bad trust password", log.Ctx{"url": r.URL.RequestURI(), "ip": r.RemoteAddr})
+	// Restricted certificates can't add other certificates.
+	_, restricted := d.userRestrictedProjects(r)
+
+	// Untrusted clients can use the secret of a pending cluster join token
+	// in place of the trust password, once, to add the certificate of the
+	// joining node.
+	joinToken := false
+	if (!trusted || restricted || (protocol == "candid" && !d.userIsAdmin(r))) && util.PasswordCheck(secret, req.Password) != nil {
+		if trusted || req.Password == "" {
+			if req.Password != "" {
+				logger.Warn("Bad trust password", log.Ctx{"url": r.URL.RequestURI(), "ip": r.RemoteAddr})
+			}
+			return response.Forbidden(nil)
 		}
-		return response.Forbidden(nil)
+
+		joinToken = true
 	}
 
-	if req.Type != "client" {
-		return response.BadRequest(fmt.Errorf("Unknown request type %s", req.Type))
+	certType, err := certificateTypeParse(req.Type)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if !req.Restricted && len(req.Projects) > 0 {
+		return response.BadRequest(fmt.Errorf("Only restricted certificates can be limited to projects"))
 	}
 
 	// Extract the certificate
@@ -162,18 +231,40 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 
 	fingerprint := shared.CertFingerprint(cert)
 
+	if joinToken {
+		validToken := false
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			var err error
+			validToken, err = cluster.JoinTokenUse(tx, req.Password, fingerprint)
+			return err
+		})
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		if !validToken {
+			logger.Warn("Bad trust password", log.Ctx{"url": r.URL.RequestURI(), "ip": r.RemoteAddr})
+			return response.Forbidden(nil)
+		}
+	}
+
 	if d.clientCerts == nil {
 		d.clientCerts = map[string]x509.Certificate{}
 	}
 
+	if d.metricsCerts == nil {
+		d.metricsCerts = map[string]x509.Certificate{}
+	}
+
 	if !isClusterNotification(r) {
 		// Check if we already have the certificate
 		existingCert, _ := d.cluster.CertificateGet(fingerprint)
 		if existingCert != nil {
 			// Deal with the cache being potentially out of sync
-			_, ok := d.clientCerts[fingerprint]
-			if !ok {
-				d.clientCerts[fingerprint] = *cert
+			_, isClient := d.clientCerts[fingerprint]
+			_, isMetrics := d.metricsCerts[fingerprint]
+			if !isClient && !isMetrics {
+				readSavedClientCAList(d)
 				return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/certificates/%s", version.APIVersion, fingerprint))
 			}
 
@@ -183,9 +274,11 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 		// Store the certificate in the cluster database
 		dbCert := db.CertInfo{
 			Fingerprint: shared.CertFingerprint(cert),
-			Type:        1,
+			Type:        certType,
 			Name:        name,
 			Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+			Restricted:  req.Restricted,
+			Projects:    req.Projects,
 		}
 
 		err = d.cluster.CertSave(&dbCert)
@@ -203,7 +296,9 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 			Certificate: base64.StdEncoding.EncodeToString(cert.Raw),
 		}
 		req.Name = name
-		req.Type = "client"
+		req.Type = certificateTypeName(certType)
+		req.Restricted = dbCert.Restricted
+		req.Projects = dbCert.Projects
 
 		err = notifier(func(client lxd.InstanceServer) error {
 			return client.CreateCertificate(req)
@@ -213,7 +308,21 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
-	d.clientCerts[shared.CertFingerprint(cert)] = *cert
+	if certType == db.CertificateTypeMetrics {
+		d.metricsCerts[shared.CertFingerprint(cert)] = *cert
+	} else {
+		d.clientCerts[shared.CertFingerprint(cert)] = *cert
+	}
+
+	if d.clientCertProjects == nil {
+		d.clientCertProjects = map[string][]string{}
+	}
+
+	if req.Restricted {
+		d.clientCertProjects[shared.CertFingerprint(cert)] = req.Projects
+	} else {
+		delete(d.clientCertProjects, shared.CertFingerprint(cert))
+	}
 
 	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/certificates/%s", version.APIVersion, fingerprint))
 }
@@ -226,6 +335,10 @@ func certificateGet(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	if !certificateVisible(d, r, cert.Fingerprint) {
+		return response.Forbidden(nil)
+	}
+
 	return response.SyncResponseETag(true, cert, cert)
 }
 
@@ -240,11 +353,9 @@ func doCertificateGet(db *db.Cluster, fingerprint string) (api.Certificate, erro
 	resp.Fingerprint = dbCertInfo.Fingerprint
 	resp.Certificate = dbCertInfo.Certificate
 	resp.Name = dbCertInfo.Name
-	if dbCertInfo.Type == 1 {
-		resp.Type = "client"
-	} else {
-		resp.Type = "unknown"
-	}
+	resp.Type = certificateTypeName(dbCertInfo.Type)
+	resp.Restricted = dbCertInfo.Restricted
+	resp.Projects = dbCertInfo.Projects
 
 	return resp, nil
 }
@@ -268,7 +379,7 @@ func certificatePut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
-	return doCertificateUpdate(d, fingerprint, req)
+	return doCertificateUpdate(d, r, fingerprint, req)
 }
 
 func certificatePatch(d *Daemon, r *http.Request) response.Response {
@@ -303,19 +414,66 @@ func certificatePatch(d *Daemon, r *http.Request) response.Response {
 		req.Type = value
 	}
 
-	return doCertificateUpdate(d, fingerprint, req.Writable())
+	// Get restricted
+	restricted, err := reqRaw.GetBool("restricted")
+	if err == nil {
+		req.Restricted = restricted
+	}
+
+	// Get projects
+	_, ok := reqRaw["projects"]
+	if ok {
+		req.Projects = []string{}
+		projects, ok := reqRaw["projects"].([]interface{})
+		if !ok {
+			return response.BadRequest(fmt.Errorf("Invalid projects"))
+		}
+
+		for _, project := range projects {
+			name, ok := project.(string)
+			if !ok {
+				return response.BadRequest(fmt.Errorf("Invalid projects"))
+			}
+
+			req.Projects = append(req.Projects, name)
+		}
+	}
+
+	return doCertificateUpdate(d, r, fingerprint, req.Writable())
 }
 
-func doCertificateUpdate(d *Daemon, fingerprint string, req api.CertificatePut) response.Response {
-	if req.Type != "client" {
-		return response.BadRequest(fmt.Errorf("Unknown request type %s", req.Type))
+func doCertificateUpdate(d *Daemon, r *http.Request, fingerprint string, req api.CertificatePut) response.Response {
+	certType, err := certificateTypeParse(req.Type)
+	if err != nil {
+		return response.BadRequest(err)
 	}
 
-	err := d.cluster.CertUpdate(fingerprint, req.Name, 1)
+	if !req.Restricted && len(req.Projects) > 0 {
+		return response.BadRequest(fmt.Errorf("Only restricted certificates can be limited to projects"))
+	}
+
+	err = d.cluster.CertUpdate(fingerprint, req.Name, certType, req.Restricted, req.Projects)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	// Notify other nodes about the new restrictions.
+	if !isClusterNotification(r) {
+		notifier, err := cluster.NewNotifier(d.State(), d.endpoints.NetworkCert(), cluster.NotifyAlive)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		err = notifier(func(client lxd.InstanceServer) error {
+			return client.UpdateCertificate(fingerprint, req, "")
+		})
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
+	readSavedClientCAList(d)
+
 	return response.EmptySyncResponse
 }
 
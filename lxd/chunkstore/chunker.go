@@ -0,0 +1,101 @@
+package chunkstore
+
+import (
+	"io"
+)
+
+// Default chunk sizes, chunks being on average 1MiB.
+const (
+	MinSize = 256 * 1024
+	AvgBits = 20
+	MaxSize = 4 * 1024 * 1024
+)
+
+// Table of random values used by the gear rolling hash. It must never
+// change, as that would change where content gets cut and so prevent the
+// deduplication of chunks stored before the change.
+var gear [256]uint64
+
+func init() {
+	// splitmix64, with a fixed seed.
+	seed := uint64(0x4c58442d63686e6b)
+	for i := range gear {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gear[i] = z ^ (z >> 31)
+	}
+}
+
+// Chunker cuts a stream into content-defined chunks, so that identical
+// content in two streams mostly results in identical chunks, even when
+// shifted by insertions or deletions.
+type Chunker struct {
+	r    io.Reader
+	buf  []byte
+	n    int
+	eof  bool
+	min  int
+	max  int
+	mask uint64
+}
+
+// NewChunker returns a chunker for the given reader, using the default chunk
+// sizes.
+func NewChunker(r io.Reader) *Chunker {
+	return newChunker(r, MinSize, AvgBits, MaxSize)
+}
+
+func newChunker(r io.Reader, min int, avgBits uint, max int) *Chunker {
+	return &Chunker{
+		r:    r,
+		buf:  make([]byte, max),
+		min:  min,
+		max:  max,
+		mask: (uint64(1) << avgBits) - 1,
+	}
+}
+
+// Next returns the next chunk of the stream, or io.EOF once it's exhausted.
+func (c *Chunker) Next() ([]byte, error) {
+	if !c.eof && c.n < c.max {
+		n, err := io.ReadFull(c.r, c.buf[c.n:])
+		c.n += n
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			c.eof = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.n == 0 {
+		return nil, io.EOF
+	}
+
+	size := c.cut(c.buf[:c.n])
+
+	chunk := make([]byte, size)
+	copy(chunk, c.buf[:size])
+	copy(c.buf, c.buf[size:c.n])
+	c.n -= size
+
+	return chunk, nil
+}
+
+// Return the size of the chunk at the beginning of the given data.
+func (c *Chunker) cut(data []byte) int {
+	if len(data) <= c.min {
+		return len(data)
+	}
+
+	hash := uint64(0)
+	for i := c.min; i < len(data); i++ {
+		hash = (hash << 1) + gear[data[i]]
+		if hash&c.mask == 0 {
+			return i + 1
+		}
+	}
+
+	return len(data)
+}
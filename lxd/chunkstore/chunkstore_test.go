@@ -0,0 +1,110 @@
+package chunkstore
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Cut the given data into chunks, using small chunk sizes.
+func chunks(t *testing.T, data []byte) [][]byte {
+	chunker := newChunker(bytes.NewReader(data), 1024, 12, 16*1024)
+	result := [][]byte{}
+
+	for {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			return result
+		}
+		require.NoError(t, err)
+
+		result = append(result, chunk)
+	}
+}
+
+func randomData(size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(42)).Read(data)
+	return data
+}
+
+// Chunks are within the size bounds and add up to the original data.
+func TestChunker_Sizes(t *testing.T) {
+	data := randomData(1024 * 1024)
+	result := chunks(t, data)
+
+	for i, chunk := range result {
+		assert.True(t, len(chunk) <= 16*1024)
+		if i < len(result)-1 {
+			assert.True(t, len(chunk) > 1024)
+		}
+	}
+
+	assert.Equal(t, data, bytes.Join(result, nil))
+}
+
+// Inserting data only changes the chunks around the insertion.
+func TestChunker_Resync(t *testing.T) {
+	data := randomData(1024 * 1024)
+	modified := append(append(append([]byte{}, data[:100000]...), []byte("inserted")...), data[100000:]...)
+
+	original := map[string]bool{}
+	for _, chunk := range chunks(t, data) {
+		original[string(chunk)] = true
+	}
+
+	result := chunks(t, modified)
+	changed := 0
+	for _, chunk := range result {
+		if !original[string(chunk)] {
+			changed++
+		}
+	}
+
+	assert.True(t, changed <= 2, "%d chunks out of %d changed", changed, len(result))
+}
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-chunkstore-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store := NewStore(dir)
+	data := randomData(10 * 1024 * 1024)
+
+	result, err := store.Split(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.True(t, len(result) > 1)
+
+	for _, chunk := range result {
+		assert.True(t, ValidHash(chunk.Hash))
+		assert.True(t, store.Has(chunk.Hash))
+	}
+
+	// Identical content is only stored once.
+	again, err := store.Split(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, result, again)
+
+	buf := &bytes.Buffer{}
+	err = store.Assemble(result, buf)
+	require.NoError(t, err)
+	assert.Equal(t, data, buf.Bytes())
+
+	// Corrupted chunks are detected.
+	err = ioutil.WriteFile(store.Path(result[0].Hash), []byte("corrupted"), 0600)
+	require.NoError(t, err)
+
+	err = store.Assemble(result, ioutil.Discard)
+	assert.EqualError(t, err, "Chunk "+result[0].Hash+" is corrupted")
+
+	err = store.Remove(result[0].Hash)
+	require.NoError(t, err)
+	assert.False(t, store.Has(result[0].Hash))
+}
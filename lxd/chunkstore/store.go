@@ -0,0 +1,146 @@
+package chunkstore
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+var hashRegexp = regexp.MustCompile("^[0-9a-f]{64}$")
+
+// Chunk describes a chunk of a file.
+type Chunk struct {
+	Hash string `json:"hash" yaml:"hash"`
+	Size int64  `json:"size" yaml:"size"`
+}
+
+// ValidHash checks whether the given string is a valid chunk hash.
+func ValidHash(hash string) bool {
+	return hashRegexp.MatchString(hash)
+}
+
+// Store is a directory holding chunks named after the SHA-256 of their
+// content, so that each distinct chunk is only ever stored once.
+type Store struct {
+	path string
+}
+
+// NewStore returns the store in the given directory.
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Path returns the path of the chunk with the given hash.
+func (s *Store) Path(hash string) string {
+	return filepath.Join(s.path, hash[0:2], hash)
+}
+
+// Has checks whether the chunk with the given hash is in the store.
+func (s *Store) Has(hash string) bool {
+	_, err := os.Lstat(s.Path(hash))
+	return err == nil
+}
+
+// Put adds the given data to the store, unless already there, and returns its
+// hash.
+func (s *Store) Put(data []byte) (string, error) {
+	hash := fmt.Sprintf("%x", sha256.Sum256(data))
+	if s.Has(hash) {
+		return hash, nil
+	}
+
+	dir := filepath.Dir(s.Path(hash))
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", err
+	}
+
+	// Write under a temporary name so partial chunks never get used.
+	f, err := ioutil.TempFile(dir, ".tmp_")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), s.Path(hash))
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	return hash, nil
+}
+
+// Remove deletes the chunk with the given hash from the store.
+func (s *Store) Remove(hash string) error {
+	err := os.Remove(s.Path(hash))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// Split cuts the content of the given reader into chunks, adds them to the
+// store and returns them in order.
+func (s *Store) Split(r io.Reader) ([]Chunk, error) {
+	chunker := NewChunker(r)
+	chunks := []Chunk{}
+
+	for {
+		data, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		hash, err := s.Put(data)
+		if err != nil {
+			return nil, err
+		}
+
+		chunks = append(chunks, Chunk{Hash: hash, Size: int64(len(data))})
+	}
+
+	return chunks, nil
+}
+
+// Assemble writes the content of the given chunks to the given writer,
+// checking that each of them is intact.
+func (s *Store) Assemble(chunks []Chunk, w io.Writer) error {
+	for _, chunk := range chunks {
+		data, err := ioutil.ReadFile(s.Path(chunk.Hash))
+		if err != nil {
+			return err
+		}
+
+		if int64(len(data)) != chunk.Size || fmt.Sprintf("%x", sha256.Sum256(data)) != chunk.Hash {
+			return fmt.Errorf("Chunk %s is corrupted", chunk.Hash)
+		}
+
+		_, err = w.Write(data)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to write chunk %s", chunk.Hash)
+		}
+	}
+
+	return nil
+}
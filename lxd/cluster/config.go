@@ -13,6 +13,7 @@ import (
 
 	"github.com/lxc/lxd/lxd/config"
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/util"
 	"github.com/pkg/errors"
 )
 
@@ -59,6 +60,17 @@ func (c *Config) HTTPSAllowedCredentials() bool {
 	return c.m.GetBool("core.https_allowed_credentials")
 }
 
+// AuditSyslog returns whether the audit log should also be sent to syslog.
+func (c *Config) AuditSyslog() bool {
+	return c.m.GetBool("core.audit_syslog")
+}
+
+// RateLimit returns the number of API requests per second allowed for each
+// client, along with the maximum burst. A rate of zero disables the limit.
+func (c *Config) RateLimit() (int64, int64) {
+	return c.m.GetInt64("core.rate_limit"), c.m.GetInt64("core.rate_limit_burst")
+}
+
 // TrustPassword returns the LXD trust password for authenticating clients.
 func (c *Config) TrustPassword() string {
 	return c.m.GetString("core.trust_password")
@@ -83,6 +95,26 @@ func (c *Config) RBACServer() (string, string, int64, string, string, string, st
 		c.m.GetString("rbac.agent.public_key")
 }
 
+// LocalRBAC returns whether the built-in RBAC backend is enabled.
+func (c *Config) LocalRBAC() bool {
+	return c.m.GetBool("rbac.local")
+}
+
+// OIDCServer returns all the OpenID Connect settings needed to validate the
+// tokens of the clients.
+func (c *Config) OIDCServer() (string, string, string, string) {
+	return c.m.GetString("oidc.issuer"),
+		c.m.GetString("oidc.client.id"),
+		c.m.GetString("oidc.audience"),
+		c.m.GetString("oidc.claim")
+}
+
+// OperationsHistoryExpiry returns the number of days the records of completed
+// operations are kept for.
+func (c *Config) OperationsHistoryExpiry() int64 {
+	return c.m.GetInt64("operations.history_expiry")
+}
+
 // AutoUpdateInterval returns the configured images auto update interval.
 func (c *Config) AutoUpdateInterval() time.Duration {
 	n := c.m.GetInt64("images.auto_update_interval")
@@ -95,6 +127,11 @@ func (c *Config) RemoteCacheExpiry() int64 {
 	return c.m.GetInt64("images.remote_cache_expiry")
 }
 
+// ImagesTrustedKeys returns the public keys trusted to sign downloaded images.
+func (c *Config) ImagesTrustedKeys() string {
+	return c.m.GetString("images.trusted_keys")
+}
+
 // ProxyHTTPS returns the configured HTTPS proxy, if any.
 func (c *Config) ProxyHTTPS() string {
 	return c.m.GetString("core.proxy_https")
@@ -130,6 +167,13 @@ func (c *Config) ImagesMinimalReplica() int64 {
 	return c.m.GetInt64("cluster.images_minimal_replica")
 }
 
+// JoinTokenExpiry returns the configured validity period of cluster join
+// tokens.
+func (c *Config) JoinTokenExpiry() time.Duration {
+	n := c.m.GetInt64("cluster.join_token_expiry")
+	return time.Duration(n) * time.Hour
+}
+
 // Dump current configuration keys and their values. Keys with values matching
 // their defaults are omitted.
 func (c *Config) Dump() map[string]interface{} {
@@ -222,6 +266,8 @@ var ConfigSchema = config.Schema{
 	"backups.compression_algorithm":  {Default: "gzip", Validator: validateCompression},
 	"cluster.offline_threshold":      {Type: config.Int64, Default: offlineThresholdDefault(), Validator: offlineThresholdValidator},
 	"cluster.images_minimal_replica": {Type: config.Int64, Default: "3", Validator: imageMinimalReplicaValidator},
+	"cluster.join_token_expiry":      {Type: config.Int64, Default: "3", Validator: joinTokenExpiryValidator},
+	"core.audit_syslog":              {Type: config.Bool},
 	"core.https_allowed_headers":     {},
 	"core.https_allowed_methods":     {},
 	"core.https_allowed_origin":      {},
@@ -229,6 +275,8 @@ var ConfigSchema = config.Schema{
 	"core.proxy_http":                {},
 	"core.proxy_https":               {},
 	"core.proxy_ignore_hosts":        {},
+	"core.rate_limit":                {Type: config.Int64},
+	"core.rate_limit_burst":          {Type: config.Int64},
 	"core.trust_password":            {Hidden: true, Setter: passwordSetter},
 	"candid.api.key":                 {},
 	"candid.api.url":                 {},
@@ -236,10 +284,18 @@ var ConfigSchema = config.Schema{
 	"candid.expiry":                  {Type: config.Int64, Default: "3600"},
 	"images.auto_update_cached":      {Type: config.Bool, Default: "true"},
 	"images.auto_update_interval":    {Type: config.Int64, Default: "6"},
+	"images.chunked_store":           {Type: config.Bool, Default: "false"},
 	"images.compression_algorithm":   {Default: "gzip", Validator: validateCompression},
+	"images.delta_generations":       {Type: config.Int64, Default: "0"},
 	"images.remote_cache_expiry":     {Type: config.Int64, Default: "10"},
+	"images.trusted_keys":            {Validator: trustedKeysValidator},
 	"maas.api.key":                   {},
 	"maas.api.url":                   {},
+	"oidc.audience":                  {},
+	"oidc.claim":                     {Default: "sub"},
+	"oidc.client.id":                 {},
+	"oidc.issuer":                    {},
+	"operations.history_expiry":      {Type: config.Int64, Default: "7"},
 	"rbac.agent.url":                 {},
 	"rbac.agent.username":            {},
 	"rbac.agent.private_key":         {},
@@ -248,6 +304,7 @@ var ConfigSchema = config.Schema{
 	"rbac.api.key":                   {},
 	"rbac.api.url":                   {},
 	"rbac.expiry":                    {Type: config.Int64, Default: "3600"},
+	"rbac.local":                     {Type: config.Bool},
 
 	// Keys deprecated since the implementation of the storage api.
 	"storage.lvm_fstype":           {Setter: deprecatedStorage, Default: "ext4"},
@@ -279,6 +336,11 @@ func offlineThresholdValidator(value string) error {
 	return nil
 }
 
+func trustedKeysValidator(value string) error {
+	_, err := util.ParseSignatureKeys(value)
+	return err
+}
+
 func imageMinimalReplicaValidator(value string) error {
 	count, err := strconv.Atoi(value)
 	if err != nil {
@@ -292,6 +354,19 @@ func imageMinimalReplicaValidator(value string) error {
 	return nil
 }
 
+func joinTokenExpiryValidator(value string) error {
+	expiry, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("Join token expiry is not a number")
+	}
+
+	if expiry <= 0 {
+		return fmt.Errorf("Value must be greater than '0'")
+	}
+
+	return nil
+}
+
 func passwordSetter(value string) (string, error) {
 	// Nothing to do on unset
 	if value == "" {
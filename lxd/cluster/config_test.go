@@ -2,6 +2,7 @@ package cluster_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
@@ -64,6 +65,22 @@ func TestConfigLoad_OfflineThresholdValidator(t *testing.T) {
 
 }
 
+// Join tokens must expire after a positive number of hours.
+func TestConfigLoad_JoinTokenExpiryValidator(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	config, err := cluster.ConfigLoad(tx)
+	require.NoError(t, err)
+
+	_, err = config.Patch(map[string]interface{}{"cluster.join_token_expiry": "0"})
+	require.EqualError(t, err, "cannot set 'cluster.join_token_expiry' to '0': Value must be greater than '0'")
+
+	_, err = config.Patch(map[string]interface{}{"cluster.join_token_expiry": "12"})
+	require.NoError(t, err)
+	assert.Equal(t, 12*time.Hour, config.JoinTokenExpiry())
+}
+
 // If some previously set values are missing from the ones passed to Replace(),
 // they are deleted from the configuration.
 func TestConfig_ReplaceDeleteValues(t *testing.T) {
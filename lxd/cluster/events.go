@@ -2,6 +2,8 @@ package cluster
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"time"
 
 	lxd "github.com/lxc/lxd/client"
@@ -18,8 +20,13 @@ import (
 // get notified about events.
 //
 // Whenever an event is received the given callback is invoked.
+//
+// The position of the last event received from each node is tracked, so that
+// events missed while a node was disconnected get replayed when reconnecting
+// to it, unless it restarted in the meantime.
 func Events(endpoints *endpoints.Endpoints, cluster *db.Cluster, f func(int64, api.Event)) (task.Func, task.Schedule) {
 	listeners := map[int64]*lxd.EventListener{}
+	sequences := &eventsSequences{last: map[string]string{}}
 
 	// Update our pool of event listeners. Since database queries are
 	// blocking, we spawn the actual logic in a goroutine, to abort
@@ -27,7 +34,7 @@ func Events(endpoints *endpoints.Endpoints, cluster *db.Cluster, f func(int64, a
 	update := func(ctx context.Context) {
 		ch := make(chan struct{})
 		go func() {
-			eventsUpdateListeners(endpoints, cluster, listeners, f)
+			eventsUpdateListeners(endpoints, cluster, listeners, sequences, f)
 			ch <- struct{}{}
 		}()
 		select {
@@ -42,7 +49,29 @@ func Events(endpoints *endpoints.Endpoints, cluster *db.Cluster, f func(int64, a
 	return update, schedule
 }
 
-func eventsUpdateListeners(endpoints *endpoints.Endpoints, cluster *db.Cluster, listeners map[int64]*lxd.EventListener, f func(int64, api.Event)) {
+// Position of the last event received from each node, as <epoch>:<sequence>,
+// by node name.
+type eventsSequences struct {
+	lock sync.Mutex
+	last map[string]string
+}
+
+func (s *eventsSequences) get(name string) (string, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	sequence, ok := s.last[name]
+	return sequence, ok
+}
+
+func (s *eventsSequences) set(name string, position string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.last[name] = position
+}
+
+func eventsUpdateListeners(endpoints *endpoints.Endpoints, cluster *db.Cluster, listeners map[int64]*lxd.EventListener, sequences *eventsSequences, f func(int64, api.Event)) {
 	// Get the current cluster nodes.
 	var nodes []db.NodeInfo
 	var offlineThreshold time.Duration
@@ -94,13 +123,21 @@ func eventsUpdateListeners(endpoints *endpoints.Endpoints, cluster *db.Cluster,
 			delete(listeners, node.ID)
 		}
 
-		listener, err := eventsConnect(node.Address, endpoints.NetworkCert())
+		listener, err := eventsConnect(node.Address, node.Name, endpoints.NetworkCert(), sequences)
 		if err != nil {
 			logger.Warnf("Failed to get events from node %s: %v", node.Address, err)
 			continue
 		}
 		logger.Debugf("Listening for events on node %s", node.Address)
-		listener.AddHandler(nil, func(event api.Event) { f(node.ID, event) })
+
+		id := node.ID
+		name := node.Name
+		listener.AddHandler(nil, func(event api.Event) {
+			if event.Epoch != "" {
+				sequences.set(name, fmt.Sprintf("%s:%d", event.Epoch, event.Sequence))
+			}
+			f(id, event)
+		})
 		listeners[node.ID] = listener
 	}
 	for id, listener := range listeners {
@@ -111,8 +148,9 @@ func eventsUpdateListeners(endpoints *endpoints.Endpoints, cluster *db.Cluster,
 	}
 }
 
-// Establish a client connection to get events from the given node.
-func eventsConnect(address string, cert *shared.CertInfo) (*lxd.EventListener, error) {
+// Establish a client connection to get events from the given node, replaying
+// the ones missed since the last one received, if any.
+func eventsConnect(address string, name string, cert *shared.CertInfo, sequences *eventsSequences) (*lxd.EventListener, error) {
 	client, err := Connect(address, cert, true)
 	if err != nil {
 		return nil, err
@@ -122,5 +160,16 @@ func eventsConnect(address string, cert *shared.CertInfo) (*lxd.EventListener, e
 	// about all events across all projects.
 	client = client.UseProject("*")
 
+	position, ok := sequences.get(name)
+	if ok && client.HasExtension("event_sequence") {
+		listener, err := client.GetEventsSince(map[string]string{name: position})
+		if err == nil {
+			return listener, nil
+		}
+
+		// The node most likely restarted, losing the events.
+		logger.Warnf("Failed to replay events from node %s: %v", address, err)
+	}
+
 	return client.GetEvents()
 }
@@ -70,9 +70,13 @@ func NewGateway(db *db.Node, cert *shared.CertInfo, options ...Option) (*Gateway
 // so).
 type Gateway struct {
 	db      *db.Node
-	cert    *shared.CertInfo
 	options *options
 
+	// The cluster certificate, which can be replaced at any time, hence
+	// its own lock.
+	cert     *shared.CertInfo
+	certLock sync.Mutex
+
 	// The raft instance to use for creating the dqlite driver. It's nil if
 	// this LXD node is not supposed to be part of the raft cluster.
 	raft *raftInstance
@@ -139,7 +143,7 @@ func (g *Gateway) HandlerFuncs(nodeRefreshTask func(*APIHeartbeat)) map[string]h
 		g.lock.RLock()
 		defer g.lock.RUnlock()
 
-		if !tlsCheckCert(r, g.cert) {
+		if !tlsCheckCert(r, g.currentCert()) {
 			http.Error(w, "403 invalid client certificate", http.StatusForbidden)
 			return
 		}
@@ -460,10 +464,27 @@ func (g *Gateway) Reset(cert *shared.CertInfo) error {
 	if err != nil {
 		return err
 	}
-	g.cert = cert
+	g.UpdateCert(cert)
 	return g.init()
 }
 
+// UpdateCert sets a new cluster certificate, to be used for any new
+// connection to other nodes.
+func (g *Gateway) UpdateCert(cert *shared.CertInfo) {
+	g.certLock.Lock()
+	defer g.certLock.Unlock()
+
+	g.cert = cert
+}
+
+// Return the current cluster certificate.
+func (g *Gateway) currentCert() *shared.CertInfo {
+	g.certLock.Lock()
+	defer g.certLock.Unlock()
+
+	return g.cert
+}
+
 // LeaderAddress returns the address of the current raft leader.
 func (g *Gateway) LeaderAddress() (string, error) {
 	g.lock.RLock()
@@ -499,7 +520,7 @@ func (g *Gateway) LeaderAddress() (string, error) {
 
 	// If this isn't a raft node, contact a raft node and ask for the
 	// address of the current leader.
-	config, err := tlsClientConfig(g.cert)
+	config, err := tlsClientConfig(g.currentCert())
 	if err != nil {
 		return "", err
 	}
@@ -564,7 +585,7 @@ func (g *Gateway) LeaderAddress() (string, error) {
 // node is a database node), and a gRPC dialer.
 func (g *Gateway) init() error {
 	logger.Debugf("Initializing database gateway")
-	raft, err := newRaft(g.db, g.cert, g.options.latency)
+	raft, err := newRaft(g.db, g.currentCert(), g.options.latency)
 	if err != nil {
 		return errors.Wrap(err, "Failed to create raft factory")
 	}
@@ -753,7 +774,7 @@ func (g *Gateway) cachedRaftNodes() ([]string, error) {
 }
 
 func dqliteNetworkDial(ctx context.Context, addr string, g *Gateway, checkLeader bool) (net.Conn, error) {
-	config, err := tlsClientConfig(g.cert)
+	config, err := tlsClientConfig(g.currentCert())
 	if err != nil {
 		return nil, err
 	}
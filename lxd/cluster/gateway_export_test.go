@@ -12,7 +12,7 @@ func (g *Gateway) IsLeader() (bool, error) {
 
 // Cert returns the gateway's internal TLS certificate information.
 func (g *Gateway) Cert() *shared.CertInfo {
-	return g.cert
+	return g.currentCert()
 }
 
 // RaftNodes returns the nodes currently part of the raft cluster.
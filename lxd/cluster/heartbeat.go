@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/metrics"
 	"github.com/lxc/lxd/lxd/task"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/logger"
@@ -244,20 +245,21 @@ func (g *Gateway) heartbeat(ctx context.Context, initialHeartbeat bool) {
 
 	// Cumulative set of node states (will be written back to database once done).
 	hbState := &APIHeartbeat{}
+	cert := g.currentCert()
 
 	// If this leader node hasn't sent a heartbeat recently, then its node state records
 	// are likely out of date, this can happen when a node becomes a leader.
 	// Send stale set to all nodes in database to get a fresh set of active nodes.
 	if initialHeartbeat {
 		hbState.Update(false, raftNodes, allNodes, offlineThreshold)
-		hbState.Send(ctx, g.cert, localAddress, allNodes, false)
+		hbState.Send(ctx, cert, localAddress, allNodes, false)
 
 		// We have the latest set of node states now, lets send that state set to all nodes.
 		hbState.Update(true, raftNodes, allNodes, offlineThreshold)
-		hbState.Send(ctx, g.cert, localAddress, allNodes, false)
+		hbState.Send(ctx, cert, localAddress, allNodes, false)
 	} else {
 		hbState.Update(true, raftNodes, allNodes, offlineThreshold)
-		hbState.Send(ctx, g.cert, localAddress, allNodes, true)
+		hbState.Send(ctx, cert, localAddress, allNodes, true)
 	}
 
 	// Look for any new node which appeared since sending last heartbeat.
@@ -296,7 +298,7 @@ func (g *Gateway) heartbeat(ctx context.Context, initialHeartbeat bool) {
 	// If any new nodes found, send heartbeat to just them (with full node state).
 	if len(newNodes) > 0 {
 		hbState.Update(true, raftNodes, allNodes, offlineThreshold)
-		hbState.Send(ctx, g.cert, localAddress, newNodes, false)
+		hbState.Send(ctx, cert, localAddress, newNodes, false)
 	}
 
 	// If the context has been cancelled, return immediately.
@@ -337,6 +339,7 @@ const heartbeatInterval = 10
 // HeartbeatNode performs a single heartbeat request against the node with the given address.
 func HeartbeatNode(taskCtx context.Context, address string, cert *shared.CertInfo, heartbeatData *APIHeartbeat) error {
 	logger.Debugf("Sending heartbeat request to %s", address)
+	defer metrics.ClusterHeartbeatTimingHistogram.ObserveSince(map[string]string{"address": address}, time.Now())
 
 	config, err := tlsClientConfig(cert)
 	if err != nil {
@@ -154,9 +154,13 @@ func Bootstrap(state *state.State, gateway *Gateway, name string) error {
 //
 // This instance must already be clustered.
 //
+// If the certificate with the given fingerprint was added to the trust store
+// using a join token, the token is consumed and must have been issued for a
+// node with the given name.
+//
 // Return an updated list raft database nodes (possibly including the newly
 // accepted node).
-func Accept(state *state.State, gateway *Gateway, name, address string, schema, api int) ([]db.RaftNode, error) {
+func Accept(state *state.State, gateway *Gateway, name, address string, schema, api int, fingerprint string) ([]db.RaftNode, error) {
 	// Check parameters
 	if name == "" {
 		return nil, fmt.Errorf("node name must not be empty")
@@ -173,6 +177,11 @@ func Accept(state *state.State, gateway *Gateway, name, address string, schema,
 			return err
 		}
 
+		err = joinTokenConsume(tx, name, fingerprint)
+		if err != nil {
+			return err
+		}
+
 		// TODO: when fixing #6380 this should be replaced with the
 		// actual architecture of the foreign node.
 		arch, err := osarch.ArchitectureGetLocalID()
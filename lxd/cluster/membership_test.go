@@ -204,7 +204,7 @@ func TestAccept_UnmetPreconditions(t *testing.T) {
 
 			c.setup(&membershipFixtures{t: t, state: state})
 
-			_, err := cluster.Accept(state, gateway, c.name, c.address, c.schema, c.api)
+			_, err := cluster.Accept(state, gateway, c.name, c.address, c.schema, c.api, "")
 			assert.EqualError(t, err, c.error)
 		})
 	}
@@ -224,7 +224,7 @@ func TestAccept(t *testing.T) {
 	f.ClusterNode("1.2.3.4:666")
 
 	nodes, err := cluster.Accept(
-		state, gateway, "buzz", "5.6.7.8:666", cluster.SchemaVersion, len(version.APIExtensions))
+		state, gateway, "buzz", "5.6.7.8:666", cluster.SchemaVersion, len(version.APIExtensions), "")
 	assert.NoError(t, err)
 	assert.Len(t, nodes, 2)
 	assert.Equal(t, int64(1), nodes[0].ID)
@@ -233,6 +233,52 @@ func TestAccept(t *testing.T) {
 	assert.Equal(t, "5.6.7.8:666", nodes[1].Address)
 }
 
+// A node whose certificate was trusted using a join token can only be
+// accepted with the name the token was issued for.
+func TestAccept_JoinToken(t *testing.T) {
+	state, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	cert := shared.TestingKeyPair()
+	gateway := newGateway(t, state.Node, cert)
+	defer gateway.Shutdown()
+
+	f := &membershipFixtures{t: t, state: state}
+	f.RaftNode("1.2.3.4:666")
+	f.ClusterNode("1.2.3.4:666")
+
+	err := state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		token, err := cluster.JoinTokenCreate(tx, "buzz", "abcd", time.Hour)
+		require.NoError(t, err)
+
+		valid, err := cluster.JoinTokenUse(tx, token.Secret, "efgh")
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		valid, err = cluster.JoinTokenUse(tx, token.Secret, "ijkl")
+		require.NoError(t, err)
+		assert.False(t, valid)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	_, err = cluster.Accept(
+		state, gateway, "rusp", "5.6.7.8:666", cluster.SchemaVersion, len(version.APIExtensions), "efgh")
+	assert.EqualError(t, err, "The join token was issued for a member with name: buzz")
+
+	_, err = cluster.Accept(
+		state, gateway, "buzz", "5.6.7.8:666", cluster.SchemaVersion, len(version.APIExtensions), "efgh")
+	require.NoError(t, err)
+
+	err = state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		_, err := tx.JoinTokenByCertificate("efgh")
+		assert.Equal(t, db.ErrNoSuchObject, err)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
 func TestJoin(t *testing.T) {
 	// Setup a target node running as leader of a cluster.
 	targetCert := shared.TestingKeyPair()
@@ -306,7 +352,7 @@ func TestJoin(t *testing.T) {
 
 	// Accept the joining node.
 	raftNodes, err := cluster.Accept(
-		targetState, targetGateway, "rusp", address, cluster.SchemaVersion, len(version.APIExtensions))
+		targetState, targetGateway, "rusp", address, cluster.SchemaVersion, len(version.APIExtensions), "")
 	require.NoError(t, err)
 
 	// Actually join the cluster.
@@ -0,0 +1,191 @@
+package cluster
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/pkg/errors"
+)
+
+// JoinTokenCreate issues a new single-use token allowing a node with the given
+// name to join the cluster, and stores it in the database.
+//
+// The returned token carries the addresses of the current cluster nodes and
+// the fingerprint of the cluster certificate, so it's all the joining node
+// needs to know.
+func JoinTokenCreate(tx *db.ClusterTx, name string, fingerprint string, expiry time.Duration) (*api.ClusterMemberJoinToken, error) {
+	if name == "" {
+		return nil, fmt.Errorf("node name must not be empty")
+	}
+
+	nodes, err := tx.Nodes()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch current cluster nodes")
+	}
+
+	if len(nodes) == 1 && nodes[0].Address == "0.0.0.0" {
+		return nil, fmt.Errorf("Clustering isn't enabled")
+	}
+
+	offlineThreshold, err := tx.NodeOfflineThreshold()
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := []string{}
+	for _, node := range nodes {
+		if node.Name == name {
+			return nil, fmt.Errorf("The cluster already has a member with name: %s", name)
+		}
+
+		if node.IsOffline(offlineThreshold) {
+			continue
+		}
+
+		addresses = append(addresses, node.Address)
+	}
+
+	now := time.Now().UTC()
+	err = tx.JoinTokensPrune(now)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to remove expired join tokens")
+	}
+
+	secret, err := shared.RandomCryptoString()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to generate join token secret")
+	}
+
+	token := &api.ClusterMemberJoinToken{
+		ServerName:  name,
+		Fingerprint: fingerprint,
+		Addresses:   addresses,
+		Secret:      secret,
+		ExpiresAt:   now.Add(expiry),
+	}
+
+	_, err = tx.JoinTokenAdd(name, joinTokenHash(secret), token.ExpiresAt)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to store join token")
+	}
+
+	return token, nil
+}
+
+// JoinTokenUse checks that the given secret matches a join token which hasn't
+// expired nor been used yet, and if so records that it was used to add the
+// certificate with the given fingerprint to the trust store.
+//
+// Tokens are single-use: they can only be used to add the certificate of the
+// joining node, and are removed once that node gets accepted.
+func JoinTokenUse(tx *db.ClusterTx, secret string, fingerprint string) (bool, error) {
+	if secret == "" {
+		return false, nil
+	}
+
+	token, err := tx.JoinTokenBySecret(joinTokenHash(secret))
+	if err != nil {
+		if err == db.ErrNoSuchObject {
+			return false, nil
+		}
+
+		return false, errors.Wrap(err, "Failed to fetch join token")
+	}
+
+	if time.Now().After(token.ExpiryDate) {
+		return false, nil
+	}
+
+	// The joining node may retry with the same certificate.
+	if token.Certificate != "" {
+		return token.Certificate == fingerprint, nil
+	}
+
+	err = tx.JoinTokenUpdateCertificate(token.ID, fingerprint)
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to update join token")
+	}
+
+	return true, nil
+}
+
+// DecodeJoinToken parses a join token as returned by POST
+// /1.0/cluster/members.
+func DecodeJoinToken(input string) (*api.ClusterMemberJoinToken, error) {
+	data, err := base64.StdEncoding.DecodeString(input)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid join token")
+	}
+
+	token := api.ClusterMemberJoinToken{}
+	err = json.Unmarshal(data, &token)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid join token")
+	}
+
+	if token.ServerName == "" || token.Secret == "" || token.Fingerprint == "" || len(token.Addresses) == 0 {
+		return nil, fmt.Errorf("Invalid join token")
+	}
+
+	return &token, nil
+}
+
+// JoinTokenTarget returns the address and the PEM-encoded certificate of the
+// first cluster node listed in the given join token which can be reached and
+// whose certificate matches the token's fingerprint.
+func JoinTokenTarget(token *api.ClusterMemberJoinToken) (string, string, error) {
+	for _, address := range token.Addresses {
+		cert, err := shared.GetRemoteCertificate(fmt.Sprintf("https://%s", address))
+		if err != nil {
+			continue
+		}
+
+		if shared.CertFingerprint(cert) != token.Fingerprint {
+			continue
+		}
+
+		certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+		return address, string(certPEM), nil
+	}
+
+	return "", "", fmt.Errorf("No reachable cluster member matches the join token fingerprint")
+}
+
+// Consume the join token which was used to add the certificate with the given
+// fingerprint to the trust store, if any, checking that it was issued for the
+// node with the given name.
+func joinTokenConsume(tx *db.ClusterTx, name string, fingerprint string) error {
+	token, err := tx.JoinTokenByCertificate(fingerprint)
+	if err != nil {
+		if err == db.ErrNoSuchObject {
+			return nil
+		}
+
+		return errors.Wrap(err, "Failed to fetch join token")
+	}
+
+	if token.Name != name {
+		return fmt.Errorf("The join token was issued for a member with name: %s", token.Name)
+	}
+
+	err = tx.JoinTokenRemove(token.ID)
+	if err != nil {
+		return errors.Wrap(err, "Failed to remove join token")
+	}
+
+	return nil
+}
+
+// Join token secrets are only stored as hashes in the database.
+func joinTokenHash(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
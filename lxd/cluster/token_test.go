@@ -0,0 +1,89 @@
+package cluster_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A join token can be issued, encoded and decoded back.
+func TestJoinTokenCreate(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.NodeAdd("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	token, err := cluster.JoinTokenCreate(tx, "rusp", "abcd", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "rusp", token.ServerName)
+	assert.Equal(t, "abcd", token.Fingerprint)
+	assert.Contains(t, token.Addresses, "1.2.3.4:666")
+
+	decoded, err := cluster.DecodeJoinToken(token.String())
+	require.NoError(t, err)
+	assert.Equal(t, token.Secret, decoded.Secret)
+	assert.Equal(t, token.Addresses, decoded.Addresses)
+}
+
+// No join token can be issued for a name already used by a cluster node.
+func TestJoinTokenCreate_ExistingName(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.NodeAdd("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	_, err = cluster.JoinTokenCreate(tx, "buzz", "abcd", time.Hour)
+	assert.EqualError(t, err, "The cluster already has a member with name: buzz")
+}
+
+// A join token can only be used once.
+func TestJoinTokenUse(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.NodeAdd("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	token, err := cluster.JoinTokenCreate(tx, "rusp", "abcd", time.Hour)
+	require.NoError(t, err)
+
+	valid, err := cluster.JoinTokenUse(tx, "garbage", "efgh")
+	require.NoError(t, err)
+	assert.False(t, valid)
+
+	valid, err = cluster.JoinTokenUse(tx, token.Secret, "efgh")
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	valid, err = cluster.JoinTokenUse(tx, token.Secret, "efgh")
+	require.NoError(t, err)
+	assert.False(t, valid)
+}
+
+// An expired join token is not valid.
+func TestJoinTokenUse_Expired(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.NodeAdd("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	token, err := cluster.JoinTokenCreate(tx, "rusp", "abcd", -time.Hour)
+	require.NoError(t, err)
+
+	valid, err := cluster.JoinTokenUse(tx, token.Secret, "efgh")
+	require.NoError(t, err)
+	assert.False(t, valid)
+}
+
+// Garbage input is not a valid join token.
+func TestDecodeJoinToken_Invalid(t *testing.T) {
+	_, err := cluster.DecodeJoinToken("garbage")
+	assert.Error(t, err)
+}
@@ -443,6 +443,11 @@ func instanceCreateFromImage(d *Daemon, args db.InstanceArgs, hash string, op *o
 		if err != nil {
 			return nil, err
 		}
+
+		err = imageChunksPack(d, hash)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Set the "image.*" keys.
@@ -475,6 +480,13 @@ func instanceCreateFromImage(d *Daemon, args db.InstanceArgs, hash string, op *o
 		return nil, fmt.Errorf("Error updating image last use date: %s", err)
 	}
 
+	// Reassemble the image files if stored as chunks.
+	release, err := imageChunksUnpack(d, hash)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	// Check if we can load new storage layer for pool driver type.
 	pool, err := storagePools.GetPoolByInstance(d.State(), inst)
 	if err != storageDrivers.ErrUnknownDriver && err != storageDrivers.ErrNotImplemented {
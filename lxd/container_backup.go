@@ -186,7 +186,7 @@ func containerBackupsPost(d *Daemon, r *http.Request) response.Response {
 	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask,
 		db.OperationBackupCreate, resources, nil, backup, nil, nil)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
@@ -273,7 +273,7 @@ func containerBackupPost(d *Daemon, r *http.Request) response.Response {
 	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask,
 		db.OperationBackupRename, resources, nil, rename, nil, nil)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
@@ -319,7 +319,7 @@ func containerBackupDelete(d *Daemon, r *http.Request) response.Response {
 	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask,
 		db.OperationBackupRemove, resources, nil, remove, nil, nil)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
@@ -317,7 +317,7 @@ func containerConsolePost(d *Daemon, r *http.Request) response.Response {
 	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassWebsocket, db.OperationConsoleShow,
 		resources, ws.Metadata(), ws.Do, nil, ws.Connect)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
@@ -45,7 +45,7 @@ func containerDelete(d *Daemon, r *http.Request) response.Response {
 
 	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, db.OperationContainerDelete, resources, nil, rmct, nil, nil)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
@@ -478,7 +478,7 @@ func containerExecPost(d *Daemon, r *http.Request) response.Response {
 
 		op, err := operations.OperationCreate(d.State(), project, operations.OperationClassWebsocket, db.OperationCommandExec, resources, ws.Metadata(), ws.Do, nil, ws.Connect)
 		if err != nil {
-			return response.InternalError(err)
+			return response.SmartError(err)
 		}
 
 		return operations.OperationResponse(op)
@@ -545,7 +545,7 @@ func containerExecPost(d *Daemon, r *http.Request) response.Response {
 
 	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, db.OperationCommandExec, resources, nil, run, nil, nil)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
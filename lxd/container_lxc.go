@@ -37,6 +37,7 @@ import (
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/instance/operationlock"
 	"github.com/lxc/lxd/lxd/maas"
+	"github.com/lxc/lxd/lxd/metrics"
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/seccomp"
@@ -3260,6 +3261,50 @@ func (c *containerLXC) RenderState() (*api.InstanceState, error) {
 	return &status, nil
 }
 
+// Metrics returns the resource usage metrics of the running container.
+func (c *containerLXC) Metrics() (*metrics.MetricSet, error) {
+	if !c.IsRunning() {
+		return nil, fmt.Errorf("Container is not running")
+	}
+
+	state := api.InstanceState{
+		CPU:       c.cpuState(),
+		Memory:    c.memoryState(),
+		Network:   c.networkState(),
+		Processes: c.processesState(),
+	}
+
+	set := instanceStateMetrics(&state)
+
+	if c.state.OS.CGroupBlkioController {
+		disks := map[string]*instanceDiskIO{}
+
+		value, err := c.CGroupGet("blkio.throttle.io_service_bytes")
+		if err != nil {
+			return nil, err
+		}
+
+		err = parseBlkioStats(value, disks, true)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err = c.CGroupGet("blkio.throttle.io_serviced")
+		if err != nil {
+			return nil, err
+		}
+
+		err = parseBlkioStats(value, disks, false)
+		if err != nil {
+			return nil, err
+		}
+
+		instanceDiskIOMetrics(set, disks)
+	}
+
+	return set, nil
+}
+
 func (c *containerLXC) Snapshots() ([]instance.Instance, error) {
 	var snaps []db.Instance
 
@@ -4870,7 +4915,7 @@ func (c *containerLXC) updateDevices(removeDevices deviceConfig.Devices, addDevi
 	return nil
 }
 
-func (c *containerLXC) Export(w io.Writer, properties map[string]string) error {
+func (c *containerLXC) Export(w io.Writer, properties map[string]string, creationDate time.Time) error {
 	ctxMap := log.Ctx{
 		"project":   c.project,
 		"name":      c.name,
@@ -4931,6 +4976,9 @@ func (c *containerLXC) Export(w io.Writer, properties map[string]string) error {
 
 	// Create the tarball
 	ctw := containerwriter.NewContainerTarWriter(w, idmap)
+	if !creationDate.IsZero() {
+		ctw.SetModTime(creationDate)
+	}
 
 	// Keep track of the first path we saw for each path with nlink>1
 	cDir := c.Path()
@@ -4991,6 +5039,9 @@ func (c *containerLXC) Export(w io.Writer, properties map[string]string) error {
 		meta := api.ImageMetadata{}
 		meta.Architecture = arch
 		meta.CreationDate = time.Now().UTC().Unix()
+		if !creationDate.IsZero() {
+			meta.CreationDate = creationDate.UTC().Unix()
+		}
 		meta.Properties = properties
 
 		data, err := yaml.Marshal(&meta)
@@ -5024,7 +5075,10 @@ func (c *containerLXC) Export(w io.Writer, properties map[string]string) error {
 			return err
 		}
 	} else {
-		if properties != nil {
+		// The existing metadata.yaml is rewritten to set the
+		// properties or the creation date.
+		rewrite := properties != nil || !creationDate.IsZero()
+		if rewrite {
 			// Parse the metadata
 			content, err := ioutil.ReadFile(fnam)
 			if err != nil {
@@ -5040,7 +5094,13 @@ func (c *containerLXC) Export(w io.Writer, properties map[string]string) error {
 				logger.Error("Failed exporting container", ctxMap)
 				return err
 			}
-			metadata.Properties = properties
+			if properties != nil {
+				metadata.Properties = properties
+			}
+
+			if !creationDate.IsZero() {
+				metadata.CreationDate = creationDate.UTC().Unix()
+			}
 
 			// Generate a new metadata.yaml
 			tempDir, err := ioutil.TempDir("", "lxd_lxd_metadata_")
@@ -5077,7 +5137,7 @@ func (c *containerLXC) Export(w io.Writer, properties map[string]string) error {
 			return err
 		}
 
-		if properties != nil {
+		if rewrite {
 			tmpOffset := len(path.Dir(fnam)) + 1
 			err = ctw.WriteFile(tmpOffset, fnam, fi)
 		} else {
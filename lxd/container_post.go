@@ -228,7 +228,7 @@ func containerPost(d *Daemon, r *http.Request) response.Response {
 
 			op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, db.OperationContainerMigrate, resources, nil, ws.Do, nil, nil)
 			if err != nil {
-				return response.InternalError(err)
+				return response.SmartError(err)
 			}
 
 			return operations.OperationResponse(op)
@@ -237,7 +237,7 @@ func containerPost(d *Daemon, r *http.Request) response.Response {
 		// Pull mode
 		op, err := operations.OperationCreate(d.State(), project, operations.OperationClassWebsocket, db.OperationContainerMigrate, resources, ws.Metadata(), ws.Do, nil, ws.Connect)
 		if err != nil {
-			return response.InternalError(err)
+			return response.SmartError(err)
 		}
 
 		return operations.OperationResponse(op)
@@ -258,7 +258,7 @@ func containerPost(d *Daemon, r *http.Request) response.Response {
 
 	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, db.OperationContainerRename, resources, nil, run, nil, nil)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
@@ -401,7 +401,7 @@ func containerPostClusteringMigrate(d *Daemon, c instance.Instance, oldName, new
 	resources["containers"] = []string{oldName}
 	op, err := operations.OperationCreate(d.State(), c.Project(), operations.OperationClassTask, db.OperationContainerMigrate, resources, nil, run, nil, nil)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
@@ -509,7 +509,7 @@ func containerPostClusteringMigrateWithCeph(d *Daemon, c instance.Instance, proj
 	resources["containers"] = []string{oldName}
 	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, db.OperationContainerMigrate, resources, nil, run, nil, nil)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
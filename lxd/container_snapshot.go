@@ -178,7 +178,7 @@ func containerSnapshotsPost(d *Daemon, r *http.Request) response.Response {
 
 	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, db.OperationSnapshotCreate, resources, nil, snapshot, nil, nil)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
@@ -301,7 +301,7 @@ func snapshotPut(d *Daemon, r *http.Request, sc instance.Instance, name string)
 	op, err := operations.OperationCreate(d.State(), sc.Project(), operations.OperationClassTask, opType, resources, nil,
 		do, nil, nil)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
@@ -378,7 +378,7 @@ func snapshotPost(d *Daemon, r *http.Request, sc instance.Instance, containerNam
 
 			op, err := operations.OperationCreate(d.State(), sc.Project(), operations.OperationClassTask, db.OperationSnapshotTransfer, resources, nil, ws.Do, nil, nil)
 			if err != nil {
-				return response.InternalError(err)
+				return response.SmartError(err)
 			}
 
 			return operations.OperationResponse(op)
@@ -387,7 +387,7 @@ func snapshotPost(d *Daemon, r *http.Request, sc instance.Instance, containerNam
 		// Pull mode
 		op, err := operations.OperationCreate(d.State(), sc.Project(), operations.OperationClassWebsocket, db.OperationSnapshotTransfer, resources, ws.Metadata(), ws.Do, nil, ws.Connect)
 		if err != nil {
-			return response.InternalError(err)
+			return response.SmartError(err)
 		}
 
 		return operations.OperationResponse(op)
@@ -420,7 +420,7 @@ func snapshotPost(d *Daemon, r *http.Request, sc instance.Instance, containerNam
 
 	op, err := operations.OperationCreate(d.State(), sc.Project(), operations.OperationClassTask, db.OperationSnapshotRename, resources, nil, rename, nil, nil)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
@@ -436,7 +436,7 @@ func snapshotDelete(sc instance.Instance, name string) response.Response {
 
 	op, err := operations.OperationCreate(sc.DaemonState(), sc.Project(), operations.OperationClassTask, db.OperationSnapshotDelete, resources, nil, remove, nil, nil)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
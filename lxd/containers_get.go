@@ -44,10 +44,31 @@ func urlInstanceTypeDetect(r *http.Request) (instancetype.Type, error) {
 }
 
 func containersGet(d *Daemon, r *http.Request) response.Response {
+	// Parse the recursion field
+	recursion, err := strconv.Atoi(r.FormValue("recursion"))
+	if err != nil {
+		recursion = 0
+	}
+
+	params, err := listParamsParse(r)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	for i := 0; i < 100; i++ {
-		result, err := doContainersGet(d, r)
-		if err == nil {
-			return response.SyncResponse(true, result)
+		var result interface{}
+		var err error
+		if params.enabled() {
+			var resp response.Response
+			resp, err = doContainersGetList(d, r, recursion, params)
+			if err == nil {
+				return resp
+			}
+		} else {
+			result, err = doContainersGet(d, r, recursion, nil)
+			if err == nil {
+				return response.SyncResponse(true, result)
+			}
 		}
 		if !query.IsRetriableError(err) {
 			logger.Debugf("DBERR: containersGet: error %q", err)
@@ -63,7 +84,9 @@ func containersGet(d *Daemon, r *http.Request) response.Response {
 	return response.InternalError(fmt.Errorf("DB is locked"))
 }
 
-func doContainersGet(d *Daemon, r *http.Request) (interface{}, error) {
+// Return the instances of the project, only considering the selected ones if
+// selected isn't nil.
+func doContainersGet(d *Daemon, r *http.Request, recursion int, selected map[string]bool) (interface{}, error) {
 	resultString := []string{}
 	resultList := []*api.Instance{}
 	resultFullList := []*api.InstanceFull{}
@@ -74,14 +97,6 @@ func doContainersGet(d *Daemon, r *http.Request) (interface{}, error) {
 		return nil, err
 	}
 
-	// Parse the recursion field
-	recursionStr := r.FormValue("recursion")
-
-	recursion, err := strconv.Atoi(recursionStr)
-	if err != nil {
-		recursion = 0
-	}
-
 	// Parse the project field
 	project := projectParam(r)
 
@@ -107,6 +122,20 @@ func doContainersGet(d *Daemon, r *http.Request) (interface{}, error) {
 		return []string{}, err
 	}
 
+	// Only keep the selected instances
+	if selected != nil {
+		for address, containers := range result {
+			kept := []string{}
+			for _, container := range containers {
+				if selected[container] {
+					kept = append(kept, container)
+				}
+			}
+
+			result[address] = kept
+		}
+	}
+
 	// Get the local instances
 	nodeCts := map[string]instance.Instance{}
 	if recursion > 0 {
@@ -122,6 +151,10 @@ func doContainersGet(d *Daemon, r *http.Request) (interface{}, error) {
 
 	// Append containers to list and handle errors
 	resultListAppend := func(name string, c api.Instance, err error) {
+		if selected != nil && !selected[name] {
+			return
+		}
+
 		if err != nil {
 			c = api.Instance{
 				Name:       name,
@@ -136,6 +169,10 @@ func doContainersGet(d *Daemon, r *http.Request) (interface{}, error) {
 	}
 
 	resultFullListAppend := func(name string, c api.InstanceFull, err error) {
+		if selected != nil && !selected[name] {
+			return
+		}
+
 		if err != nil {
 			c = api.InstanceFull{Instance: api.Instance{
 				Name:       name,
@@ -216,14 +253,7 @@ func doContainersGet(d *Daemon, r *http.Request) (interface{}, error) {
 
 		if recursion == 0 {
 			for _, container := range containers {
-				instancePath := "instances"
-				if strings.HasPrefix(mux.CurrentRoute(r).GetName(), "container") {
-					instancePath = "containers"
-				} else if strings.HasPrefix(mux.CurrentRoute(r).GetName(), "vm") {
-					instancePath = "virtual-machines"
-				}
-				url := fmt.Sprintf("/%s/%s/%s", version.APIVersion, instancePath, container)
-				resultString = append(resultString, url)
+				resultString = append(resultString, instanceURL(r, container))
 			}
 		} else {
 			threads := 4
@@ -296,6 +326,168 @@ func doContainersGet(d *Daemon, r *http.Request) (interface{}, error) {
 	return resultFullList, nil
 }
 
+// Return the URL of the instance with the given name, matching the endpoint of
+// the request.
+func instanceURL(r *http.Request, name string) string {
+	instancePath := "instances"
+	if strings.HasPrefix(mux.CurrentRoute(r).GetName(), "container") {
+		instancePath = "containers"
+	} else if strings.HasPrefix(mux.CurrentRoute(r).GetName(), "vm") {
+		instancePath = "virtual-machines"
+	}
+
+	return fmt.Sprintf("/%s/%s/%s", version.APIVersion, instancePath, name)
+}
+
+// Return the filtered, paginated and projected instances of the project.
+//
+// If the filter only refers to the name and location of the instances, it's
+// evaluated against the database before rendering the selected instances.
+// Otherwise it's evaluated against their recursion=1 representation along
+// with their project, and their state is only rendered for the matching
+// instances, unless the filter itself refers to it.
+func doContainersGetList(d *Daemon, r *http.Request, recursion int, params *listParams) (response.Response, error) {
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return nil, err
+	}
+
+	project := projectParam(r)
+
+	if params.filterOnly("name", "location") {
+		var nodes map[string]string
+		err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			var err error
+			nodes, err = tx.ContainersByNodeName(project, instanceType)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		entries := []listEntry{}
+		for name, location := range nodes {
+			entries = append(entries, listEntry{
+				key:    name,
+				url:    instanceURL(r, name),
+				object: map[string]interface{}{"name": name, "location": location},
+			})
+		}
+
+		entries, next, err := params.apply(entries)
+		if err != nil {
+			return nil, err
+		}
+
+		if recursion == 0 {
+			return params.response(entries, next, false), nil
+		}
+
+		selected := map[string]bool{}
+		for _, entry := range entries {
+			selected[entry.key] = true
+		}
+
+		result, err := doContainersGet(d, r, recursion, selected)
+		if err != nil {
+			return nil, err
+		}
+
+		entries, _ = params.paginate(instanceListEntries(r, result))
+
+		return params.response(entries, next, true), nil
+	}
+
+	filterRecursion := 1
+	if listFieldsRefer(params.filter.Fields(), instanceFullFields...) {
+		filterRecursion = 2
+	}
+
+	result, err := doContainersGet(d, r, filterRecursion, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []listEntry{}
+	for _, entry := range instanceListEntries(r, result) {
+		object := instanceFilterObject{Project: project}
+		switch instance := entry.object.(type) {
+		case *api.Instance:
+			object.InstanceFull = &api.InstanceFull{Instance: *instance}
+		case *api.InstanceFull:
+			object.InstanceFull = instance
+		}
+
+		entry.object = object
+		entries = append(entries, entry)
+	}
+
+	entries, next, err := params.apply(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	if recursion == 0 {
+		return params.response(entries, next, false), nil
+	}
+
+	// Render the state of the matching instances if it was asked for.
+	if recursion > 1 && filterRecursion == 1 && (len(params.fields) == 0 || listFieldsRefer(params.fields, instanceFullFields...)) {
+		selected := map[string]bool{}
+		for _, entry := range entries {
+			selected[entry.key] = true
+		}
+
+		result, err := doContainersGet(d, r, recursion, selected)
+		if err != nil {
+			return nil, err
+		}
+
+		entries, _ = params.paginate(instanceListEntries(r, result))
+
+		return params.response(entries, next, true), nil
+	}
+
+	for i, entry := range entries {
+		object := entry.object.(instanceFilterObject)
+		if recursion > 1 {
+			entries[i].object = object.InstanceFull
+		} else {
+			entries[i].object = &object.InstanceFull.Instance
+		}
+	}
+
+	return params.response(entries, next, true), nil
+}
+
+// Fields of the recursion=2 representation of the instances which aren't part
+// of their recursion=1 one.
+var instanceFullFields = []string{"state", "snapshots", "backups"}
+
+// Representation of an instance the filters are evaluated against.
+type instanceFilterObject struct {
+	*api.InstanceFull
+	Project string `json:"project"`
+}
+
+// Convert the result of doContainersGet to list entries.
+func instanceListEntries(r *http.Request, result interface{}) []listEntry {
+	entries := []listEntry{}
+
+	switch instances := result.(type) {
+	case []*api.Instance:
+		for _, instance := range instances {
+			entries = append(entries, listEntry{key: instance.Name, url: instanceURL(r, instance.Name), object: instance})
+		}
+	case []*api.InstanceFull:
+		for _, instance := range instances {
+			entries = append(entries, listEntry{key: instance.Name, url: instanceURL(r, instance.Name), object: instance})
+		}
+	}
+
+	return entries
+}
+
 // Fetch information about the containers on the given remote node, using the
 // rest API and with a timeout of 30 seconds.
 func doContainersGetFromNode(project, node string, cert *shared.CertInfo, instanceType instancetype.Type) ([]api.Instance, error) {
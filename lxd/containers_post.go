@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
@@ -11,7 +12,9 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/dustinkirkland/golang-petname"
 	"github.com/gorilla/websocket"
@@ -28,6 +31,7 @@ import (
 	"github.com/lxc/lxd/lxd/response"
 	storagePools "github.com/lxc/lxd/lxd/storage"
 	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
+	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	log "github.com/lxc/lxd/shared/log15"
@@ -130,7 +134,7 @@ func createFromImage(d *Daemon, project string, req *api.InstancesPost) response
 
 			info, err = d.ImageDownload(
 				op, req.Source.Server, req.Source.Protocol, req.Source.Certificate,
-				req.Source.Secret, hash, imgType, true, autoUpdate, "", true, project)
+				req.Source.Secret, req.Source.RequireSignature, hash, imgType, true, autoUpdate, "", true, project)
 			if err != nil {
 				return err
 			}
@@ -156,7 +160,159 @@ func createFromImage(d *Daemon, project string, req *api.InstancesPost) response
 
 	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, db.OperationContainerCreate, resources, nil, run, nil, nil)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+func createFromDisk(d *Daemon, project string, req *api.InstancesPost) response.Response {
+	if req.Type == "" {
+		req.Type = api.InstanceTypeVM
+	}
+
+	if req.Type != api.InstanceTypeVM {
+		return response.BadRequest(fmt.Errorf("Disk images can only be used for virtual machines"))
+	}
+
+	u, err := url.Parse(req.Source.URL)
+	if err != nil || u.Scheme != "https" {
+		return response.BadRequest(fmt.Errorf("Disk images must be retrieved from a https URL"))
+	}
+
+	architecture := req.Architecture
+	if architecture == "" {
+		architecture, err = osarch.ArchitectureName(d.os.Architectures[0])
+		if err != nil {
+			return response.InternalError(err)
+		}
+	}
+
+	run := func(op *operations.Operation) error {
+		builddir, err := ioutil.TempDir(shared.VarPath("images"), "lxd_build_")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(builddir)
+
+		// Download the disk image
+		httpClient, err := util.HTTPClient("", d.proxy)
+		if err != nil {
+			return err
+		}
+
+		resp, err := httpClient.Get(req.Source.URL)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("Unable to fetch %s: %s", req.Source.URL, resp.Status)
+		}
+
+		fname := filepath.Join(builddir, filepath.Base(u.Path))
+		f, err := os.Create(fname)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(f, resp.Body)
+		f.Close()
+		if err != nil {
+			return err
+		}
+
+		// Convert it into an image
+		disk, err := imageDiskConvert(fname, builddir, architecture, d.os.Architectures)
+		if err != nil {
+			return err
+		}
+
+		hash := sha256.New()
+		var size int64
+		for _, path := range []string{disk.MetadataFile, disk.RootfsFile} {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+
+			n, err := io.Copy(hash, f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+			size += n
+		}
+		fingerprint := fmt.Sprintf("%x", hash.Sum(nil))
+
+		// Store the image, keeping concurrent downloads of it from racing
+		// with us
+		err = func() error {
+			unlock := imagesDownloadingLockImage(fingerprint)
+			defer unlock()
+
+			exists, err := d.cluster.ImageExists(project, fingerprint)
+			if err != nil {
+				return err
+			}
+
+			if !exists {
+				err = shared.FileMove(disk.MetadataFile, shared.VarPath("images", fingerprint))
+				if err != nil {
+					return err
+				}
+
+				err = shared.FileMove(disk.RootfsFile, shared.VarPath("images", fingerprint+".rootfs"))
+				if err != nil {
+					return err
+				}
+
+				err = d.cluster.ImageInsert(project, fingerprint, filepath.Base(u.Path), size, false, false, architecture, time.Unix(disk.Metadata.CreationDate, 0), time.Time{}, disk.Metadata.Properties, string(api.InstanceTypeVM))
+				if err != nil {
+					return err
+				}
+
+				// Mark the image as "cached"
+				err = d.cluster.ImageLastAccessInit(fingerprint)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}()
+		if err != nil {
+			return err
+		}
+
+		args := db.InstanceArgs{
+			Project:     project,
+			Config:      req.Config,
+			Type:        instancetype.VM,
+			Description: req.Description,
+			Devices:     deviceConfig.NewDevices(req.Devices),
+			Ephemeral:   req.Ephemeral,
+			Name:        req.Name,
+			Profiles:    req.Profiles,
+		}
+
+		args.Architecture, err = osarch.ArchitectureId(architecture)
+		if err != nil {
+			return err
+		}
+
+		_, err = instanceCreateFromImage(d, args, fingerprint, op)
+		return err
+	}
+
+	resources := map[string][]string{}
+	resources["instances"] = []string{req.Name}
+	resources["containers"] = resources["instances"] // Populate old field name.
+
+	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, db.OperationContainerCreate, resources, nil, run, nil, nil)
+	if err != nil {
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
@@ -198,7 +354,7 @@ func createFromNone(d *Daemon, project string, req *api.InstancesPost) response.
 
 	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, db.OperationContainerCreate, resources, nil, run, nil, nil)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
@@ -460,12 +616,12 @@ func createFromMigration(d *Daemon, project string, req *api.InstancesPost) resp
 	if push {
 		op, err = operations.OperationCreate(d.State(), project, operations.OperationClassWebsocket, db.OperationContainerCreate, resources, sink.Metadata(), run, nil, sink.Connect)
 		if err != nil {
-			return response.InternalError(err)
+			return response.SmartError(err)
 		}
 	} else {
 		op, err = operations.OperationCreate(d.State(), project, operations.OperationClassTask, db.OperationContainerCreate, resources, nil, run, nil, nil)
 		if err != nil {
-			return response.InternalError(err)
+			return response.SmartError(err)
 		}
 	}
 
@@ -635,7 +791,7 @@ func createFromCopy(d *Daemon, project string, req *api.InstancesPost) response.
 
 	op, err := operations.OperationCreate(d.State(), targetProject, operations.OperationClassTask, db.OperationContainerCreate, resources, nil, run, nil, nil)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
@@ -797,7 +953,7 @@ func createFromBackup(d *Daemon, project string, data io.Reader, pool string) re
 		resources, nil, run, nil, nil)
 	if err != nil {
 		backupFile.Close()
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
@@ -923,6 +1079,8 @@ func containersPost(d *Daemon, r *http.Request) response.Response {
 		return createFromImage(d, project, &req)
 	case "none":
 		return createFromNone(d, project, &req)
+	case "disk":
+		return createFromDisk(d, project, &req)
 	case "migration":
 		return createFromMigration(d, project, &req)
 	case "copy":
@@ -28,6 +28,7 @@ import (
 	"gopkg.in/macaroon-bakery.v2/bakery/identchecker"
 	"gopkg.in/macaroon-bakery.v2/httpbakery"
 
+	"github.com/lxc/lxd/lxd/audit"
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/daemon"
 	"github.com/lxc/lxd/lxd/db"
@@ -35,7 +36,11 @@ import (
 	"github.com/lxc/lxd/lxd/endpoints"
 	"github.com/lxc/lxd/lxd/events"
 	"github.com/lxc/lxd/lxd/maas"
+	"github.com/lxc/lxd/lxd/metrics"
 	"github.com/lxc/lxd/lxd/node"
+	"github.com/lxc/lxd/lxd/oidc"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/ratelimit"
 	"github.com/lxc/lxd/lxd/rbac"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/lxd/seccomp"
@@ -43,6 +48,7 @@ import (
 	"github.com/lxc/lxd/lxd/sys"
 	"github.com/lxc/lxd/lxd/task"
 	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/lxd/webhooks"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/idmap"
 	"github.com/lxc/lxd/shared/logger"
@@ -63,6 +69,12 @@ type Daemon struct {
 	readyChan    chan struct{} // Closed when LXD is fully ready
 	shutdownChan chan struct{}
 
+	// Certificates only trusted for scraping metrics
+	metricsCerts map[string]x509.Certificate
+
+	// Projects of the restricted client certificates, by fingerprint
+	clientCertProjects map[string][]string
+
 	// Event servers
 	devlxdEvents *events.Server
 	events       *events.Server
@@ -73,8 +85,9 @@ type Daemon struct {
 	clusterTasks task.Group
 
 	// Indexes of tasks that need to be reset when their execution interval changes
-	taskPruneImages *task.Task
-	taskAutoUpdate  *task.Task
+	taskPruneImages           *task.Task
+	taskAutoUpdate            *task.Task
+	taskPruneOperationRecords *task.Task
 
 	config    *DaemonConfig
 	endpoints *endpoints.Endpoints
@@ -85,8 +98,24 @@ type Daemon struct {
 
 	externalAuth *externalAuth
 
+	// Validation of the OpenID Connect tokens
+	oidcVerifier *oidc.Verifier
+
+	// Built-in RBAC backend
+	localRBAC *rbac.Local
+
+	// Per-client rate limit of the API requests
+	rateLimiter     *ratelimit.Limiter
+	rateLimiterLock sync.Mutex
+
 	// Stores last heartbeat node information to detect node changes.
 	lastNodeList *cluster.APIHeartbeat
+
+	// Audit log of the API mutations
+	audit *audit.Logger
+
+	// Delivers the events to the webhooks
+	webhooks *webhooks.Dispatcher
 }
 
 type externalAuth struct {
@@ -236,6 +265,13 @@ func (d *Daemon) checkTrustedClient(r *http.Request) error {
 //
 // This does not perform authorization, only validates authentication
 func (d *Daemon) Authenticate(r *http.Request) (bool, string, string, error) {
+	trusted, username, protocol, _, err := d.authenticate(r)
+	return trusted, username, protocol, err
+}
+
+// Same as Authenticate, also returning the API token the request was
+// authenticated with, if any.
+func (d *Daemon) authenticate(r *http.Request) (bool, string, string, *db.AuthToken, error) {
 	// Allow internal cluster traffic
 	if r.TLS != nil {
 		cert, _ := x509.ParseCertificate(d.endpoints.NetworkCert().KeyPair().Certificate[0])
@@ -243,29 +279,29 @@ func (d *Daemon) Authenticate(r *http.Request) (bool, string, string, error) {
 		for i := range r.TLS.PeerCertificates {
 			trusted, _ := util.CheckTrustState(*r.TLS.PeerCertificates[i], clusterCerts)
 			if trusted {
-				return true, "", "cluster", nil
+				return true, "", "cluster", nil, nil
 			}
 		}
 	}
 
 	// Local unix socket queries
 	if r.RemoteAddr == "@" {
-		return true, "", "unix", nil
+		return true, "", "unix", nil, nil
 	}
 
 	// Devlxd unix socket credentials on main API
 	if r.RemoteAddr == "@devlxd" {
-		return false, "", "", fmt.Errorf("Main API query can't come from /dev/lxd socket")
+		return false, "", "", nil, fmt.Errorf("Main API query can't come from /dev/lxd socket")
 	}
 
 	// Cluster notification with wrong certificate
 	if isClusterNotification(r) {
-		return false, "", "", fmt.Errorf("Cluster notification isn't using cluster certificate")
+		return false, "", "", nil, fmt.Errorf("Cluster notification isn't using cluster certificate")
 	}
 
 	// Bad query, no TLS found
 	if r.TLS == nil {
-		return false, "", "", fmt.Errorf("Bad/missing TLS on network query")
+		return false, "", "", nil, fmt.Errorf("Bad/missing TLS on network query")
 	}
 
 	if d.externalAuth != nil && r.Header.Get(httpbakery.BakeryProtocolHeader) != "" {
@@ -281,28 +317,62 @@ func (d *Daemon) Authenticate(r *http.Request) (bool, string, string, error) {
 		info, err := authChecker.Allow(ctx, ops...)
 		if err != nil {
 			// Bad macaroon
-			return false, "", "", err
+			return false, "", "", nil, err
 		}
 
 		if info != nil && info.Identity != nil {
 			// Valid identity macaroon found
-			return true, info.Identity.Id(), "candid", nil
+			return true, info.Identity.Id(), "candid", nil, nil
 		}
 
 		// Valid macaroon with no identity information
-		return true, "", "candid", nil
+		return true, "", "candid", nil, nil
+	}
+
+	if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer "+authTokenPrefix) {
+		// Validate API token
+		token, err := authTokenValidate(d, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if err != nil {
+			return false, "", "", nil, err
+		}
+
+		if token == nil {
+			return false, "", "", nil, nil
+		}
+
+		return true, token.Name, "token", token, nil
+	}
+
+	if d.oidcVerifier != nil && strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		// Validate OpenID Connect access token
+		username, err := d.oidcVerifier.Auth(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if err != nil {
+			return false, "", "", nil, err
+		}
+
+		return true, username, "oidc", nil, nil
 	}
 
 	// Validate normal TLS access
 	for i := range r.TLS.PeerCertificates {
 		trusted, username := util.CheckTrustState(*r.TLS.PeerCertificates[i], d.clientCerts)
 		if trusted {
-			return true, username, "tls", nil
+			return true, username, "tls", nil, nil
+		}
+	}
+
+	// Metrics certificates are only trusted for the metrics endpoint
+	if r.URL.Path == fmt.Sprintf("/%s/metrics", version.APIVersion) {
+		for i := range r.TLS.PeerCertificates {
+			trusted, username := util.CheckTrustState(*r.TLS.PeerCertificates[i], d.metricsCerts)
+			if trusted {
+				return true, username, "tls", nil, nil
+			}
 		}
 	}
 
 	// Reject unauthorized
-	return false, "", "", nil
+	return false, "", "", nil, nil
 }
 
 func writeMacaroonsRequiredResponse(b *identchecker.Bakery, r *http.Request, w http.ResponseWriter, derr *bakery.DischargeRequiredError, expiry int64) {
@@ -370,8 +440,38 @@ func (d *Daemon) createCmd(restAPI *mux.Router, version string, c APIEndpoint) {
 		}
 
 		// Authentication
-		trusted, username, protocol, err := d.Authenticate(r)
+		trusted, username, protocol, token, err := d.authenticate(r)
+
+		// Throttle the clients sending too many requests
+		allowed, retryAfter := rateLimitRequest(d, r, username, protocol)
+		if !allowed {
+			logger.Warn("Rejecting request from rate limited client", log.Ctx{"ip": r.RemoteAddr, "user": username})
+			response.TooManyRequests(fmt.Errorf("Too many requests"), retryAfter).Render(w)
+			return
+		}
+
+		// Record the API mutations in the audit log, except for the
+		// notifications from the other members, as authenticated by
+		// their certificate.
+		if r.Method != "GET" && version != "internal" && protocol != "cluster" && d.audit != nil {
+			var done func()
+			w, r, done = auditRequest(d, w, r, username, protocol)
+			defer done()
+		}
+
+		// Let untrusted clients know where to get an OpenID Connect token
+		if !trusted && d.oidcVerifier != nil {
+			d.oidcVerifier.WriteHeaders(w)
+		}
+
 		if err != nil {
+			// Invalid or expired OpenID Connect token
+			if _, ok := err.(*oidc.AuthError); ok {
+				logger.Warn("Rejecting request with invalid OIDC token", log.Ctx{"ip": r.RemoteAddr, "err": err})
+				response.ErrorResponse(http.StatusUnauthorized, err.Error()).Render(w)
+				return
+			}
+
 			// If not a macaroon discharge request, return the error
 			_, ok := err.(*bakery.DischargeRequiredError)
 			if !ok {
@@ -394,6 +494,16 @@ func (d *Daemon) createCmd(restAPI *mux.Router, version string, c APIEndpoint) {
 		if trusted {
 			logger.Debug("Handling", log.Ctx{"method": r.Method, "url": r.URL.RequestURI(), "ip": r.RemoteAddr, "user": username})
 			r = r.WithContext(context.WithValue(r.Context(), "username", username))
+			r = r.WithContext(context.WithValue(r.Context(), "protocol", protocol))
+			if token != nil {
+				r = r.WithContext(context.WithValue(r.Context(), "token", token))
+			}
+
+			// Read-only API tokens can't change anything.
+			if token != nil && token.Scope == authTokenScopeReadOnly && r.Method != "GET" {
+				response.Forbidden(nil).Render(w)
+				return
+			}
 		} else if untrustedOk && r.Header.Get("X-LXD-authenticated") == "" {
 			logger.Debug(fmt.Sprintf("Allowing untrusted %s", r.Method), log.Ctx{"url": r.URL.RequestURI(), "ip": r.RemoteAddr})
 		} else if derr, ok := err.(*bakery.DischargeRequiredError); ok {
@@ -419,6 +529,9 @@ func (d *Daemon) createCmd(restAPI *mux.Router, version string, c APIEndpoint) {
 			shared.DebugJson(captured)
 		}
 
+		// Track the time taken to handle the request, rendering included.
+		defer metrics.APIRequestDurationHistogram.ObserveSince(map[string]string{"method": r.Method, "endpoint": uri}, time.Now())
+
 		// Actually process the request
 		var resp response.Response
 		resp = response.NotImplemented(nil)
@@ -459,6 +572,12 @@ func (d *Daemon) createCmd(restAPI *mux.Router, version string, c APIEndpoint) {
 			resp = response.NotFound(fmt.Errorf("Method '%s' not found", r.Method))
 		}
 
+		// Record who requested the operation, if any
+		op := operations.ResponseOperation(resp)
+		if op != nil {
+			op.SetRequestor(username, protocol)
+		}
+
 		// Handle errors
 		if err := resp.Render(w); err != nil {
 			err := response.InternalError(err).Render(w)
@@ -842,6 +961,16 @@ func (d *Daemon) init() error {
 	maasAPIKey := ""
 	maasMachine := ""
 
+	oidcIssuer := ""
+	oidcClientID := ""
+	oidcAudience := ""
+	oidcClaim := ""
+
+	auditSyslog := false
+	localRBAC := false
+	rateLimit := int64(0)
+	rateLimitBurst := int64(0)
+
 	err = d.db.Transaction(func(tx *db.NodeTx) error {
 		config, err := node.ConfigLoad(tx)
 		if err != nil {
@@ -869,6 +998,10 @@ func (d *Daemon) init() error {
 		candidAPIURL, candidAPIKey, candidExpiry, candidDomains = config.CandidServer()
 		maasAPIURL, maasAPIKey = config.MAASController()
 		rbacAPIURL, rbacAPIKey, rbacExpiry, rbacAgentURL, rbacAgentUsername, rbacAgentPrivateKey, rbacAgentPublicKey = config.RBACServer()
+		oidcIssuer, oidcClientID, oidcAudience, oidcClaim = config.OIDCServer()
+		auditSyslog = config.AuditSyslog()
+		localRBAC = config.LocalRBAC()
+		rateLimit, rateLimitBurst = config.RateLimit()
 
 		return nil
 	})
@@ -876,6 +1009,20 @@ func (d *Daemon) init() error {
 		return err
 	}
 
+	// Setup the audit log
+	d.audit, err = audit.NewLogger(shared.LogPath("audit.log"), audit.DefaultMaxSize, audit.DefaultMaxFiles)
+	if err != nil {
+		return err
+	}
+
+	err = d.audit.SetSyslog(auditSyslog)
+	if err != nil {
+		logger.Warn("Failed to send the audit log to syslog", log.Ctx{"err": err})
+	}
+
+	// Setup the webhooks
+	d.setupWebhooks()
+
 	if rbacAPIURL != "" {
 		err = d.setupRBACServer(rbacAPIURL, rbacAPIKey, rbacExpiry, rbacAgentURL, rbacAgentUsername, rbacAgentPrivateKey, rbacAgentPublicKey)
 		if err != nil {
@@ -890,6 +1037,10 @@ func (d *Daemon) init() error {
 		}
 	}
 
+	d.setupOIDC(oidcIssuer, oidcClientID, oidcAudience, oidcClaim)
+	d.setupLocalRBAC(localRBAC)
+	d.setupRateLimit(rateLimit, rateLimitBurst)
+
 	if !d.os.MockMode {
 		// Start the scheduler
 		go deviceEventListener(d.State())
@@ -1006,6 +1157,9 @@ func (d *Daemon) Ready() error {
 
 		// Remove expired container snapshots (minutely)
 		d.tasks.Add(pruneExpiredContainerSnapshotsTask(d))
+
+		// Remove expired operation records (hourly)
+		d.taskPruneOperationRecords = d.tasks.Add(pruneOperationRecordsTask(d))
 	}
 
 	// Start all background tasks
@@ -1128,6 +1282,10 @@ func (d *Daemon) Stop() error {
 		trackError(d.seccomp.Stop())
 	}
 
+	if d.audit != nil {
+		trackError(d.audit.Close())
+	}
+
 	var err error
 	if n := len(errs); n > 0 {
 		format := "%v"
@@ -1268,7 +1426,54 @@ func (d *Daemon) setupRBACServer(rbacURL string, rbacKey string, rbacExpiry int6
 	return nil
 }
 
+// Return the projects the client certificate of the request is restricted
+// to, if it's a restricted one.
+func (d *Daemon) userRestrictedProjects(r *http.Request) ([]string, bool) {
+	if r.RemoteAddr == "@" {
+		return nil, false
+	}
+
+	protocol, _ := r.Context().Value("protocol").(string)
+	if protocol != "tls" {
+		return nil, false
+	}
+
+	username, _ := r.Context().Value("username").(string)
+	projects, restricted := d.clientCertProjects[username]
+
+	return projects, restricted
+}
+
+// Return the API token the request was authenticated with, if any.
+func (d *Daemon) userAuthToken(r *http.Request) *db.AuthToken {
+	protocol, _ := r.Context().Value("protocol").(string)
+	if protocol != "token" {
+		return nil
+	}
+
+	token, _ := r.Context().Value("token").(*db.AuthToken)
+
+	return token
+}
+
 func (d *Daemon) userIsAdmin(r *http.Request) bool {
+	// Restricted certificates never have admin privileges.
+	_, restricted := d.userRestrictedProjects(r)
+	if restricted {
+		return false
+	}
+
+	// Only unrestricted API tokens with the manage scope have admin
+	// privileges.
+	token := d.userAuthToken(r)
+	if token != nil {
+		return token.Scope == authTokenScopeManage && len(token.Projects) == 0
+	}
+
+	if d.localRBAC != nil && r.RemoteAddr != "@" {
+		return d.userIsClusterMember(r) || d.localRBAC.IsAdmin(userIdentity(r))
+	}
+
 	if d.externalAuth == nil || d.rbac == nil || r.RemoteAddr == "@" {
 		return true
 	}
@@ -1277,6 +1482,22 @@ func (d *Daemon) userIsAdmin(r *http.Request) bool {
 }
 
 func (d *Daemon) userHasPermission(r *http.Request, project string, permission string) bool {
+	// Restricted certificates have full access to their projects, except
+	// for changing the projects themselves.
+	projects, restricted := d.userRestrictedProjects(r)
+	if restricted {
+		return permission != "manage-projects" && shared.StringInSlice(project, projects)
+	}
+
+	token := d.userAuthToken(r)
+	if token != nil {
+		return authTokenHasPermission(token, project, permission)
+	}
+
+	if d.localRBAC != nil && r.RemoteAddr != "@" {
+		return d.userIsClusterMember(r) || d.localRBAC.HasPermission(userIdentity(r), project, permission)
+	}
+
 	if d.externalAuth == nil || d.rbac == nil || r.RemoteAddr == "@" {
 		return true
 	}
@@ -1284,6 +1505,56 @@ func (d *Daemon) userHasPermission(r *http.Request, project string, permission s
 	return d.rbac.HasPermission(r.Context().Value("username").(string), project, permission)
 }
 
+// Return the identity of the user in the groups of the local RBAC backend.
+func userIdentity(r *http.Request) string {
+	protocol, _ := r.Context().Value("protocol").(string)
+	username, _ := r.Context().Value("username").(string)
+	return rbac.Identity(protocol, username)
+}
+
+// Return whether the request comes from another member of the cluster.
+func (d *Daemon) userIsClusterMember(r *http.Request) bool {
+	protocol, _ := r.Context().Value("protocol").(string)
+	return protocol == "cluster"
+}
+
+// Setup the built-in RBAC backend
+func (d *Daemon) setupLocalRBAC(enabled bool) {
+	if !enabled {
+		d.localRBAC = nil
+		return
+	}
+
+	d.localRBAC = rbac.NewLocal(func(identity string) ([]rbac.Binding, error) {
+		var roles []db.AuthGroupRole
+		err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			var err error
+			roles, err = tx.AuthGroupRolesForIdentity(identity)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		bindings := make([]rbac.Binding, len(roles))
+		for i, role := range roles {
+			bindings[i] = rbac.Binding{Role: role.Role, Project: role.Project}
+		}
+
+		return bindings, nil
+	})
+}
+
+// Setup OpenID Connect authentication
+func (d *Daemon) setupOIDC(issuer string, clientID string, audience string, claim string) {
+	if issuer == "" || clientID == "" {
+		d.oidcVerifier = nil
+		return
+	}
+
+	d.oidcVerifier = oidc.NewVerifier(issuer, clientID, audience, claim)
+}
+
 // Setup MAAS
 func (d *Daemon) setupMAASController(server string, key string, machine string) error {
 	var err error
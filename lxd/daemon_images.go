@@ -12,6 +12,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
 
 	"github.com/lxc/lxd/client"
@@ -44,6 +45,34 @@ var imageStreamCacheLock sync.Mutex
 var imagesDownloading = map[string]chan bool{}
 var imagesDownloadingLock sync.Mutex
 
+// imagesDownloadingLockImage waits for any ongoing download of the image with
+// the given fingerprint, then marks it as being downloaded until the returned
+// function is called.
+func imagesDownloadingLockImage(fp string) func() {
+	for {
+		imagesDownloadingLock.Lock()
+		waitChannel, ok := imagesDownloading[fp]
+		if !ok {
+			waitChannel = make(chan bool)
+			imagesDownloading[fp] = waitChannel
+			imagesDownloadingLock.Unlock()
+			break
+		}
+		imagesDownloadingLock.Unlock()
+
+		<-waitChannel
+	}
+
+	return func() {
+		imagesDownloadingLock.Lock()
+		if waitChannel, ok := imagesDownloading[fp]; ok {
+			close(waitChannel)
+			delete(imagesDownloading, fp)
+		}
+		imagesDownloadingLock.Unlock()
+	}
+}
+
 func imageSaveStreamCache(os *sys.OS, imageStreamCache map[string]*imageStreamCacheEntry) error {
 	data, err := yaml.Marshal(&imageStreamCache)
 	if err != nil {
@@ -80,7 +109,7 @@ func imageGetStreamCache(d *Daemon) (map[string]*imageStreamCacheEntry, error) {
 }
 
 // ImageDownload resolves the image fingerprint and if not in the database, downloads it
-func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol string, certificate string, secret string, alias string, imageType string, forContainer bool, autoUpdate bool, storagePool string, preferCached bool, project string) (*api.Image, error) {
+func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol string, certificate string, secret string, requireSignature bool, alias string, imageType string, forContainer bool, autoUpdate bool, storagePool string, preferCached bool, project string) (*api.Image, error) {
 	var err error
 	var ctxMap log.Ctx
 
@@ -266,7 +295,7 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 			if err != nil {
 				return nil, err
 			}
-			err = d.cluster.ImageSourceInsert(id, server, protocol, certificate, alias)
+			err = d.cluster.ImageSourceInsert(id, server, protocol, certificate, alias, requireSignature)
 			if err != nil {
 				return nil, err
 			}
@@ -277,6 +306,11 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 		logger.Debug("Image already exists in the db", log.Ctx{"image": fp})
 		info = imgInfo
 
+		// The existing image must have been verified when downloaded.
+		if requireSignature && !shared.PathExists(shared.VarPath("images", info.Fingerprint+".sig")) {
+			return nil, fmt.Errorf("Image %s is already present without a verified signature", info.Fingerprint)
+		}
+
 		// If not requested in a particular pool, we're done.
 		if storagePool == "" {
 			return info, nil
@@ -370,6 +404,8 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 		if failure {
 			os.Remove(destName)
 			os.Remove(destName + ".rootfs")
+			os.Remove(destName + ".sig")
+			os.Remove(destName + ".rootfs.sig")
 		}
 	}
 	defer cleanup()
@@ -397,6 +433,8 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 		op.SetCanceler(canceler)
 	}
 
+	signatures := &lxd.ImageSignatures{}
+
 	if protocol == "lxd" || protocol == "simplestreams" {
 		// Create the target files
 		dest, err := os.Create(destName)
@@ -481,6 +519,12 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 				return nil, err
 			}
 		}
+
+		// Get the detached signatures
+		signatures, err = remote.GetImageSignatures(fp, secret)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to retrieve the image signatures")
+		}
 	} else if protocol == "direct" {
 		// Setup HTTP client
 		httpClient, err := util.HTTPClient(certificate, d.proxy)
@@ -539,6 +583,12 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 			return nil, fmt.Errorf("Hash mismatch for %s: %s != %s", server, result, fp)
 		}
 
+		// Get the detached signature
+		signatures.Meta, err = imageDownloadSignature(httpClient, server+".sig")
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to retrieve the image signature")
+		}
+
 		// Parse the image
 		imageMeta, imageType, err := getImageMetadata(destName)
 		if err != nil {
@@ -557,6 +607,12 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 		return nil, fmt.Errorf("Unsupported protocol: %v", protocol)
 	}
 
+	// Verify the detached signatures
+	err = imageVerifySignatures(d, destName, signatures, requireSignature)
+	if err != nil {
+		return nil, err
+	}
+
 	// Override visiblity
 	info.Public = false
 
@@ -584,8 +640,12 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 			return nil, err
 		}
 
-		if shared.PathExists(destName + ".rootfs") {
-			err = shared.FileMove(destName+".rootfs", newDestName+".rootfs")
+		for _, suffix := range []string{".rootfs", ".sig", ".rootfs.sig"} {
+			if !shared.PathExists(destName + suffix) {
+				continue
+			}
+
+			err = shared.FileMove(destName+suffix, newDestName+suffix)
 			if err != nil {
 				return nil, err
 			}
@@ -599,7 +659,7 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 			return nil, err
 		}
 
-		err = d.cluster.ImageSourceInsert(id, server, protocol, certificate, alias)
+		err = d.cluster.ImageSourceInsert(id, server, protocol, certificate, alias, requireSignature)
 		if err != nil {
 			return nil, err
 		}
@@ -613,6 +673,12 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 		}
 	}
 
+	// Move the image files to the chunked image store
+	err = imageChunksPack(d, fp)
+	if err != nil {
+		return nil, err
+	}
+
 	// Mark the image as "cached" if downloading for a container
 	if forContainer {
 		err := d.cluster.ImageLastAccessInit(fp)
@@ -624,3 +690,129 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 	logger.Info("Image downloaded", ctxMap)
 	return info, nil
 }
+
+// ImageResolve returns the fingerprint of the image the given alias or
+// fingerprint currently points to on the given server, without downloading
+// it.
+func (d *Daemon) ImageResolve(server string, protocol string, certificate string, alias string, imageType string) (string, error) {
+	var remote lxd.ImageServer
+	var err error
+
+	args := &lxd.ConnectionArgs{
+		TLSServerCert: certificate,
+		UserAgent:     version.UserAgent,
+		Proxy:         d.proxy,
+	}
+
+	switch protocol {
+	case "", "lxd":
+		remote, err = lxd.ConnectPublicLXD(server, args)
+	case "simplestreams":
+		remote, err = lxd.ConnectSimpleStreams(server, args)
+	default:
+		return "", fmt.Errorf("Images from %q sources can't be resolved without downloading them", protocol)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	fp := alias
+	entry, _, err := remote.GetImageAliasType(imageType, alias)
+	if err == nil {
+		fp = entry.Target
+	}
+
+	info, _, err := remote.GetImage(fp)
+	if err != nil {
+		return "", err
+	}
+
+	return info.Fingerprint, nil
+}
+
+// Download the detached signature at the given URL, returning nil if there's
+// none.
+func imageDownloadSignature(httpClient *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", version.UserAgent)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unable to fetch %s: %s", url, resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// Verify the detached signatures of the downloaded image files against the
+// keys in images.trusted_keys, and record them next to the image files.
+//
+// Unsigned images are only rejected if a signature is required, while signed
+// images are always verified when trusted keys are configured.
+func imageVerifySignatures(d *Daemon, destName string, signatures *lxd.ImageSignatures, requireSignature bool) error {
+	fingerprint := filepath.Base(destName)
+
+	if len(signatures.Meta) == 0 {
+		if requireSignature {
+			return fmt.Errorf("Image %s isn't signed but a signature is required", fingerprint)
+		}
+
+		return nil
+	}
+
+	value, err := cluster.ConfigGetString(d.cluster, "images.trusted_keys")
+	if err != nil {
+		return err
+	}
+
+	keys, err := util.ParseSignatureKeys(value)
+	if err != nil {
+		return err
+	}
+
+	if len(keys) == 0 {
+		if requireSignature {
+			return fmt.Errorf("Image %s can't be verified as images.trusted_keys isn't set", fingerprint)
+		}
+
+		return nil
+	}
+
+	files := map[string][]byte{destName: signatures.Meta}
+	if shared.PathExists(destName + ".rootfs") {
+		if len(signatures.Rootfs) == 0 {
+			return fmt.Errorf("Image %s rootfs isn't signed", fingerprint)
+		}
+
+		files[destName+".rootfs"] = signatures.Rootfs
+	}
+
+	for path, signature := range files {
+		err := util.VerifySignature(keys, path, signature)
+		if err != nil {
+			return errors.Wrapf(err, "Image %s failed signature verification", fingerprint)
+		}
+	}
+
+	for path, signature := range files {
+		err := ioutil.WriteFile(path+".sig", signature, 0600)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
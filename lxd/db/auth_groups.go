@@ -0,0 +1,219 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db/query"
+)
+
+// AuthGroup holds information about a group of the local RBAC backend.
+type AuthGroup struct {
+	ID          int64
+	Name        string
+	Description string
+	Identities  []string        // Members, as <protocol>:<certificate fingerprint or username>
+	Roles       []AuthGroupRole // Roles granted to the members
+}
+
+// AuthGroupRole is a role granted on a project, or on all projects if the
+// project is empty.
+type AuthGroupRole struct {
+	Role    string
+	Project string
+}
+
+// AuthGroups returns all the local RBAC groups.
+func (c *ClusterTx) AuthGroups() ([]AuthGroup, error) {
+	groups := []AuthGroup{}
+	dest := func(i int) []interface{} {
+		groups = append(groups, AuthGroup{})
+		return []interface{}{&groups[i].ID, &groups[i].Name, &groups[i].Description}
+	}
+
+	stmt, err := c.tx.Prepare("SELECT id, name, description FROM auth_groups ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	err = query.SelectObjects(stmt, dest)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range groups {
+		err := c.authGroupFill(&groups[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return groups, nil
+}
+
+// AuthGroupByName returns the local RBAC group with the given name.
+func (c *ClusterTx) AuthGroupByName(name string) (AuthGroup, error) {
+	group := AuthGroup{}
+
+	row := c.tx.QueryRow("SELECT id, name, description FROM auth_groups WHERE name=?", name)
+	err := row.Scan(&group.ID, &group.Name, &group.Description)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return group, ErrNoSuchObject
+		}
+
+		return group, err
+	}
+
+	err = c.authGroupFill(&group)
+	if err != nil {
+		return group, err
+	}
+
+	return group, nil
+}
+
+// Load the members and roles of the given group.
+func (c *ClusterTx) authGroupFill(group *AuthGroup) error {
+	var err error
+
+	group.Identities, err = query.SelectStrings(c.tx, "SELECT identity FROM auth_groups_identities WHERE auth_group_id=? ORDER BY identity", group.ID)
+	if err != nil {
+		return err
+	}
+
+	group.Roles, err = c.authGroupRoles("auth_groups_roles.auth_group_id=?", group.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *ClusterTx) authGroupRoles(where string, args ...interface{}) ([]AuthGroupRole, error) {
+	roles := []AuthGroupRole{}
+	dest := func(i int) []interface{} {
+		roles = append(roles, AuthGroupRole{})
+		return []interface{}{&roles[i].Role, &roles[i].Project}
+	}
+
+	stmt, err := c.tx.Prepare(fmt.Sprintf(`
+SELECT DISTINCT auth_groups_roles.role, COALESCE(projects.name, '') FROM auth_groups_roles
+  LEFT JOIN projects ON projects.id = auth_groups_roles.project_id
+  WHERE %s
+  ORDER BY auth_groups_roles.role, projects.name
+`, where))
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	err = query.SelectObjects(stmt, dest, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// AuthGroupRolesForIdentity returns the roles granted to the given identity,
+// of the form <protocol>:<certificate fingerprint or username>, through all
+// the groups it's a member of.
+func (c *ClusterTx) AuthGroupRolesForIdentity(identity string) ([]AuthGroupRole, error) {
+	return c.authGroupRoles(`auth_groups_roles.auth_group_id IN (
+    SELECT auth_group_id FROM auth_groups_identities WHERE identity=?)`, identity)
+}
+
+// AuthGroupAdd stores a new local RBAC group.
+func (c *ClusterTx) AuthGroupAdd(group AuthGroup) (int64, error) {
+	columns := []string{"name", "description"}
+	values := []interface{}{group.Name, group.Description}
+	id, err := query.UpsertObject(c.tx, "auth_groups", columns, values)
+	if err != nil {
+		return -1, err
+	}
+
+	err = c.authGroupSet(id, group)
+	if err != nil {
+		return -1, err
+	}
+
+	return id, nil
+}
+
+// AuthGroupUpdate replaces the description, members and roles of the local
+// RBAC group with the given name.
+func (c *ClusterTx) AuthGroupUpdate(name string, group AuthGroup) error {
+	current, err := c.AuthGroupByName(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.tx.Exec("UPDATE auth_groups SET description=? WHERE id=?", group.Description, current.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.authGroupSet(current.ID, group)
+}
+
+// Replace the members and roles of the group with the given ID.
+func (c *ClusterTx) authGroupSet(id int64, group AuthGroup) error {
+	_, err := c.tx.Exec("DELETE FROM auth_groups_identities WHERE auth_group_id=?", id)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.tx.Exec("DELETE FROM auth_groups_roles WHERE auth_group_id=?", id)
+	if err != nil {
+		return err
+	}
+
+	for _, identity := range group.Identities {
+		_, err := c.tx.Exec("INSERT INTO auth_groups_identities (auth_group_id, identity) VALUES (?, ?)", id, identity)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, role := range group.Roles {
+		var projectID interface{}
+		if role.Project != "" {
+			projectID, err = c.ProjectID(role.Project)
+			if err != nil {
+				return errors.Wrapf(err, "Fetch project %q", role.Project)
+			}
+		}
+
+		_, err := c.tx.Exec("INSERT INTO auth_groups_roles (auth_group_id, role, project_id) VALUES (?, ?, ?)", id, role.Role, projectID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AuthGroupRemove deletes the local RBAC group with the given name.
+func (c *ClusterTx) AuthGroupRemove(name string) error {
+	result, err := c.tx.Exec("DELETE FROM auth_groups WHERE name=?", name)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrNoSuchObject
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Add, update and remove local RBAC groups.
+func TestAuthGroup(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.AuthGroupAdd(db.AuthGroup{
+		Name:        "ops",
+		Description: "Operators",
+		Identities:  []string{"oidc:alice", "tls:abcd"},
+		Roles: []db.AuthGroupRole{
+			{Role: "viewer"},
+			{Role: "operator", Project: "default"},
+		},
+	})
+	require.NoError(t, err)
+
+	group, err := tx.AuthGroupByName("ops")
+	require.NoError(t, err)
+	assert.Equal(t, "Operators", group.Description)
+	assert.Equal(t, []string{"oidc:alice", "tls:abcd"}, group.Identities)
+	assert.Equal(t, []db.AuthGroupRole{{Role: "operator", Project: "default"}, {Role: "viewer"}}, group.Roles)
+
+	roles, err := tx.AuthGroupRolesForIdentity("oidc:alice")
+	require.NoError(t, err)
+	assert.Len(t, roles, 2)
+
+	roles, err = tx.AuthGroupRolesForIdentity("oidc:bob")
+	require.NoError(t, err)
+	assert.Len(t, roles, 0)
+
+	err = tx.AuthGroupUpdate("ops", db.AuthGroup{Identities: []string{"oidc:bob"}, Roles: []db.AuthGroupRole{{Role: "admin"}}})
+	require.NoError(t, err)
+
+	roles, err = tx.AuthGroupRolesForIdentity("oidc:alice")
+	require.NoError(t, err)
+	assert.Len(t, roles, 0)
+
+	roles, err = tx.AuthGroupRolesForIdentity("oidc:bob")
+	require.NoError(t, err)
+	assert.Equal(t, []db.AuthGroupRole{{Role: "admin"}}, roles)
+
+	groups, err := tx.AuthGroups()
+	require.NoError(t, err)
+	assert.Len(t, groups, 1)
+
+	err = tx.AuthGroupRemove("ops")
+	require.NoError(t, err)
+
+	_, err = tx.AuthGroupByName("ops")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+
+	err = tx.AuthGroupRemove("ops")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+}
+
+// Roles on unknown projects are rejected.
+func TestAuthGroupAdd_UnknownProject(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.AuthGroupAdd(db.AuthGroup{Name: "ops", Roles: []db.AuthGroupRole{{Role: "viewer", Project: "foo"}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,155 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db/query"
+)
+
+// AuthToken holds information about an API token.
+type AuthToken struct {
+	ID           int64     // Stable database identifier
+	Name         string    // Public identifier of the token
+	Description  string    // Free form description
+	Secret       string    // Hash of the token secret
+	Scope        string    // Permission scope of the token
+	Projects     []string  // Projects the token is restricted to, if any
+	CreationDate time.Time // Date the token was issued
+	ExpiryDate   time.Time // Date after which the token is no longer valid
+}
+
+// AuthTokenAdd stores a new API token.
+func (c *ClusterTx) AuthTokenAdd(token AuthToken) (int64, error) {
+	columns := []string{"name", "description", "secret", "scope", "creation_date", "expiry_date"}
+	values := []interface{}{token.Name, token.Description, token.Secret, token.Scope, token.CreationDate, token.ExpiryDate}
+	id, err := query.UpsertObject(c.tx, "auth_tokens", columns, values)
+	if err != nil {
+		return -1, err
+	}
+
+	for _, name := range token.Projects {
+		projectID, err := c.ProjectID(name)
+		if err != nil {
+			return -1, errors.Wrapf(err, "Fetch project %q", name)
+		}
+
+		_, err = c.tx.Exec("INSERT INTO auth_tokens_projects (auth_token_id, project_id) VALUES (?, ?)", id, projectID)
+		if err != nil {
+			return -1, err
+		}
+	}
+
+	return id, nil
+}
+
+// AuthTokens returns all API tokens.
+func (c *ClusterTx) AuthTokens() ([]AuthToken, error) {
+	return c.authTokens("")
+}
+
+// AuthTokenByName returns the API token with the given name.
+func (c *ClusterTx) AuthTokenByName(name string) (AuthToken, error) {
+	return c.authToken("name=?", name)
+}
+
+// AuthTokenBySecret returns the API token with the given secret hash.
+func (c *ClusterTx) AuthTokenBySecret(secret string) (AuthToken, error) {
+	return c.authToken("secret=?", secret)
+}
+
+func (c *ClusterTx) authToken(where string, args ...interface{}) (AuthToken, error) {
+	null := AuthToken{}
+
+	tokens, err := c.authTokens(where, args...)
+	if err != nil {
+		return null, err
+	}
+
+	switch len(tokens) {
+	case 0:
+		return null, ErrNoSuchObject
+	case 1:
+		return tokens[0], nil
+	default:
+		return null, fmt.Errorf("more than one API token matches")
+	}
+}
+
+func (c *ClusterTx) authTokens(where string, args ...interface{}) ([]AuthToken, error) {
+	tokens := []AuthToken{}
+	dest := func(i int) []interface{} {
+		tokens = append(tokens, AuthToken{})
+		return []interface{}{
+			&tokens[i].ID,
+			&tokens[i].Name,
+			&tokens[i].Description,
+			&tokens[i].Secret,
+			&tokens[i].Scope,
+			&tokens[i].CreationDate,
+			&tokens[i].ExpiryDate,
+		}
+	}
+
+	sql := "SELECT id, name, description, secret, scope, creation_date, expiry_date FROM auth_tokens"
+	if where != "" {
+		sql += " WHERE " + where
+	}
+
+	sql += " ORDER BY creation_date"
+
+	stmt, err := c.tx.Prepare(sql)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	err = query.SelectObjects(stmt, dest, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range tokens {
+		tokens[i].Projects, err = query.SelectStrings(c.tx, `
+SELECT projects.name FROM projects
+  JOIN auth_tokens_projects ON projects.id = auth_tokens_projects.project_id
+  WHERE auth_tokens_projects.auth_token_id = ?
+  ORDER BY projects.name
+`, tokens[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tokens, nil
+}
+
+// AuthTokenRemove deletes the API token with the given name.
+func (c *ClusterTx) AuthTokenRemove(name string) error {
+	result, err := c.tx.Exec("DELETE FROM auth_tokens WHERE name=?", name)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrNoSuchObject
+	}
+
+	return nil
+}
+
+// AuthTokensPrune deletes all API tokens which expired before the given
+// date.
+func (c *ClusterTx) AuthTokensPrune(date time.Time) error {
+	_, err := c.tx.Exec("DELETE FROM auth_tokens WHERE expiry_date < ?", date)
+	return err
+}
@@ -0,0 +1,77 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Add, get and remove an API token.
+func TestAuthToken(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	now := time.Now().UTC()
+	id, err := tx.AuthTokenAdd(db.AuthToken{
+		Name:         "abc",
+		Description:  "CI",
+		Secret:       "abcd",
+		Scope:        "read-only",
+		Projects:     []string{"default"},
+		CreationDate: now,
+		ExpiryDate:   now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	token, err := tx.AuthTokenBySecret("abcd")
+	require.NoError(t, err)
+	assert.Equal(t, id, token.ID)
+	assert.Equal(t, "abc", token.Name)
+	assert.Equal(t, "CI", token.Description)
+	assert.Equal(t, "read-only", token.Scope)
+	assert.Equal(t, []string{"default"}, token.Projects)
+	assert.True(t, now.Add(time.Hour).Equal(token.ExpiryDate))
+
+	tokens, err := tx.AuthTokens()
+	require.NoError(t, err)
+	assert.Len(t, tokens, 1)
+
+	_, err = tx.AuthTokenBySecret("efgh")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+
+	err = tx.AuthTokenRemove("abc")
+	require.NoError(t, err)
+
+	_, err = tx.AuthTokenByName("abc")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+
+	err = tx.AuthTokenRemove("abc")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+}
+
+// Expired tokens get pruned.
+func TestAuthTokensPrune(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	now := time.Now().UTC()
+	_, err := tx.AuthTokenAdd(db.AuthToken{Name: "abc", Secret: "abcd", Scope: "manage", CreationDate: now, ExpiryDate: now.Add(-time.Hour)})
+	require.NoError(t, err)
+
+	_, err = tx.AuthTokenAdd(db.AuthToken{Name: "def", Secret: "efgh", Scope: "manage", CreationDate: now, ExpiryDate: now.Add(time.Hour)})
+	require.NoError(t, err)
+
+	err = tx.AuthTokensPrune(now)
+	require.NoError(t, err)
+
+	_, err = tx.AuthTokenByName("abc")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+
+	_, err = tx.AuthTokenByName("def")
+	require.NoError(t, err)
+}
@@ -4,6 +4,16 @@ package db
 
 import (
 	"database/sql"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db/query"
+)
+
+// Types of certificates.
+const (
+	CertificateTypeClient  = 1
+	CertificateTypeMetrics = 2
 )
 
 // CertInfo is here to pass the certificates content
@@ -14,20 +24,20 @@ type CertInfo struct {
 	Type        int
 	Name        string
 	Certificate string
+	Restricted  bool
+	Projects    []string
 }
 
 // CertificatesGet returns all certificates from the DB as CertBaseInfo objects.
 func (c *Cluster) CertificatesGet() (certs []*CertInfo, err error) {
 	err = c.Transaction(func(tx *ClusterTx) error {
 		rows, err := tx.tx.Query(
-			"SELECT id, fingerprint, type, name, certificate FROM certificates",
+			"SELECT id, fingerprint, type, name, certificate, restricted FROM certificates",
 		)
 		if err != nil {
 			return err
 		}
 
-		defer rows.Close()
-
 		for rows.Next() {
 			cert := new(CertInfo)
 			rows.Scan(
@@ -36,11 +46,25 @@ func (c *Cluster) CertificatesGet() (certs []*CertInfo, err error) {
 				&cert.Type,
 				&cert.Name,
 				&cert.Certificate,
+				&cert.Restricted,
 			)
 			certs = append(certs, cert)
 		}
 
-		return rows.Err()
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, cert := range certs {
+			cert.Projects, err = tx.certificateProjects(cert.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
 	})
 	if err != nil {
 		return certs, err
@@ -64,11 +88,12 @@ func (c *Cluster) CertificateGet(fingerprint string) (cert *CertInfo, err error)
 		&cert.Type,
 		&cert.Name,
 		&cert.Certificate,
+		&cert.Restricted,
 	}
 
 	query := `
 		SELECT
-			id, fingerprint, type, name, certificate
+			id, fingerprint, type, name, certificate, restricted
 		FROM
 			certificates
 		WHERE fingerprint LIKE ?`
@@ -81,7 +106,49 @@ func (c *Cluster) CertificateGet(fingerprint string) (cert *CertInfo, err error)
 		return nil, err
 	}
 
-	return cert, err
+	err = c.Transaction(func(tx *ClusterTx) error {
+		cert.Projects, err = tx.certificateProjects(cert.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cert, nil
+}
+
+// Return the names of the projects the certificate with the given ID is
+// restricted to.
+func (c *ClusterTx) certificateProjects(id int) ([]string, error) {
+	stmt := `
+SELECT projects.name FROM projects
+  JOIN certificates_projects ON projects.id = certificates_projects.project_id
+  WHERE certificates_projects.certificate_id = ?
+  ORDER BY projects.name
+`
+	return query.SelectStrings(c.tx, stmt, id)
+}
+
+// Replace the projects the certificate with the given ID is restricted to.
+func (c *ClusterTx) certificateProjectsSet(id int64, projects []string) error {
+	_, err := c.tx.Exec("DELETE FROM certificates_projects WHERE certificate_id=?", id)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range projects {
+		projectID, err := c.ProjectID(name)
+		if err != nil {
+			return errors.Wrapf(err, "Fetch project %q", name)
+		}
+
+		_, err = c.tx.Exec("INSERT INTO certificates_projects (certificate_id, project_id) VALUES (?, ?)", id, projectID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // CertSave stores a CertBaseInfo object in the db,
@@ -93,23 +160,31 @@ func (c *Cluster) CertSave(cert *CertInfo) error {
 				fingerprint,
 				type,
 				name,
-				certificate
-			) VALUES (?, ?, ?, ?)`,
+				certificate,
+				restricted
+			) VALUES (?, ?, ?, ?, ?)`,
 		)
 		if err != nil {
 			return err
 		}
 		defer stmt.Close()
-		_, err = stmt.Exec(
+		result, err := stmt.Exec(
 			cert.Fingerprint,
 			cert.Type,
 			cert.Name,
 			cert.Certificate,
+			cert.Restricted,
 		)
 		if err != nil {
 			return err
 		}
-		return nil
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		return tx.certificateProjectsSet(id, cert.Projects)
 	})
 	return err
 }
@@ -125,10 +200,23 @@ func (c *Cluster) CertDelete(fingerprint string) error {
 }
 
 // CertUpdate updates the certificate with the given fingerprint.
-func (c *Cluster) CertUpdate(fingerprint string, certName string, certType int) error {
+func (c *Cluster) CertUpdate(fingerprint string, certName string, certType int, restricted bool, projects []string) error {
 	err := c.Transaction(func(tx *ClusterTx) error {
-		_, err := tx.tx.Exec("UPDATE certificates SET name=?, type=? WHERE fingerprint=?", certName, certType, fingerprint)
-		return err
+		_, err := tx.tx.Exec("UPDATE certificates SET name=?, type=?, restricted=? WHERE fingerprint=?", certName, certType, restricted, fingerprint)
+		if err != nil {
+			return err
+		}
+
+		ids, err := query.SelectIntegers(tx.tx, "SELECT id FROM certificates WHERE fingerprint=?", fingerprint)
+		if err != nil {
+			return err
+		}
+
+		if len(ids) != 1 {
+			return ErrNoSuchObject
+		}
+
+		return tx.certificateProjectsSet(int64(ids[0]), projects)
 	})
 	return err
 }
@@ -0,0 +1,59 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Restricted certificates keep track of their projects.
+func TestCertificateProjects(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	err := cluster.Transaction(func(tx *db.ClusterTx) error {
+		_, err := tx.ProjectCreate(api.ProjectsPost{Name: "p1"})
+		return err
+	})
+	require.NoError(t, err)
+
+	err = cluster.CertSave(&db.CertInfo{
+		Fingerprint: "abc",
+		Type:        1,
+		Name:        "ci",
+		Certificate: "cert",
+		Restricted:  true,
+		Projects:    []string{"p1"},
+	})
+	require.NoError(t, err)
+
+	cert, err := cluster.CertificateGet("abc")
+	require.NoError(t, err)
+	assert.True(t, cert.Restricted)
+	assert.Equal(t, []string{"p1"}, cert.Projects)
+
+	err = cluster.CertUpdate("abc", "ci", 1, true, []string{"default", "p1"})
+	require.NoError(t, err)
+
+	certs, err := cluster.CertificatesGet()
+	require.NoError(t, err)
+	require.Len(t, certs, 1)
+	assert.Equal(t, []string{"default", "p1"}, certs[0].Projects)
+
+	// Unknown projects are rejected.
+	err = cluster.CertUpdate("abc", "ci", 1, true, []string{"p2"})
+	assert.Error(t, err)
+
+	err = cluster.CertUpdate("abc", "ci", 1, false, nil)
+	require.NoError(t, err)
+
+	cert, err = cluster.CertificateGet("abc")
+	require.NoError(t, err)
+	assert.False(t, cert.Restricted)
+	assert.Len(t, cert.Projects, 0)
+}
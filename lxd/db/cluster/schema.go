@@ -6,14 +6,60 @@ package cluster
 // modify the database schema, please add a new schema update to update.go
 // and the run 'make update-schema'.
 const freshSchema = `
+CREATE TABLE auth_groups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    UNIQUE (name)
+);
+CREATE TABLE auth_groups_identities (
+    auth_group_id INTEGER NOT NULL,
+    identity TEXT NOT NULL,
+    FOREIGN KEY (auth_group_id) REFERENCES auth_groups (id) ON DELETE CASCADE,
+    UNIQUE (auth_group_id, identity)
+);
+CREATE TABLE auth_groups_roles (
+    auth_group_id INTEGER NOT NULL,
+    role TEXT NOT NULL,
+    project_id INTEGER,
+    FOREIGN KEY (auth_group_id) REFERENCES auth_groups (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+    UNIQUE (auth_group_id, role, project_id)
+);
+CREATE TABLE auth_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    secret TEXT NOT NULL,
+    scope TEXT NOT NULL,
+    creation_date DATETIME NOT NULL,
+    expiry_date DATETIME NOT NULL,
+    UNIQUE (name),
+    UNIQUE (secret)
+);
+CREATE TABLE auth_tokens_projects (
+    auth_token_id INTEGER NOT NULL,
+    project_id INTEGER NOT NULL,
+    FOREIGN KEY (auth_token_id) REFERENCES auth_tokens (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+    UNIQUE (auth_token_id, project_id)
+);
 CREATE TABLE certificates (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
     fingerprint TEXT NOT NULL,
     type INTEGER NOT NULL,
     name TEXT NOT NULL,
     certificate TEXT NOT NULL,
+    restricted INTEGER NOT NULL DEFAULT 0,
     UNIQUE (fingerprint)
 );
+CREATE TABLE certificates_projects (
+    certificate_id INTEGER NOT NULL,
+    project_id INTEGER NOT NULL,
+    FOREIGN KEY (certificate_id) REFERENCES certificates (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+    UNIQUE (certificate_id, project_id)
+);
 CREATE TABLE config (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
     key TEXT NOT NULL,
@@ -35,6 +81,10 @@ CREATE TABLE "images" (
     auto_update INTEGER NOT NULL DEFAULT 0,
     project_id INTEGER NOT NULL,
     type INTEGER NOT NULL DEFAULT 0,
+    auto_update_window TEXT NOT NULL DEFAULT '',
+    auto_update_pin TEXT NOT NULL DEFAULT '',
+    auto_update_max_age TEXT NOT NULL DEFAULT '',
+    auto_update_notify_only INTEGER NOT NULL DEFAULT 0,
     UNIQUE (project_id, fingerprint),
     FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
 );
@@ -73,6 +123,7 @@ CREATE TABLE images_source (
     protocol INTEGER NOT NULL,
     certificate TEXT NOT NULL,
     alias TEXT NOT NULL,
+    require_signature INTEGER NOT NULL DEFAULT 0,
     FOREIGN KEY (image_id) REFERENCES images (id) ON DELETE CASCADE
 );
 CREATE TABLE "instances" (
@@ -269,6 +320,15 @@ CREATE VIEW instances_snapshots_devices_ref (
      JOIN instances ON instances.id=instances_snapshots.instance_id
      JOIN projects ON projects.id=instances.project_id
      JOIN instances_snapshots ON instances_snapshots.id=instances_snapshots_devices.instance_snapshot_id;
+CREATE TABLE join_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    expiry_date DATETIME NOT NULL,
+    certificate TEXT NOT NULL DEFAULT '',
+    UNIQUE (name),
+    UNIQUE (secret)
+);
 CREATE TABLE networks (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
     name TEXT NOT NULL,
@@ -323,6 +383,24 @@ CREATE TABLE "operations" (
     FOREIGN KEY (node_id) REFERENCES nodes (id) ON DELETE CASCADE,
     FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
 );
+CREATE TABLE operations_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    uuid TEXT NOT NULL,
+    project_id INTEGER,
+    location TEXT NOT NULL DEFAULT '',
+    type INTEGER NOT NULL DEFAULT 0,
+    class TEXT NOT NULL DEFAULT '',
+    status_code INTEGER NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    resources TEXT NOT NULL DEFAULT '{}',
+    requestor_username TEXT NOT NULL DEFAULT '',
+    requestor_protocol TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    UNIQUE (uuid),
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+);
+CREATE INDEX operations_history_updated_at_idx ON operations_history (updated_at);
 CREATE TABLE "profiles" (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
     name TEXT NOT NULL,
@@ -487,6 +565,35 @@ CREATE TABLE storage_volumes_config (
     UNIQUE (storage_volume_id, key),
     FOREIGN KEY (storage_volume_id) REFERENCES storage_volumes (id) ON DELETE CASCADE
 );
+CREATE TABLE webhooks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL DEFAULT '',
+    UNIQUE (name)
+);
+CREATE TABLE webhooks_config (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    webhook_id INTEGER NOT NULL,
+    key TEXT NOT NULL,
+    value TEXT,
+    FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE,
+    UNIQUE (webhook_id, key)
+);
+CREATE TABLE webhooks_events (
+    webhook_id INTEGER NOT NULL,
+    event TEXT NOT NULL,
+    FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE,
+    UNIQUE (webhook_id, event)
+);
+CREATE TABLE webhooks_projects (
+    webhook_id INTEGER NOT NULL,
+    project_id INTEGER NOT NULL,
+    FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+    UNIQUE (webhook_id, project_id)
+);
 
-INSERT INTO schema (version, updated_at) VALUES (20, strftime("%s"))
+INSERT INTO schema (version, updated_at) VALUES (28, strftime("%s"))
 `
@@ -56,6 +56,186 @@ var updates = map[int]schema.Update{
 	18: updateFromV17,
 	19: updateFromV18,
 	20: updateFromV19,
+	21: updateFromV20,
+	22: updateFromV21,
+	23: updateFromV22,
+	24: updateFromV23,
+	25: updateFromV24,
+	26: updateFromV25,
+	27: updateFromV26,
+	28: updateFromV27,
+}
+
+// Add the table keeping the records of completed operations.
+func updateFromV27(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE operations_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    uuid TEXT NOT NULL,
+    project_id INTEGER,
+    location TEXT NOT NULL DEFAULT '',
+    type INTEGER NOT NULL DEFAULT 0,
+    class TEXT NOT NULL DEFAULT '',
+    status_code INTEGER NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    resources TEXT NOT NULL DEFAULT '{}',
+    requestor_username TEXT NOT NULL DEFAULT '',
+    requestor_protocol TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    UNIQUE (uuid),
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+);
+CREATE INDEX operations_history_updated_at_idx ON operations_history (updated_at);
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add the tables of the webhooks events are delivered to, along with their
+// configuration and the events and projects they're restricted to.
+func updateFromV26(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE webhooks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL DEFAULT '',
+    UNIQUE (name)
+);
+CREATE TABLE webhooks_config (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    webhook_id INTEGER NOT NULL,
+    key TEXT NOT NULL,
+    value TEXT,
+    FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE,
+    UNIQUE (webhook_id, key)
+);
+CREATE TABLE webhooks_events (
+    webhook_id INTEGER NOT NULL,
+    event TEXT NOT NULL,
+    FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE,
+    UNIQUE (webhook_id, event)
+);
+CREATE TABLE webhooks_projects (
+    webhook_id INTEGER NOT NULL,
+    project_id INTEGER NOT NULL,
+    FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+    UNIQUE (webhook_id, project_id)
+);
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add the tables of the local RBAC groups, their members and role bindings.
+// Role bindings with no project apply to all projects.
+func updateFromV25(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE auth_groups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    UNIQUE (name)
+);
+CREATE TABLE auth_groups_identities (
+    auth_group_id INTEGER NOT NULL,
+    identity TEXT NOT NULL,
+    FOREIGN KEY (auth_group_id) REFERENCES auth_groups (id) ON DELETE CASCADE,
+    UNIQUE (auth_group_id, identity)
+);
+CREATE TABLE auth_groups_roles (
+    auth_group_id INTEGER NOT NULL,
+    role TEXT NOT NULL,
+    project_id INTEGER,
+    FOREIGN KEY (auth_group_id) REFERENCES auth_groups (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+    UNIQUE (auth_group_id, role, project_id)
+);
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add the auth_tokens table, along with the list of projects each API token
+// is restricted to.
+func updateFromV24(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE auth_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    secret TEXT NOT NULL,
+    scope TEXT NOT NULL,
+    creation_date DATETIME NOT NULL,
+    expiry_date DATETIME NOT NULL,
+    UNIQUE (name),
+    UNIQUE (secret)
+);
+CREATE TABLE auth_tokens_projects (
+    auth_token_id INTEGER NOT NULL,
+    project_id INTEGER NOT NULL,
+    FOREIGN KEY (auth_token_id) REFERENCES auth_tokens (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+    UNIQUE (auth_token_id, project_id)
+);
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add the "restricted" column to the "certificates" table, along with the
+// list of projects restricted certificates have access to.
+func updateFromV23(tx *sql.Tx) error {
+	stmts := `
+ALTER TABLE certificates ADD COLUMN restricted INTEGER NOT NULL DEFAULT 0;
+CREATE TABLE certificates_projects (
+    certificate_id INTEGER NOT NULL,
+    project_id INTEGER NOT NULL,
+    FOREIGN KEY (certificate_id) REFERENCES certificates (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+    UNIQUE (certificate_id, project_id)
+);
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add the auto-update policy columns to the "images" table.
+func updateFromV22(tx *sql.Tx) error {
+	stmts := `
+ALTER TABLE images ADD COLUMN auto_update_window TEXT NOT NULL DEFAULT '';
+ALTER TABLE images ADD COLUMN auto_update_pin TEXT NOT NULL DEFAULT '';
+ALTER TABLE images ADD COLUMN auto_update_max_age TEXT NOT NULL DEFAULT '';
+ALTER TABLE images ADD COLUMN auto_update_notify_only INTEGER NOT NULL DEFAULT 0;
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add a new "require_signature" column to the "images_source" table.
+func updateFromV21(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE images_source ADD COLUMN require_signature INTEGER NOT NULL DEFAULT 0")
+	return err
+}
+
+// Add join_tokens table.
+func updateFromV20(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE join_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    expiry_date DATETIME NOT NULL,
+    certificate TEXT NOT NULL DEFAULT '',
+    UNIQUE (name),
+    UNIQUE (secret)
+);
+`
+	_, err := tx.Exec(stmts)
+	return err
 }
 
 // Add a new "arch" column to the "nodes" table.
@@ -16,6 +16,7 @@ import (
 	"github.com/lxc/lxd/lxd/db/cluster"
 	"github.com/lxc/lxd/lxd/db/node"
 	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/lxd/metrics"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/logger"
 )
@@ -381,6 +382,8 @@ func (c *Cluster) transaction(f func(*ClusterTx) error) error {
 		stmts:  c.stmts,
 	}
 
+	defer metrics.ClusterTransactionTimingHistogram.ObserveSince(nil, time.Now())
+
 	return query.Retry(func() error {
 		return query.Transaction(c.db, func(tx *sql.Tx) error {
 			clusterTx.tx = tx
@@ -251,7 +251,7 @@ func (s *dbTestSuite) Test_ImageSourceGetCachedFingerprint() {
 	imageID, _, err := s.db.ImageGet("default", "fingerprint", false, false)
 	s.Nil(err)
 
-	err = s.db.ImageSourceInsert(imageID, "server.remote", "simplestreams", "", "test")
+	err = s.db.ImageSourceInsert(imageID, "server.remote", "simplestreams", "", "test", false)
 	s.Nil(err)
 
 	fingerprint, err := s.db.ImageSourceGetCachedFingerprint("server.remote", "simplestreams", "test", "container")
@@ -263,7 +263,7 @@ func (s *dbTestSuite) Test_ImageSourceGetCachedFingerprint_no_match() {
 	imageID, _, err := s.db.ImageGet("default", "fingerprint", false, false)
 	s.Nil(err)
 
-	err = s.db.ImageSourceInsert(imageID, "server.remote", "simplestreams", "", "test")
+	err = s.db.ImageSourceInsert(imageID, "server.remote", "simplestreams", "", "test", false)
 	s.Nil(err)
 
 	_, err = s.db.ImageSourceGetCachedFingerprint("server.remote", "lxd", "test", "container")
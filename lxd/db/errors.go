@@ -16,4 +16,8 @@ var (
 	// isn't found so we don't abuse sql.ErrNoRows any more than we
 	// already do.
 	ErrNoSuchObject = fmt.Errorf("No such object")
+
+	// ErrTooManyOperations happens when a project already has as many
+	// ongoing operations as its limits.operations allows.
+	ErrTooManyOperations = fmt.Errorf("Too many concurrent operations")
 )
@@ -0,0 +1,102 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/chunkstore"
+	"github.com/lxc/lxd/lxd/db/query"
+)
+
+// ImageChunksGet returns the chunks making up the given image file of the
+// chunked image store, in order. The list is empty if the file isn't stored
+// as chunks.
+func (n *NodeTx) ImageChunksGet(file string) ([]chunkstore.Chunk, error) {
+	chunks := []chunkstore.Chunk{}
+	dest := func(i int) []interface{} {
+		chunks = append(chunks, chunkstore.Chunk{})
+		return []interface{}{&chunks[i].Hash, &chunks[i].Size}
+	}
+
+	stmt, err := n.tx.Prepare("SELECT chunk, size FROM image_chunks WHERE file=? ORDER BY position")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	err = query.SelectObjects(stmt, dest, file)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch image chunks")
+	}
+
+	return chunks, nil
+}
+
+// ImageChunksSet replaces the chunks making up the given image file.
+func (n *NodeTx) ImageChunksSet(file string, chunks []chunkstore.Chunk) error {
+	err := n.ImageChunksDelete(file)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := n.tx.Prepare("INSERT INTO image_chunks (file, position, chunk, size) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i, chunk := range chunks {
+		_, err = stmt.Exec(file, i, chunk.Hash, chunk.Size)
+		if err != nil {
+			return errors.Wrap(err, "Failed to insert image chunk")
+		}
+	}
+
+	return nil
+}
+
+// ImageChunksDelete removes the chunks of the given image file from the
+// index, along with its hash.
+func (n *NodeTx) ImageChunksDelete(file string) error {
+	_, err := n.tx.Exec("DELETE FROM image_chunks WHERE file=?", file)
+	if err != nil {
+		return err
+	}
+
+	_, err = n.tx.Exec("DELETE FROM image_chunks_files WHERE file=?", file)
+	return err
+}
+
+// ImageChunksHashGet returns the SHA-256 of the given image file of the
+// chunked image store, or an empty string if it isn't known.
+func (n *NodeTx) ImageChunksHashGet(file string) (string, error) {
+	hashes, err := query.SelectStrings(n.tx, "SELECT hash FROM image_chunks_files WHERE file=?", file)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to fetch image file hash")
+	}
+
+	if len(hashes) == 0 {
+		return "", nil
+	}
+
+	return hashes[0], nil
+}
+
+// ImageChunksHashSet records the SHA-256 of the given image file of the
+// chunked image store.
+func (n *NodeTx) ImageChunksHashSet(file string, hash string) error {
+	_, err := query.UpsertObject(n.tx, "image_chunks_files", []string{"file", "hash"}, []interface{}{file, hash})
+	return err
+}
+
+// ImageChunkUsed checks whether any image file is made of the chunk with the
+// given hash.
+func (n *NodeTx) ImageChunkUsed(hash string) (bool, error) {
+	count, err := query.Count(n.tx, "image_chunks", "chunk=?", hash)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
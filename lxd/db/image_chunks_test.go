@@ -0,0 +1,69 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/lxc/lxd/lxd/chunkstore"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageChunks(t *testing.T) {
+	tx, cleanup := db.NewTestNodeTx(t)
+	defer cleanup()
+
+	chunks := []chunkstore.Chunk{
+		{Hash: "aaa", Size: 10},
+		{Hash: "bbb", Size: 20},
+		{Hash: "aaa", Size: 10},
+	}
+
+	err := tx.ImageChunksSet("abc.rootfs", chunks)
+	require.NoError(t, err)
+
+	result, err := tx.ImageChunksGet("abc.rootfs")
+	require.NoError(t, err)
+	assert.Equal(t, chunks, result)
+
+	// Setting the chunks again replaces them.
+	err = tx.ImageChunksSet("abc.rootfs", chunks[1:2])
+	require.NoError(t, err)
+
+	result, err = tx.ImageChunksGet("abc.rootfs")
+	require.NoError(t, err)
+	assert.Equal(t, chunks[1:2], result)
+
+	used, err := tx.ImageChunkUsed("aaa")
+	require.NoError(t, err)
+	assert.False(t, used)
+
+	used, err = tx.ImageChunkUsed("bbb")
+	require.NoError(t, err)
+	assert.True(t, used)
+
+	hash, err := tx.ImageChunksHashGet("abc.rootfs")
+	require.NoError(t, err)
+	assert.Equal(t, "", hash)
+
+	err = tx.ImageChunksHashSet("abc.rootfs", "ccc")
+	require.NoError(t, err)
+
+	hash, err = tx.ImageChunksHashGet("abc.rootfs")
+	require.NoError(t, err)
+	assert.Equal(t, "ccc", hash)
+
+	// The hash goes away along with the chunks.
+	err = tx.ImageChunksDelete("abc.rootfs")
+	require.NoError(t, err)
+
+	result, err = tx.ImageChunksGet("abc.rootfs")
+	require.NoError(t, err)
+	assert.Len(t, result, 0)
+
+	hash, err = tx.ImageChunksHashGet("abc.rootfs")
+	require.NoError(t, err)
+	assert.Equal(t, "", hash)
+}
@@ -46,6 +46,39 @@ SELECT images.fingerprint
 	return results, nil
 }
 
+// ImagesGetSimpleStreams returns the fingerprints of the public images
+// available on this node, indexed by the name of their project, for all the
+// projects which have the images.simplestreams config key set.
+func (c *Cluster) ImagesGetSimpleStreams() (map[string][]string, error) {
+	q := `
+SELECT projects.name, images.fingerprint
+  FROM images
+  JOIN projects ON projects.id = images.project_id
+  JOIN projects_config ON projects_config.project_id = projects.id
+  JOIN images_nodes ON images_nodes.image_id = images.id
+ WHERE images.public = 1
+   AND images_nodes.node_id = ?
+   AND projects_config.key = 'images.simplestreams'
+   AND projects_config.value = 'true'
+ ORDER BY projects.name, images.fingerprint
+`
+	var project, fp string
+	inargs := []interface{}{c.nodeID}
+	outfmt := []interface{}{project, fp}
+	dbResults, err := queryScan(c.db, q, inargs, outfmt)
+	if err != nil {
+		return nil, err
+	}
+
+	results := map[string][]string{}
+	for _, r := range dbResults {
+		project = r[0].(string)
+		results[project] = append(results[project], r[1].(string))
+	}
+
+	return results, nil
+}
+
 // ImagesGet returns the names of all images (optionally only the public ones).
 func (c *Cluster) ImagesGet(project string, public bool) ([]string, error) {
 	err := c.Transaction(func(tx *ClusterTx) error {
@@ -131,8 +164,8 @@ func (c *Cluster) ImagesGetExpired(expiry int64) ([]string, error) {
 }
 
 // ImageSourceInsert inserts a new image source.
-func (c *Cluster) ImageSourceInsert(id int, server string, protocol string, certificate string, alias string) error {
-	stmt := `INSERT INTO images_source (image_id, server, protocol, certificate, alias) values (?, ?, ?, ?, ?)`
+func (c *Cluster) ImageSourceInsert(id int, server string, protocol string, certificate string, alias string, requireSignature bool) error {
+	stmt := `INSERT INTO images_source (image_id, server, protocol, certificate, alias, require_signature) values (?, ?, ?, ?, ?, ?)`
 
 	protocolInt := -1
 	for protoInt, protoString := range ImageSourceProtocol {
@@ -145,20 +178,20 @@ func (c *Cluster) ImageSourceInsert(id int, server string, protocol string, cert
 		return fmt.Errorf("Invalid protocol: %s", protocol)
 	}
 
-	err := exec(c.db, stmt, id, server, protocolInt, certificate, alias)
+	err := exec(c.db, stmt, id, server, protocolInt, certificate, alias, requireSignature)
 	return err
 }
 
 // ImageSourceGet returns the image source with the given ID.
 func (c *Cluster) ImageSourceGet(imageID int) (int, api.ImageSource, error) {
-	q := `SELECT id, server, protocol, certificate, alias FROM images_source WHERE image_id=?`
+	q := `SELECT id, server, protocol, certificate, alias, require_signature FROM images_source WHERE image_id=?`
 
 	id := 0
 	protocolInt := -1
 	result := api.ImageSource{}
 
 	arg1 := []interface{}{imageID}
-	arg2 := []interface{}{&id, &result.Server, &protocolInt, &result.Certificate, &result.Alias}
+	arg2 := []interface{}{&id, &result.Server, &protocolInt, &result.Certificate, &result.Alias, &result.RequireSignature}
 	err := dbQueryRowScan(c.db, q, arg1, arg2)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -505,6 +538,13 @@ func (c *Cluster) imageFill(id int, image *api.Image, create, expire, used, uplo
 		image.UpdateSource = &source
 	}
 
+	policy, err := c.ImageAutoUpdatePolicyGet(id)
+	if err != nil {
+		return err
+	}
+
+	image.AutoUpdatePolicy = *policy
+
 	return nil
 }
 
@@ -951,6 +991,64 @@ func (c *Cluster) ImageUploadedAt(id int, uploadedAt time.Time) error {
 	return err
 }
 
+// ImageGetInstances returns the names of the instances created from the image
+// with the given fingerprint, as recorded by their volatile.base_image key,
+// indexed by project.
+func (c *Cluster) ImageGetInstances(fingerprint string) (map[string][]string, error) {
+	q := `
+SELECT projects.name, instances.name
+  FROM instances_config
+  JOIN instances ON instances.id = instances_config.instance_id
+  JOIN projects ON projects.id = instances.project_id
+ WHERE instances_config.key = 'volatile.base_image' AND instances_config.value = ?
+ ORDER BY projects.name, instances.name
+`
+	var project, name string
+	inargs := []interface{}{fingerprint}
+	outfmt := []interface{}{project, name}
+	results, err := queryScan(c.db, q, inargs, outfmt)
+	if err != nil {
+		return nil, err
+	}
+
+	instances := map[string][]string{}
+	for _, r := range results {
+		project = r[0].(string)
+		name = r[1].(string)
+		instances[project] = append(instances[project], name)
+	}
+
+	return instances, nil
+}
+
+// ImageAutoUpdatePolicyGet returns the auto-update policy of the image with
+// the given ID.
+func (c *Cluster) ImageAutoUpdatePolicyGet(id int) (*api.ImageAutoUpdatePolicy, error) {
+	q := `SELECT auto_update_window, auto_update_pin, auto_update_max_age, auto_update_notify_only FROM images WHERE id=?`
+
+	policy := api.ImageAutoUpdatePolicy{}
+	inargs := []interface{}{id}
+	outfmt := []interface{}{&policy.Window, &policy.Pin, &policy.MaxAge, &policy.NotifyOnly}
+	err := dbQueryRowScan(c.db, q, inargs, outfmt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNoSuchObject
+		}
+
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+// ImageAutoUpdatePolicyUpdate updates the auto-update policy of the image
+// with the given ID.
+func (c *Cluster) ImageAutoUpdatePolicyUpdate(id int, policy api.ImageAutoUpdatePolicy) error {
+	stmt := `UPDATE images SET auto_update_window=?, auto_update_pin=?, auto_update_max_age=?, auto_update_notify_only=? WHERE id=?`
+	err := exec(c.db, stmt, policy.Window, policy.Pin, policy.MaxAge, policy.NotifyOnly, id)
+	return err
+}
+
 // ImagesGetOnCurrentNode returns all images that the current LXD node instance has.
 func (c *Cluster) ImagesGetOnCurrentNode() (map[string][]string, error) {
 	return c.ImagesGetByNodeID(c.nodeID)
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -39,3 +40,88 @@ func TestImageLocate(t *testing.T) {
 	require.Equal(t, "", address)
 	require.EqualError(t, err, "image not available on any online node")
 }
+
+// Only public images from projects which opted in are part of the
+// simplestreams feed.
+func TestImagesGetSimpleStreams(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	err := cluster.ImageInsert(
+		"default", "abc", "x.gz", 16, true, false, "amd64", time.Now(), time.Now(), map[string]string{}, "container")
+	require.NoError(t, err)
+
+	err = cluster.ImageInsert(
+		"default", "def", "y.gz", 16, false, false, "amd64", time.Now(), time.Now(), map[string]string{}, "container")
+	require.NoError(t, err)
+
+	images, err := cluster.ImagesGetSimpleStreams()
+	require.NoError(t, err)
+	assert.Len(t, images, 0)
+
+	err = cluster.Transaction(func(tx *db.ClusterTx) error {
+		project, err := tx.ProjectGet("default")
+		if err != nil {
+			return err
+		}
+
+		project.Config["images.simplestreams"] = "true"
+		return tx.ProjectUpdate("default", project.Writable())
+	})
+	require.NoError(t, err)
+
+	images, err = cluster.ImagesGetSimpleStreams()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"default": {"abc"}}, images)
+}
+
+func TestImageAutoUpdatePolicy(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	err := cluster.ImageInsert(
+		"default", "abc", "x.gz", 16, false, true, "amd64", time.Now(), time.Now(), map[string]string{}, "container")
+	require.NoError(t, err)
+
+	id, image, err := cluster.ImageGet("default", "abc", false, true)
+	require.NoError(t, err)
+	assert.Equal(t, api.ImageAutoUpdatePolicy{}, image.AutoUpdatePolicy)
+
+	policy := api.ImageAutoUpdatePolicy{
+		Window:     "* 0-5 * * *",
+		Pin:        "def",
+		MaxAge:     "2w",
+		NotifyOnly: true,
+	}
+
+	err = cluster.ImageAutoUpdatePolicyUpdate(id, policy)
+	require.NoError(t, err)
+
+	_, image, err = cluster.ImageGet("default", "abc", false, true)
+	require.NoError(t, err)
+	assert.Equal(t, policy, image.AutoUpdatePolicy)
+}
+
+func TestImageGetInstances(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	err := cluster.Transaction(func(tx *db.ClusterTx) error {
+		addContainer(t, tx, 1, "c1")
+		addContainer(t, tx, 1, "c2")
+		addContainer(t, tx, 1, "c3")
+		addContainerConfig(t, tx, "c1", "volatile.base_image", "abc")
+		addContainerConfig(t, tx, "c2", "volatile.base_image", "def")
+		addContainerConfig(t, tx, "c3", "volatile.base_image", "abc")
+		return nil
+	})
+	require.NoError(t, err)
+
+	instances, err := cluster.ImageGetInstances("abc")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"default": {"c1", "c3"}}, instances)
+
+	instances, err = cluster.ImageGetInstances("ghi")
+	require.NoError(t, err)
+	assert.Len(t, instances, 0)
+}
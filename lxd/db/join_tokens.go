@@ -0,0 +1,128 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lxc/lxd/lxd/db/query"
+)
+
+// JoinToken holds information about a token that allows a new node to join
+// the cluster.
+type JoinToken struct {
+	ID         int64     // Stable database identifier
+	Name       string    // Name of the node allowed to join
+	Secret     string    // Hash of the token secret
+	ExpiryDate time.Time // Date after which the token is no longer valid
+
+	// Fingerprint of the certificate added to the trust store using the
+	// token, if any.
+	Certificate string
+}
+
+// JoinTokenAdd stores a new join token for the node with the given name,
+// replacing any existing token for that name.
+func (c *ClusterTx) JoinTokenAdd(name string, secret string, expiryDate time.Time) (int64, error) {
+	_, err := c.tx.Exec("DELETE FROM join_tokens WHERE name=?", name)
+	if err != nil {
+		return -1, err
+	}
+
+	columns := []string{"name", "secret", "expiry_date"}
+	values := []interface{}{name, secret, expiryDate}
+	return query.UpsertObject(c.tx, "join_tokens", columns, values)
+}
+
+// JoinTokenBySecret returns the join token with the given secret hash.
+func (c *ClusterTx) JoinTokenBySecret(secret string) (JoinToken, error) {
+	return c.joinTokenBy("secret", secret)
+}
+
+// JoinTokenByCertificate returns the join token which was used to add the
+// certificate with the given fingerprint to the trust store.
+func (c *ClusterTx) JoinTokenByCertificate(fingerprint string) (JoinToken, error) {
+	if fingerprint == "" {
+		return JoinToken{}, ErrNoSuchObject
+	}
+
+	return c.joinTokenBy("certificate", fingerprint)
+}
+
+// Return the join token whose given column matches the given value.
+func (c *ClusterTx) joinTokenBy(column string, value string) (JoinToken, error) {
+	null := JoinToken{}
+	tokens := []JoinToken{}
+	dest := func(i int) []interface{} {
+		tokens = append(tokens, JoinToken{})
+		return []interface{}{
+			&tokens[i].ID,
+			&tokens[i].Name,
+			&tokens[i].Secret,
+			&tokens[i].ExpiryDate,
+			&tokens[i].Certificate,
+		}
+	}
+
+	stmt, err := c.tx.Prepare(fmt.Sprintf("SELECT id, name, secret, expiry_date, certificate FROM join_tokens WHERE %s=?", column))
+	if err != nil {
+		return null, err
+	}
+	defer stmt.Close()
+
+	err = query.SelectObjects(stmt, dest, value)
+	if err != nil {
+		return null, err
+	}
+
+	switch len(tokens) {
+	case 0:
+		return null, ErrNoSuchObject
+	case 1:
+		return tokens[0], nil
+	default:
+		return null, fmt.Errorf("more than one join token matches")
+	}
+}
+
+// JoinTokenUpdateCertificate records the fingerprint of the certificate added
+// to the trust store using the join token with the given ID.
+func (c *ClusterTx) JoinTokenUpdateCertificate(id int64, fingerprint string) error {
+	result, err := c.tx.Exec("UPDATE join_tokens SET certificate=? WHERE id=?", fingerprint, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n != 1 {
+		return ErrNoSuchObject
+	}
+
+	return nil
+}
+
+// JoinTokenRemove deletes the join token with the given ID.
+func (c *ClusterTx) JoinTokenRemove(id int64) error {
+	deleted, err := query.DeleteObject(c.tx, "join_tokens", id)
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return ErrNoSuchObject
+	}
+
+	return nil
+}
+
+// JoinTokensPrune deletes all join tokens which expired before the given
+// date.
+func (c *ClusterTx) JoinTokensPrune(date time.Time) error {
+	_, err := c.tx.Exec("DELETE FROM join_tokens WHERE expiry_date < ?", date)
+	return err
+}
@@ -0,0 +1,78 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Add, get and remove a join token.
+func TestJoinToken(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	expiry := time.Now().Add(time.Hour).UTC()
+	id, err := tx.JoinTokenAdd("node2", "abcd", expiry)
+	require.NoError(t, err)
+
+	token, err := tx.JoinTokenBySecret("abcd")
+	require.NoError(t, err)
+	assert.Equal(t, id, token.ID)
+	assert.Equal(t, "node2", token.Name)
+	assert.True(t, expiry.Equal(token.ExpiryDate))
+
+	_, err = tx.JoinTokenBySecret("efgh")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+
+	err = tx.JoinTokenRemove(id)
+	require.NoError(t, err)
+
+	_, err = tx.JoinTokenBySecret("abcd")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+}
+
+// Adding a new token for the same node replaces the old one.
+func TestJoinTokenAdd_Replace(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	expiry := time.Now().Add(time.Hour)
+	_, err := tx.JoinTokenAdd("node2", "abcd", expiry)
+	require.NoError(t, err)
+
+	_, err = tx.JoinTokenAdd("node2", "efgh", expiry)
+	require.NoError(t, err)
+
+	_, err = tx.JoinTokenBySecret("abcd")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+
+	token, err := tx.JoinTokenBySecret("efgh")
+	require.NoError(t, err)
+	assert.Equal(t, "node2", token.Name)
+}
+
+// Expired tokens get pruned.
+func TestJoinTokensPrune(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.JoinTokenAdd("node2", "abcd", time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+
+	_, err = tx.JoinTokenAdd("node3", "efgh", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	err = tx.JoinTokensPrune(time.Now())
+	require.NoError(t, err)
+
+	_, err = tx.JoinTokenBySecret("abcd")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+
+	_, err = tx.JoinTokenBySecret("efgh")
+	require.NoError(t, err)
+}
@@ -12,6 +12,21 @@ CREATE TABLE config (
     value TEXT,
     UNIQUE (key)
 );
+CREATE TABLE image_chunks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    file TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    chunk TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    UNIQUE (file, position)
+);
+CREATE INDEX image_chunks_chunk_idx ON image_chunks (chunk);
+CREATE TABLE image_chunks_files (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    file TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    UNIQUE (file)
+);
 CREATE TABLE patches (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
     name VARCHAR(255) NOT NULL,
@@ -24,5 +39,5 @@ CREATE TABLE raft_nodes (
     UNIQUE (address)
 );
 
-INSERT INTO schema (version, updated_at) VALUES (38, strftime("%s"))
+INSERT INTO schema (version, updated_at) VALUES (39, strftime("%s"))
 `
@@ -95,6 +95,7 @@ var updates = map[int]schema.Update{
 	36: updateFromV35,
 	37: updateFromV36,
 	38: updateFromV37,
+	39: updateFromV38,
 }
 
 // UpdateFromPreClustering is the last schema version where clustering support
@@ -103,6 +104,30 @@ const UpdateFromPreClustering = 36
 
 // Schema updates begin here
 
+// Add the index of the chunks making up the image files stored in the
+// chunked image store, along with the SHA-256 of the whole files.
+func updateFromV38(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE image_chunks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    file TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    chunk TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    UNIQUE (file, position)
+);
+CREATE INDEX image_chunks_chunk_idx ON image_chunks (chunk);
+CREATE TABLE image_chunks_files (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    file TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    UNIQUE (file)
+);
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
 // Copy core.https_address to cluster.https_address in case this node is
 // clustered.
 func updateFromV37(tx *sql.Tx) error {
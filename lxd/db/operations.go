@@ -16,6 +16,7 @@ type Operation struct {
 	UUID        string        // User-visible identifier
 	NodeAddress string        // Address of the node the operation is running on
 	Type        OperationType // Type of the operation
+	Project     string        // Project of the operation, if any
 }
 
 // Operations returns all operations associated with this node.
@@ -42,6 +43,19 @@ SELECT DISTINCT nodes.address
 	return query.SelectStrings(c.tx, stmt, project)
 }
 
+// OperationsByProject returns the operations of the given project running on
+// any node, along with the ones not tied to any project.
+func (c *ClusterTx) OperationsByProject(project string) ([]Operation, error) {
+	return c.operations("projects.name = ? OR operations.project_id IS NULL", project)
+}
+
+// OperationsCountElsewhere returns the number of operations of the given
+// project running on the other nodes.
+func (c *ClusterTx) OperationsCountElsewhere(project string) (int, error) {
+	where := "project_id = (SELECT id FROM projects WHERE name = ?) AND node_id != ?"
+	return query.Count(c.tx, "operations", where, project, c.nodeID)
+}
+
 // OperationByUUID returns the operation with the given UUID.
 func (c *ClusterTx) OperationByUUID(uuid string) (Operation, error) {
 	null := Operation{}
@@ -114,10 +128,14 @@ func (c *ClusterTx) operations(where string, args ...interface{}) ([]Operation,
 			&operations[i].UUID,
 			&operations[i].NodeAddress,
 			&operations[i].Type,
+			&operations[i].Project,
 		}
 	}
 	sql := `
-SELECT operations.id, uuid, nodes.address, type FROM operations JOIN nodes ON nodes.id = node_id `
+SELECT operations.id, uuid, nodes.address, type, coalesce(projects.name, '')
+  FROM operations
+  JOIN nodes ON nodes.id = node_id
+  LEFT OUTER JOIN projects ON projects.id = operations.project_id `
 	if where != "" {
 		sql += fmt.Sprintf("WHERE %s ", where)
 	}
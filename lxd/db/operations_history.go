@@ -0,0 +1,153 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db/query"
+)
+
+// OperationRecord holds information about a completed LXD operation, kept
+// after the operation itself is gone.
+type OperationRecord struct {
+	ID                int64               // Stable database identifier
+	UUID              string              // User-visible identifier
+	Project           string              // Project of the operation, if any
+	Location          string              // Name of the node the operation ran on
+	Type              OperationType       // Type of the operation
+	Class             string              // Class of the operation (task, websocket or token)
+	StatusCode        int                 // Final status of the operation
+	Error             string              // Error the operation failed with, if any
+	Resources         map[string][]string // Resources affected by the operation
+	RequestorUsername string              // User who requested the operation, if known
+	RequestorProtocol string              // Protocol the user was authenticated with
+	CreatedAt         time.Time           // Date the operation was created
+	UpdatedAt         time.Time           // Date the operation completed
+}
+
+// OperationRecordAdd stores the record of a completed operation.
+func (c *ClusterTx) OperationRecordAdd(record OperationRecord) (int64, error) {
+	var projectID interface{}
+
+	if record.Project != "" {
+		var err error
+		projectID, err = c.ProjectID(record.Project)
+		if err != nil {
+			return -1, errors.Wrap(err, "Fetch project ID")
+		}
+	}
+
+	resources := record.Resources
+	if resources == nil {
+		resources = map[string][]string{}
+	}
+
+	data, err := json.Marshal(resources)
+	if err != nil {
+		return -1, err
+	}
+
+	columns := []string{
+		"uuid", "project_id", "location", "type", "class", "status_code", "error", "resources",
+		"requestor_username", "requestor_protocol", "created_at", "updated_at",
+	}
+	values := []interface{}{
+		record.UUID, projectID, record.Location, record.Type, record.Class, record.StatusCode, record.Error, string(data),
+		record.RequestorUsername, record.RequestorProtocol, record.CreatedAt, record.UpdatedAt,
+	}
+
+	return query.UpsertObject(c.tx, "operations_history", columns, values)
+}
+
+// OperationRecords returns the records of the completed operations of the
+// given project, along with the ones not tied to any project.
+func (c *ClusterTx) OperationRecords(project string) ([]OperationRecord, error) {
+	return c.operationRecords("projects.name = ? OR operations_history.project_id IS NULL", project)
+}
+
+// OperationRecordByUUID returns the record of the completed operation with
+// the given UUID.
+func (c *ClusterTx) OperationRecordByUUID(uuid string) (OperationRecord, error) {
+	null := OperationRecord{}
+
+	records, err := c.operationRecords("operations_history.uuid = ?", uuid)
+	if err != nil {
+		return null, err
+	}
+
+	switch len(records) {
+	case 0:
+		return null, ErrNoSuchObject
+	case 1:
+		return records[0], nil
+	default:
+		return null, fmt.Errorf("more than one operation record matches")
+	}
+}
+
+func (c *ClusterTx) operationRecords(where string, args ...interface{}) ([]OperationRecord, error) {
+	records := []OperationRecord{}
+	resources := []string{}
+	dest := func(i int) []interface{} {
+		records = append(records, OperationRecord{})
+		resources = append(resources, "")
+		return []interface{}{
+			&records[i].ID,
+			&records[i].UUID,
+			&records[i].Project,
+			&records[i].Location,
+			&records[i].Type,
+			&records[i].Class,
+			&records[i].StatusCode,
+			&records[i].Error,
+			&resources[i],
+			&records[i].RequestorUsername,
+			&records[i].RequestorProtocol,
+			&records[i].CreatedAt,
+			&records[i].UpdatedAt,
+		}
+	}
+
+	sql := `
+SELECT operations_history.id, uuid, coalesce(projects.name, ''), location, type, class, status_code, error,
+       resources, requestor_username, requestor_protocol, created_at, updated_at
+  FROM operations_history
+  LEFT OUTER JOIN projects ON projects.id = operations_history.project_id`
+	if where != "" {
+		sql += fmt.Sprintf(" WHERE %s", where)
+	}
+
+	sql += " ORDER BY created_at, operations_history.id"
+
+	stmt, err := c.tx.Prepare(sql)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	err = query.SelectObjects(stmt, dest, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch operation records")
+	}
+
+	for i := range records {
+		err := json.Unmarshal([]byte(resources[i]), &records[i].Resources)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse resources of operation %s", records[i].UUID)
+		}
+	}
+
+	return records, nil
+}
+
+// OperationRecordsPrune deletes the records of the operations which completed
+// before the given date.
+func (c *ClusterTx) OperationRecordsPrune(date time.Time) error {
+	_, err := c.tx.Exec("DELETE FROM operations_history WHERE updated_at < ?", date)
+	return err
+}
@@ -0,0 +1,74 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Add, get and prune operation records.
+func TestOperationRecord(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	_, err := tx.OperationRecordAdd(db.OperationRecord{
+		UUID:              "abcd",
+		Project:           "default",
+		Location:          "none",
+		Type:              db.OperationContainerCreate,
+		Class:             "task",
+		StatusCode:        int(api.Failure),
+		Error:             "Boom",
+		Resources:         map[string][]string{"containers": {"c1"}},
+		RequestorUsername: "admin",
+		RequestorProtocol: "tls",
+		CreatedAt:         now.Add(-time.Hour),
+		UpdatedAt:         now.Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	_, err = tx.OperationRecordAdd(db.OperationRecord{
+		UUID:       "efgh",
+		Type:       db.OperationImageDownload,
+		Class:      "task",
+		StatusCode: int(api.Success),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	})
+	require.NoError(t, err)
+
+	record, err := tx.OperationRecordByUUID("abcd")
+	require.NoError(t, err)
+	assert.Equal(t, "default", record.Project)
+	assert.Equal(t, db.OperationContainerCreate, record.Type)
+	assert.Equal(t, int(api.Failure), record.StatusCode)
+	assert.Equal(t, "Boom", record.Error)
+	assert.Equal(t, map[string][]string{"containers": {"c1"}}, record.Resources)
+	assert.Equal(t, "admin", record.RequestorUsername)
+	assert.Equal(t, now.Add(-time.Hour), record.CreatedAt.UTC())
+
+	records, err := tx.OperationRecords("default")
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "abcd", records[0].UUID)
+	assert.Equal(t, "", records[1].Project)
+	assert.Equal(t, map[string][]string{}, records[1].Resources)
+
+	err = tx.OperationRecordsPrune(now.Add(-time.Second))
+	require.NoError(t, err)
+
+	_, err = tx.OperationRecordByUUID("abcd")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+
+	records, err = tx.OperationRecords("default")
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+}
@@ -28,6 +28,7 @@ func TestOperation(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, id, operation.ID)
 	assert.Equal(t, db.OperationContainerCreate, operation.Type)
+	assert.Equal(t, "default", operation.Project)
 
 	uuids, err := tx.OperationsUUIDs()
 	require.NoError(t, err)
@@ -58,6 +59,7 @@ func TestOperationNoProject(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, id, operation.ID)
 	assert.Equal(t, db.OperationContainerCreate, operation.Type)
+	assert.Equal(t, "", operation.Project)
 
 	uuids, err := tx.OperationsUUIDs()
 	require.NoError(t, err)
@@ -69,3 +71,26 @@ func TestOperationNoProject(t *testing.T) {
 	_, err = tx.OperationByUUID("abcd")
 	assert.Equal(t, db.ErrNoSuchObject, err)
 }
+
+// Count the operations of a project running on the other nodes.
+func TestOperationsCountElsewhere(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	nodeID, err := tx.NodeAdd("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	_, err = tx.OperationAdd("default", "abcd", db.OperationContainerCreate)
+	require.NoError(t, err)
+
+	_, err = tx.Tx().Exec("INSERT INTO operations (uuid, node_id, type, project_id) VALUES ('efgh', ?, ?, 1)", nodeID, db.OperationContainerCreate)
+	require.NoError(t, err)
+
+	count, err := tx.OperationsCountElsewhere("default")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	count, err = tx.OperationsCountElsewhere("other")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
@@ -111,9 +111,9 @@ func dumpTable(tx *sql.Tx, table, schema string) (string, error) {
 			case int64:
 				values[j] = strconv.FormatInt(v, 10)
 			case string:
-				values[j] = fmt.Sprintf("'%s'", v)
+				values[j] = dumpQuote(v)
 			case []byte:
-				values[j] = fmt.Sprintf("'%s'", string(v))
+				values[j] = dumpQuote(string(v))
 			case time.Time:
 				values[j] = strconv.FormatInt(v.Unix(), 10)
 			default:
@@ -129,6 +129,108 @@ func dumpTable(tx *sql.Tx, table, schema string) (string, error) {
 	return strings.Join(statements, "\n") + "\n", nil
 }
 
+// Quote a string value, escaping any single quote in it.
+func dumpQuote(value string) string {
+	return fmt.Sprintf("'%s'", strings.Replace(value, "'", "''", -1))
+}
+
+// Restore loads a SQL text dump generated with Dump, replacing all rows of the
+// tables it defines with the dumped ones. The content of the schema table is
+// left untouched, so the caller must make sure that the dump was taken from a
+// database with the same schema version.
+func Restore(tx *sql.Tx, dump string) error {
+	tables := []string{}
+	inserts := []string{}
+	sequences := []string{}
+	for _, statement := range dumpSplitStatements(dump) {
+		fields := strings.Fields(statement)
+		if len(fields) < 3 {
+			continue
+		}
+
+		table := fields[2]
+		if table == "schema" {
+			continue
+		}
+
+		if strings.HasPrefix(statement, "CREATE TABLE ") {
+			tables = append(tables, table)
+			continue
+		}
+
+		if strings.HasPrefix(statement, "INSERT INTO ") {
+			if table != "sqlite_sequence" {
+				inserts = append(inserts, statement)
+			} else if !strings.HasPrefix(statement, "INSERT INTO sqlite_sequence VALUES('schema',") {
+				sequences = append(sequences, statement)
+			}
+		}
+	}
+
+	// Tables are dumped in alphabetical order, so foreign key constraints
+	// can only be checked once all rows are inserted.
+	_, err := tx.Exec("PRAGMA defer_foreign_keys=ON")
+	if err != nil {
+		return errors.Wrap(err, "failed to defer foreign keys checks")
+	}
+
+	for _, table := range tables {
+		_, err := tx.Exec(fmt.Sprintf("DELETE FROM %s", table))
+		if err != nil {
+			return errors.Wrapf(err, "failed to delete rows of table %s", table)
+		}
+	}
+
+	for _, statement := range inserts {
+		_, err := tx.Exec(statement)
+		if err != nil {
+			return errors.Wrapf(err, "failed to execute %q", statement)
+		}
+	}
+
+	// Inserting rows updates the sequences, so they are overwritten
+	// afterwards.
+	_, err = tx.Exec("DELETE FROM sqlite_sequence WHERE name != 'schema'")
+	if err != nil {
+		return errors.Wrap(err, "failed to reset sequences")
+	}
+
+	for _, statement := range sequences {
+		_, err := tx.Exec(statement)
+		if err != nil {
+			return errors.Wrapf(err, "failed to execute %q", statement)
+		}
+	}
+
+	return nil
+}
+
+// Split a SQL text dump into individual statements, taking care of semicolons
+// inside quoted values.
+func dumpSplitStatements(dump string) []string {
+	statements := []string{}
+	quoted := false
+	start := 0
+	for i, c := range dump {
+		switch c {
+		case '\'':
+			// An escaped quote just toggles the state twice.
+			quoted = !quoted
+		case ';':
+			if quoted {
+				continue
+			}
+			statement := strings.TrimSpace(dump[start:i])
+			if statement != "" {
+				statements = append(statements, statement)
+			}
+			start = i + 1
+		}
+	}
+
+	return statements
+}
+
 // Schema of the schema table.
 const dumpSchemaTable = `CREATE TABLE schema (
     id         INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
@@ -101,6 +101,42 @@ INSERT INTO storage_pools_config VALUES(1,1,NULL,'k','v');
 `, dump)
 }
 
+// Quotes in values get escaped.
+func TestDumpTable_Quotes(t *testing.T) {
+	tx := newTxForDump(t, "local")
+	tables := query.DumpParseSchema(schemas["local"])
+
+	_, err := tx.Exec("INSERT INTO config VALUES(1,'core.trust_password','it''s; a secret')")
+	require.NoError(t, err)
+
+	dump, err := query.DumpTable(tx, "config", tables["config"])
+	require.NoError(t, err)
+	assert.Contains(t, dump, "INSERT INTO config VALUES(1,'core.trust_password','it''s; a secret');\n")
+}
+
+// A dump can be restored on top of a database with different content.
+func TestRestore(t *testing.T) {
+	tx := newTxForDump(t, "global")
+	_, err := tx.Exec("INSERT INTO storage_pools_config VALUES(2,1,NULL,'x','it''s; here')")
+	require.NoError(t, err)
+
+	dump, err := query.Dump(tx, schemas["global"], false /* schemaOnly */)
+	require.NoError(t, err)
+
+	target := newTxForDump(t, "global")
+	_, err = target.Exec("DELETE FROM storage_pools")
+	require.NoError(t, err)
+	_, err = target.Exec("INSERT INTO storage_pools VALUES(5,'p5','zfs','',0)")
+	require.NoError(t, err)
+
+	err = query.Restore(target, dump)
+	require.NoError(t, err)
+
+	restored, err := query.Dump(target, schemas["global"], false /* schemaOnly */)
+	require.NoError(t, err)
+	assert.Equal(t, dump, restored)
+}
+
 func TestDumpParseSchema(t *testing.T) {
 	cases := []struct {
 		schema string   // Schema name
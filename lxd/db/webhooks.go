@@ -0,0 +1,197 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db/query"
+)
+
+// Webhook holds information about a webhook events are delivered to.
+type Webhook struct {
+	ID          int64
+	Name        string
+	Description string
+	URL         string
+	Secret      string            // Key used to sign the deliveries, if any
+	Config      map[string]string // Delivery settings
+	Events      []string          // Events delivered to the webhook (all if empty)
+	Projects    []string          // Projects the webhook is restricted to (all if empty)
+}
+
+// Webhooks returns all the webhooks.
+func (c *ClusterTx) Webhooks() ([]Webhook, error) {
+	webhooks := []Webhook{}
+	dest := func(i int) []interface{} {
+		webhooks = append(webhooks, Webhook{})
+		return []interface{}{
+			&webhooks[i].ID,
+			&webhooks[i].Name,
+			&webhooks[i].Description,
+			&webhooks[i].URL,
+			&webhooks[i].Secret,
+		}
+	}
+
+	stmt, err := c.tx.Prepare("SELECT id, name, description, url, secret FROM webhooks ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	err = query.SelectObjects(stmt, dest)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range webhooks {
+		err := c.webhookFill(&webhooks[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return webhooks, nil
+}
+
+// WebhookByName returns the webhook with the given name.
+func (c *ClusterTx) WebhookByName(name string) (Webhook, error) {
+	webhook := Webhook{}
+
+	row := c.tx.QueryRow("SELECT id, name, description, url, secret FROM webhooks WHERE name=?", name)
+	err := row.Scan(&webhook.ID, &webhook.Name, &webhook.Description, &webhook.URL, &webhook.Secret)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return webhook, ErrNoSuchObject
+		}
+
+		return webhook, err
+	}
+
+	err = c.webhookFill(&webhook)
+	if err != nil {
+		return webhook, err
+	}
+
+	return webhook, nil
+}
+
+// Load the configuration, events and projects of the given webhook.
+func (c *ClusterTx) webhookFill(webhook *Webhook) error {
+	var err error
+
+	webhook.Config, err = query.SelectConfig(c.tx, "webhooks_config", "webhook_id=?", webhook.ID)
+	if err != nil {
+		return err
+	}
+
+	webhook.Events, err = query.SelectStrings(c.tx, "SELECT event FROM webhooks_events WHERE webhook_id=? ORDER BY event", webhook.ID)
+	if err != nil {
+		return err
+	}
+
+	webhook.Projects, err = query.SelectStrings(c.tx, `
+SELECT projects.name FROM projects
+  JOIN webhooks_projects ON projects.id = webhooks_projects.project_id
+  WHERE webhooks_projects.webhook_id = ?
+  ORDER BY projects.name
+`, webhook.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// WebhookAdd stores a new webhook.
+func (c *ClusterTx) WebhookAdd(webhook Webhook) (int64, error) {
+	columns := []string{"name", "description", "url", "secret"}
+	values := []interface{}{webhook.Name, webhook.Description, webhook.URL, webhook.Secret}
+	id, err := query.UpsertObject(c.tx, "webhooks", columns, values)
+	if err != nil {
+		return -1, err
+	}
+
+	err = c.webhookSet(id, webhook)
+	if err != nil {
+		return -1, err
+	}
+
+	return id, nil
+}
+
+// WebhookUpdate replaces the fields of the webhook with the given name.
+func (c *ClusterTx) WebhookUpdate(name string, webhook Webhook) error {
+	current, err := c.WebhookByName(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.tx.Exec("UPDATE webhooks SET description=?, url=?, secret=? WHERE id=?", webhook.Description, webhook.URL, webhook.Secret, current.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.webhookSet(current.ID, webhook)
+}
+
+// Replace the configuration, events and projects of the webhook with the given
+// ID.
+func (c *ClusterTx) webhookSet(id int64, webhook Webhook) error {
+	for _, table := range []string{"webhooks_config", "webhooks_events", "webhooks_projects"} {
+		_, err := c.tx.Exec("DELETE FROM "+table+" WHERE webhook_id=?", id)
+		if err != nil {
+			return err
+		}
+	}
+
+	for key, value := range webhook.Config {
+		_, err := c.tx.Exec("INSERT INTO webhooks_config (webhook_id, key, value) VALUES (?, ?, ?)", id, key, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, event := range webhook.Events {
+		_, err := c.tx.Exec("INSERT INTO webhooks_events (webhook_id, event) VALUES (?, ?)", id, event)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range webhook.Projects {
+		projectID, err := c.ProjectID(name)
+		if err != nil {
+			return errors.Wrapf(err, "Fetch project %q", name)
+		}
+
+		_, err = c.tx.Exec("INSERT INTO webhooks_projects (webhook_id, project_id) VALUES (?, ?)", id, projectID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WebhookRemove deletes the webhook with the given name.
+func (c *ClusterTx) WebhookRemove(name string) error {
+	result, err := c.tx.Exec("DELETE FROM webhooks WHERE name=?", name)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrNoSuchObject
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Add, update and remove webhooks.
+func TestWebhook(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.WebhookAdd(db.Webhook{
+		Name:        "chatops",
+		Description: "Chat notifications",
+		URL:         "https://example.com/hook",
+		Secret:      "s3cr3t",
+		Config:      map[string]string{"retry.count": "3"},
+		Events:      []string{"operation/failure", "lifecycle"},
+		Projects:    []string{"default"},
+	})
+	require.NoError(t, err)
+
+	webhook, err := tx.WebhookByName("chatops")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/hook", webhook.URL)
+	assert.Equal(t, "s3cr3t", webhook.Secret)
+	assert.Equal(t, map[string]string{"retry.count": "3"}, webhook.Config)
+	assert.Equal(t, []string{"lifecycle", "operation/failure"}, webhook.Events)
+	assert.Equal(t, []string{"default"}, webhook.Projects)
+
+	err = tx.WebhookUpdate("chatops", db.Webhook{URL: "https://example.com/other", Config: map[string]string{}})
+	require.NoError(t, err)
+
+	webhook, err = tx.WebhookByName("chatops")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/other", webhook.URL)
+	assert.Equal(t, "", webhook.Secret)
+	assert.Len(t, webhook.Config, 0)
+	assert.Len(t, webhook.Events, 0)
+	assert.Len(t, webhook.Projects, 0)
+
+	webhooks, err := tx.Webhooks()
+	require.NoError(t, err)
+	assert.Len(t, webhooks, 1)
+
+	err = tx.WebhookRemove("chatops")
+	require.NoError(t, err)
+
+	_, err = tx.WebhookByName("chatops")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+
+	err = tx.WebhookRemove("chatops")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+}
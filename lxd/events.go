@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/events"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/logger"
@@ -18,23 +21,28 @@ var eventsCmd = APIEndpoint{
 }
 
 type eventsServe struct {
-	req *http.Request
-	d   *Daemon
+	req   *http.Request
+	d     *Daemon
+	since map[string]events.Position
 }
 
 func (r *eventsServe) Render(w http.ResponseWriter) error {
-	return eventsSocket(r.d, r.req, w)
+	return eventsSocket(r.d, r.req, w, r.since)
 }
 
 func (r *eventsServe) String() string {
 	return "event handler"
 }
 
-func eventsSocket(d *Daemon, r *http.Request, w http.ResponseWriter) error {
+func eventsSocket(d *Daemon, r *http.Request, w http.ResponseWriter, since map[string]events.Position) error {
 	project := projectParam(r)
+	location := r.FormValue("location")
 	typeStr := r.FormValue("type")
 	if typeStr == "" {
-		typeStr = "logging,operation,lifecycle"
+		typeStr = "operation,lifecycle"
+		if eventsUserCanLog(d, r) {
+			typeStr = "logging," + typeStr
+		}
 	}
 
 	// Upgrade the connection to websocket
@@ -59,7 +67,7 @@ func eventsSocket(d *Daemon, r *http.Request, w http.ResponseWriter) error {
 	// If this request is an internal one initiated by another node wanting
 	// to watch the events on this node, set the listener to broadcast only
 	// local events.
-	listener, err := d.events.AddListener(project, c, strings.Split(typeStr, ","), serverName, isClusterNotification(r))
+	listener, err := d.events.AddListener(project, c, strings.Split(typeStr, ","), serverName, isClusterNotification(r), location, since)
 	if err != nil {
 		return err
 	}
@@ -94,5 +102,91 @@ func eventsSocket(d *Daemon, r *http.Request, w http.ResponseWriter) error {
 }
 
 func eventsGet(d *Daemon, r *http.Request) response.Response {
-	return &eventsServe{req: r, d: d}
+	since, err := eventsParseSince(r.FormValue("since"))
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if since != nil {
+		var serverName string
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			serverName, err = tx.NodeName()
+			return err
+		})
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		// A position without member name refers to events
+		// originating from this member.
+		position, ok := since[""]
+		if ok {
+			delete(since, "")
+			since[serverName] = position
+		}
+
+		// Events from a previous run of a member, or already
+		// dropped from the buffer, can't be replayed.
+		err = d.events.CheckSince(serverName, since)
+		if err != nil {
+			return response.ErrorResponse(http.StatusGone, err.Error())
+		}
+	}
+
+	if !d.userHasPermission(r, projectParam(r), "view") {
+		return response.Forbidden(nil)
+	}
+
+	// Logging events aren't tied to any project.
+	if shared.StringInSlice("logging", strings.Split(r.FormValue("type"), ",")) && !eventsUserCanLog(d, r) {
+		return response.Forbidden(nil)
+	}
+
+	return &eventsServe{req: r, d: d, since: since}
+}
+
+// Return whether the user can get logging events, which is restricted to
+// admins and to the other cluster members.
+func eventsUserCanLog(d *Daemon, r *http.Request) bool {
+	return d.userIsAdmin(r) || d.userIsClusterMember(r)
+}
+
+// Parse the value of the since parameter of GET /1.0/events, which is either
+// a plain <epoch>:<sequence> position for events originating from the member
+// handling the request, or a comma-separated list of
+// <member>:<epoch>:<sequence> ones.
+func eventsParseSince(value string) (map[string]events.Position, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	since := map[string]events.Position{}
+	for _, field := range strings.Split(value, ",") {
+		parts := strings.Split(field, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("Invalid since value %q: expected [<member>:]<epoch>:<sequence>", field)
+		}
+
+		name := ""
+		if len(parts) == 3 {
+			name = parts[0]
+			parts = parts[1:]
+			if name == "" {
+				return nil, fmt.Errorf("Invalid since value %q: missing member name", field)
+			}
+		}
+
+		if parts[0] == "" {
+			return nil, fmt.Errorf("Invalid since value %q: missing epoch", field)
+		}
+
+		sequence, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || sequence < 0 {
+			return nil, fmt.Errorf("Invalid since value %q: bad sequence number", field)
+		}
+
+		since[name] = events.Position{Epoch: parts[0], Sequence: sequence}
+	}
+
+	return since, nil
 }
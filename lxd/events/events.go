@@ -15,50 +15,246 @@ import (
 	"github.com/lxc/lxd/shared/logger"
 )
 
+// Number of recent events kept in memory, to be replayed to listeners
+// reconnecting after a disconnection.
+const bufferSize = 1024
+
 // Server represents an instance of an event server.
 type Server struct {
 	debug   bool
 	verbose bool
 
 	listeners map[string]*Listener
+	handlers  []HandlerFunc
 	lock      sync.Mutex
+
+	// Random identifier of this run of the server, and sequence number of
+	// the last event originating from it.
+	epoch    string
+	sequence int64
+
+	// Ring buffer of recent events, both local and forwarded ones.
+	buffer     []bufferedEvent
+	bufferNext int
+}
+
+// An event kept in the replay buffer.
+type bufferedEvent struct {
+	group     string
+	event     api.Event
+	isForward bool
+}
+
+// Position is the position of a listener in the events originating from a
+// cluster member, made of the epoch of the member and of the sequence number
+// of the last event the listener got from it.
+type Position struct {
+	Epoch    string
+	Sequence int64
 }
 
+// HandlerFunc is called with the events originating from this server, along with
+// the group they were sent to.
+type HandlerFunc func(group string, event api.Event)
+
 // NewServer returns a new event server.
 func NewServer(debug bool, verbose bool) *Server {
 	server := &Server{
 		debug:     debug,
 		verbose:   verbose,
 		listeners: map[string]*Listener{},
+		buffer:    make([]bufferedEvent, 0, bufferSize),
+		epoch:     uuid.NewRandom().String(),
 	}
 
 	return server
 }
 
+// Epoch returns the random identifier of this run of the server, which the
+// events originating from it carry along with their sequence number.
+func (s *Server) Epoch() string {
+	return s.epoch
+}
+
+// CheckSince returns an error if any of the given positions refers to a
+// previous epoch of its member, or if some of the events following it were
+// already dropped from the buffer, as they can't be replayed anymore.
+//
+// The current epoch of the other members is the one of the last event
+// forwarded from them, if any.
+func (s *Server) CheckSince(location string, since map[string]Position) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for name, position := range since {
+		local := name == location
+
+		epoch := ""
+		if local {
+			epoch = s.epoch
+		} else {
+			// Go through the buffer from the newest event.
+			for i := 1; i <= len(s.buffer); i++ {
+				entry := s.buffer[(s.bufferNext-i+len(s.buffer))%len(s.buffer)]
+				if entry.isForward && entry.event.Location == name {
+					epoch = entry.event.Epoch
+					break
+				}
+			}
+		}
+
+		if epoch == "" {
+			continue
+		}
+
+		if epoch != position.Epoch {
+			return fmt.Errorf("The events of %s from epoch %s are gone as it restarted since", name, position.Epoch)
+		}
+
+		// The events following the position must all still be
+		// buffered, the local ones being all numbered in sequence.
+		oldest, ok := s.oldestSequence(local, name, epoch)
+		if !ok && local {
+			oldest = s.sequence + 1
+		}
+
+		if oldest > position.Sequence+1 {
+			return fmt.Errorf("The events of %s following %d are gone as too many happened since", name, position.Sequence)
+		}
+	}
+
+	return nil
+}
+
+// Return the sequence number of the oldest buffered event from the given
+// epoch, originating either from this server or from the given member.
+//
+// Must be called with the server lock held.
+func (s *Server) oldestSequence(local bool, location string, epoch string) (int64, bool) {
+	start := 0
+	if len(s.buffer) == bufferSize {
+		start = s.bufferNext
+	}
+
+	for i := 0; i < len(s.buffer); i++ {
+		entry := s.buffer[(start+i)%len(s.buffer)]
+
+		if entry.isForward == local || entry.event.Epoch != epoch {
+			continue
+		}
+
+		if !local && entry.event.Location != location {
+			continue
+		}
+
+		return entry.event.Sequence, true
+	}
+
+	return 0, false
+}
+
 // AddListener creates and returns a new event listener.
-func (s *Server) AddListener(group string, connection *websocket.Conn, messageTypes []string, location string, noForward bool) (*Listener, error) {
+//
+// If locationFilter is not empty, only events originating from the cluster
+// member with that name are sent to the listener.
+//
+// If since is not nil, buffered events from the epoch and with a sequence
+// number higher than the ones given for the member they originate from are
+// replayed to the listener before any new event. Events from members not in
+// the map aren't replayed.
+func (s *Server) AddListener(group string, connection *websocket.Conn, messageTypes []string, location string, noForward bool, locationFilter string, since map[string]Position) (*Listener, error) {
 	listener := &Listener{
-		group:        group,
-		connection:   connection,
-		messageTypes: messageTypes,
-		location:     location,
-		noForward:    noForward,
-		active:       make(chan bool, 1),
-		id:           uuid.NewRandom().String(),
+		group:          group,
+		connection:     connection,
+		messageTypes:   messageTypes,
+		location:       location,
+		locationFilter: locationFilter,
+		noForward:      noForward,
+		active:         make(chan bool, 1),
+		id:             uuid.NewRandom().String(),
 	}
 
 	s.lock.Lock()
-	defer s.lock.Unlock()
 
 	if s.listeners[listener.id] != nil {
+		s.lock.Unlock()
 		return nil, fmt.Errorf("A listener with id '%s' already exists", listener.id)
 	}
 
+	replay := []api.Event{}
+	if since != nil {
+		replay = s.replay(listener, since)
+	}
+
 	s.listeners[listener.id] = listener
 
+	// Hold the listener lock until the replayed events are sent, so that
+	// they go out before any new event.
+	listener.lock.Lock()
+	defer listener.lock.Unlock()
+	s.lock.Unlock()
+
+	for _, event := range replay {
+		err := listener.send(event)
+		if err != nil {
+			s.lock.Lock()
+			delete(s.listeners, listener.id)
+			s.lock.Unlock()
+			return nil, err
+		}
+	}
+
 	return listener, nil
 }
 
+// Return the buffered events that the given listener should get replayed.
+//
+// Must be called with the server lock held.
+func (s *Server) replay(listener *Listener, since map[string]Position) []api.Event {
+	events := []api.Event{}
+
+	// The buffer is full once it wrapped around, in which case the oldest
+	// event is the one that will be overwritten next.
+	start := 0
+	if len(s.buffer) == bufferSize {
+		start = s.bufferNext
+	}
+
+	for i := 0; i < len(s.buffer); i++ {
+		entry := s.buffer[(start+i)%len(s.buffer)]
+
+		if !listener.wants(entry.group, entry.event, entry.isForward) {
+			continue
+		}
+
+		location := entry.event.Location
+		if location == "" {
+			location = listener.location
+		}
+
+		position, ok := since[location]
+		if !ok {
+			continue
+		}
+
+		if entry.event.Epoch != position.Epoch || entry.event.Sequence <= position.Sequence {
+			continue
+		}
+
+		events = append(events, entry.event)
+	}
+
+	return events
+}
+
+// AddHandler registers a function called in the background with every event
+// originating from this server, except logging ones.
+func (s *Server) AddHandler(handler HandlerFunc) {
+	s.lock.Lock()
+	s.handlers = append(s.handlers, handler)
+	s.lock.Unlock()
+}
+
 // SendLifecycle broadcasts a lifecycle event.
 func (s *Server) SendLifecycle(group, action, source string,
 	context map[string]interface{}) error {
@@ -79,6 +275,7 @@ func (s *Server) Send(group, eventType string, eventMessage interface{}) error {
 		Type:      eventType,
 		Timestamp: time.Now(),
 		Metadata:  encodedMessage,
+		Project:   group,
 	}
 
 	return s.broadcast(group, event, false)
@@ -103,7 +300,8 @@ func (s *Server) Forward(id int64, event api.Event) {
 		}
 	}
 
-	err := s.broadcast("", event, true)
+	// Forwarded events keep the project they were sent to.
+	err := s.broadcast(event.Project, event, true)
 	if err != nil {
 		logger.Warnf("Failed to forward event from node %d: %v", id, err)
 	}
@@ -111,17 +309,35 @@ func (s *Server) Forward(id int64, event api.Event) {
 
 func (s *Server) broadcast(group string, event api.Event, isForward bool) error {
 	s.lock.Lock()
-	listeners := s.listeners
-	for _, listener := range listeners {
-		if group != "" && listener.group != "*" && group != listener.group {
-			continue
+
+	// Logging events are too many to be worth replaying, so only the
+	// other events originating from this server get the next sequence
+	// number, while forwarded ones keep the one set by their origin.
+	if !isForward && event.Type != "logging" {
+		s.sequence++
+		event.Epoch = s.epoch
+		event.Sequence = s.sequence
+	}
+
+	if event.Type != "logging" {
+		entry := bufferedEvent{group: group, event: event, isForward: isForward}
+		if len(s.buffer) < bufferSize {
+			s.buffer = append(s.buffer, entry)
+		} else {
+			s.buffer[s.bufferNext] = entry
 		}
+		s.bufferNext = (s.bufferNext + 1) % bufferSize
 
-		if isForward && listener.noForward {
-			continue
+		if !isForward {
+			for _, handler := range s.handlers {
+				go handler(group, event)
+			}
 		}
+	}
 
-		if !shared.StringInSlice(event.Type, listener.messageTypes) {
+	listeners := s.listeners
+	for _, listener := range listeners {
+		if !listener.wants(group, event, isForward) {
 			continue
 		}
 
@@ -140,24 +356,7 @@ func (s *Server) broadcast(group string, event api.Event, isForward bool) error
 				return
 			}
 
-			// Set the Location to the expected serverName
-			if event.Location == "" {
-				eventCopy := api.Event{}
-				err := shared.DeepCopy(&event, &eventCopy)
-				if err != nil {
-					return
-				}
-				eventCopy.Location = listener.location
-
-				event = eventCopy
-			}
-
-			body, err := json.Marshal(event)
-			if err != nil {
-				return
-			}
-
-			err = listener.connection.WriteMessage(websocket.TextMessage, body)
+			err := listener.send(event)
 			if err != nil {
 				// Remove the listener from the list
 				s.lock.Lock()
@@ -188,12 +387,67 @@ type Listener struct {
 	done         bool
 	location     string
 
+	// If not empty, only events originating from the cluster member with
+	// this name are sent to the listener.
+	locationFilter string
+
 	// If true, this listener won't get events forwarded from other
 	// nodes. It only used by listeners created internally by LXD nodes
 	// connecting to other LXD nodes to get their local events only.
 	noForward bool
 }
 
+// Check whether an event should be sent to the listener.
+func (e *Listener) wants(group string, event api.Event, isForward bool) bool {
+	if group != "" && e.group != "*" && group != e.group {
+		return false
+	}
+
+	if isForward && e.noForward {
+		return false
+	}
+
+	if !shared.StringInSlice(event.Type, e.messageTypes) {
+		return false
+	}
+
+	if e.locationFilter != "" {
+		location := event.Location
+		if location == "" {
+			location = e.location
+		}
+
+		if location != e.locationFilter {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Send an event over the listener connection. Must be called with the
+// listener lock held.
+func (e *Listener) send(event api.Event) error {
+	// Set the Location to the expected serverName
+	if event.Location == "" {
+		eventCopy := api.Event{}
+		err := shared.DeepCopy(&event, &eventCopy)
+		if err != nil {
+			return err
+		}
+		eventCopy.Location = e.location
+
+		event = eventCopy
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return e.connection.WriteMessage(websocket.TextMessage, body)
+}
+
 // MessageTypes returns a list of message types the listener will be notified of.
 func (e *Listener) MessageTypes() []string {
 	return e.messageTypes
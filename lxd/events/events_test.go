@@ -0,0 +1,155 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// Local events get increasing sequence numbers and are replayed starting
+// after the given one.
+func TestServer_Replay(t *testing.T) {
+	s := NewServer(false, false)
+
+	for i := 0; i < 3; i++ {
+		err := s.SendLifecycle("default", "instance-started", "/1.0/instances/c1", nil)
+		require.NoError(t, err)
+	}
+
+	listener := &Listener{group: "default", messageTypes: []string{"lifecycle"}, location: "node1"}
+
+	events := s.replay(listener, map[string]Position{"node1": {Epoch: s.Epoch(), Sequence: 1}})
+	require.Len(t, events, 2)
+	assert.Equal(t, s.Epoch(), events[0].Epoch)
+	assert.Equal(t, int64(2), events[0].Sequence)
+	assert.Equal(t, int64(3), events[1].Sequence)
+
+	// Members not listed don't get replayed.
+	events = s.replay(listener, map[string]Position{"node2": {Epoch: s.Epoch()}})
+	assert.Len(t, events, 0)
+}
+
+// Positions from a previous run of a member are rejected.
+func TestServer_CheckSince(t *testing.T) {
+	s := NewServer(false, false)
+
+	s.Forward(2, api.Event{Type: "lifecycle", Location: "node2", Epoch: "old", Sequence: 7})
+	s.Forward(2, api.Event{Type: "lifecycle", Location: "node2", Epoch: "new", Sequence: 1})
+
+	assert.NoError(t, s.CheckSince("node1", map[string]Position{"node1": {Epoch: s.Epoch(), Sequence: 10}}))
+	assert.Error(t, s.CheckSince("node1", map[string]Position{"node1": {Epoch: "old", Sequence: 10}}))
+
+	assert.NoError(t, s.CheckSince("node1", map[string]Position{"node2": {Epoch: "new", Sequence: 1}}))
+	assert.Error(t, s.CheckSince("node1", map[string]Position{"node2": {Epoch: "old", Sequence: 7}}))
+
+	// Members without buffered events can't be checked.
+	assert.NoError(t, s.CheckSince("node1", map[string]Position{"node3": {Epoch: "old", Sequence: 7}}))
+}
+
+// Positions followed by events dropped from the buffer are rejected.
+func TestServer_CheckSinceDropped(t *testing.T) {
+	s := NewServer(false, false)
+
+	// Logging events aren't numbered.
+	err := s.Send("", "logging", api.EventLogging{Message: "hello", Level: "info"})
+	require.NoError(t, err)
+
+	for i := 0; i < bufferSize+2; i++ {
+		err := s.SendLifecycle("default", "instance-started", "/1.0/instances/c1", nil)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, int64(bufferSize+2), s.sequence)
+	assert.Error(t, s.CheckSince("node1", map[string]Position{"node1": {Epoch: s.Epoch(), Sequence: 1}}))
+	assert.NoError(t, s.CheckSince("node1", map[string]Position{"node1": {Epoch: s.Epoch(), Sequence: 2}}))
+
+	s.Forward(2, api.Event{Type: "lifecycle", Location: "node2", Epoch: "e1", Sequence: 5})
+	assert.Error(t, s.CheckSince("node1", map[string]Position{"node2": {Epoch: "e1", Sequence: 3}}))
+	assert.NoError(t, s.CheckSince("node1", map[string]Position{"node2": {Epoch: "e1", Sequence: 4}}))
+}
+
+// Forwarded events keep their origin sequence number and location.
+func TestServer_ReplayForwarded(t *testing.T) {
+	s := NewServer(false, false)
+
+	s.Forward(2, api.Event{Type: "lifecycle", Location: "node2", Epoch: "e1", Sequence: 7})
+	s.Forward(2, api.Event{Type: "lifecycle", Location: "node2", Epoch: "e1", Sequence: 8})
+
+	listener := &Listener{group: "default", messageTypes: []string{"lifecycle"}, location: "node1"}
+
+	events := s.replay(listener, map[string]Position{"node2": {Epoch: "e1", Sequence: 7}})
+	require.Len(t, events, 1)
+	assert.Equal(t, int64(8), events[0].Sequence)
+
+	// Events from other epochs aren't replayed.
+	events = s.replay(listener, map[string]Position{"node2": {Epoch: "e0", Sequence: 0}})
+	assert.Len(t, events, 0)
+
+	// Internal listeners only get local events.
+	listener.noForward = true
+	events = s.replay(listener, map[string]Position{"node2": {Epoch: "e1", Sequence: 0}})
+	assert.Len(t, events, 0)
+}
+
+// Listeners with a location filter only get events from that member.
+func TestListener_LocationFilter(t *testing.T) {
+	listener := &Listener{group: "*", messageTypes: []string{"lifecycle"}, location: "node1", locationFilter: "node2"}
+
+	assert.False(t, listener.wants("", api.Event{Type: "lifecycle"}, false))
+	assert.True(t, listener.wants("", api.Event{Type: "lifecycle", Location: "node2"}, true))
+}
+
+// Only the most recent events are kept.
+func TestServer_BufferWrap(t *testing.T) {
+	s := NewServer(false, false)
+
+	for i := 0; i < bufferSize+10; i++ {
+		err := s.SendLifecycle("default", "instance-started", "/1.0/instances/c1", nil)
+		require.NoError(t, err)
+	}
+
+	listener := &Listener{group: "default", messageTypes: []string{"lifecycle"}, location: "node1"}
+
+	events := s.replay(listener, map[string]Position{"node1": {Epoch: s.Epoch()}})
+	require.Len(t, events, bufferSize)
+	assert.Equal(t, int64(11), events[0].Sequence)
+	assert.Equal(t, int64(bufferSize+10), events[bufferSize-1].Sequence)
+}
+
+// Handlers get local events, but neither forwarded nor logging ones.
+func TestServer_Handler(t *testing.T) {
+	s := NewServer(false, false)
+
+	received := make(chan string, 10)
+	s.AddHandler(func(group string, event api.Event) {
+		received <- group + " " + event.Type
+	})
+
+	s.Forward(2, api.Event{Type: "lifecycle", Location: "node2"})
+	require.NoError(t, s.Send("default", "logging", api.EventLogging{Level: "info"}))
+	require.NoError(t, s.SendLifecycle("default", "instance-started", "/1.0/instances/c1", nil))
+
+	assert.Equal(t, "default lifecycle", <-received)
+	assert.Len(t, received, 0)
+}
+
+// Forwarded events are only sent to the listeners of their project.
+func TestServer_ForwardProject(t *testing.T) {
+	s := NewServer(false, false)
+
+	s.Forward(2, api.Event{Type: "lifecycle", Location: "node2", Epoch: "e1", Sequence: 1, Project: "p1"})
+	s.Forward(2, api.Event{Type: "lifecycle", Location: "node2", Epoch: "e1", Sequence: 2})
+
+	listener := &Listener{group: "p2", messageTypes: []string{"lifecycle"}, location: "node1"}
+
+	events := s.replay(listener, map[string]Position{"node2": {Epoch: "e1"}})
+	require.Len(t, events, 1)
+	assert.Equal(t, int64(2), events[0].Sequence)
+
+	listener.group = "p1"
+	events = s.replay(listener, map[string]Position{"node2": {Epoch: "e1"}})
+	assert.Len(t, events, 2)
+}
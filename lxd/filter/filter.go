@@ -0,0 +1,380 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expression is a parsed filter expression, such as:
+//
+//	status eq Running and (config.user.team eq web or not name eq c1)
+//
+// Fields are the dot-separated paths of the JSON representation of the
+// filtered objects, values being compared as strings.
+type Expression struct {
+	root node
+}
+
+type node interface {
+	match(obj map[string]interface{}) bool
+	fields() []string
+}
+
+type and struct{ left, right node }
+type or struct{ left, right node }
+type not struct{ operand node }
+
+type comparison struct {
+	field    string
+	operator string
+	value    string
+}
+
+func (n and) match(obj map[string]interface{}) bool { return n.left.match(obj) && n.right.match(obj) }
+func (n or) match(obj map[string]interface{}) bool  { return n.left.match(obj) || n.right.match(obj) }
+func (n not) match(obj map[string]interface{}) bool { return !n.operand.match(obj) }
+
+func (n and) fields() []string { return append(n.left.fields(), n.right.fields()...) }
+func (n or) fields() []string  { return append(n.left.fields(), n.right.fields()...) }
+func (n not) fields() []string { return n.operand.fields() }
+
+func (n comparison) match(obj map[string]interface{}) bool {
+	value, _ := Lookup(obj, n.field)
+	equal := stringify(value) == n.value
+
+	if n.operator == "ne" {
+		return !equal
+	}
+
+	return equal
+}
+
+func (n comparison) fields() []string { return []string{n.field} }
+
+// Parse parses the given filter expression.
+func Parse(s string) (*Expression, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("Empty filter")
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("Unexpected %q in filter", p.tokens[p.pos].text)
+	}
+
+	return &Expression{root: root}, nil
+}
+
+// Match returns whether the given object matches the expression. The object
+// is compared through its JSON representation.
+func (e *Expression) Match(obj interface{}) (bool, error) {
+	m, err := toMap(obj)
+	if err != nil {
+		return false, err
+	}
+
+	return e.root.match(m), nil
+}
+
+// Fields returns the fields the expression refers to.
+func (e *Expression) Fields() []string {
+	return e.root.fields()
+}
+
+// Lookup returns the value of the field at the given dot-separated path.
+// Since map keys may themselves contain dots (e.g. config.user.team), the
+// longest matching key wins.
+func Lookup(obj map[string]interface{}, path string) (interface{}, bool) {
+	parts := strings.Split(path, ".")
+
+	for i := len(parts); i > 0; i-- {
+		value, ok := obj[strings.Join(parts[:i], ".")]
+		if !ok {
+			continue
+		}
+
+		if i == len(parts) {
+			return value, true
+		}
+
+		child, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		value, ok = Lookup(child, strings.Join(parts[i:], "."))
+		if ok {
+			return value, true
+		}
+	}
+
+	return nil, false
+}
+
+// Select returns a copy of the object only holding the given fields.
+func Select(obj interface{}, fields []string) (map[string]interface{}, error) {
+	m, err := toMap(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{}
+	for _, field := range fields {
+		selectField(m, result, strings.Split(field, "."))
+	}
+
+	return result, nil
+}
+
+// Copy the field at the given path from src to dst, creating the
+// intermediate maps.
+func selectField(src map[string]interface{}, dst map[string]interface{}, parts []string) {
+	for i := len(parts); i > 0; i-- {
+		key := strings.Join(parts[:i], ".")
+		value, ok := src[key]
+		if !ok {
+			continue
+		}
+
+		if i == len(parts) {
+			dst[key] = value
+			return
+		}
+
+		child, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		dstChild, ok := dst[key].(map[string]interface{})
+		if !ok {
+			dstChild = map[string]interface{}{}
+			dst[key] = dstChild
+		}
+
+		selectField(child, dstChild, parts[i:])
+		return
+	}
+}
+
+func toMap(obj interface{}) (map[string]interface{}, error) {
+	m, ok := obj.(map[string]interface{})
+	if ok {
+		return m, nil
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	m = map[string]interface{}{}
+	err = json.Unmarshal(data, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func stringify(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+
+		return string(data)
+	}
+}
+
+type token struct {
+	text   string
+	quoted bool
+}
+
+func tokenize(s string) ([]token, error) {
+	tokens := []token{}
+	runes := []rune(s)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, token{text: string(r)})
+			i++
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+
+			if end == len(runes) {
+				return nil, fmt.Errorf("Unterminated quoted value in filter")
+			}
+
+			tokens = append(tokens, token{text: string(runes[i+1 : end]), quoted: true})
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '(' && runes[end] != ')' {
+				end++
+			}
+
+			tokens = append(tokens, token{text: string(runes[i:end])})
+			i = end
+		}
+	}
+
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Whether the next token is the given keyword.
+func (p *parser) peek(keyword string) bool {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].quoted {
+		return false
+	}
+
+	return strings.ToLower(p.tokens[p.pos].text) == keyword
+}
+
+func (p *parser) next() (token, error) {
+	if p.pos >= len(p.tokens) {
+		return token{}, fmt.Errorf("Unexpected end of filter")
+	}
+
+	t := p.tokens[p.pos]
+	p.pos++
+
+	return t, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek("or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = or{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek("and") {
+		p.pos++
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		left = and{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.peek("not") {
+		p.pos++
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		return not{operand}, nil
+	}
+
+	if p.peek("(") {
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.peek(")") {
+			return nil, fmt.Errorf("Missing closing parenthesis in filter")
+		}
+
+		p.pos++
+
+		return expr, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	field, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	if field.quoted || field.text == "(" || field.text == ")" {
+		return nil, fmt.Errorf("Expected a field name in filter, got %q", field.text)
+	}
+
+	operator, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	op := strings.ToLower(operator.text)
+	if operator.quoted || (op != "eq" && op != "ne") {
+		return nil, fmt.Errorf("Invalid operator %q in filter, expected eq or ne", operator.text)
+	}
+
+	value, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	if !value.quoted && (value.text == "(" || value.text == ")") {
+		return nil, fmt.Errorf("Expected a value in filter, got %q", value.text)
+	}
+
+	return comparison{field: field.text, operator: op, value: value.text}, nil
+}
@@ -0,0 +1,89 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/filter"
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestExpression_Match(t *testing.T) {
+	instance := api.Instance{
+		Name:     "c1",
+		Status:   "Running",
+		Location: "node1",
+		InstancePut: api.InstancePut{
+			Config:    map[string]string{"user.team": "web", "limits.cpu": "2"},
+			Ephemeral: true,
+		},
+	}
+
+	cases := []struct {
+		filter string
+		match  bool
+	}{
+		{"name eq c1", true},
+		{"name ne c1", false},
+		{"status eq Running and config.user.team eq web", true},
+		{"status eq Running and config.user.team eq db", false},
+		{"status eq Stopped or config.user.team eq web", true},
+		{"not status eq Stopped", true},
+		{"(status eq Stopped or name eq c1) and location eq node1", true},
+		{"status eq Stopped or (name eq c1 and location eq node2)", false},
+		{"ephemeral eq true", true},
+		{"config.limits.cpu eq 2", true},
+		{"config.user.missing eq ''", true},
+		{`description eq "" AND name EQ 'c1'`, true},
+	}
+
+	for _, c := range cases {
+		expr, err := filter.Parse(c.filter)
+		require.NoError(t, err, c.filter)
+
+		match, err := expr.Match(instance)
+		require.NoError(t, err)
+		assert.Equal(t, c.match, match, c.filter)
+	}
+}
+
+func TestParse_Error(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"name",
+		"name eq",
+		"name is c1",
+		"(name eq c1",
+		"name eq c1 and",
+		"name eq c1 c2",
+		"name eq 'c1",
+	} {
+		_, err := filter.Parse(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestExpression_Fields(t *testing.T) {
+	expr, err := filter.Parse("name eq c1 or not config.user.team eq web")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"name", "config.user.team"}, expr.Fields())
+}
+
+func TestSelect(t *testing.T) {
+	instance := api.Instance{
+		Name:   "c1",
+		Status: "Running",
+		InstancePut: api.InstancePut{
+			Config: map[string]string{"user.team": "web", "limits.cpu": "2"},
+		},
+	}
+
+	result, err := filter.Select(instance, []string{"name", "config.user.team", "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"name":   "c1",
+		"config": map[string]interface{}{"user.team": "web"},
+	}, result)
+}
@@ -16,6 +16,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -268,7 +269,7 @@ func imgPostContInfo(d *Daemon, r *http.Request, req api.ImagesPost, op *operati
 		writer = io.MultiWriter(imageProgressWriter, sha256)
 	}
 
-	err = c.Export(writer, req.Properties)
+	err = c.Export(writer, req.Properties, req.CreatedAt)
 	// When compression is used, Close on imageProgressWriter/tarWriter
 	// is required for compressFile/gzip to know it is finished.
 	// Otherwise It is equivalent to imageFile.Close.
@@ -329,7 +330,7 @@ func imgPostRemoteInfo(d *Daemon, req api.ImagesPost, op *operations.Operation,
 		return nil, fmt.Errorf("must specify one of alias or fingerprint for init from image")
 	}
 
-	info, err := d.ImageDownload(op, req.Source.Server, req.Source.Protocol, req.Source.Certificate, req.Source.Secret, hash, req.Source.ImageType, false, req.AutoUpdate, "", false, project)
+	info, err := d.ImageDownload(op, req.Source.Server, req.Source.Protocol, req.Source.Certificate, req.Source.Secret, req.Source.RequireSignature, hash, req.Source.ImageType, false, req.AutoUpdate, "", false, project)
 	if err != nil {
 		return nil, err
 	}
@@ -398,7 +399,7 @@ func imgPostURLInfo(d *Daemon, req api.ImagesPost, op *operations.Operation, pro
 	}
 
 	// Import the image
-	info, err := d.ImageDownload(op, url, "direct", "", "", hash, "", false, req.AutoUpdate, "", false, project)
+	info, err := d.ImageDownload(op, url, "direct", "", "", req.Source.RequireSignature, hash, "", false, req.AutoUpdate, "", false, project)
 	if err != nil {
 		return nil, err
 	}
@@ -435,10 +436,23 @@ func getImgPostInfo(d *Daemon, r *http.Request, builddir string, project string,
 		ctype = "application/octet-stream"
 	}
 
+	sourceType := r.Header.Get("X-LXD-source-type")
+	if !shared.StringInSlice(sourceType, []string{"", "oci", "disk"}) {
+		return nil, fmt.Errorf("Unknown image source type %q", sourceType)
+	}
+
 	sha256 := sha256.New()
 	var size int64
 
 	if ctype == "multipart/form-data" {
+		if sourceType == "oci" {
+			return nil, fmt.Errorf("OCI images must be uploaded as a single tarball")
+		}
+
+		if sourceType == "disk" {
+			return nil, fmt.Errorf("Disk images must be uploaded as a single file")
+		}
+
 		// Create a temporary file for the image tarball
 		imageTarf, err := ioutil.TempFile(builddir, "lxd_tar_")
 		if err != nil {
@@ -535,7 +549,74 @@ func getImgPostInfo(d *Daemon, r *http.Request, builddir string, project string,
 				"dest":   imgfname})
 			return nil, err
 		}
+	} else if sourceType == "disk" {
+		// Convert raw disk images to split LXD virtual machine images.
+		architecture := ""
+		for _, ph := range propHeaders {
+			p, _ := url.ParseQuery(ph)
+			if p.Get("architecture") != "" {
+				architecture = p.Get("architecture")
+			}
+		}
+
+		if architecture == "" {
+			architecture, err = osarch.ArchitectureName(d.os.Architectures[0])
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		disk, err := imageDiskConvert(post.Name(), builddir, architecture, d.os.Architectures)
+		if err != nil {
+			logger.Error("Failed to convert disk image", log.Ctx{"err": err})
+			return nil, err
+		}
+		defer disk.Remove()
+
+		for _, fname := range []string{disk.MetadataFile, disk.RootfsFile} {
+			f, err := os.Open(fname)
+			if err != nil {
+				return nil, err
+			}
+
+			size, err = io.Copy(sha256, f)
+			f.Close()
+			if err != nil {
+				return nil, err
+			}
+			info.Size += size
+		}
+
+		info.Filename = r.Header.Get("X-LXD-filename")
+		info.Fingerprint = fmt.Sprintf("%x", sha256.Sum(nil))
+		info.Type = instancetype.VM.String()
+		imageMeta = disk.Metadata
+
+		err = shared.FileMove(disk.MetadataFile, shared.VarPath("images", info.Fingerprint))
+		if err != nil {
+			return nil, err
+		}
+
+		err = shared.FileMove(disk.RootfsFile, shared.VarPath("images", info.Fingerprint+".rootfs"))
+		if err != nil {
+			return nil, err
+		}
 	} else {
+		// Convert OCI images to unified LXD images.
+		if sourceType == "oci" {
+			compress, err := cluster.ConfigGetString(d.cluster, "images.compression_algorithm")
+			if err != nil {
+				return nil, err
+			}
+
+			post, err = imageOCIConvert(post.Name(), builddir, compress, d.os.RunningInUserNS, d.os.Architectures)
+			if err != nil {
+				logger.Error("Failed to convert OCI image", log.Ctx{"err": err})
+				return nil, err
+			}
+			defer post.Close()
+		}
+
 		post.Seek(0, 0)
 		size, err = io.Copy(sha256, post)
 		if err != nil {
@@ -626,6 +707,13 @@ func imageCreateInPool(d *Daemon, info *api.Image, storagePool string) error {
 		return fmt.Errorf("No storage pool specified")
 	}
 
+	// Reassemble the image files if stored as chunks.
+	release, err := imageChunksUnpack(d, info.Fingerprint)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	// Check if we can load new storage layer for pool driver type.
 	pool, err := storagePools.GetPoolByName(d.State(), storagePool)
 	if err != storageDrivers.ErrUnknownDriver {
@@ -715,6 +803,12 @@ func imagesPost(d *Daemon, r *http.Request) response.Response {
 		return response.InternalError(fmt.Errorf("Invalid images JSON"))
 	}
 
+	err = imageValidateAutoUpdatePolicy(req.AutoUpdatePolicy)
+	if err != nil {
+		cleanup(builddir, post)
+		return response.BadRequest(err)
+	}
+
 	/* Forward requests for containers on other nodes */
 	if !imageUpload && shared.StringInSlice(req.Source.Type, []string{"container", "snapshot"}) {
 		name := req.Source.Name
@@ -792,6 +886,25 @@ func imagesPost(d *Daemon, r *http.Request) response.Response {
 			}
 		}
 
+		// Apply any provided auto-update policy
+		if req.AutoUpdatePolicy != (api.ImageAutoUpdatePolicy{}) {
+			id, _, err := d.cluster.ImageGet(project, info.Fingerprint, false, false)
+			if err != nil {
+				return errors.Wrapf(err, "Fetch image %q", info.Fingerprint)
+			}
+
+			err = d.cluster.ImageAutoUpdatePolicyUpdate(id, req.AutoUpdatePolicy)
+			if err != nil {
+				return errors.Wrapf(err, "Set image auto-update policy")
+			}
+		}
+
+		// Move the image files to the chunked image store
+		err = imageChunksPack(d, info.Fingerprint)
+		if err != nil {
+			return errors.Wrapf(err, "Store image as chunks")
+		}
+
 		// Sync the images between each node in the cluster on demand
 		err = imageSyncBetweenNodes(d, project, info.Fingerprint)
 		if err != nil {
@@ -804,7 +917,7 @@ func imagesPost(d *Daemon, r *http.Request) response.Response {
 	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, db.OperationImageDownload, nil, nil, run, nil, nil)
 	if err != nil {
 		cleanup(builddir, post)
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
@@ -915,7 +1028,7 @@ func getImageMetadata(fname string) (*api.ImageMetadata, string, error) {
 	return &result, imageType, nil
 }
 
-func doImagesGet(d *Daemon, recursion bool, project string, public bool) (interface{}, error) {
+func doImagesGet(d *Daemon, r *http.Request, recursion bool, project string, public bool) (interface{}, error) {
 	results, err := d.cluster.ImagesGet(project, public)
 	if err != nil {
 		return []string{}, err
@@ -929,7 +1042,7 @@ func doImagesGet(d *Daemon, recursion bool, project string, public bool) (interf
 			url := fmt.Sprintf("/%s/images/%s", version.APIVersion, name)
 			resultString[i] = url
 		} else {
-			image, response := doImageGet(d.cluster, project, name, public)
+			image, response := doImageGet(d, r, project, name, public)
 			if response != nil {
 				continue
 			}
@@ -950,13 +1063,68 @@ func imagesGet(d *Daemon, r *http.Request) response.Response {
 	project := projectParam(r)
 	public := d.checkTrustedClient(r) != nil || AllowProjectPermission("images", "view")(d, r) != response.EmptySyncResponse
 
-	result, err := doImagesGet(d, util.IsRecursionRequest(r), project, public)
+	params, err := listParamsParse(r)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if params.enabled() {
+		return doImagesGetList(d, r, util.IsRecursionRequest(r), project, public, params)
+	}
+
+	result, err := doImagesGet(d, r, util.IsRecursionRequest(r), project, public)
 	if err != nil {
 		return response.SmartError(err)
 	}
 	return response.SyncResponse(true, result)
 }
 
+// Return the filtered, paginated and projected images of the project. Without
+// a filter, the images are paginated before being loaded.
+func doImagesGetList(d *Daemon, r *http.Request, recursion bool, project string, public bool, params *listParams) response.Response {
+	fingerprints, err := d.cluster.ImagesGet(project, public)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	entries := []listEntry{}
+	for _, fingerprint := range fingerprints {
+		entries = append(entries, listEntry{
+			key: fingerprint,
+			url: fmt.Sprintf("/%s/images/%s", version.APIVersion, fingerprint),
+		})
+	}
+
+	next := ""
+	if params.filter == nil {
+		entries, next = params.paginate(entries)
+		if !recursion {
+			return params.response(entries, next, false)
+		}
+	}
+
+	// Load the images
+	loaded := []listEntry{}
+	for _, entry := range entries {
+		image, resp := doImageGet(d, r, project, entry.key, public)
+		if resp != nil {
+			continue
+		}
+
+		entry.object = image
+		loaded = append(loaded, entry)
+	}
+
+	if params.filter != nil {
+		loaded, next, err = params.apply(loaded)
+		if err != nil {
+			return response.InternalError(err)
+		}
+	}
+
+	return params.response(loaded, next, recursion)
+}
+
 func autoUpdateImagesTask(d *Daemon) (task.Func, task.Schedule) {
 	f := func(ctx context.Context) {
 		opRun := func(op *operations.Operation) error {
@@ -995,7 +1163,19 @@ func autoUpdateImagesTask(d *Daemon) (task.Func, task.Schedule) {
 	return f, schedule
 }
 
+// Time of the last check of the images to auto-update, as the windows of their
+// auto-update policies are matched against the time elapsed since.
+var autoUpdateImagesLastCheck time.Time
+
 func autoUpdateImages(ctx context.Context, d *Daemon) error {
+	// The first check only matches the current minute.
+	now := time.Now()
+	since := autoUpdateImagesLastCheck
+	if since.IsZero() {
+		since = now.Add(-time.Minute)
+	}
+	autoUpdateImagesLastCheck = now
+
 	projectNames := []string{}
 	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		projects, err := tx.ProjectList(db.ProjectFilter{})
@@ -1014,7 +1194,7 @@ func autoUpdateImages(ctx context.Context, d *Daemon) error {
 	}
 
 	for _, project := range projectNames {
-		err := autoUpdateImagesInProject(ctx, d, project)
+		err := autoUpdateImagesInProject(ctx, d, project, since, now)
 		if err != nil {
 			return errors.Wrapf(err, "Unable to update images for project %s", project)
 		}
@@ -1023,7 +1203,7 @@ func autoUpdateImages(ctx context.Context, d *Daemon) error {
 	return nil
 }
 
-func autoUpdateImagesInProject(ctx context.Context, d *Daemon, project string) error {
+func autoUpdateImagesInProject(ctx context.Context, d *Daemon, project string, since time.Time, now time.Time) error {
 	images, err := d.cluster.ImagesGet(project, false)
 	if err != nil {
 		return errors.Wrap(err, "Unable to retrieve the list of images")
@@ -1040,6 +1220,20 @@ func autoUpdateImagesInProject(ctx context.Context, d *Daemon, project string) e
 			continue
 		}
 
+		if !imageAutoUpdateDue(info, since, now) {
+			logger.Debug("Outside of the image auto-update window", log.Ctx{"fp": fingerprint, "project": project})
+			continue
+		}
+
+		if info.AutoUpdatePolicy.NotifyOnly {
+			err := imageAutoUpdateNotify(d, info, project)
+			if err != nil {
+				logger.Error("Failed to check for image update", log.Ctx{"err": err, "fp": fingerprint, "project": project})
+			}
+
+			continue
+		}
+
 		// FIXME: since our APIs around image downloading don't support
 		//        cancelling, we run the function in a different
 		//        goroutine and simply abort when the context expires.
@@ -1100,11 +1294,27 @@ func autoUpdateImage(d *Daemon, op *operations.Operation, id int, info *api.Imag
 		op.UpdateMetadata(metadata)
 	}
 
+	// Only ever update pinned images to the fingerprint they're pinned to.
+	if info.AutoUpdatePolicy.Pin != "" {
+		upstream, err := d.ImageResolve(source.Server, source.Protocol, source.Certificate, source.Alias, info.Type)
+		if err != nil {
+			logger.Error("Failed to resolve the image update", log.Ctx{"err": err, "fp": fingerprint})
+			return err
+		}
+
+		if !imageAutoUpdatePinAllows(info, upstream) {
+			logger.Debug("Image update doesn't match the pin", log.Ctx{"fp": fingerprint, "upstream": upstream, "pin": info.AutoUpdatePolicy.Pin})
+			setRefreshResult(false)
+			return nil
+		}
+	}
+
 	// Update the image on each pool where it currently exists.
 	hash := fingerprint
+	deltasGenerated := false
 
 	for _, poolName := range poolNames {
-		newInfo, err := d.ImageDownload(op, source.Server, source.Protocol, source.Certificate, "", source.Alias, info.Type, false, true, poolName, false, project)
+		newInfo, err := d.ImageDownload(op, source.Server, source.Protocol, source.Certificate, "", source.RequireSignature, source.Alias, info.Type, false, true, poolName, false, project)
 
 		if err != nil {
 			logger.Error("Failed to update the image", log.Ctx{"err": err, "fp": fingerprint})
@@ -1117,6 +1327,17 @@ func autoUpdateImage(d *Daemon, op *operations.Operation, id int, info *api.Imag
 			continue
 		}
 
+		// Generate the deltas from the old image before any of its
+		// copies gets removed, to serve them to our own clients.
+		if !deltasGenerated {
+			deltasGenerated = true
+
+			err = imageDeltasGenerate(d, hash, fingerprint)
+			if err != nil {
+				logger.Warn("Failed to generate image deltas", log.Ctx{"err": err, "fp": hash})
+			}
+		}
+
 		newId, _, err := d.cluster.ImageGet("default", hash, false, true)
 		if err != nil {
 			logger.Error("Error loading image", log.Ctx{"err": err, "fp": hash})
@@ -1143,6 +1364,12 @@ func autoUpdateImage(d *Daemon, op *operations.Operation, id int, info *api.Imag
 			continue
 		}
 
+		err = d.cluster.ImageAutoUpdatePolicyUpdate(newId, info.AutoUpdatePolicy)
+		if err != nil {
+			logger.Error("Error setting auto-update policy", log.Ctx{"err": err, "fp": hash})
+			continue
+		}
+
 		// If we do have optimized pools, make sure we remove
 		// the volumes associated with the image.
 		if poolName != "" {
@@ -1159,27 +1386,12 @@ func autoUpdateImage(d *Daemon, op *operations.Operation, id int, info *api.Imag
 		return nil
 	}
 
-	// Remove main image file.
-	fname := filepath.Join(d.os.VarDir, "images", fingerprint)
-	if shared.PathExists(fname) {
-		err = os.Remove(fname)
-		if err != nil {
-			logger.Debugf("Error deleting image file %s: %s", fname, err)
-		}
-	}
-
-	// Remove the rootfs file for the image.
-	fname = filepath.Join(d.os.VarDir, "images", fingerprint) + ".rootfs"
-	if shared.PathExists(fname) {
-		err = os.Remove(fname)
-		if err != nil {
-			logger.Debugf("Error deleting image file %s: %s", fname, err)
-		}
-	}
+	// Remove the image files.
+	imageDeleteFromDisk(d, fingerprint)
 
 	// Remove the database entry for the image.
 	if err = d.cluster.ImageDelete(id); err != nil {
-		logger.Debugf("Error deleting image from database %s: %s", fname, err)
+		logger.Debugf("Error deleting image from database %s: %s", fingerprint, err)
 	}
 
 	setRefreshResult(true)
@@ -1256,6 +1468,11 @@ func pruneLeftoverImages(d *Daemon) {
 
 		// Check and delete leftovers
 		for _, entry := range entries {
+			// The chunked image store is cleaned up as images get deleted.
+			if entry.Name() == "chunks" {
+				continue
+			}
+
 			fp := strings.Split(entry.Name(), ".")[0]
 			if !shared.StringInSlice(fp, images) {
 				err = os.RemoveAll(shared.VarPath("images", entry.Name()))
@@ -1308,6 +1525,18 @@ func pruneExpiredImages(ctx context.Context, d *Daemon) error {
 		default:
 		}
 
+		// Keep the images instances were created from around, however
+		// long ago that was.
+		instances, err := d.cluster.ImageGetInstances(fp)
+		if err != nil {
+			continue
+		}
+
+		if len(instances) > 0 {
+			logger.Debug("Not expiring image still in use", log.Ctx{"fp": fp})
+			continue
+		}
+
 		// Get the IDs of all storage pools on which a storage volume
 		// for the requested image currently exists.
 		poolIDs, err := d.cluster.ImageGetPools(fp)
@@ -1380,6 +1609,24 @@ func imageDelete(d *Daemon, r *http.Request) response.Response {
 	project := projectParam(r)
 	fingerprint := mux.Vars(r)["fingerprint"]
 
+	// Refuse to delete images which instances were created from, unless
+	// forced to, as the client may not be able to see all of them.
+	if !isClusterNotification(r) && !shared.IsTrue(r.FormValue("force")) {
+		_, imgInfo, err := d.cluster.ImageGet(project, fingerprint, false, false)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		instances, err := d.cluster.ImageGetInstances(imgInfo.Fingerprint)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		if len(instances) > 0 {
+			return response.Conflict(fmt.Errorf("The image is still used by instances"))
+		}
+	}
+
 	deleteFromAllPools := func() error {
 		// Use the fingerprint we received in a LIKE query and use the full
 		// fingerprint we receive from the database in all further queries.
@@ -1430,7 +1677,7 @@ func imageDelete(d *Daemon, r *http.Request) response.Response {
 			}
 		}
 
-		imageDeleteFromDisk(imgInfo.Fingerprint)
+		imageDeleteFromDisk(d, imgInfo.Fingerprint)
 
 		err = d.cluster.ImageDelete(imgID)
 		if err != nil {
@@ -1468,7 +1715,7 @@ func imageDelete(d *Daemon, r *http.Request) response.Response {
 	}
 
 	deleteFromDisk := func() error {
-		imageDeleteFromDisk(fingerprint)
+		imageDeleteFromDisk(d, fingerprint)
 		return nil
 	}
 
@@ -1485,14 +1732,26 @@ func imageDelete(d *Daemon, r *http.Request) response.Response {
 
 	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, db.OperationImageDelete, resources, nil, rmimg, nil, nil)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
 }
 
 // Helper to delete an image file from the local images directory.
-func imageDeleteFromDisk(fingerprint string) {
+func imageDeleteFromDisk(d *Daemon, fingerprint string) {
+	simpleStreamsHashesForget(fingerprint)
+
+	// Remove the image from the chunked image store.
+	err := imageChunksDelete(d, fingerprint)
+	if err != nil {
+		logger.Errorf("Error deleting image chunks for %s: %s", fingerprint, err)
+	}
+
+	// Wait for the deltas being generated from or to the image.
+	_, unlock := imageChunksLockImage(fingerprint)
+	defer unlock()
+
 	// Remove main image file.
 	fname := shared.VarPath("images", fingerprint)
 	if shared.PathExists(fname) {
@@ -1510,17 +1769,100 @@ func imageDeleteFromDisk(fingerprint string) {
 			logger.Errorf("Error deleting image file %s: %s", fname, err)
 		}
 	}
+
+	// Remove the detached signatures of the image files.
+	for _, suffix := range []string{".sig", ".rootfs.sig"} {
+		fname = shared.VarPath("images", fingerprint) + suffix
+		if shared.PathExists(fname) {
+			err := os.Remove(fname)
+			if err != nil && !os.IsNotExist(err) {
+				logger.Errorf("Error deleting image signature %s: %s", fname, err)
+			}
+		}
+	}
+
+	// Remove the deltas to the rootfs of the image.
+	sources, err := imageDeltaSources(fingerprint)
+	if err != nil {
+		logger.Errorf("Error listing image deltas for %s: %s", fingerprint, err)
+		return
+	}
+
+	for _, source := range sources {
+		fname = imageDeltaPath(fingerprint, source)
+		err := os.Remove(fname)
+		if err != nil && !os.IsNotExist(err) {
+			logger.Errorf("Error deleting image delta %s: %s", fname, err)
+		}
+	}
 }
 
-func doImageGet(db *db.Cluster, project, fingerprint string, public bool) (*api.Image, response.Response) {
-	_, imgInfo, err := db.ImageGet(project, fingerprint, public, false)
+func doImageGet(d *Daemon, r *http.Request, project, fingerprint string, public bool) (*api.Image, response.Response) {
+	_, imgInfo, err := d.cluster.ImageGet(project, fingerprint, public, false)
 	if err != nil {
 		return nil, response.SmartError(err)
 	}
 
+	// Only trusted clients get to know what's using the image.
+	if !public {
+		imgInfo.UsedBy, err = imageUsedBy(d, r, imgInfo.Fingerprint)
+		if err != nil {
+			return nil, response.SmartError(err)
+		}
+	}
+
 	return imgInfo, nil
 }
 
+// Return the URLs of the instances created from the image, in the projects
+// the user can view, and of the storage pools holding an optimized volume of
+// it.
+func imageUsedBy(d *Daemon, r *http.Request, fingerprint string) ([]string, error) {
+	usedBy := []string{}
+
+	instances, err := d.cluster.ImageGetInstances(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	projects := []string{}
+	for project := range instances {
+		if !d.userHasPermission(r, project, "view") {
+			continue
+		}
+
+		projects = append(projects, project)
+	}
+	sort.Strings(projects)
+
+	for _, project := range projects {
+		for _, name := range instances[project] {
+			uri := fmt.Sprintf("/%s/instances/%s", version.APIVersion, name)
+			if project != "default" {
+				uri += fmt.Sprintf("?project=%s", project)
+			}
+
+			usedBy = append(usedBy, uri)
+		}
+	}
+
+	poolIDs, err := d.cluster.ImageGetPools(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	poolNames, err := d.cluster.ImageGetPoolNamesFromIDs(poolIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pool := range poolNames {
+		usedBy = append(usedBy, fmt.Sprintf("/%s/storage-pools/%s", version.APIVersion, pool))
+	}
+
+	return usedBy, nil
+}
+
 func imageValidSecret(fingerprint string, secret string) bool {
 	for _, op := range operations.Operations() {
 		if op.Resources() == nil {
@@ -1557,7 +1899,7 @@ func imageGet(d *Daemon, r *http.Request) response.Response {
 	public := d.checkTrustedClient(r) != nil || AllowProjectPermission("images", "view")(d, r) != response.EmptySyncResponse
 	secret := r.FormValue("secret")
 
-	info, resp := doImageGet(d.cluster, project, fingerprint, false)
+	info, resp := doImageGet(d, r, project, fingerprint, false)
 	if resp != nil {
 		return resp
 	}
@@ -1566,6 +1908,10 @@ func imageGet(d *Daemon, r *http.Request) response.Response {
 		return response.NotFound(fmt.Errorf("Image '%s' not found", info.Fingerprint))
 	}
 
+	if public {
+		info.UsedBy = nil
+	}
+
 	etag := []interface{}{info.Public, info.AutoUpdate, info.Properties}
 	return response.SyncResponseETag(true, info, etag)
 }
@@ -1596,11 +1942,21 @@ func imagePut(d *Daemon, r *http.Request) response.Response {
 		info.ExpiresAt = req.ExpiresAt
 	}
 
+	err = imageValidateAutoUpdatePolicy(req.AutoUpdatePolicy)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	err = d.cluster.ImageUpdate(id, info.Filename, info.Size, req.Public, req.AutoUpdate, info.Architecture, info.CreatedAt, info.ExpiresAt, req.Properties)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	err = d.cluster.ImageAutoUpdatePolicyUpdate(id, req.AutoUpdatePolicy)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	return response.EmptySyncResponse
 }
 
@@ -1663,11 +2019,27 @@ func imagePatch(d *Daemon, r *http.Request) response.Response {
 		info.Properties = properties
 	}
 
+	// Get AutoUpdatePolicy
+	_, ok = reqRaw["auto_update_policy"]
+	if ok {
+		err = imageValidateAutoUpdatePolicy(req.AutoUpdatePolicy)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		info.AutoUpdatePolicy = req.AutoUpdatePolicy
+	}
+
 	err = d.cluster.ImageUpdate(id, info.Filename, info.Size, info.Public, info.AutoUpdate, info.Architecture, info.CreatedAt, info.ExpiresAt, info.Properties)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	err = d.cluster.ImageAutoUpdatePolicyUpdate(id, info.AutoUpdatePolicy)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	return response.EmptySyncResponse
 }
 
@@ -1789,7 +2161,7 @@ func imageAliasPut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(fmt.Errorf("The target field is required"))
 	}
 
-	imageId, _, err := d.cluster.ImageGet(project, req.Target, false, false)
+	imageId, image, err := d.cluster.ImageGet(project, req.Target, false, false)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -1799,6 +2171,11 @@ func imageAliasPut(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	// Generate deltas from the image the alias used to point to
+	if image.Fingerprint != alias.Target {
+		imageDeltasGenerateBackground(d, image.Fingerprint, alias.Target)
+	}
+
 	return response.EmptySyncResponse
 }
 
@@ -1822,6 +2199,8 @@ func imageAliasPatch(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	previous := alias.Target
+
 	_, ok := req["target"]
 	if ok {
 		target, err := req.GetString("target")
@@ -1842,7 +2221,7 @@ func imageAliasPatch(d *Daemon, r *http.Request) response.Response {
 		alias.Description = description
 	}
 
-	imageId, _, err := d.cluster.ImageGet(project, alias.Target, false, false)
+	imageId, image, err := d.cluster.ImageGet(project, alias.Target, false, false)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -1852,6 +2231,11 @@ func imageAliasPatch(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	// Generate deltas from the image the alias used to point to
+	if image.Fingerprint != previous {
+		imageDeltasGenerateBackground(d, image.Fingerprint, previous)
+	}
+
 	return response.EmptySyncResponse
 }
 
@@ -1931,6 +2315,41 @@ func imageExport(d *Daemon, r *http.Request) response.Response {
 	imagePath := shared.VarPath("images", imgInfo.Fingerprint)
 	rootfsPath := imagePath + ".rootfs"
 
+	if shared.IsTrue(r.FormValue("signature")) {
+		return imageExportSignatures(r, imgInfo.Fingerprint, imagePath, rootfsPath)
+	}
+
+	if shared.IsTrue(r.FormValue("deltas")) {
+		sources, err := imageDeltaSources(imgInfo.Fingerprint)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.SyncResponse(true, sources)
+	}
+
+	if shared.IsTrue(r.FormValue("chunks")) || r.FormValue("chunk") != "" {
+		return imageExportChunks(d, r, imgInfo.Fingerprint, r.FormValue("chunk"))
+	}
+
+	// Reassemble the image files if stored as chunks.
+	release, err := imageChunksUnpack(d, imgInfo.Fingerprint)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return &imageChunksResponse{
+		Response: imageExportFiles(r, imgInfo, imagePath, rootfsPath),
+		release:  release,
+	}
+}
+
+// Return the image files, or the delta to the image rootfs if requested.
+func imageExportFiles(r *http.Request, imgInfo *api.Image, imagePath string, rootfsPath string) response.Response {
+	if r.FormValue("delta") != "" {
+		return imageExportDelta(r, imgInfo, imagePath, r.FormValue("delta"))
+	}
+
 	_, ext, _, err := shared.DetectCompression(imagePath)
 	if err != nil {
 		ext = ""
@@ -1971,6 +2390,34 @@ func imageExport(d *Daemon, r *http.Request) response.Response {
 	return response.FileResponse(r, files, nil, false)
 }
 
+// Return the detached signatures of the image files, recorded when the image
+// was downloaded.
+func imageExportSignatures(r *http.Request, fingerprint string, imagePath string, rootfsPath string) response.Response {
+	if !shared.PathExists(imagePath + ".sig") {
+		return response.NotFound(fmt.Errorf("Image '%s' isn't signed", fingerprint))
+	}
+
+	files := []response.FileResponseEntry{{
+		Identifier: "metadata",
+		Path:       imagePath + ".sig",
+		Filename:   fmt.Sprintf("%s.sig", fingerprint),
+	}}
+
+	if shared.PathExists(rootfsPath) {
+		if !shared.PathExists(rootfsPath + ".sig") {
+			return response.NotFound(fmt.Errorf("Image '%s' isn't signed", fingerprint))
+		}
+
+		files = append(files, response.FileResponseEntry{
+			Identifier: "rootfs",
+			Path:       rootfsPath + ".sig",
+			Filename:   fmt.Sprintf("%s.rootfs.sig", fingerprint),
+		})
+	}
+
+	return response.FileResponse(r, files, nil, false)
+}
+
 func imageSecret(d *Daemon, r *http.Request) response.Response {
 	project := projectParam(r)
 	fingerprint := mux.Vars(r)["fingerprint"]
@@ -1993,7 +2440,7 @@ func imageSecret(d *Daemon, r *http.Request) response.Response {
 
 	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassToken, db.OperationImageToken, resources, meta, nil, nil, nil)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
@@ -2082,7 +2529,7 @@ func imageRefresh(d *Daemon, r *http.Request) response.Response {
 
 	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, db.OperationImageRefresh, nil, nil, run, nil, nil)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
@@ -2231,6 +2678,16 @@ func imageSyncBetweenNodes(d *Daemon, project string, fingerprint string) error
 	// Select the right project
 	client = client.UseProject(project)
 
+	// Only send the missing chunks of images stored as chunks.
+	synced, err := imageChunksSyncToNode(d, client, project, fingerprint)
+	if err != nil {
+		return err
+	}
+
+	if synced {
+		return nil
+	}
+
 	createArgs := &lxd.ImageCreateArgs{}
 	imageMetaPath := shared.VarPath("images", fingerprint)
 	imageRootfsPath := shared.VarPath("images", fingerprint+".rootfs")
@@ -0,0 +1,599 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/chunkstore"
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/node"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
+)
+
+// Serializes the changes to the index of the chunked image store with the
+// removal of the chunks no image is made of anymore.
+var imageChunksLock sync.Mutex
+
+// State of the files of an image being packed, unpacked or used.
+type imageChunksImage struct {
+	lock  sync.Mutex // Serializes the packing and unpacking of the files
+	refs  int        // Number of goroutines holding or waiting for the lock
+	users int        // Number of users of the whole files, which must be kept around until they're all done
+}
+
+// State of the files of each image, and the lock protecting the map.
+var imageChunksImages = map[string]*imageChunksImage{}
+var imageChunksImagesLock sync.Mutex
+
+// Lock the files of the given image, returning their state and the function
+// unlocking them.
+func imageChunksLockImage(fingerprint string) (*imageChunksImage, func()) {
+	imageChunksImagesLock.Lock()
+	image := imageChunksImages[fingerprint]
+	if image == nil {
+		image = &imageChunksImage{}
+		imageChunksImages[fingerprint] = image
+	}
+	image.refs++
+	imageChunksImagesLock.Unlock()
+
+	image.lock.Lock()
+
+	return image, func() {
+		image.lock.Unlock()
+
+		imageChunksImagesLock.Lock()
+		image.refs--
+		if image.refs == 0 && image.users == 0 {
+			delete(imageChunksImages, fingerprint)
+		}
+		imageChunksImagesLock.Unlock()
+	}
+}
+
+// Return the chunked image store of this node.
+func imageChunksStore() *chunkstore.Store {
+	return chunkstore.NewStore(shared.VarPath("images", "chunks"))
+}
+
+// Return the names of the files of the given image in the images directory,
+// keyed by their export identifier.
+func imageChunksFiles(fingerprint string) map[string]string {
+	return map[string]string{
+		"metadata": fingerprint,
+		"rootfs":   fingerprint + ".rootfs",
+	}
+}
+
+// Return the chunks making up the files of the given image, keyed by their
+// export identifier. The result is empty if the image isn't stored as chunks.
+func imageChunksIndex(d *Daemon, fingerprint string) (map[string][]chunkstore.Chunk, error) {
+	index := map[string][]chunkstore.Chunk{}
+
+	err := d.db.Transaction(func(tx *db.NodeTx) error {
+		for identifier, file := range imageChunksFiles(fingerprint) {
+			chunks, err := tx.ImageChunksGet(file)
+			if err != nil {
+				return err
+			}
+
+			if len(chunks) > 0 {
+				index[identifier] = chunks
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+// imageChunksPack moves the files of the given image into the chunked image
+// store, if enabled. The whole files are kept until released by their current
+// users, if any.
+func imageChunksPack(d *Daemon, fingerprint string) error {
+	enabled, err := cluster.ConfigGetBool(d.cluster, "images.chunked_store")
+	if err != nil {
+		return err
+	}
+
+	if !enabled {
+		return nil
+	}
+
+	image, unlock := imageChunksLockImage(fingerprint)
+	defer unlock()
+
+	store := imageChunksStore()
+	packed := map[string][]chunkstore.Chunk{}
+	hashes := map[string]string{}
+
+	for _, file := range imageChunksFiles(fingerprint) {
+		path := shared.VarPath("images", file)
+		if !shared.PathExists(path) {
+			continue
+		}
+
+		chunks, hash, err := imageChunksSplit(store, path)
+		if err != nil {
+			return err
+		}
+
+		if len(chunks) == 0 {
+			continue
+		}
+
+		packed[file] = chunks
+		hashes[file] = hash
+	}
+
+	err = imageChunksIndexFiles(d, store, packed, hashes)
+	if err != nil {
+		return err
+	}
+
+	if image.users > 0 {
+		return nil
+	}
+
+	for file := range packed {
+		path := shared.VarPath("images", file)
+		err := os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Split the given image file into chunks added to the store, returning them
+// along with the SHA-256 of the whole file.
+func imageChunksSplit(store *chunkstore.Store, path string) ([]chunkstore.Chunk, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	chunks, err := store.Split(io.TeeReader(f, hasher))
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "Failed to split image file %q into chunks", path)
+	}
+
+	return chunks, fmt.Sprintf("%x", hasher.Sum(nil)), nil
+}
+
+// Add the given image files, already split into chunks, to the index of the
+// chunked image store along with their hash.
+//
+// Splitting the files may take a while, so it's done without holding the
+// index lock. The chunks removed in the meantime by the deletion of another
+// image are added again once the lock is held.
+func imageChunksIndexFiles(d *Daemon, store *chunkstore.Store, packed map[string][]chunkstore.Chunk, hashes map[string]string) error {
+	imageChunksLock.Lock()
+	defer imageChunksLock.Unlock()
+
+	for file, chunks := range packed {
+		if imageChunksComplete(store, chunks) {
+			continue
+		}
+
+		chunks, _, err := imageChunksSplit(store, shared.VarPath("images", file))
+		if err != nil {
+			return err
+		}
+
+		packed[file] = chunks
+	}
+
+	return d.db.Transaction(func(tx *db.NodeTx) error {
+		for file, chunks := range packed {
+			err := tx.ImageChunksSet(file, chunks)
+			if err != nil {
+				return err
+			}
+
+			err = tx.ImageChunksHashSet(file, hashes[file])
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Check whether all the given chunks are in the store.
+func imageChunksComplete(store *chunkstore.Store, chunks []chunkstore.Chunk) bool {
+	for _, chunk := range chunks {
+		if !store.Has(chunk.Hash) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// imageChunksUnpack makes sure the files of the given image are available
+// whole in the images directory, reassembling them from the chunked image
+// store if needed. The returned function must be called once done with them.
+func imageChunksUnpack(d *Daemon, fingerprint string) (func(), error) {
+	image, unlock := imageChunksLockImage(fingerprint)
+	defer unlock()
+
+	index, err := imageChunksIndex(d, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	store := imageChunksStore()
+	files := imageChunksFiles(fingerprint)
+
+	for identifier, chunks := range index {
+		path := shared.VarPath("images", files[identifier])
+		if shared.PathExists(path) {
+			continue
+		}
+
+		err := imageChunksAssemble(store, chunks, path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	image.users++
+
+	return func() { imageChunksRelease(d, fingerprint) }, nil
+}
+
+// Reassemble the given chunks into a file at the given path.
+func imageChunksAssemble(store *chunkstore.Store, chunks []chunkstore.Chunk, path string) error {
+	f, err := os.OpenFile(path+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	err = store.Assemble(chunks, f)
+	if err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+
+	if err != nil {
+		os.Remove(path + ".tmp")
+		return errors.Wrapf(err, "Failed to reassemble image file %q", path)
+	}
+
+	return nil
+}
+
+// Drop a user of the whole files of the given image, removing them once
+// unused if the image is stored as chunks.
+func imageChunksRelease(d *Daemon, fingerprint string) {
+	image, unlock := imageChunksLockImage(fingerprint)
+	defer unlock()
+
+	image.users--
+	if image.users > 0 {
+		return
+	}
+
+	index, err := imageChunksIndex(d, fingerprint)
+	if err != nil {
+		logger.Warnf("Failed to get the chunks of image %s: %v", fingerprint, err)
+		return
+	}
+
+	files := imageChunksFiles(fingerprint)
+	for identifier := range index {
+		path := shared.VarPath("images", files[identifier])
+		err := os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			logger.Warnf("Failed to remove unpacked image file %s: %v", path, err)
+		}
+	}
+}
+
+// imageChunksDelete drops the given image from the chunked image store,
+// removing the chunks no other image is made of.
+func imageChunksDelete(d *Daemon, fingerprint string) error {
+	_, unlock := imageChunksLockImage(fingerprint)
+	defer unlock()
+
+	imageChunksLock.Lock()
+	defer imageChunksLock.Unlock()
+
+	// Chunks are only removed from the store once the transaction is
+	// committed, so that a failed deletion doesn't leave indexed chunks
+	// missing.
+	unused := []string{}
+	err := d.db.Transaction(func(tx *db.NodeTx) error {
+		unused = []string{}
+		hashes := map[string]bool{}
+
+		for _, file := range imageChunksFiles(fingerprint) {
+			chunks, err := tx.ImageChunksGet(file)
+			if err != nil {
+				return err
+			}
+
+			for _, chunk := range chunks {
+				hashes[chunk.Hash] = true
+			}
+
+			err = tx.ImageChunksDelete(file)
+			if err != nil {
+				return err
+			}
+		}
+
+		for hash := range hashes {
+			used, err := tx.ImageChunkUsed(hash)
+			if err != nil {
+				return err
+			}
+
+			if !used {
+				unused = append(unused, hash)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	store := imageChunksStore()
+	for _, hash := range unused {
+		err := store.Remove(hash)
+		if err != nil {
+			logger.Warnf("Failed to remove unused image chunk %s: %v", hash, err)
+		}
+	}
+
+	return nil
+}
+
+// imageChunksResponse releases the whole files of an image once they've been
+// served.
+type imageChunksResponse struct {
+	response.Response
+	release func()
+}
+
+func (r *imageChunksResponse) Render(w http.ResponseWriter) error {
+	defer r.release()
+	return r.Response.Render(w)
+}
+
+// Return the chunk index of the image, or one of its chunks if a hash is
+// given.
+func imageExportChunks(d *Daemon, r *http.Request, fingerprint string, hash string) response.Response {
+	index, err := imageChunksIndex(d, fingerprint)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if len(index) == 0 {
+		return response.NotFound(fmt.Errorf("Image '%s' isn't stored as chunks", fingerprint))
+	}
+
+	if hash == "" {
+		return response.SyncResponse(true, index)
+	}
+
+	if !chunkstore.ValidHash(hash) {
+		return response.BadRequest(fmt.Errorf("Invalid chunk %q", hash))
+	}
+
+	// Only serve the chunks of the requested image.
+	for _, chunks := range index {
+		for _, chunk := range chunks {
+			if chunk.Hash != hash {
+				continue
+			}
+
+			files := []response.FileResponseEntry{{
+				Identifier: "chunk",
+				Path:       imageChunksStore().Path(hash),
+				Filename:   hash,
+			}}
+
+			return response.FileResponse(r, files, nil, false)
+		}
+	}
+
+	return response.NotFound(fmt.Errorf("Image '%s' has no chunk '%s'", fingerprint, hash))
+}
+
+type internalImageChunksPost struct {
+	Project     string `json:"project" yaml:"project"`
+	Fingerprint string `json:"fingerprint" yaml:"fingerprint"`
+	Address     string `json:"address" yaml:"address"`
+}
+
+var internalImageChunksCmd = APIEndpoint{
+	Path: "image-chunks",
+
+	Post: APIEndpointAction{Handler: internalImageChunksSync},
+}
+
+// Fetch the chunks of an image this node is missing from the cluster member
+// holding it, and record that this node now has the image.
+func internalImageChunksSync(d *Daemon, r *http.Request) response.Response {
+	req := internalImageChunksPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	source, err := cluster.Connect(req.Address, d.endpoints.NetworkCert(), true)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	exportURL := fmt.Sprintf("/%s/images/%s/export?project=%s", version.APIVersion, req.Fingerprint, url.QueryEscape(req.Project))
+
+	resp, _, err := source.RawQuery("GET", exportURL+"&chunks=1", nil, "")
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	index := map[string][]chunkstore.Chunk{}
+	err = resp.MetadataAsStruct(&index)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	info, err := source.GetConnectionInfo()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	httpClient, err := source.GetHTTPClient()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	store := imageChunksStore()
+	files := imageChunksFiles(req.Fingerprint)
+
+	fetch := func() error {
+		for _, chunks := range index {
+			for _, chunk := range chunks {
+				if store.Has(chunk.Hash) {
+					continue
+				}
+
+				err := imageChunksFetch(httpClient, info.URL+exportURL+"&chunk="+chunk.Hash, store, chunk)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}
+
+	// Fetching the chunks may take a while, so only hold the lock to index
+	// them. The chunks removed in the meantime by the deletion of another
+	// image are fetched again once the lock is held.
+	err = fetch()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	_, unlock := imageChunksLockImage(req.Fingerprint)
+	defer unlock()
+
+	imageChunksLock.Lock()
+	defer imageChunksLock.Unlock()
+
+	err = fetch()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = d.db.Transaction(func(tx *db.NodeTx) error {
+		for identifier, chunks := range index {
+			err := tx.ImageChunksSet(files[identifier], chunks)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = d.cluster.ImageAssociateNode(req.Project, req.Fingerprint)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// Download the given chunk into the store, checking its content.
+func imageChunksFetch(httpClient *http.Client, url string, store *chunkstore.Store, chunk chunkstore.Chunk) error {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unable to fetch chunk %s: %s", chunk.Hash, resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if fmt.Sprintf("%x", sha256.Sum256(data)) != chunk.Hash {
+		return fmt.Errorf("Chunk %s is corrupted", chunk.Hash)
+	}
+
+	_, err = store.Put(data)
+	return err
+}
+
+// Send the given image to another cluster member as chunks, the member only
+// fetching the chunks it's missing. Returns false if the image isn't stored
+// as chunks.
+func imageChunksSyncToNode(d *Daemon, client lxd.InstanceServer, project string, fingerprint string) (bool, error) {
+	index, err := imageChunksIndex(d, fingerprint)
+	if err != nil {
+		return false, err
+	}
+
+	if len(index) == 0 {
+		return false, nil
+	}
+
+	address, err := node.ClusterAddress(d.db)
+	if err != nil {
+		return false, err
+	}
+
+	req := internalImageChunksPost{
+		Project:     project,
+		Fingerprint: fingerprint,
+		Address:     address,
+	}
+
+	_, _, err = client.RawQuery("POST", "/internal/image-chunks", req, "")
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to sync image chunks")
+	}
+
+	return true, nil
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// Deltas are only ever generated between full image fingerprints.
+var imageDeltaSourceRegexp = regexp.MustCompile("^[0-9a-f]{64}$")
+
+// Serializes delta generation, xdelta3 being both CPU and I/O intensive.
+var imageDeltasLock sync.Mutex
+
+// Return the path of the delta to the rootfs of the given image from the
+// rootfs of the source image.
+func imageDeltaPath(fingerprint string, source string) string {
+	return shared.VarPath("images", fmt.Sprintf("%s.rootfs.delta-%s", fingerprint, source))
+}
+
+// Return the fingerprints of the images the given image has a rootfs delta
+// from.
+func imageDeltaSources(fingerprint string) ([]string, error) {
+	prefix := shared.VarPath("images", fmt.Sprintf("%s.rootfs.delta-", fingerprint))
+
+	paths, err := filepath.Glob(prefix + "*")
+	if err != nil {
+		return nil, err
+	}
+
+	sources := []string{}
+	for _, path := range paths {
+		source := strings.TrimPrefix(path, prefix)
+		if !imageDeltaSourceRegexp.MatchString(source) {
+			continue
+		}
+
+		sources = append(sources, source)
+	}
+
+	return sources, nil
+}
+
+// Generate the deltas to the rootfs of the given image from the image it
+// replaces, and from as many of the replaced image's own delta sources as
+// allowed by images.delta_generations. Only split images stored on this node
+// get deltas.
+func imageDeltasGenerate(d *Daemon, fingerprint string, previous string) error {
+	generations, err := cluster.ConfigGetInt64(d.cluster, "images.delta_generations")
+	if err != nil {
+		return err
+	}
+
+	if generations <= 0 || fingerprint == previous {
+		return nil
+	}
+
+	// Reassemble the image files if stored as chunks.
+	release, err := imageChunksUnpack(d, fingerprint)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	_, err = exec.LookPath("xdelta3")
+	if err != nil {
+		return fmt.Errorf("Image deltas require xdelta3")
+	}
+
+	imageDeltasLock.Lock()
+	defer imageDeltasLock.Unlock()
+
+	// Keep the images from being deleted while generating their deltas.
+	// Deltas being generated one at a time, the images can't be locked in
+	// a different order by another generation.
+	_, unlock := imageChunksLockImage(fingerprint)
+	defer unlock()
+
+	rootfsPath := shared.VarPath("images", fingerprint+".rootfs")
+	if !shared.PathExists(rootfsPath) {
+		return nil
+	}
+
+	// Older generations come from the deltas of the replaced image, most
+	// recent first.
+	older, err := imageDeltaSources(previous)
+	if err != nil {
+		return err
+	}
+
+	created := map[string]int64{}
+	for _, source := range older {
+		_, image, err := d.cluster.ImageGetFromAnyProject(source)
+		if err == nil {
+			created[source] = image.CreatedAt.Unix()
+		}
+	}
+
+	sort.SliceStable(older, func(i, j int) bool {
+		return created[older[i]] > created[older[j]]
+	})
+
+	// Each image is only locked once.
+	sources := []string{}
+	checked := map[string]bool{fingerprint: true}
+	for _, source := range append([]string{previous}, older...) {
+		if int64(len(sources)) >= generations {
+			break
+		}
+
+		if checked[source] {
+			continue
+		}
+		checked[source] = true
+
+		// The source rootfs is needed to compute the delta.
+		release, err := imageChunksUnpack(d, source)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		_, unlock := imageChunksLockImage(source)
+		defer unlock()
+
+		if !shared.PathExists(shared.VarPath("images", source+".rootfs")) {
+			continue
+		}
+
+		sources = append(sources, source)
+	}
+
+	for _, source := range sources {
+		deltaPath := imageDeltaPath(fingerprint, source)
+		if shared.PathExists(deltaPath) {
+			continue
+		}
+
+		// Generate under a temporary name so partial deltas never get served.
+		_, err := shared.RunCommand("xdelta3", "-f", "-e", "-s", shared.VarPath("images", source+".rootfs"), rootfsPath, deltaPath+".tmp")
+		if err != nil {
+			os.Remove(deltaPath + ".tmp")
+			return errors.Wrapf(err, "Failed to generate delta from image %q", source)
+		}
+
+		err = os.Rename(deltaPath+".tmp", deltaPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Drop the deltas from generations which are now out of range.
+	existing, err := imageDeltaSources(fingerprint)
+	if err != nil {
+		return err
+	}
+
+	for _, source := range existing {
+		if !shared.StringInSlice(source, sources) {
+			os.Remove(imageDeltaPath(fingerprint, source))
+		}
+	}
+
+	return nil
+}
+
+// Generate the deltas for an image an alias got moved to in the background,
+// so that the API request isn't held up by it.
+func imageDeltasGenerateBackground(d *Daemon, fingerprint string, previous string) {
+	go func() {
+		err := imageDeltasGenerate(d, fingerprint, previous)
+		if err != nil {
+			logger.Warnf("Failed to generate deltas for image %s: %v", fingerprint, err)
+		}
+	}()
+}
+
+// Return the metadata of the image along with the delta to its rootfs from
+// the given source image.
+func imageExportDelta(r *http.Request, image *api.Image, imagePath string, source string) response.Response {
+	if !imageDeltaSourceRegexp.MatchString(source) {
+		return response.BadRequest(fmt.Errorf("Invalid delta source %q", source))
+	}
+
+	deltaPath := imageDeltaPath(image.Fingerprint, source)
+	if !shared.PathExists(deltaPath) {
+		return response.NotFound(fmt.Errorf("No delta from image '%s'", source))
+	}
+
+	_, ext, _, err := shared.DetectCompression(imagePath)
+	if err != nil {
+		ext = ""
+	}
+
+	files := []response.FileResponseEntry{
+		{
+			Identifier: "metadata",
+			Path:       imagePath,
+			Filename:   fmt.Sprintf("meta-%s%s", image.Fingerprint, ext),
+		},
+		{
+			Identifier: "rootfs.delta",
+			Path:       deltaPath,
+			Filename:   fmt.Sprintf("%s.rootfs.delta-%s.vcdiff", image.Fingerprint, source),
+		},
+	}
+
+	return response.FileResponse(r, files, nil, false)
+}
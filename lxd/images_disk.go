@@ -0,0 +1,400 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/osarch"
+)
+
+// Formats of the raw disk images which can be imported.
+var imageDiskFormats = []string{"qcow2", "raw", "vmdk"}
+
+// Magic strings of the disk image formats which qemu-img would otherwise
+// probe. Some of them, like VMDK descriptors, refer to other files and so must
+// never be interpreted, nor silently imported as raw disks.
+var imageDiskForeignMagics = map[string][]byte{
+	"vmdk3":     []byte("COWD"),
+	"vmdk-desc": []byte("# Disk DescriptorFile"),
+	"vhdx":      []byte("vhdxfile"),
+	"vpc":       []byte("conectix"),
+	"vdi":       []byte("<<< "),
+	"qed":       []byte("QED\x00"),
+	"luks":      []byte("LUKS\xba\xbe"),
+	"parallels": []byte("WithoutFreeSpace"),
+	"ploop":     []byte("WithouFreSpacExt"),
+	"bochs":     []byte("Bochs Virtual HD Image"),
+}
+
+// Magic string of qcow2 disk images, along with the incompatible feature bit
+// of version 3 images with an external data file.
+var imageDiskQcow2Magic = []byte("QFI\xfb")
+
+const imageDiskQcow2DataFileFeature = 1 << 2
+
+// Magic string of sparse VMDK extents, along with the largest embedded
+// descriptor we accept (the one qemu-img reads at most).
+var imageDiskVmdkMagic = []byte("KDMV")
+
+const imageDiskVmdkMaxDescriptor = 1024 * 1024
+
+// imageDiskInfo is the subset of "qemu-img info" we care about.
+type imageDiskInfo struct {
+	Format              string `json:"format"`
+	VirtualSize         int64  `json:"virtual-size"`
+	BackingFilename     string `json:"backing-filename"`
+	FullBackingFilename string `json:"full-backing-filename"`
+	FormatSpecific      struct {
+		Data struct {
+			DataFile   string `json:"data-file"`
+			CreateType string `json:"create-type"`
+			Extents    []struct {
+				Filename string `json:"filename"`
+			} `json:"extents"`
+		} `json:"data"`
+	} `json:"format-specific"`
+}
+
+// imageDisk is a raw disk image converted to the split format of LXD virtual
+// machine images.
+type imageDisk struct {
+	Metadata     *api.ImageMetadata
+	MetadataFile string
+	RootfsFile   string
+}
+
+// Remove the files of the converted disk image.
+func (d *imageDisk) Remove() {
+	os.Remove(d.MetadataFile)
+	os.Remove(d.RootfsFile)
+}
+
+// Detect the format of the disk image found at the given path from its
+// header, rather than letting qemu-img probe it. Only self-contained qcow2
+// and monolithic sparse VMDK images are recognized, anything without a known
+// magic string being a raw disk.
+func imageDiskDetect(fname string) (string, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	header := make([]byte, 512)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	header = header[:n]
+
+	if bytes.HasPrefix(header, imageDiskQcow2Magic) {
+		err := imageDiskQcow2Check(header)
+		if err != nil {
+			return "", err
+		}
+
+		return "qcow2", nil
+	}
+
+	if bytes.HasPrefix(header, imageDiskVmdkMagic) {
+		err := imageDiskVmdkCheck(f, header)
+		if err != nil {
+			return "", err
+		}
+
+		return "vmdk", nil
+	}
+
+	for format, magic := range imageDiskForeignMagics {
+		if bytes.HasPrefix(header, magic) {
+			return "", fmt.Errorf("Unsupported disk image format %q", format)
+		}
+	}
+
+	// VMDK descriptors may start with any comment.
+	if bytes.Contains(header, []byte("createType=")) {
+		return "", fmt.Errorf("Unsupported disk image format %q", "vmdk-desc")
+	}
+
+	return "raw", nil
+}
+
+// Check that the given qcow2 header describes a self-contained image, that is
+// with neither a backing file nor an external data file, and not encrypted.
+func imageDiskQcow2Check(header []byte) error {
+	// Version 2 headers are 72 bytes long, version 3 ones at least 104.
+	if len(header) < 72 {
+		return fmt.Errorf("Truncated qcow2 header")
+	}
+
+	version := binary.BigEndian.Uint32(header[4:8])
+	if version != 2 && version != 3 {
+		return fmt.Errorf("Unsupported qcow2 version %d", version)
+	}
+
+	if version == 3 && len(header) < 104 {
+		return fmt.Errorf("Truncated qcow2 header")
+	}
+
+	backingFileOffset := binary.BigEndian.Uint64(header[8:16])
+	if backingFileOffset != 0 {
+		return fmt.Errorf("Disk images with a backing file aren't supported")
+	}
+
+	cryptMethod := binary.BigEndian.Uint32(header[32:36])
+	if cryptMethod != 0 {
+		return fmt.Errorf("Encrypted disk images aren't supported")
+	}
+
+	if version == 3 {
+		incompatibleFeatures := binary.BigEndian.Uint64(header[72:80])
+		if incompatibleFeatures&imageDiskQcow2DataFileFeature != 0 {
+			return fmt.Errorf("Disk images with an external data file aren't supported")
+		}
+	}
+
+	return nil
+}
+
+// Check that the given sparse VMDK header, read from f, describes a
+// monolithic sparse image, that is one whose embedded descriptor lists no
+// other extent than the file itself.
+func imageDiskVmdkCheck(f *os.File, header []byte) error {
+	if len(header) < 44 {
+		return fmt.Errorf("Truncated VMDK header")
+	}
+
+	version := binary.LittleEndian.Uint32(header[4:8])
+	if version < 1 || version > 3 {
+		return fmt.Errorf("Unsupported VMDK version %d", version)
+	}
+
+	// qemu-img follows the embedded descriptor, and so its extents, when
+	// the header has no capacity.
+	capacity := binary.LittleEndian.Uint64(header[12:20])
+	if capacity == 0 {
+		return fmt.Errorf("VMDK images without a capacity aren't supported")
+	}
+
+	descriptorOffset := binary.LittleEndian.Uint64(header[28:36])
+	descriptorSize := binary.LittleEndian.Uint64(header[36:44])
+	if descriptorOffset == 0 || descriptorSize == 0 {
+		return fmt.Errorf("VMDK images without an embedded descriptor aren't supported")
+	}
+
+	if descriptorSize > imageDiskVmdkMaxDescriptor/512 {
+		return fmt.Errorf("VMDK descriptor is too large")
+	}
+
+	if descriptorOffset > math.MaxInt64/512 {
+		return fmt.Errorf("Invalid VMDK descriptor offset")
+	}
+
+	descriptor := make([]byte, descriptorSize*512)
+	n, err := f.ReadAt(descriptor, int64(descriptorOffset*512))
+	if err != nil && err != io.EOF {
+		return err
+	}
+	descriptor = bytes.TrimRight(descriptor[:n], "\x00")
+
+	createType := ""
+	extents := 0
+	for _, line := range strings.Split(string(descriptor), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if strings.HasPrefix(fields[0], "createType=") {
+			createType = strings.Trim(strings.TrimPrefix(fields[0], "createType="), `"`)
+			continue
+		}
+
+		if shared.StringInSlice(fields[0], []string{"RW", "RDONLY", "NOACCESS"}) {
+			extents++
+			if len(fields) < 3 || fields[2] != "SPARSE" {
+				return fmt.Errorf("VMDK images with non-sparse extents aren't supported")
+			}
+		}
+	}
+
+	if createType != "monolithicSparse" {
+		return fmt.Errorf("Unsupported VMDK image type %q", createType)
+	}
+
+	if extents != 1 {
+		return fmt.Errorf("VMDK images with external extents aren't supported")
+	}
+
+	return nil
+}
+
+// Inspect the disk image found at the given path with qemu-img, using the
+// format detected from its header.
+func imageDiskInspect(fname string) (*imageDiskInfo, error) {
+	format, err := imageDiskDetect(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := shared.RunCommand("qemu-img", "info", "-f", format, "--output=json", fname)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to inspect disk image")
+	}
+
+	info := imageDiskInfo{}
+	err = json.Unmarshal([]byte(out), &info)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse disk image information")
+	}
+
+	if info.Format != format || !shared.StringInSlice(info.Format, imageDiskFormats) {
+		return nil, fmt.Errorf("Unsupported disk image format %q", info.Format)
+	}
+
+	if info.BackingFilename != "" || info.FullBackingFilename != "" {
+		return nil, fmt.Errorf("Disk images with a backing file aren't supported")
+	}
+
+	if info.FormatSpecific.Data.DataFile != "" {
+		return nil, fmt.Errorf("Disk images with an external data file aren't supported")
+	}
+
+	if info.Format == "vmdk" {
+		err := imageDiskVmdkCheckInfo(fname, &info)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if info.VirtualSize <= 0 {
+		return nil, fmt.Errorf("Disk image has no virtual size")
+	}
+
+	return &info, nil
+}
+
+// Check that qemu-img opened the VMDK image found at the given path as a
+// monolithic sparse image, whose only extent is the file itself.
+func imageDiskVmdkCheckInfo(fname string, info *imageDiskInfo) error {
+	if info.FormatSpecific.Data.CreateType != "monolithicSparse" {
+		return fmt.Errorf("Unsupported VMDK image type %q", info.FormatSpecific.Data.CreateType)
+	}
+
+	if len(info.FormatSpecific.Data.Extents) != 1 {
+		return fmt.Errorf("VMDK images with external extents aren't supported")
+	}
+
+	fi, err := os.Stat(fname)
+	if err != nil {
+		return err
+	}
+
+	extentFi, err := os.Stat(info.FormatSpecific.Data.Extents[0].Filename)
+	if err != nil || !os.SameFile(fi, extentFi) {
+		return fmt.Errorf("VMDK images with external extents aren't supported")
+	}
+
+	return nil
+}
+
+// imageDiskConvert turns the qcow2, VMDK or raw disk image found at the given
+// path into a LXD virtual machine image for the given architecture, that is a
+// metadata tarball and a qcow2 root disk, both created in builddir.
+func imageDiskConvert(fname string, builddir string, architecture string, architectures []int) (*imageDisk, error) {
+	archID, err := osarch.ArchitectureId(architecture)
+	if err != nil {
+		return nil, err
+	}
+
+	if !shared.IntInSlice(archID, architectures) {
+		return nil, fmt.Errorf("Architecture %q isn't supported by this server", architecture)
+	}
+
+	info, err := imageDiskInspect(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	disk := &imageDisk{}
+	success := false
+	defer func() {
+		if !success {
+			disk.Remove()
+		}
+	}()
+
+	// Convert the disk to qcow2
+	rootfsFile, err := ioutil.TempFile(builddir, "lxd_disk_rootfs_")
+	if err != nil {
+		return nil, err
+	}
+	rootfsFile.Close()
+	disk.RootfsFile = rootfsFile.Name()
+
+	_, err = shared.RunCommand("qemu-img", "convert", "-f", info.Format, "-O", "qcow2", fname, disk.RootfsFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to convert disk image")
+	}
+
+	// Generate the image metadata
+	disk.Metadata = &api.ImageMetadata{
+		Architecture: architecture,
+		CreationDate: time.Now().UTC().Unix(),
+		Properties: map[string]string{
+			"architecture": architecture,
+			"description":  fmt.Sprintf("Imported %s disk image %s", info.Format, filepath.Base(fname)),
+		},
+	}
+
+	data, err := yaml.Marshal(disk.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	metadataFile, err := ioutil.TempFile(builddir, "lxd_disk_meta_")
+	if err != nil {
+		return nil, err
+	}
+	defer metadataFile.Close()
+	disk.MetadataFile = metadataFile.Name()
+
+	tw := tar.NewWriter(metadataFile)
+	err = tw.WriteHeader(&tar.Header{
+		Name:    "metadata.yaml",
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Unix(disk.Metadata.CreationDate, 0),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tw.Write(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	success = true
+	return disk, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Return a qcow2 header of the given version, with the given backing file
+// offset and incompatible features.
+func diskQcow2Header(version uint32, backingFileOffset uint64, incompatibleFeatures uint64) []byte {
+	header := make([]byte, 104)
+	copy(header, "QFI\xfb")
+	binary.BigEndian.PutUint32(header[4:8], version)
+	binary.BigEndian.PutUint64(header[8:16], backingFileOffset)
+	binary.BigEndian.PutUint64(header[72:80], incompatibleFeatures)
+	binary.BigEndian.PutUint32(header[100:104], 104)
+
+	return header
+}
+
+// Return a sparse VMDK extent with the given capacity, whose embedded
+// descriptor has the given content.
+func diskVmdk(capacity uint64, descriptor string) []byte {
+	content := make([]byte, 1024)
+	copy(content, "KDMV")
+	binary.LittleEndian.PutUint32(content[4:8], 1)
+	binary.LittleEndian.PutUint64(content[12:20], capacity)
+	binary.LittleEndian.PutUint64(content[28:36], 1)
+	binary.LittleEndian.PutUint64(content[36:44], 1)
+	copy(content[512:], descriptor)
+
+	return content
+}
+
+func TestImageDiskDetect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-disk-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		name    string
+		content []byte
+		format  string
+		err     string
+	}{
+		{"raw", make([]byte, 4096), "raw", ""},
+		{"empty", []byte{}, "raw", ""},
+		{"qcow2", diskQcow2Header(3, 0, 0), "qcow2", ""},
+		{"qcow2v2", diskQcow2Header(2, 0, 0)[:72], "qcow2", ""},
+		{"backing", diskQcow2Header(3, 512, 0), "", "backing file"},
+		{"data-file", diskQcow2Header(3, 0, imageDiskQcow2DataFileFeature), "", "external data file"},
+		{"truncated", diskQcow2Header(3, 0, 0)[:80], "", "Truncated"},
+		{"vmdk", diskVmdk(2048, "createType=\"monolithicSparse\"\nRW 2048 SPARSE \"disk.vmdk\"\n"), "vmdk", ""},
+		{"vmdk-truncated", []byte("KDMV\x01\x00\x00\x00"), "", "Truncated"},
+		{"vmdk-no-capacity", diskVmdk(0, "createType=\"monolithicSparse\"\nRW 2048 SPARSE \"disk.vmdk\"\n"), "", "capacity"},
+		{
+			"vmdk-flat-extent",
+			diskVmdk(2048, "createType=\"monolithicSparse\"\nRW 2048 FLAT \"/etc/shadow\" 0\n"),
+			"",
+			"non-sparse extents",
+		},
+		{
+			"vmdk-external-extents",
+			diskVmdk(2048, "createType=\"monolithicSparse\"\nRW 2048 SPARSE \"disk.vmdk\"\nRW 2048 SPARSE \"other.vmdk\"\n"),
+			"",
+			"external extents",
+		},
+		{
+			"vmdk-split",
+			diskVmdk(2048, "createType=\"twoGbMaxExtentSparse\"\nRW 2048 SPARSE \"disk-s001.vmdk\"\n"),
+			"",
+			`"twoGbMaxExtentSparse"`,
+		},
+		{
+			"vmdk-descriptor",
+			[]byte("# Disk DescriptorFile\nversion=1\ncreateType=\"monolithicFlat\"\nRW 2048 FLAT \"/etc/shadow\" 0\n"),
+			"",
+			`"vmdk-desc"`,
+		},
+		{
+			"vmdk-descriptor-comment",
+			[]byte("# Innocent\nversion=1\ncreateType=\"monolithicFlat\"\nRW 2048 FLAT \"/etc/shadow\" 0\n"),
+			"",
+			`"vmdk-desc"`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(dir, c.name)
+			require.NoError(t, ioutil.WriteFile(path, c.content, 0644))
+
+			format, err := imageDiskDetect(path)
+			if c.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), c.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, c.format, format)
+		})
+	}
+}
@@ -0,0 +1,898 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/osarch"
+)
+
+// Media types of OCI image indexes and manifests.
+const (
+	ociMediaTypeIndex          = "application/vnd.oci.image.index.v1+json"
+	ociMediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.list.v2+json"
+)
+
+// Annotation holding the reference name (tag) of an OCI image.
+const ociAnnotationRefName = "org.opencontainers.image.ref.name"
+
+// Prefix of the files marking deleted entries in OCI layers.
+const ociWhiteoutPrefix = ".wh."
+
+// Name of the file marking a directory whose lower layers content is hidden.
+const ociWhiteoutOpaque = ".wh..wh..opq"
+
+var ociDigestRegexp = regexp.MustCompile(`^([a-z0-9]+):([a-f0-9]+)$`)
+
+// Media types of the supported layers, along with their compression.
+var ociLayerMediaTypes = map[string]string{
+	"application/vnd.oci.image.layer.v1.tar":                       "",
+	"application/vnd.oci.image.layer.v1.tar+gzip":                  "gzip",
+	"application/vnd.oci.image.layer.nondistributable.v1.tar":      "",
+	"application/vnd.oci.image.layer.nondistributable.v1.tar+gzip": "gzip",
+	"application/vnd.docker.image.rootfs.diff.tar.gzip":            "gzip",
+	"application/vnd.docker.image.rootfs.foreign.diff.tar.gzip":    "gzip",
+}
+
+// Maximum number of symlinks followed when resolving a path.
+const ociMaxSymlinks = 255
+
+// ociDescriptor points to a blob in an OCI image layout.
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Annotations map[string]string `json:"annotations"`
+	Platform    *ociPlatform      `json:"platform"`
+}
+
+// ociPlatform is the platform of a manifest in an image index.
+type ociPlatform struct {
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant"`
+	OS           string `json:"os"`
+}
+
+// ociIndex is the content of index.json or of an image index blob.
+type ociIndex struct {
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+// ociManifest is the content of an image manifest blob.
+type ociManifest struct {
+	Config ociDescriptor   `json:"config"`
+	Layers []ociDescriptor `json:"layers"`
+}
+
+// ociDockerManifest is an entry of the manifest.json file of "docker save"
+// tarballs.
+type ociDockerManifest struct {
+	Config   string   `json:"Config"`
+	RepoTags []string `json:"RepoTags"`
+	Layers   []string `json:"Layers"`
+}
+
+// ociConfig is the subset of the image configuration we care about.
+type ociConfig struct {
+	Created      time.Time `json:"created"`
+	Architecture string    `json:"architecture"`
+	Variant      string    `json:"variant"`
+	OS           string    `json:"os"`
+	Config       struct {
+		User       string   `json:"User"`
+		Env        []string `json:"Env"`
+		Entrypoint []string `json:"Entrypoint"`
+		Cmd        []string `json:"Cmd"`
+		WorkingDir string   `json:"WorkingDir"`
+	} `json:"config"`
+}
+
+// ociLayer is a layer tarball of an OCI image.
+type ociLayer struct {
+	Path      string
+	MediaType string // Empty for the layers of "docker save" tarballs
+}
+
+// ociImage describes an image found in an OCI layout or "docker save"
+// directory.
+type ociImage struct {
+	Name   string
+	Config ociConfig
+	Layers []ociLayer
+}
+
+// imageOCIConvert turns the OCI image layout or "docker save" tarball found
+// at the given path into a unified LXD container image, compressed with the
+// given algorithm, and returns it. Multi-architecture images are converted
+// for the first of the given architectures they support.
+func imageOCIConvert(fname string, builddir string, compress string, runningInUserns bool, architectures []int) (*os.File, error) {
+	workdir, err := ioutil.TempDir(builddir, "lxd_oci_")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workdir)
+
+	// Unpack the source tarball
+	sourceDir := filepath.Join(workdir, "source")
+	err = os.Mkdir(sourceDir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	err = shared.Unpack(fname, sourceDir, false, false, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to unpack OCI image")
+	}
+
+	image, err := ociImageLoad(sourceDir, architectures)
+	if err != nil {
+		return nil, err
+	}
+
+	// Flatten the layers into the rootfs
+	imageDir := filepath.Join(workdir, "image")
+	rootfsDir := filepath.Join(imageDir, "rootfs")
+	err = os.MkdirAll(rootfsDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layer := range image.Layers {
+		err = ociLayerApply(layer, rootfsDir, runningInUserns)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to apply OCI layer %q", filepath.Base(layer.Path))
+		}
+	}
+
+	// Generate the image metadata
+	metadata, err := ociImageMetadata(image)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := yaml.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ioutil.WriteFile(filepath.Join(imageDir, "metadata.yaml"), data, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pack the result as a unified image
+	imageFile, err := ioutil.TempFile(builddir, "lxd_oci_image_")
+	if err != nil {
+		return nil, err
+	}
+
+	err = ociImagePack(imageDir, imageFile, compress)
+	if err != nil {
+		imageFile.Close()
+		os.Remove(imageFile.Name())
+		return nil, errors.Wrap(err, "Failed to pack OCI image")
+	}
+
+	return imageFile, nil
+}
+
+// Load the description of the image found in the given unpacked OCI image
+// layout or "docker save" tarball.
+func ociImageLoad(dir string, architectures []int) (*ociImage, error) {
+	if shared.PathExists(filepath.Join(dir, "oci-layout")) && shared.PathExists(filepath.Join(dir, "index.json")) {
+		return ociImageLoadLayout(dir, architectures)
+	}
+
+	if shared.PathExists(filepath.Join(dir, "manifest.json")) {
+		return ociImageLoadDocker(dir)
+	}
+
+	return nil, fmt.Errorf("Not an OCI image layout nor a Docker image archive")
+}
+
+// Load an image from an OCI image layout, using the manifest of its index for
+// the first of the given architectures it supports.
+func ociImageLoadLayout(dir string, architectures []int) (*ociImage, error) {
+	path, err := ociFilePath(dir, "index.json")
+	if err != nil {
+		return nil, err
+	}
+
+	index := ociIndex{}
+	err = ociReadJSON(path, &index)
+	if err != nil {
+		return nil, err
+	}
+
+	image := &ociImage{}
+
+	for {
+		descriptor, err := ociSelectManifest(index.Manifests, architectures)
+		if err != nil {
+			return nil, err
+		}
+
+		if image.Name == "" {
+			image.Name = descriptor.Annotations[ociAnnotationRefName]
+		}
+
+		path, err := ociBlobPath(dir, descriptor.Digest)
+		if err != nil {
+			return nil, err
+		}
+
+		// Nested indexes, typically for multi-architecture images.
+		if descriptor.MediaType == ociMediaTypeIndex || descriptor.MediaType == ociMediaTypeDockerManifest {
+			index = ociIndex{}
+			err = ociReadJSON(path, &index)
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		manifest := ociManifest{}
+		err = ociReadJSON(path, &manifest)
+		if err != nil {
+			return nil, err
+		}
+
+		path, err = ociBlobPath(dir, manifest.Config.Digest)
+		if err != nil {
+			return nil, err
+		}
+
+		err = ociReadJSON(path, &image.Config)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, layer := range manifest.Layers {
+			_, ok := ociLayerMediaTypes[layer.MediaType]
+			if !ok {
+				return nil, fmt.Errorf("Unsupported OCI layer media type %q", layer.MediaType)
+			}
+
+			path, err = ociBlobPath(dir, layer.Digest)
+			if err != nil {
+				return nil, err
+			}
+
+			image.Layers = append(image.Layers, ociLayer{Path: path, MediaType: layer.MediaType})
+		}
+
+		return image, nil
+	}
+}
+
+// Select the manifest of an image index for the first of the given
+// architectures it has one for. Manifests without a platform match any
+// architecture, as long as no manifest has a matching one.
+func ociSelectManifest(manifests []ociDescriptor, architectures []int) (ociDescriptor, error) {
+	if len(manifests) == 0 {
+		return ociDescriptor{}, fmt.Errorf("No manifest found in OCI image index")
+	}
+
+	for _, architecture := range architectures {
+		for _, descriptor := range manifests {
+			platform := descriptor.Platform
+			if platform == nil || (platform.OS != "" && platform.OS != "linux") {
+				continue
+			}
+
+			id, err := ociArchitectureId(platform.Architecture, platform.Variant)
+			if err == nil && id == architecture {
+				return descriptor, nil
+			}
+		}
+	}
+
+	for _, descriptor := range manifests {
+		if descriptor.Platform == nil {
+			return descriptor, nil
+		}
+	}
+
+	names := []string{}
+	for _, architecture := range architectures {
+		name, err := osarch.ArchitectureName(architecture)
+		if err == nil {
+			names = append(names, name)
+		}
+	}
+
+	return ociDescriptor{}, fmt.Errorf("No manifest found in OCI image index for architecture %s", strings.Join(names, ", "))
+}
+
+// Return the LXD architecture of the given OCI architecture and variant.
+func ociArchitectureId(architecture string, variant string) (int, error) {
+	if architecture == "arm" && variant != "" {
+		architecture = fmt.Sprintf("arm%s", variant)
+	}
+
+	return osarch.ArchitectureId(architecture)
+}
+
+// Load an image from the content of a "docker save" tarball, using its first
+// manifest.
+func ociImageLoadDocker(dir string) (*ociImage, error) {
+	path, err := ociFilePath(dir, "manifest.json")
+	if err != nil {
+		return nil, err
+	}
+
+	manifests := []ociDockerManifest{}
+	err = ociReadJSON(path, &manifests)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(manifests) == 0 {
+		return nil, fmt.Errorf("No manifest found in Docker image archive")
+	}
+
+	manifest := manifests[0]
+	image := &ociImage{}
+	if len(manifest.RepoTags) > 0 {
+		image.Name = manifest.RepoTags[0]
+	}
+
+	path, err = ociFilePath(dir, manifest.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ociReadJSON(path, &image.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layer := range manifest.Layers {
+		path, err = ociFilePath(dir, layer)
+		if err != nil {
+			return nil, err
+		}
+
+		image.Layers = append(image.Layers, ociLayer{Path: path})
+	}
+
+	return image, nil
+}
+
+// Return the path of the blob with the given digest in an OCI image layout.
+func ociBlobPath(dir string, digest string) (string, error) {
+	match := ociDigestRegexp.FindStringSubmatch(digest)
+	if match == nil {
+		return "", fmt.Errorf("Invalid OCI digest %q", digest)
+	}
+
+	return ociResolvePath(dir, filepath.Join("blobs", match[1], match[2]))
+}
+
+// Return the path of a file of the unpacked image archive, as referenced by
+// a "docker save" manifest for example. Symlinks are resolved within the
+// archive so that the path can't point outside of it.
+func ociFilePath(dir string, name string) (string, error) {
+	path, err := ociResolvePath(dir, name)
+	if err != nil {
+		return "", err
+	}
+
+	if name == "" || path == dir {
+		return "", fmt.Errorf("Invalid path %q in image archive", name)
+	}
+
+	return path, nil
+}
+
+// Open the regular file found at the given path, which must have been
+// resolved with ociResolvePath.
+func ociOpenFile(path string) (*os.File, error) {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fi.Mode().IsRegular() {
+		return nil, fmt.Errorf("%q isn't a regular file", filepath.Base(path))
+	}
+
+	return os.Open(path)
+}
+
+func ociReadJSON(path string, v interface{}) error {
+	f, err := ociOpenFile(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = json.NewDecoder(f).Decode(v)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to parse %q", filepath.Base(path))
+	}
+
+	return nil
+}
+
+// Extract the given layer tarball into rootfs, honoring its whiteout entries.
+// Entries are written at their path as resolved from within rootfs, so that
+// symlinks, including the ones of lower layers, never lead outside of it.
+func ociLayerApply(layer ociLayer, rootfs string, runningInUserns bool) error {
+	// Whiteouts only hide content from lower layers, so apply them all
+	// before extracting the layer itself.
+	whiteouts, err := ociLayerWhiteouts(layer)
+	if err != nil {
+		return err
+	}
+
+	for _, whiteout := range whiteouts {
+		err = ociWhiteoutApply(rootfs, whiteout)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Directory times are set once their content is extracted.
+	dirs := []*tar.Header{}
+
+	err = ociLayerRead(layer, func(tr *tar.Reader) error {
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			name := filepath.Clean("/" + hdr.Name)
+			if name == "/" || strings.HasPrefix(filepath.Base(name), ociWhiteoutPrefix) {
+				continue
+			}
+
+			// Device nodes can't be created when running unprivileged.
+			if runningInUserns && strings.HasPrefix(name, "/dev/") {
+				continue
+			}
+
+			err = ociEntryExtract(tr, hdr, rootfs, runningInUserns)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to extract %q", hdr.Name)
+			}
+
+			if hdr.Typeflag == tar.TypeDir {
+				dirs = append(dirs, hdr)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		path, err := ociEntryPath(rootfs, dirs[i].Name)
+		if err != nil {
+			return err
+		}
+
+		err = ociEntryTimes(path, dirs[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Call the given function with a reader of the uncompressed tarball of the
+// given layer.
+func ociLayerRead(layer ociLayer, f func(tr *tar.Reader) error) error {
+	file, err := ociOpenFile(layer.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+
+	compression, ok := ociLayerMediaTypes[layer.MediaType]
+	if layer.MediaType == "" {
+		// Layers of "docker save" tarballs are usually uncompressed.
+		magic, _ := reader.Peek(2)
+		if bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+			compression = "gzip"
+		}
+	} else if !ok {
+		return fmt.Errorf("Unsupported OCI layer media type %q", layer.MediaType)
+	}
+
+	if compression == "gzip" {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+
+		return f(tar.NewReader(gz))
+	}
+
+	return f(tar.NewReader(reader))
+}
+
+// Return the names of the whiteout entries of the given layer tarball.
+func ociLayerWhiteouts(layer ociLayer) ([]string, error) {
+	whiteouts := []string{}
+	err := ociLayerRead(layer, func(tr *tar.Reader) error {
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if strings.HasPrefix(filepath.Base(hdr.Name), ociWhiteoutPrefix) {
+				whiteouts = append(whiteouts, hdr.Name)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return whiteouts, nil
+}
+
+// Extract the given tarball entry into rootfs, replacing whatever was found at
+// its path unless both are directories.
+func ociEntryExtract(tr *tar.Reader, hdr *tar.Header, rootfs string, runningInUserns bool) error {
+	path, err := ociEntryPath(rootfs, hdr.Name)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	fi, err := os.Lstat(path)
+	if err == nil && !(fi.IsDir() && hdr.Typeflag == tar.TypeDir) {
+		err = os.RemoveAll(path)
+		if err != nil {
+			return err
+		}
+	} else if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		err = os.Mkdir(path, 0700)
+		if err != nil && !os.IsExist(err) {
+			return err
+		}
+	case tar.TypeReg, tar.TypeRegA:
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(f, tr)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	case tar.TypeSymlink:
+		err = os.Symlink(hdr.Linkname, path)
+		if err != nil {
+			return err
+		}
+	case tar.TypeLink:
+		target, err := ociEntryPath(rootfs, hdr.Linkname)
+		if err != nil {
+			return err
+		}
+
+		fi, err := os.Lstat(target)
+		if err != nil {
+			return err
+		}
+
+		if fi.IsDir() {
+			return fmt.Errorf("Hard link to directory %q", hdr.Linkname)
+		}
+
+		// Hard links share the metadata of their target.
+		return os.Link(target, path)
+	case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+		if runningInUserns && hdr.Typeflag != tar.TypeFifo {
+			return nil
+		}
+
+		mode := map[byte]uint32{tar.TypeChar: unix.S_IFCHR, tar.TypeBlock: unix.S_IFBLK, tar.TypeFifo: unix.S_IFIFO}[hdr.Typeflag]
+		err = unix.Mknod(path, mode|0600, int(unix.Mkdev(uint32(hdr.Devmajor), uint32(hdr.Devminor))))
+		if err != nil {
+			return err
+		}
+	default:
+		// Other entries, like global headers, aren't files.
+		return nil
+	}
+
+	// Ownership can't be set to unmapped ids when running unprivileged.
+	err = os.Lchown(path, hdr.Uid, hdr.Gid)
+	if err != nil && !runningInUserns {
+		return err
+	}
+
+	if hdr.Typeflag != tar.TypeSymlink {
+		err = os.Chmod(path, hdr.FileInfo().Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky))
+		if err != nil {
+			return err
+		}
+	}
+
+	for key, value := range hdr.PAXRecords {
+		if !strings.HasPrefix(key, "SCHILY.xattr.") {
+			continue
+		}
+
+		// Like tar, extended attributes which the filesystem or the
+		// privileges don't allow are skipped.
+		unix.Lsetxattr(path, strings.TrimPrefix(key, "SCHILY.xattr."), []byte(value), 0)
+	}
+
+	if hdr.Typeflag != tar.TypeDir {
+		return ociEntryTimes(path, hdr)
+	}
+
+	return nil
+}
+
+// Set the access and modification times of the entry at the given path.
+func ociEntryTimes(path string, hdr *tar.Header) error {
+	atime := hdr.AccessTime
+	if atime.IsZero() {
+		atime = hdr.ModTime
+	}
+
+	times := []unix.Timespec{unix.NsecToTimespec(atime.UnixNano()), unix.NsecToTimespec(hdr.ModTime.UnixNano())}
+
+	return unix.UtimesNanoAt(unix.AT_FDCWD, path, times, unix.AT_SYMLINK_NOFOLLOW)
+}
+
+// Return the path of the given entry inside rootfs, resolving the symlinks
+// of its parent directories but not of the entry itself.
+func ociEntryPath(rootfs string, name string) (string, error) {
+	dir, base := filepath.Split(filepath.Clean("/" + name))
+	if base == "" {
+		return "", fmt.Errorf("Invalid entry %q", name)
+	}
+
+	parent, err := ociResolvePath(rootfs, dir)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(parent, base), nil
+}
+
+// Return the path of the given path inside root as resolved from a chroot to
+// root: symlinks are followed, with absolute ones being relative to root and
+// ".." never going above it. The components which don't exist are kept as is,
+// so the returned path never goes through a symlink.
+func ociResolvePath(root string, path string) (string, error) {
+	resolved := "/"
+	components := strings.Split(path, "/")
+	symlinks := 0
+
+	for len(components) > 0 {
+		component := components[0]
+		components = components[1:]
+
+		if component == "" || component == "." {
+			continue
+		}
+
+		if component == ".." {
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+
+		next := filepath.Join(resolved, component)
+
+		fi, err := os.Lstat(filepath.Join(root, next))
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+
+		if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		symlinks++
+		if symlinks > ociMaxSymlinks {
+			return "", fmt.Errorf("Too many levels of symbolic links in %q", path)
+		}
+
+		target, err := os.Readlink(filepath.Join(root, next))
+		if err != nil {
+			return "", err
+		}
+
+		if filepath.IsAbs(target) {
+			resolved = "/"
+		}
+
+		components = append(strings.Split(target, "/"), components...)
+	}
+
+	return filepath.Join(root, resolved), nil
+}
+
+// Remove the entries of rootfs hidden by the given whiteout entry.
+func ociWhiteoutApply(rootfs string, whiteout string) error {
+	name := filepath.Clean("/" + whiteout)
+	dir, base := filepath.Split(name)
+
+	path, err := ociResolvePath(rootfs, dir)
+	if err != nil {
+		return err
+	}
+
+	// Nothing to hide if the parent directory isn't there.
+	fi, err := os.Lstat(path)
+	if err != nil || !fi.IsDir() {
+		return nil
+	}
+
+	// Other special whiteout entries are only meaningful to aufs.
+	if strings.HasPrefix(base, ociWhiteoutPrefix+ociWhiteoutPrefix) && base != ociWhiteoutOpaque {
+		return nil
+	}
+
+	if base == ociWhiteoutOpaque {
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			err = os.RemoveAll(filepath.Join(path, entry.Name()))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	target := strings.TrimPrefix(base, ociWhiteoutPrefix)
+	if target == "" || target == "." || target == ".." {
+		return fmt.Errorf("Invalid whiteout entry %q", whiteout)
+	}
+
+	return os.RemoveAll(filepath.Join(path, target))
+}
+
+// Generate the LXD image metadata for the given OCI image.
+func ociImageMetadata(image *ociImage) (*api.ImageMetadata, error) {
+	config := image.Config
+
+	if config.OS != "" && config.OS != "linux" {
+		return nil, fmt.Errorf("Unsupported OCI image OS %q", config.OS)
+	}
+
+	id, err := ociArchitectureId(config.Architecture, config.Variant)
+	if err != nil {
+		return nil, err
+	}
+
+	architecture, err := osarch.ArchitectureName(id)
+	if err != nil {
+		return nil, err
+	}
+
+	created := config.Created
+	if created.IsZero() {
+		created = time.Now()
+	}
+
+	properties := map[string]string{
+		"architecture": architecture,
+	}
+
+	if image.Name != "" {
+		properties["description"] = image.Name
+	}
+
+	for key, value := range map[string][]string{
+		"user.entrypoint": config.Config.Entrypoint,
+		"user.cmd":        config.Config.Cmd,
+		"user.env":        config.Config.Env,
+	} {
+		if len(value) == 0 {
+			continue
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+
+		properties[key] = string(data)
+	}
+
+	if config.Config.WorkingDir != "" {
+		properties["user.working_dir"] = config.Config.WorkingDir
+	}
+
+	if config.Config.User != "" {
+		properties["user.user"] = config.Config.User
+	}
+
+	metadata := &api.ImageMetadata{
+		Architecture: architecture,
+		CreationDate: created.Unix(),
+		Properties:   properties,
+	}
+
+	return metadata, nil
+}
+
+// Write the metadata.yaml file and rootfs directory found in dir to the given
+// file as a tarball, compressed with the given algorithm.
+func ociImagePack(dir string, imageFile *os.File, compress string) error {
+	args := []string{"-C", dir, "--numeric-owner", "--xattrs", "-cf", "-", "metadata.yaml", "rootfs"}
+
+	if compress == "none" {
+		return shared.RunCommandWithFds(nil, imageFile, "tar", args...)
+	}
+
+	tarReader, tarWriter := io.Pipe()
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+
+	var compressErr error
+	go func() {
+		defer wg.Done()
+		compressErr = compressFile(compress, tarReader, imageFile)
+
+		// Unblock tar if compression failed.
+		tarReader.Close()
+	}()
+
+	err := shared.RunCommandWithFds(nil, tarWriter, "tar", args...)
+	tarWriter.Close()
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+
+	return compressErr
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"archive/tar"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/osarch"
+)
+
+// Write the given files, creating parent directories as needed.
+func ociWriteFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func TestOCIImageLoad_Layout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-oci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ociWriteFiles(t, dir, map[string]string{
+		"oci-layout":      `{"imageLayoutVersion": "1.0.0"}`,
+		"index.json":      `{"manifests": [{"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:aa", "annotations": {"org.opencontainers.image.ref.name": "alpine:3.11"}}]}`,
+		"blobs/sha256/aa": `{"config": {"digest": "sha256:bb"}, "layers": [{"mediaType": "application/vnd.oci.image.layer.v1.tar", "digest": "sha256:cc"}, {"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": "sha256:dd"}]}`,
+		"blobs/sha256/cc": "",
+		"blobs/sha256/dd": "",
+		"blobs/sha256/bb": `{"architecture": "arm", "variant": "v7", "os": "linux", "config": {"Entrypoint": ["/bin/sh", "-c"], "Cmd": ["echo hi"]}}`,
+	})
+
+	image, err := ociImageLoad(dir, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "alpine:3.11", image.Name)
+	assert.Equal(t, []ociLayer{
+		{Path: filepath.Join(dir, "blobs/sha256/cc"), MediaType: "application/vnd.oci.image.layer.v1.tar"},
+		{Path: filepath.Join(dir, "blobs/sha256/dd"), MediaType: "application/vnd.oci.image.layer.v1.tar+gzip"},
+	}, image.Layers)
+
+	metadata, err := ociImageMetadata(image)
+	require.NoError(t, err)
+	assert.Equal(t, "armv7l", metadata.Architecture)
+	assert.Equal(t, `["/bin/sh","-c"]`, metadata.Properties["user.entrypoint"])
+	assert.Equal(t, `["echo hi"]`, metadata.Properties["user.cmd"])
+	assert.Equal(t, "alpine:3.11", metadata.Properties["description"])
+}
+
+// Multi-architecture images are loaded for the first supported architecture.
+func TestOCIImageLoad_Platform(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-oci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ociWriteFiles(t, dir, map[string]string{
+		"oci-layout":      `{"imageLayoutVersion": "1.0.0"}`,
+		"index.json":      `{"manifests": [{"mediaType": "application/vnd.oci.image.index.v1+json", "digest": "sha256:aa"}]}`,
+		"blobs/sha256/aa": `{"manifests": [{"digest": "sha256:bb", "platform": {"architecture": "arm", "variant": "v7", "os": "linux"}}, {"digest": "sha256:cc", "platform": {"architecture": "amd64", "os": "linux"}}]}`,
+		"blobs/sha256/bb": `{"config": {"digest": "sha256:dd"}}`,
+		"blobs/sha256/cc": `{"config": {"digest": "sha256:ee"}}`,
+		"blobs/sha256/dd": `{"architecture": "arm", "variant": "v7", "os": "linux"}`,
+		"blobs/sha256/ee": `{"architecture": "amd64", "os": "linux"}`,
+	})
+
+	image, err := ociImageLoad(dir, []int{osarch.ARCH_64BIT_INTEL_X86, osarch.ARCH_32BIT_ARMV7_LITTLE_ENDIAN})
+	require.NoError(t, err)
+	assert.Equal(t, "amd64", image.Config.Architecture)
+
+	image, err = ociImageLoad(dir, []int{osarch.ARCH_32BIT_ARMV7_LITTLE_ENDIAN})
+	require.NoError(t, err)
+	assert.Equal(t, "arm", image.Config.Architecture)
+
+	_, err = ociImageLoad(dir, []int{osarch.ARCH_64BIT_ARMV8_LITTLE_ENDIAN})
+	assert.EqualError(t, err, "No manifest found in OCI image index for architecture aarch64")
+}
+
+func TestOCIImageLoad_Docker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-oci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ociWriteFiles(t, dir, map[string]string{
+		"manifest.json": `[{"Config": "config.json", "RepoTags": ["busybox:latest"], "Layers": ["../../aa/layer.tar"]}]`,
+		"config.json":   `{"architecture": "amd64", "os": "linux"}`,
+	})
+
+	image, err := ociImageLoad(dir, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "busybox:latest", image.Name)
+
+	// Paths can't escape the archive.
+	assert.Equal(t, []ociLayer{{Path: filepath.Join(dir, "aa/layer.tar")}}, image.Layers)
+
+	metadata, err := ociImageMetadata(image)
+	require.NoError(t, err)
+	assert.Equal(t, "x86_64", metadata.Architecture)
+}
+
+func TestOCIImageLoad_Invalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-oci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = ociImageLoad(dir, nil)
+	assert.EqualError(t, err, "Not an OCI image layout nor a Docker image archive")
+
+	ociWriteFiles(t, dir, map[string]string{
+		"oci-layout": `{"imageLayoutVersion": "1.0.0"}`,
+		"index.json": `{"manifests": [{"digest": "sha256:../../etc/passwd"}]}`,
+	})
+
+	_, err = ociImageLoad(dir, nil)
+	assert.EqualError(t, err, `Invalid OCI digest "sha256:../../etc/passwd"`)
+
+	ociWriteFiles(t, dir, map[string]string{
+		"index.json":      `{"manifests": [{"digest": "sha256:aa"}]}`,
+		"blobs/sha256/aa": `{"config": {"digest": "sha256:bb"}, "layers": [{"mediaType": "application/vnd.oci.image.layer.v1.tar+zstd", "digest": "sha256:cc"}]}`,
+		"blobs/sha256/bb": `{"architecture": "amd64", "os": "linux"}`,
+		"blobs/sha256/cc": "",
+	})
+
+	_, err = ociImageLoad(dir, nil)
+	assert.EqualError(t, err, `Unsupported OCI layer media type "application/vnd.oci.image.layer.v1.tar+zstd"`)
+}
+
+func TestOCIWhiteoutApply(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-oci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	rootfs := filepath.Join(dir, "rootfs")
+	ociWriteFiles(t, rootfs, map[string]string{
+		"etc/hosts":      "",
+		"etc/hostname":   "",
+		"var/cache/a":    "",
+		"var/cache/b/c":  "",
+		"outside/secret": "",
+	})
+
+	// Regular whiteouts remove a single entry.
+	require.NoError(t, ociWhiteoutApply(rootfs, "etc/.wh.hosts"))
+	assert.False(t, shared.PathExists(filepath.Join(rootfs, "etc/hosts")))
+	assert.True(t, shared.PathExists(filepath.Join(rootfs, "etc/hostname")))
+
+	// Opaque whiteouts empty the directory.
+	require.NoError(t, ociWhiteoutApply(rootfs, "./var/cache/.wh..wh..opq"))
+	assert.True(t, shared.PathExists(filepath.Join(rootfs, "var/cache")))
+	assert.False(t, shared.PathExists(filepath.Join(rootfs, "var/cache/a")))
+	assert.False(t, shared.PathExists(filepath.Join(rootfs, "var/cache/b")))
+
+	// Whiteouts for missing directories are ignored.
+	require.NoError(t, ociWhiteoutApply(rootfs, "usr/.wh.bin"))
+
+	// Symlinks are resolved within rootfs.
+	require.NoError(t, os.Symlink(filepath.Join(rootfs, "outside"), filepath.Join(rootfs, "link")))
+	require.NoError(t, ociWhiteoutApply(rootfs, "link/.wh.secret"))
+	assert.True(t, shared.PathExists(filepath.Join(rootfs, "outside/secret")))
+}
+
+func TestOCIResolvePath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-oci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ociWriteFiles(t, dir, map[string]string{"usr/lib/a": ""})
+	require.NoError(t, os.Symlink("usr/lib", filepath.Join(dir, "lib")))
+	require.NoError(t, os.Symlink("/usr", filepath.Join(dir, "abs")))
+	require.NoError(t, os.Symlink("../../../..", filepath.Join(dir, "usr/up")))
+	require.NoError(t, os.Symlink("loop", filepath.Join(dir, "loop")))
+
+	cases := map[string]string{
+		"":               "",
+		"/lib/a":         "usr/lib/a",
+		"lib/../missing": "usr/missing",
+		"abs/lib/a":      "usr/lib/a",
+		"usr/up/etc":     "etc",
+		"../../tmp":      "tmp",
+	}
+
+	for path, expected := range cases {
+		resolved, err := ociResolvePath(dir, path)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, expected), resolved, path)
+	}
+
+	_, err = ociResolvePath(dir, "loop/a")
+	assert.Error(t, err)
+}
+
+func TestOCILayerApply(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-oci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	rootfs := filepath.Join(dir, "rootfs")
+	outside := filepath.Join(dir, "outside")
+	require.NoError(t, os.MkdirAll(outside, 0755))
+
+	// Write a layer tarball with the given entries.
+	writeLayer := func(name string, headers ...*tar.Header) ociLayer {
+		path := filepath.Join(dir, name)
+		f, err := os.Create(path)
+		require.NoError(t, err)
+		defer f.Close()
+
+		tw := tar.NewWriter(f)
+		for _, hdr := range headers {
+			hdr.Uid = os.Getuid()
+			hdr.Gid = os.Getgid()
+			require.NoError(t, tw.WriteHeader(hdr))
+			if hdr.Size > 0 {
+				_, err = tw.Write([]byte("x"))
+				require.NoError(t, err)
+			}
+		}
+		require.NoError(t, tw.Close())
+
+		return ociLayer{Path: path, MediaType: "application/vnd.oci.image.layer.v1.tar"}
+	}
+
+	lower := writeLayer("lower.tar",
+		&tar.Header{Name: "etc", Typeflag: tar.TypeSymlink, Linkname: outside},
+		&tar.Header{Name: "bin/", Typeflag: tar.TypeDir, Mode: 0755},
+		&tar.Header{Name: "bin/sh", Typeflag: tar.TypeReg, Mode: 04755, Size: 1},
+		&tar.Header{Name: "bin/ash", Typeflag: tar.TypeLink, Linkname: "/bin/sh"},
+	)
+
+	upper := writeLayer("upper.tar",
+		&tar.Header{Name: "etc/hosts", Typeflag: tar.TypeReg, Mode: 0644, Size: 1},
+		&tar.Header{Name: "../../escape", Typeflag: tar.TypeReg, Mode: 0644, Size: 1},
+		&tar.Header{Name: "bin/.wh.sh", Typeflag: tar.TypeReg},
+	)
+
+	require.NoError(t, os.MkdirAll(rootfs, 0755))
+	require.NoError(t, ociLayerApply(lower, rootfs, false))
+	require.NoError(t, ociLayerApply(upper, rootfs, false))
+
+	// Symlinks of lower layers are resolved within rootfs.
+	assert.True(t, shared.PathExists(filepath.Join(rootfs, outside, "hosts")))
+	assert.False(t, shared.PathExists(filepath.Join(outside, "hosts")))
+	assert.True(t, shared.PathExists(filepath.Join(rootfs, "escape")))
+	assert.False(t, shared.PathExists(filepath.Join(dir, "escape")))
+
+	// Whiteouts apply to lower layers only.
+	assert.False(t, shared.PathExists(filepath.Join(rootfs, "bin/sh")))
+	assert.True(t, shared.PathExists(filepath.Join(rootfs, "bin/ash")))
+
+	fi, err := os.Stat(filepath.Join(rootfs, "bin/ash"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755)|os.ModeSetuid, fi.Mode())
+}
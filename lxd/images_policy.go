@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	cron "gopkg.in/robfig/cron.v2"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+var imageAutoUpdatePinRegexp = regexp.MustCompile("^[0-9a-f]{1,64}$")
+
+// Validate the auto-update policy of an image.
+func imageValidateAutoUpdatePolicy(policy api.ImageAutoUpdatePolicy) error {
+	if policy.Window != "" {
+		if len(strings.Fields(policy.Window)) != 5 {
+			return fmt.Errorf("Auto-update window must be of the form: <minute> <hour> <day-of-month> <month> <day-of-week>")
+		}
+
+		_, err := cron.Parse(fmt.Sprintf("* %s", policy.Window))
+		if err != nil {
+			return errors.Wrap(err, "Invalid auto-update window")
+		}
+	}
+
+	if policy.Pin != "" && !imageAutoUpdatePinRegexp.MatchString(policy.Pin) {
+		return fmt.Errorf("Invalid auto-update pin %q: not a fingerprint", policy.Pin)
+	}
+
+	_, err := shared.GetSnapshotExpiry(time.Now(), policy.MaxAge)
+	if err != nil {
+		return errors.Wrap(err, "Invalid auto-update maximum age")
+	}
+
+	return nil
+}
+
+// Check whether the auto-update policy of the image allows for it to be
+// updated at the given time, that is whether a minute of the update window
+// occurred since the last check, or the image is older than its maximum age.
+func imageAutoUpdateDue(info *api.Image, since time.Time, now time.Time) bool {
+	policy := info.AutoUpdatePolicy
+	if policy.Window == "" {
+		return true
+	}
+
+	if policy.MaxAge != "" {
+		expiry, err := shared.GetSnapshotExpiry(info.UploadedAt, policy.MaxAge)
+		if err == nil && !expiry.After(now) {
+			return true
+		}
+	}
+
+	sched, err := cron.Parse(fmt.Sprintf("* %s", policy.Window))
+	if err != nil {
+		return false
+	}
+
+	// Only minutes matter, the minute of the last check being excluded as
+	// it was already matched back then, and the current one included.
+	next := sched.Next(since.Truncate(time.Minute).Add(time.Minute - time.Second))
+
+	return !next.After(now)
+}
+
+// Check whether the image may be replaced by the given one, as per its
+// auto-update pin.
+func imageAutoUpdatePinAllows(info *api.Image, fingerprint string) bool {
+	pin := info.AutoUpdatePolicy.Pin
+
+	return pin == "" || strings.HasPrefix(fingerprint, pin)
+}
+
+// Emit an event if a newer version of the image is available upstream,
+// rather than updating it.
+func imageAutoUpdateNotify(d *Daemon, info *api.Image, project string) error {
+	source := info.UpdateSource
+	if source == nil {
+		return nil
+	}
+
+	fingerprint, err := d.ImageResolve(source.Server, source.Protocol, source.Certificate, source.Alias, info.Type)
+	if err != nil {
+		return err
+	}
+
+	if fingerprint == info.Fingerprint || !imageAutoUpdatePinAllows(info, fingerprint) {
+		logger.Debug("No image update to notify about", log.Ctx{"fp": info.Fingerprint})
+		return nil
+	}
+
+	d.events.SendLifecycle(project, "image-update-available",
+		fmt.Sprintf("/1.0/images/%s", info.Fingerprint),
+		map[string]interface{}{
+			"fingerprint": fingerprint,
+			"server":      source.Server,
+			"alias":       source.Alias,
+		})
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestImageAutoUpdateDue(t *testing.T) {
+	at := func(value string) time.Time {
+		date, err := time.Parse("2006-01-02 15:04:05", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return date
+	}
+
+	cases := []struct {
+		name   string
+		policy api.ImageAutoUpdatePolicy
+		since  string
+		now    string
+		due    bool
+	}{
+		{"no window", api.ImageAutoUpdatePolicy{}, "2020-06-01 10:00:00", "2020-06-01 16:00:00", true},
+		{"window minute since last check", api.ImageAutoUpdatePolicy{Window: "30 12 * * *"}, "2020-06-01 10:00:00", "2020-06-01 16:00:00", true},
+		{"window minute is now", api.ImageAutoUpdatePolicy{Window: "0 16 * * *"}, "2020-06-01 10:00:00", "2020-06-01 16:00:30", true},
+		{"window minute before last check", api.ImageAutoUpdatePolicy{Window: "0 9 * * *"}, "2020-06-01 10:00:00", "2020-06-01 16:00:00", false},
+		{"window minute is last check", api.ImageAutoUpdatePolicy{Window: "0 10 * * *"}, "2020-06-01 10:00:10", "2020-06-01 16:00:00", false},
+		{"window minute after now", api.ImageAutoUpdatePolicy{Window: "1 16 * * *"}, "2020-06-01 10:00:00", "2020-06-01 16:00:00", false},
+		{"window on another day", api.ImageAutoUpdatePolicy{Window: "* * * * 0"}, "2020-06-01 10:00:00", "2020-06-01 16:00:00", false},
+		{"maximum age reached", api.ImageAutoUpdatePolicy{Window: "* * * * 0", MaxAge: "1w"}, "2020-06-01 10:00:00", "2020-06-01 16:00:00", true},
+		{"maximum age not reached", api.ImageAutoUpdatePolicy{Window: "* * * * 0", MaxAge: "4w"}, "2020-06-01 10:00:00", "2020-06-01 16:00:00", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			info := &api.Image{UploadedAt: at("2020-05-15 00:00:00")}
+			info.AutoUpdatePolicy = c.policy
+
+			assert.Equal(t, c.due, imageAutoUpdateDue(info, at(c.since), at(c.now)))
+		})
+	}
+}
@@ -0,0 +1,464 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/chunkstore"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/simplestreams"
+)
+
+// Cache of the SHA-256 of the image files served through the simplestreams
+// feed, by image fingerprint and path. Image files never change once stored,
+// so entries only go away along with their image.
+var simpleStreamsHashes = map[string]map[string]string{}
+var simpleStreamsHashesLock sync.Mutex
+
+// simpleStreamsHandlers returns the handlers serving the public images of
+// the projects which have images.simplestreams set as a simplestreams feed.
+func simpleStreamsHandlers(d *Daemon) map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/streams/v1/index.json": func(w http.ResponseWriter, r *http.Request) {
+			simpleStreamsRender(w, simpleStreamsIndexGet(d, r))
+		},
+		"/streams/v1/images/{project}.json": func(w http.ResponseWriter, r *http.Request) {
+			simpleStreamsRender(w, simpleStreamsProductsGet(d, r))
+		},
+		"/streams/images/{project}/{fingerprint}/{file}": func(w http.ResponseWriter, r *http.Request) {
+			simpleStreamsRender(w, simpleStreamsFileGet(d, r))
+		},
+	}
+}
+
+func simpleStreamsRender(w http.ResponseWriter, resp response.Response) {
+	err := resp.Render(w)
+	if err != nil {
+		logger.Warnf("Failed to render simplestreams response: %v", err)
+	}
+}
+
+// A response with the raw JSON encoding of the given data, as expected by
+// simplestreams clients.
+type simpleStreamsResponse struct {
+	data interface{}
+}
+
+func (r *simpleStreamsResponse) Render(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	return util.WriteJSON(w, r.data, false)
+}
+
+func (r *simpleStreamsResponse) String() string {
+	return "simplestreams"
+}
+
+func simpleStreamsIndexGet(d *Daemon, r *http.Request) response.Response {
+	if r.Method != "GET" {
+		return response.NotImplemented(nil)
+	}
+
+	projects, err := d.cluster.ImagesGetSimpleStreams()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	stream := simplestreams.Stream{
+		Format: "index:1.0",
+		Index:  map[string]simplestreams.StreamIndex{},
+	}
+
+	for project, fingerprints := range projects {
+		stream.Index[fmt.Sprintf("images:%s", project)] = simplestreams.StreamIndex{
+			DataType: "image-downloads",
+			Path:     fmt.Sprintf("streams/v1/images/%s.json", url.PathEscape(project)),
+			Format:   "products:1.0",
+			Products: fingerprints,
+		}
+	}
+
+	return &simpleStreamsResponse{data: stream}
+}
+
+func simpleStreamsProductsGet(d *Daemon, r *http.Request) response.Response {
+	if r.Method != "GET" {
+		return response.NotImplemented(nil)
+	}
+
+	project := mux.Vars(r)["project"]
+
+	projects, err := d.cluster.ImagesGetSimpleStreams()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	fingerprints, ok := projects[project]
+	if !ok {
+		return response.NotFound(nil)
+	}
+
+	products := simplestreams.Products{
+		ContentID: fmt.Sprintf("images:%s", project),
+		DataType:  "image-downloads",
+		Format:    "products:1.0",
+		Products:  map[string]simplestreams.Product{},
+	}
+
+	for _, fingerprint := range fingerprints {
+		_, image, err := d.cluster.ImageGet(project, fingerprint, true, true)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		product, err := simpleStreamsProduct(d, project, image)
+		if err != nil {
+			return response.SmartError(errors.Wrapf(err, "Failed to generate simplestreams product for image %q", fingerprint))
+		}
+
+		products.Products[fingerprint] = *product
+	}
+
+	return &simpleStreamsResponse{data: products}
+}
+
+func simpleStreamsFileGet(d *Daemon, r *http.Request) response.Response {
+	if r.Method != "GET" {
+		return response.NotImplemented(nil)
+	}
+
+	project := mux.Vars(r)["project"]
+	fingerprint := mux.Vars(r)["fingerprint"]
+	file := mux.Vars(r)["file"]
+
+	projects, err := d.cluster.ImagesGetSimpleStreams()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !shared.StringInSlice(fingerprint, projects[project]) {
+		return response.NotFound(nil)
+	}
+
+	// Reassemble the image files if stored as chunks.
+	release, err := imageChunksUnpack(d, fingerprint)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	resp := simpleStreamsFile(r, fingerprint, file)
+
+	return &imageChunksResponse{Response: resp, release: release}
+}
+
+// Return the given file of the image, or its detached signature.
+func simpleStreamsFile(r *http.Request, fingerprint string, file string) response.Response {
+	path := shared.VarPath("images", fingerprint)
+	if strings.HasPrefix(file, "rootfs.delta-") {
+		source := strings.TrimPrefix(file, "rootfs.delta-")
+		source = strings.TrimSuffix(strings.TrimSuffix(source, ".sig"), ".vcdiff")
+		if !imageDeltaSourceRegexp.MatchString(source) {
+			return response.NotFound(nil)
+		}
+
+		path = imageDeltaPath(fingerprint, source)
+	} else if strings.HasPrefix(file, "rootfs") {
+		path += ".rootfs"
+	} else if !strings.HasPrefix(file, "lxd") {
+		return response.NotFound(nil)
+	}
+
+	// Detached signatures are served alongside the files they sign.
+	if strings.HasSuffix(file, ".sig") {
+		path += ".sig"
+	}
+
+	if !shared.PathExists(path) {
+		return response.NotFound(nil)
+	}
+
+	files := []response.FileResponseEntry{{Path: path, Filename: file}}
+
+	return response.FileResponse(r, files, nil, false)
+}
+
+// Generate the simplestreams product for the given image, with a single
+// version holding its files.
+func simpleStreamsProduct(d *Daemon, project string, image *api.Image) (*simplestreams.Product, error) {
+	// Image files stored as chunks are described from the index of the
+	// chunked image store, rather than reassembled.
+	index, err := imageChunksIndex(d, image.Fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	metaPath := shared.VarPath("images", image.Fingerprint)
+	rootfsPath := shared.VarPath("images", image.Fingerprint+".rootfs")
+	baseURL := fmt.Sprintf("streams/images/%s/%s", url.PathEscape(project), image.Fingerprint)
+
+	meta, err := simpleStreamsItem(d, image.Fingerprint, metaPath, index["metadata"], fmt.Sprintf("%s/lxd", baseURL))
+	if err != nil {
+		return nil, err
+	}
+
+	items := map[string]simplestreams.ProductVersionItem{}
+	versions := map[string]simplestreams.ProductVersion{}
+
+	if len(index["rootfs"]) > 0 || shared.PathExists(rootfsPath) {
+		// Split image
+		root, err := simpleStreamsItem(d, image.Fingerprint, rootfsPath, index["rootfs"], fmt.Sprintf("%s/rootfs", baseURL))
+		if err != nil {
+			return nil, err
+		}
+
+		meta.FileType = "lxd.tar.xz"
+		meta.LXDHashSha256 = image.Fingerprint
+
+		if image.Type == "virtual-machine" {
+			root.FileType = "disk-kvm.img"
+			meta.LXDHashSha256DiskKvmImg = image.Fingerprint
+		} else if strings.HasSuffix(root.Path, ".squashfs") {
+			root.FileType = "squashfs"
+			meta.LXDHashSha256SquashFs = image.Fingerprint
+
+			// Clients only apply deltas to squashfs images.
+			err = simpleStreamsDeltas(image.Fingerprint, baseURL, items, versions)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			root.FileType = "root.tar.xz"
+			meta.LXDHashSha256RootXz = image.Fingerprint
+		}
+
+		items[root.FileType] = *root
+	} else {
+		// Unified image
+		meta.FileType = "lxd_combined.tar.gz"
+	}
+
+	items[meta.FileType] = *meta
+
+	aliases := []string{}
+	for _, alias := range image.Aliases {
+		aliases = append(aliases, alias.Name)
+	}
+	sort.Strings(aliases)
+
+	release := image.Properties["release"]
+	if release == "" {
+		release = image.Fingerprint[0:12]
+	}
+
+	versions[image.CreatedAt.UTC().Format("20060102_15:04")] = simplestreams.ProductVersion{
+		Items: items,
+		Label: image.Properties["label"],
+	}
+
+	product := &simplestreams.Product{
+		Aliases:         strings.Join(aliases, ","),
+		Architecture:    image.Architecture,
+		OperatingSystem: image.Properties["os"],
+		Release:         release,
+		ReleaseTitle:    release,
+		Version:         image.Properties["version"],
+		Versions:        versions,
+	}
+
+	return product, nil
+}
+
+// Add the deltas to the rootfs of the given image to its items. Simplestreams
+// clients resolve the source of a delta through another version of the same
+// product, so a stub version only identifying the source image is added for
+// each of them.
+func simpleStreamsDeltas(fingerprint string, baseURL string, items map[string]simplestreams.ProductVersionItem, versions map[string]simplestreams.ProductVersion) error {
+	sources, err := imageDeltaSources(fingerprint)
+	if err != nil {
+		return err
+	}
+
+	for _, source := range sources {
+		path := imageDeltaPath(fingerprint, source)
+
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		hash, err := simpleStreamsHash(fingerprint, path)
+		if err != nil {
+			return err
+		}
+
+		base := fmt.Sprintf("delta-%s", source)
+
+		items[fmt.Sprintf("squashfs.vcdiff-%s", source)] = simplestreams.ProductVersionItem{
+			FileType:   "squashfs.vcdiff",
+			Path:       fmt.Sprintf("%s/rootfs.delta-%s.vcdiff", baseURL, source),
+			HashSha256: hash,
+			Size:       fi.Size(),
+			DeltaBase:  base,
+		}
+
+		versions[base] = simplestreams.ProductVersion{
+			Items: map[string]simplestreams.ProductVersionItem{
+				"lxd.tar.xz": {
+					FileType:              "lxd.tar.xz",
+					LXDHashSha256SquashFs: source,
+				},
+			},
+		}
+	}
+
+	return nil
+}
+
+// Generate the simplestreams item for the given file of the image with the
+// given fingerprint, made of the given chunks if stored as chunks, served
+// under the given path with the extension matching its compression.
+func simpleStreamsItem(d *Daemon, fingerprint string, path string, chunks []chunkstore.Chunk, urlPath string) (*simplestreams.ProductVersionItem, error) {
+	if len(chunks) > 0 {
+		return simpleStreamsChunkedItem(d, fingerprint, path, chunks, urlPath)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	_, ext, _, err := shared.DetectCompression(path)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := simpleStreamsHash(fingerprint, path)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &simplestreams.ProductVersionItem{
+		Path:       urlPath + ext,
+		HashSha256: hash,
+		Size:       fi.Size(),
+	}
+
+	return item, nil
+}
+
+// Generate the simplestreams item for the given image file stored as the
+// given chunks, using the hash recorded when it was packed. The compression
+// is detected from the first chunk, holding the start of the file.
+func simpleStreamsChunkedItem(d *Daemon, fingerprint string, path string, chunks []chunkstore.Chunk, urlPath string) (*simplestreams.ProductVersionItem, error) {
+	store := imageChunksStore()
+
+	size := int64(0)
+	for _, chunk := range chunks {
+		size += chunk.Size
+	}
+
+	_, ext, _, err := shared.DetectCompression(store.Path(chunks[0].Hash))
+	if err != nil {
+		return nil, err
+	}
+
+	file := filepath.Base(path)
+	hash := ""
+	err = d.db.Transaction(func(tx *db.NodeTx) error {
+		var err error
+		hash, err = tx.ImageChunksHashGet(file)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Images fetched as chunks from another cluster member have no hash
+	// recorded yet, so it's computed from the chunks once.
+	if hash == "" {
+		_, unlock := imageChunksLockImage(fingerprint)
+		defer unlock()
+
+		hasher := sha256.New()
+		err = store.Assemble(chunks, hasher)
+		if err != nil {
+			return nil, err
+		}
+
+		hash = fmt.Sprintf("%x", hasher.Sum(nil))
+		err = d.db.Transaction(func(tx *db.NodeTx) error {
+			return tx.ImageChunksHashSet(file, hash)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	item := &simplestreams.ProductVersionItem{
+		Path:       urlPath + ext,
+		HashSha256: hash,
+		Size:       size,
+	}
+
+	return item, nil
+}
+
+// Return the SHA-256 of the given file of the image with the given
+// fingerprint.
+func simpleStreamsHash(fingerprint string, path string) (string, error) {
+	simpleStreamsHashesLock.Lock()
+	hash, ok := simpleStreamsHashes[fingerprint][path]
+	simpleStreamsHashesLock.Unlock()
+	if ok {
+		return hash, nil
+	}
+
+	// Hashing the file may take a while, so it's done without holding the
+	// lock.
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	_, err = io.Copy(hasher, f)
+	if err != nil {
+		return "", err
+	}
+
+	hash = fmt.Sprintf("%x", hasher.Sum(nil))
+
+	simpleStreamsHashesLock.Lock()
+	defer simpleStreamsHashesLock.Unlock()
+
+	if simpleStreamsHashes[fingerprint] == nil {
+		simpleStreamsHashes[fingerprint] = map[string]string{}
+	}
+	simpleStreamsHashes[fingerprint][path] = hash
+
+	return hash, nil
+}
+
+// Forget the SHA-256 of the files of the image with the given fingerprint.
+func simpleStreamsHashesForget(fingerprint string) {
+	simpleStreamsHashesLock.Lock()
+	defer simpleStreamsHashesLock.Unlock()
+
+	delete(simpleStreamsHashes, fingerprint)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/lxc/lxd/lxd/db"
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/metrics"
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/shared/api"
@@ -37,7 +38,11 @@ type Instance interface {
 	Update(newConfig db.InstanceArgs, userRequested bool) error
 
 	Delete() error
-	Export(w io.Writer, properties map[string]string) error
+
+	// Export the instance as an image tarball. A non-zero creation date
+	// is also used as the modification time of all the files, making the
+	// tarball reproducible.
+	Export(w io.Writer, properties map[string]string, creationDate time.Time) error
 
 	// Live configuration
 	CGroupGet(key string) (string, error)
@@ -58,6 +63,7 @@ type Instance interface {
 	Render() (interface{}, interface{}, error)
 	RenderFull() (*api.InstanceFull, interface{}, error)
 	RenderState() (*api.InstanceState, error)
+	Metrics() (*metrics.MetricSet, error)
 	IsRunning() bool
 	IsFrozen() bool
 	IsEphemeral() bool
@@ -2,7 +2,9 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -36,6 +38,14 @@ func (c *cmdCluster) Command() *cobra.Command {
 	recover := cmdClusterRecoverFromQuorumLoss{global: c.global}
 	cmd.AddCommand(recover.Command())
 
+	// Backup database
+	backup := cmdClusterBackupDatabase{global: c.global}
+	cmd.AddCommand(backup.Command())
+
+	// Restore database
+	restore := cmdClusterRestoreDatabase{global: c.global}
+	cmd.AddCommand(restore.Command())
+
 	return cmd
 }
 
@@ -147,3 +157,140 @@ Do you want to proceed? (yes/no): `)
 	}
 	return nil
 }
+
+type cmdClusterBackupDatabase struct {
+	global *cmdGlobal
+}
+
+func (c *cmdClusterBackupDatabase) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = "backup-database <file>"
+	cmd.Short = "Save a consistent backup of the cluster database to a file"
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterBackupDatabase) Run(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		cmd.Help()
+
+		if len(args) == 0 {
+			return nil
+		}
+
+		return fmt.Errorf("Invalid number of arguments")
+	}
+
+	// Connect to LXD
+	d, err := lxd.ConnectLXDUnix("", &lxd.ConnectionArgs{SkipGetServer: true})
+	if err != nil {
+		return err
+	}
+
+	response, _, err := d.RawQuery("GET", "/internal/cluster/database", nil, "")
+	if err != nil {
+		return errors.Wrap(err, "Failed to request database backup")
+	}
+
+	backup := internalClusterDatabase{}
+	err = json.Unmarshal(response.Metadata, &backup)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse database backup")
+	}
+
+	data, err := json.Marshal(backup)
+	if err != nil {
+		return err
+	}
+
+	// The backup contains certificates and secrets, so keep it private.
+	err = ioutil.WriteFile(args[0], data, 0600)
+	if err != nil {
+		return errors.Wrap(err, "Failed to write database backup")
+	}
+
+	return nil
+}
+
+type cmdClusterRestoreDatabase struct {
+	global             *cmdGlobal
+	flagNonInteractive bool
+}
+
+func (c *cmdClusterRestoreDatabase) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = "restore-database <file>"
+	cmd.Short = "Restore a backup of the cluster database onto a freshly bootstrapped member"
+
+	cmd.RunE = c.Run
+
+	cmd.Flags().BoolVarP(&c.flagNonInteractive, "quiet", "q", false, "Don't require user confirmation")
+
+	return cmd
+}
+
+func (c *cmdClusterRestoreDatabase) Run(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		cmd.Help()
+
+		if len(args) == 0 {
+			return nil
+		}
+
+		return fmt.Errorf("Invalid number of arguments")
+	}
+
+	data, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return errors.Wrap(err, "Failed to read database backup")
+	}
+
+	backup := internalClusterDatabase{}
+	err = json.Unmarshal(data, &backup)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse database backup")
+	}
+
+	// Prompt for confirmation unless --quiet was passed.
+	if !c.flagNonInteractive {
+		err := c.promptConfirmation()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Connect to LXD
+	d, err := lxd.ConnectLXDUnix("", &lxd.ConnectionArgs{SkipGetServer: true})
+	if err != nil {
+		return err
+	}
+
+	_, _, err = d.RawQuery("POST", "/internal/cluster/database", backup, "")
+	if err != nil {
+		return errors.Wrap(err, "Failed to restore database backup")
+	}
+
+	fmt.Println("The cluster database has been restored, please restart LXD on this member.")
+
+	return nil
+}
+
+func (c *cmdClusterRestoreDatabase) promptConfirmation() error {
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Printf(`This replaces the whole content of the cluster database with the backup.
+
+This member must be freshly bootstrapped, with the same name as the member of
+the backed up cluster it replaces. The other members in the backup will show up
+as offline, and can be removed with "lxc cluster remove <member-name> --force".
+
+Do you want to proceed? (yes/no): `)
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSuffix(input, "\n")
+
+	if !shared.StringInSlice(strings.ToLower(input), []string{"yes"}) {
+		return fmt.Errorf("Restore operation aborted")
+	}
+	return nil
+}
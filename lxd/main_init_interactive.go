@@ -121,7 +121,36 @@ func (c *cmdInit) askClustering(config *cmdInitData, d lxd.InstanceServer) error
 		if cli.AskBool("Are you joining an existing cluster? (yes/no) [default=no]: ", "no") {
 			// Existing cluster
 			config.Cluster.ServerAddress = serverAddress
-			for {
+
+			// Join token
+			joinSecret := ""
+			if cli.AskBool("Do you have a join token? (yes/no) [default=no]: ", "no") {
+				for {
+					token, err := cluster.DecodeJoinToken(cli.AskString("Please provide join token: ", "", nil))
+					if err != nil {
+						fmt.Printf("Invalid join token: %v\n", err)
+						continue
+					}
+
+					clusterAddress, clusterCert, err := cluster.JoinTokenTarget(token)
+					if err != nil {
+						return err
+					}
+
+					if config.Cluster.ServerName != token.ServerName {
+						fmt.Printf("Using the member name %q the join token was issued for\n", token.ServerName)
+						config.Cluster.ServerName = token.ServerName
+					}
+
+					config.Cluster.ClusterAddress = clusterAddress
+					config.Cluster.ClusterCertificate = clusterCert
+					config.Cluster.ClusterToken = token.String()
+					joinSecret = token.Secret
+					break
+				}
+			}
+
+			for joinSecret == "" {
 				// Cluster URL
 				clusterAddress := cli.AskString("IP address or FQDN of an existing cluster node: ", "", nil)
 				_, _, err := net.SplitHostPort(clusterAddress)
@@ -166,9 +195,14 @@ func (c *cmdInit) askClustering(config *cmdInitData, d lxd.InstanceServer) error
 				return err
 			}
 
+			password := config.Cluster.ClusterPassword
+			if joinSecret != "" {
+				password = joinSecret
+			}
+
 			err = cluster.SetupTrust(string(cert.PublicKey()),
 				config.Cluster.ClusterAddress,
-				string(config.Cluster.ClusterCertificate), config.Cluster.ClusterPassword)
+				string(config.Cluster.ClusterCertificate), password)
 			if err != nil {
 				return errors.Wrap(err, "Failed to setup trust relationship with cluster")
 			}
@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the upper bounds of the histogram buckets, in seconds,
+// suitable for request latencies.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Daemon-wide histograms.
+var (
+	APIRequestDurationHistogram       = NewHistogram(APIRequestDuration, DefaultBuckets)
+	ClusterTransactionTimingHistogram = NewHistogram(ClusterTransactionTiming, DefaultBuckets)
+	ClusterHeartbeatTimingHistogram   = NewHistogram(ClusterHeartbeatTiming, DefaultBuckets)
+)
+
+// Histogram counts observed values into buckets, for each set of labels.
+type Histogram struct {
+	name    string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labels map[string]string
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram returns a new histogram for the given metric family, using
+// the given bucket upper bounds.
+func NewHistogram(name string, buckets []float64) *Histogram {
+	return &Histogram{
+		name:    name,
+		buckets: buckets,
+		series:  map[string]*histogramSeries{},
+	}
+}
+
+// Observe records the given value.
+func (h *Histogram) Observe(labels map[string]string, value float64) {
+	key := renderLabels(labels)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	series, ok := h.series[key]
+	if !ok {
+		series = &histogramSeries{
+			labels: labels,
+			counts: make([]uint64, len(h.buckets)),
+		}
+
+		h.series[key] = series
+	}
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			series.counts[i]++
+		}
+	}
+
+	series.sum += value
+	series.count++
+}
+
+// ObserveSince records the time elapsed since the given start time.
+func (h *Histogram) ObserveSince(labels map[string]string, start time.Time) {
+	h.Observe(labels, time.Since(start).Seconds())
+}
+
+// Collect adds the samples of the histogram to the given set.
+func (h *Histogram) Collect(set *MetricSet) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		series := h.series[key]
+		samples := []Sample{}
+
+		for i, bound := range h.buckets {
+			samples = append(samples, Sample{
+				Labels: bucketLabels(series.labels, renderValue(bound)),
+				Value:  float64(series.counts[i]),
+				Suffix: "_bucket",
+			})
+		}
+
+		samples = append(samples,
+			Sample{Labels: bucketLabels(series.labels, "+Inf"), Value: float64(series.count), Suffix: "_bucket"},
+			Sample{Labels: series.labels, Value: series.roundedSum(), Suffix: "_sum"},
+			Sample{Labels: series.labels, Value: float64(series.count), Suffix: "_count"},
+		)
+
+		set.AddSamples(h.name, samples...)
+	}
+}
+
+// Return the sum of the observed values, rounded to the nanosecond to avoid
+// rendering floating point noise.
+func (s *histogramSeries) roundedSum() float64 {
+	return math.Round(s.sum*1e9) / 1e9
+}
+
+func bucketLabels(labels map[string]string, bound string) map[string]string {
+	result := map[string]string{}
+	for k, v := range labels {
+		result[k] = v
+	}
+
+	result["le"] = bound
+
+	return result
+}
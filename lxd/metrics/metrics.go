@@ -0,0 +1,194 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the content type of the OpenMetrics text format.
+const ContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// Type is the type of a metric family.
+type Type string
+
+// Metric family types.
+const (
+	TypeCounter   Type = "counter"
+	TypeGauge     Type = "gauge"
+	TypeHistogram Type = "histogram"
+)
+
+// Names of the metric families.
+const (
+	CPUSeconds               = "lxd_cpu_seconds"
+	MemoryUsageBytes         = "lxd_memory_usage_bytes"
+	MemoryUsagePeakBytes     = "lxd_memory_usage_peak_bytes"
+	MemorySwapUsageBytes     = "lxd_memory_swap_usage_bytes"
+	DiskReadBytes            = "lxd_disk_read_bytes"
+	DiskWrittenBytes         = "lxd_disk_written_bytes"
+	DiskReadsCompleted       = "lxd_disk_reads_completed"
+	DiskWritesCompleted      = "lxd_disk_writes_completed"
+	NetworkReceiveBytes      = "lxd_network_receive_bytes"
+	NetworkTransmitBytes     = "lxd_network_transmit_bytes"
+	NetworkReceivePackets    = "lxd_network_receive_packets"
+	NetworkTransmitPackets   = "lxd_network_transmit_packets"
+	Procs                    = "lxd_procs"
+	GoGoroutines             = "lxd_go_goroutines"
+	Operations               = "lxd_operations"
+	APIRequestDuration       = "lxd_api_request_duration_seconds"
+	ClusterTransactionTiming = "lxd_cluster_transaction_duration_seconds"
+	ClusterHeartbeatTiming   = "lxd_cluster_heartbeat_duration_seconds"
+)
+
+type definition struct {
+	Type Type
+	Help string
+}
+
+var definitions = map[string]definition{
+	CPUSeconds:               {TypeCounter, "The total CPU time used, in seconds."},
+	MemoryUsageBytes:         {TypeGauge, "The amount of used memory."},
+	MemoryUsagePeakBytes:     {TypeGauge, "The peak amount of used memory."},
+	MemorySwapUsageBytes:     {TypeGauge, "The amount of used swap."},
+	DiskReadBytes:            {TypeCounter, "The total number of bytes read."},
+	DiskWrittenBytes:         {TypeCounter, "The total number of bytes written."},
+	DiskReadsCompleted:       {TypeCounter, "The total number of completed reads."},
+	DiskWritesCompleted:      {TypeCounter, "The total number of completed writes."},
+	NetworkReceiveBytes:      {TypeCounter, "The total number of bytes received."},
+	NetworkTransmitBytes:     {TypeCounter, "The total number of bytes transmitted."},
+	NetworkReceivePackets:    {TypeCounter, "The total number of packets received."},
+	NetworkTransmitPackets:   {TypeCounter, "The total number of packets transmitted."},
+	Procs:                    {TypeGauge, "The number of running processes."},
+	GoGoroutines:             {TypeGauge, "The number of goroutines of the daemon."},
+	Operations:               {TypeGauge, "The number of operations, by type and status."},
+	APIRequestDuration:       {TypeHistogram, "The time taken to handle API requests."},
+	ClusterTransactionTiming: {TypeHistogram, "The time taken by cluster database transactions."},
+	ClusterHeartbeatTiming:   {TypeHistogram, "The time taken by heartbeat requests to cluster members."},
+}
+
+// Sample is a single value of a metric family.
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+
+	// Suffix appended to the family name, for histogram samples.
+	Suffix string
+}
+
+// MetricSet is a set of metric families and their samples.
+type MetricSet struct {
+	samples map[string][]Sample
+	labels  map[string]string
+}
+
+// NewMetricSet returns an empty set of metrics, the given labels being added
+// to all of its samples.
+func NewMetricSet(labels map[string]string) *MetricSet {
+	return &MetricSet{
+		samples: map[string][]Sample{},
+		labels:  labels,
+	}
+}
+
+// AddSamples adds samples to the given metric family.
+func (m *MetricSet) AddSamples(name string, samples ...Sample) {
+	_, ok := definitions[name]
+	if !ok {
+		panic(fmt.Sprintf("Unknown metric %q", name))
+	}
+
+	for _, sample := range samples {
+		labels := map[string]string{}
+		for k, v := range m.labels {
+			labels[k] = v
+		}
+
+		for k, v := range sample.Labels {
+			labels[k] = v
+		}
+
+		sample.Labels = labels
+		m.samples[name] = append(m.samples[name], sample)
+	}
+}
+
+// Add adds a single sample to the given metric family.
+func (m *MetricSet) Add(name string, value float64, labels map[string]string) {
+	m.AddSamples(name, Sample{Labels: labels, Value: value})
+}
+
+// Merge adds all the samples of the given set to this one. The labels of
+// this set get added to them.
+func (m *MetricSet) Merge(other *MetricSet) {
+	if other == nil {
+		return
+	}
+
+	for name, samples := range other.samples {
+		m.AddSamples(name, samples...)
+	}
+}
+
+// String renders the set in the OpenMetrics text format.
+func (m *MetricSet) String() string {
+	names := make([]string, 0, len(m.samples))
+	for name := range m.samples {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var b strings.Builder
+
+	for _, name := range names {
+		def := definitions[name]
+
+		fmt.Fprintf(&b, "# HELP %s %s\n", name, def.Help)
+		fmt.Fprintf(&b, "# TYPE %s %s\n", name, def.Type)
+
+		for _, sample := range m.samples[name] {
+			suffix := sample.Suffix
+			if def.Type == TypeCounter {
+				suffix = "_total"
+			}
+
+			fmt.Fprintf(&b, "%s%s%s %s\n", name, suffix, renderLabels(sample.Labels), renderValue(sample.Value))
+		}
+	}
+
+	b.WriteString("# EOF\n")
+
+	return b.String()
+}
+
+func renderLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s=\"%s\"", k, escapeLabel(labels[k]))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func renderValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricSet_String(t *testing.T) {
+	set := NewMetricSet(map[string]string{"project": "default"})
+	set.Add(Procs, 12, map[string]string{"name": "c1"})
+	set.Add(CPUSeconds, 1.5, map[string]string{"name": "c\"1"})
+
+	other := NewMetricSet(map[string]string{"name": "c2"})
+	other.Add(Procs, 3, nil)
+	set.Merge(other)
+
+	expected := `# HELP lxd_cpu_seconds The total CPU time used, in seconds.
+# TYPE lxd_cpu_seconds counter
+lxd_cpu_seconds_total{name="c\"1",project="default"} 1.5
+# HELP lxd_procs The number of running processes.
+# TYPE lxd_procs gauge
+lxd_procs{name="c1",project="default"} 12
+lxd_procs{name="c2",project="default"} 3
+# EOF
+`
+	assert.Equal(t, expected, set.String())
+}
+
+func TestHistogram_Collect(t *testing.T) {
+	h := NewHistogram(APIRequestDuration, []float64{0.1, 1})
+	h.Observe(map[string]string{"method": "GET"}, 0.05)
+	h.Observe(map[string]string{"method": "GET"}, 0.5)
+	h.Observe(map[string]string{"method": "GET"}, 3)
+
+	set := NewMetricSet(nil)
+	h.Collect(set)
+
+	expected := `# HELP lxd_api_request_duration_seconds The time taken to handle API requests.
+# TYPE lxd_api_request_duration_seconds histogram
+lxd_api_request_duration_seconds_bucket{le="0.1",method="GET"} 1
+lxd_api_request_duration_seconds_bucket{le="1",method="GET"} 2
+lxd_api_request_duration_seconds_bucket{le="+Inf",method="GET"} 3
+lxd_api_request_duration_seconds_sum{method="GET"} 3.55
+lxd_api_request_duration_seconds_count{method="GET"} 3
+# EOF
+`
+	assert.Equal(t, expected, set.String())
+}
@@ -148,6 +148,10 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 
 	targetNode := queryParam(r, "target")
 	if targetNode != "" {
+		if len(req.Members) > 0 {
+			return response.BadRequest(fmt.Errorf("Node-specific configs can't be combined with a target node"))
+		}
+
 		// A targetNode was specified, let's just define the node's
 		// network without actually creating it. The only legal key
 		// value for the storage config is 'bridge.external_interfaces'.
@@ -175,7 +179,11 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 	}
 
 	if count > 1 {
-		err = networksPostCluster(d, req)
+		if len(req.Members) > 0 {
+			err = networksPostMembers(d, req)
+		} else {
+			err = networksPostCluster(d, req, false)
+		}
 		if err != nil {
 			return response.SmartError(err)
 		}
@@ -183,6 +191,15 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 		return resp
 	}
 
+	// A single node can only carry its own node-specific config, which
+	// can just be merged into the global one.
+	if len(req.Members) > 0 {
+		err = networkMergeMembersConfig(d, &req)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+	}
+
 	err = networkFillConfig(&req)
 	if err != nil {
 		return response.SmartError(err)
@@ -214,10 +231,118 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 	return resp
 }
 
-func networksPostCluster(d *Daemon, req api.NetworksPost) error {
+// Define the network as pending on all nodes using the node-specific configs
+// passed in the request, then create it everywhere. If creation fails on any
+// node, the network is removed again from all nodes.
+func networksPostMembers(d *Daemon, req api.NetworksPost) error {
+	for nodeName, config := range req.Members {
+		for key := range config {
+			if !shared.StringInSlice(key, db.NetworkNodeConfigKeys) {
+				return fmt.Errorf("Config key '%s' of node %s may not be used as node-specific key", key, nodeName)
+			}
+		}
+	}
+
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		// Check that all the given nodes exist.
+		for nodeName := range req.Members {
+			_, err := tx.NodeByName(nodeName)
+			if err != nil {
+				if err == db.ErrNoSuchObject {
+					return fmt.Errorf("Node %s is not a member of the cluster", nodeName)
+				}
+				return err
+			}
+		}
+
+		_, err := tx.NetworkID(req.Name)
+		if err == nil {
+			return fmt.Errorf("The network is already defined")
+		}
+		if err != db.ErrNoSuchObject {
+			return err
+		}
+
+		nodes, err := tx.Nodes()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			config, ok := req.Members[node.Name]
+			if !ok {
+				config = map[string]string{}
+			}
+
+			err := tx.NetworkCreatePending(node.Name, req.Name, config)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	req.Members = nil
+
+	return networksPostCluster(d, req, true)
+}
+
+// Merge the node-specific config of the local node into the global config.
+func networkMergeMembersConfig(d *Daemon, req *api.NetworksPost) error {
+	var nodeName string
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		nodeName, err = tx.NodeName()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for name, config := range req.Members {
+		if name != nodeName {
+			return fmt.Errorf("Node %s is not a member of the cluster", name)
+		}
+
+		for key, value := range config {
+			if !shared.StringInSlice(key, db.NetworkNodeConfigKeys) {
+				return fmt.Errorf("Config key '%s' of node %s may not be used as node-specific key", key, name)
+			}
+
+			req.Config[key] = value
+		}
+	}
+
+	req.Members = nil
+
+	return nil
+}
+
+// Create a network pending on all nodes. If revert is true, any failure
+// will cause the network to be deleted from the nodes it was created on and
+// from the database, otherwise the network will be marked as errored.
+func networksPostCluster(d *Daemon, req api.NetworksPost, revert bool) error {
+	// Remove the pending network from the database if it fails to get
+	// created on this node.
+	revertPending := func() {
+		if !revert {
+			return
+		}
+
+		err := d.cluster.NetworkDelete(req.Name)
+		if err != nil {
+			logger.Errorf("Failed to delete network %s from the database: %v", req.Name, err)
+		}
+	}
+
 	// Check that no node-specific config key has been defined.
 	for key := range req.Config {
 		if shared.StringInSlice(key, db.NetworkNodeConfigKeys) {
+			revertPending()
 			return fmt.Errorf("Config key '%s' is node-specific", key)
 		}
 	}
@@ -225,6 +350,7 @@ func networksPostCluster(d *Daemon, req api.NetworksPost) error {
 	// Merge the current config.
 	networkID, dbNetwork, err := d.cluster.NetworkGet(req.Name)
 	if err != nil {
+		revertPending()
 		return err
 	}
 
@@ -238,6 +364,7 @@ func networksPostCluster(d *Daemon, req api.NetworksPost) error {
 	// Add default values.
 	err = networkFillConfig(&req)
 	if err != nil {
+		revertPending()
 		return err
 	}
 
@@ -262,6 +389,7 @@ func networksPostCluster(d *Daemon, req api.NetworksPost) error {
 		return tx.NetworkConfigAdd(networkID, 0, req.Config)
 	})
 	if err != nil {
+		revertPending()
 		if err == db.ErrNoSuchObject {
 			return fmt.Errorf("Network not pending on any node (use --target <node> first)")
 		}
@@ -275,14 +403,21 @@ func networksPostCluster(d *Daemon, req api.NetworksPost) error {
 	}
 	err = doNetworksCreate(d, nodeReq, false)
 	if err != nil {
+		revertPending()
 		return err
 	}
 
 	// Notify all other nodes to create the network.
 	notifier, err := cluster.NewNotifier(d.State(), d.endpoints.NetworkCert(), cluster.NotifyAll)
 	if err != nil {
+		if revert {
+			networksPostClusterRevert(d, req.Name, nil)
+		}
 		return err
 	}
+
+	created := []string{}
+	createdLock := sync.Mutex{}
 	notifyErr := notifier(func(client lxd.InstanceServer) error {
 		server, _, err := client.GetServer()
 		if err != nil {
@@ -294,7 +429,16 @@ func networksPostCluster(d *Daemon, req api.NetworksPost) error {
 			nodeReq.Config[key] = value
 		}
 
-		return client.CreateNetwork(nodeReq)
+		err = client.CreateNetwork(nodeReq)
+		if err != nil {
+			return err
+		}
+
+		createdLock.Lock()
+		created = append(created, server.Environment.ServerName)
+		createdLock.Unlock()
+
+		return nil
 	})
 
 	errored := notifyErr != nil
@@ -307,12 +451,61 @@ func networksPostCluster(d *Daemon, req api.NetworksPost) error {
 		return tx.NetworkCreated(req.Name)
 	})
 	if err != nil {
+		if revert {
+			networksPostClusterRevert(d, req.Name, created)
+		}
 		return err
 	}
 
+	if errored && revert {
+		networksPostClusterRevert(d, req.Name, created)
+	}
+
 	return notifyErr
 }
 
+// Delete the network from this node and from the given other nodes, and
+// then remove it from the database. The network must not be in the pending
+// state anymore, otherwise the other nodes would only drop the database
+// entry.
+func networksPostClusterRevert(d *Daemon, name string, nodes []string) {
+	if len(nodes) > 0 {
+		notifier, err := cluster.NewNotifier(d.State(), d.endpoints.NetworkCert(), cluster.NotifyAlive)
+		if err != nil {
+			logger.Errorf("Failed to revert creation of network %s on other nodes: %v", name, err)
+		} else {
+			err = notifier(func(client lxd.InstanceServer) error {
+				server, _, err := client.GetServer()
+				if err != nil {
+					return err
+				}
+
+				if !shared.StringInSlice(server.Environment.ServerName, nodes) {
+					return nil
+				}
+
+				return client.DeleteNetwork(name)
+			})
+			if err != nil {
+				logger.Errorf("Failed to revert creation of network %s on other nodes: %v", name, err)
+			}
+		}
+	}
+
+	n, err := networkLoadByName(d.State(), name)
+	if err == nil {
+		err = n.Delete(false)
+		if err != nil {
+			logger.Errorf("Failed to revert creation of network %s: %v", name, err)
+		}
+	}
+
+	err = d.cluster.NetworkDelete(name)
+	if err != nil {
+		logger.Errorf("Failed to delete network %s from the database: %v", name, err)
+	}
+}
+
 func networkFillConfig(req *api.NetworksPost) error {
 	// Set some default values where needed
 	if req.Config["bridge.mode"] == "fan" {
@@ -0,0 +1,441 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Tolerated clock skew when checking the validity period of tokens.
+const leeway = time.Minute
+
+// How long the signing keys of the provider are cached for.
+const keysExpiry = time.Hour
+
+// Minimum time between two fetches of the signing keys, when looking for an
+// unknown key.
+const keysMinInterval = 10 * time.Second
+
+// AuthError is returned when a bearer token can't be validated.
+type AuthError struct {
+	Err error
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("Failed to validate OIDC token: %v", e.Err)
+}
+
+// Verifier validates the access tokens issued by an OpenID Connect provider.
+type Verifier struct {
+	issuer   string
+	clientID string
+	audience string
+	claim    string
+	client   *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey
+	keysFetched time.Time
+
+	// Closed once the ongoing fetch of the signing keys, if any, is done,
+	// with its error recorded in fetchErr.
+	fetching chan struct{}
+	fetchErr error
+}
+
+// NewVerifier returns a verifier of the tokens of the given issuer, meant for
+// the given audience (defaulting to the client ID). The username of the
+// caller is taken from the given claim.
+func NewVerifier(issuer string, clientID string, audience string, claim string) *Verifier {
+	if audience == "" {
+		audience = clientID
+	}
+
+	if claim == "" {
+		claim = "sub"
+	}
+
+	return &Verifier{
+		issuer:   strings.TrimSuffix(issuer, "/"),
+		clientID: clientID,
+		audience: audience,
+		claim:    claim,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// WriteHeaders advertises the provider to clients, so they can log in.
+func (v *Verifier) WriteHeaders(w http.ResponseWriter) {
+	w.Header().Set("X-LXD-OIDC-issuer", v.issuer)
+	w.Header().Set("X-LXD-OIDC-clientid", v.clientID)
+	w.Header().Set("X-LXD-OIDC-audience", v.audience)
+}
+
+// Auth validates the given token, returning the username of its bearer.
+func (v *Verifier) Auth(token string) (string, error) {
+	claims, err := v.verify(token)
+	if err != nil {
+		return "", &AuthError{Err: err}
+	}
+
+	username, ok := claims[v.claim].(string)
+	if !ok || username == "" {
+		return "", &AuthError{Err: fmt.Errorf("Missing %q claim", v.claim)}
+	}
+
+	return username, nil
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// Check the signature and the standard claims of the token, returning all
+// of its claims.
+func (v *Verifier) verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("Malformed token")
+	}
+
+	h := header{}
+	err := decodeSegment(parts[0], &h)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid token header")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid token signature")
+	}
+
+	key, err := v.key(h.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = verifySignature(h.Algorithm, key, []byte(parts[0]+"."+parts[1]), signature)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := map[string]interface{}{}
+	err = decodeSegment(parts[1], &claims)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid token claims")
+	}
+
+	issuer, _ := claims["iss"].(string)
+	if strings.TrimSuffix(issuer, "/") != v.issuer {
+		return nil, fmt.Errorf("Unexpected issuer %q", issuer)
+	}
+
+	if !hasAudience(claims["aud"], v.audience) {
+		return nil, fmt.Errorf("Token isn't meant for audience %q", v.audience)
+	}
+
+	now := time.Now()
+
+	expiry, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("Missing expiry")
+	}
+
+	if now.After(time.Unix(int64(expiry), 0).Add(leeway)) {
+		return nil, fmt.Errorf("Token has expired")
+	}
+
+	notBefore, ok := claims["nbf"].(float64)
+	if ok && now.Add(leeway).Before(time.Unix(int64(notBefore), 0)) {
+		return nil, fmt.Errorf("Token isn't valid yet")
+	}
+
+	return claims, nil
+}
+
+func decodeSegment(segment string, target interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, target)
+}
+
+func hasAudience(value interface{}, audience string) bool {
+	switch aud := value.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, entry := range aud {
+			if entry == audience {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Curves of the keys used by each ECDSA signing algorithm.
+var ecdsaCurves = map[string]string{
+	"ES256": "P-256",
+	"ES384": "P-384",
+	"ES512": "P-521",
+}
+
+func verifySignature(algorithm string, key crypto.PublicKey, payload []byte, signature []byte) error {
+	if len(algorithm) != 5 {
+		return fmt.Errorf("Unsupported signing algorithm %q", algorithm)
+	}
+
+	var hash crypto.Hash
+	switch algorithm[2:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("Unsupported signing algorithm %q", algorithm)
+	}
+
+	h := hash.New()
+	h.Write(payload)
+	digest := h.Sum(nil)
+
+	switch {
+	case strings.HasPrefix(algorithm, "RS"), strings.HasPrefix(algorithm, "PS"):
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("Key doesn't match signing algorithm %q", algorithm)
+		}
+
+		if strings.HasPrefix(algorithm, "PS") {
+			return rsa.VerifyPSS(rsaKey, hash, digest, signature, nil)
+		}
+
+		return rsa.VerifyPKCS1v15(rsaKey, hash, digest, signature)
+	case strings.HasPrefix(algorithm, "ES"):
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || ecKey.Curve.Params().Name != ecdsaCurves[algorithm] {
+			return fmt.Errorf("Key doesn't match signing algorithm %q", algorithm)
+		}
+
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("Invalid signature size")
+		}
+
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(ecKey, digest, r, s) {
+			return fmt.Errorf("Invalid signature")
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("Unsupported signing algorithm %q", algorithm)
+}
+
+// Return the signing key with the given ID, fetching the keys of the
+// provider if they're stale or if the key is unknown.
+func (v *Verifier) key(id string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	key, ok := v.keys[id]
+	stale := time.Since(v.keysFetched) > keysExpiry
+	refresh := stale || (!ok && time.Since(v.keysFetched) > keysMinInterval)
+	v.mu.Unlock()
+
+	if ok && !stale {
+		return key, nil
+	}
+
+	if refresh {
+		err := v.refreshKeys()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	v.mu.Lock()
+	key, ok = v.keys[id]
+	v.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("Unknown signing key %q", id)
+	}
+
+	return key, nil
+}
+
+// Fetch the signing keys of the provider, without holding the lock while
+// doing so. Concurrent callers wait for the ongoing fetch rather than
+// starting their own.
+func (v *Verifier) refreshKeys() error {
+	v.mu.Lock()
+	if v.fetching != nil {
+		fetching := v.fetching
+		v.mu.Unlock()
+
+		<-fetching
+
+		v.mu.Lock()
+		defer v.mu.Unlock()
+
+		return v.fetchErr
+	}
+
+	fetching := make(chan struct{})
+	v.fetching = fetching
+	v.mu.Unlock()
+
+	keys, err := v.fetchKeys()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if err == nil {
+		v.keys = keys
+		v.keysFetched = time.Now()
+	}
+
+	v.fetchErr = err
+	v.fetching = nil
+	close(fetching)
+
+	return err
+}
+
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+
+	// RSA keys
+	N string `json:"n"`
+	E string `json:"e"`
+
+	// EC keys
+	Curve string `json:"crv"`
+	X     string `json:"x"`
+	Y     string `json:"y"`
+}
+
+// Fetch the signing keys of the provider, found through its discovery
+// document.
+func (v *Verifier) fetchKeys() (map[string]crypto.PublicKey, error) {
+	discovery := struct {
+		JWKSURI string `json:"jwks_uri"`
+	}{}
+
+	err := v.getJSON(v.issuer+"/.well-known/openid-configuration", &discovery)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch OIDC provider configuration")
+	}
+
+	jwks := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+
+	err = v.getJSON(discovery.JWKSURI, &jwks)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch OIDC provider keys")
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+
+		keys[jwk.KeyID] = key
+	}
+
+	return keys, nil
+}
+
+func (v *Verifier) getJSON(url string, target interface{}) error {
+	resp, err := v.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unexpected status from %s: %s", url, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("Unsupported curve %q", k.Curve)
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("Unsupported key type %q", k.KeyType)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
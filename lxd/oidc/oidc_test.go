@@ -0,0 +1,185 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A fake OIDC provider, serving its discovery document and signing keys.
+type fakeIssuer struct {
+	server  *httptest.Server
+	key     *rsa.PrivateKey
+	fetches int32
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	issuer := &fakeIssuer{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   issuer.server.URL,
+			"jwks_uri": issuer.server.URL + "/keys",
+		})
+	})
+
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&issuer.fetches, 1)
+		time.Sleep(100 * time.Millisecond)
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+
+	issuer.server = httptest.NewServer(mux)
+
+	return issuer
+}
+
+func (i *fakeIssuer) token(t *testing.T, kid string, claims map[string]interface{}) string {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"})
+	require.NoError(t, err)
+
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, i.key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerifier_Auth(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	defer issuer.server.Close()
+
+	verifier := NewVerifier(issuer.server.URL, "lxd", "", "email")
+
+	claims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":   issuer.server.URL,
+			"aud":   []string{"lxd", "other"},
+			"sub":   "1234",
+			"email": "user@example.com",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+		}
+	}
+
+	username, err := verifier.Auth(issuer.token(t, "test", claims()))
+	require.NoError(t, err)
+	assert.Equal(t, "user@example.com", username)
+
+	cases := map[string]func(c map[string]interface{}){
+		"expired":        func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"not yet valid":  func(c map[string]interface{}) { c["nbf"] = time.Now().Add(time.Hour).Unix() },
+		"wrong issuer":   func(c map[string]interface{}) { c["iss"] = "https://example.com" },
+		"wrong audience": func(c map[string]interface{}) { c["aud"] = "other" },
+		"missing claim":  func(c map[string]interface{}) { delete(c, "email") },
+	}
+
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := claims()
+			mutate(c)
+
+			_, err := verifier.Auth(issuer.token(t, "test", c))
+			assert.IsType(t, &AuthError{}, err)
+		})
+	}
+
+	// Unknown keys and tampered tokens are rejected.
+	_, err = verifier.Auth(issuer.token(t, "unknown", claims()))
+	assert.IsType(t, &AuthError{}, err)
+
+	token := issuer.token(t, "test", claims())
+	_, err = verifier.Auth(token[:len(token)-4] + "AAAA")
+	assert.IsType(t, &AuthError{}, err)
+
+	_, err = verifier.Auth("garbage")
+	assert.IsType(t, &AuthError{}, err)
+}
+
+func TestVerifier_KeyConcurrentFetch(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	defer issuer.server.Close()
+
+	verifier := NewVerifier(issuer.server.URL, "lxd", "", "")
+
+	// Concurrent lookups share a single fetch of the keys.
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := verifier.key("test")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&issuer.fetches))
+
+	// Unknown keys don't trigger a new fetch right away.
+	_, err := verifier.key("unknown")
+	assert.EqualError(t, err, `Unknown signing key "unknown"`)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&issuer.fetches))
+}
+
+func TestVerifySignature_Curve(t *testing.T) {
+	payload := []byte("header.claims")
+	digest := sha256.Sum256(payload)
+
+	sign := func(curve elliptic.Curve) (*ecdsa.PublicKey, []byte) {
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		require.NoError(t, err)
+
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		require.NoError(t, err)
+
+		size := (curve.Params().BitSize + 7) / 8
+		signature := make([]byte, 2*size)
+		rBytes := r.Bytes()
+		sBytes := s.Bytes()
+		copy(signature[size-len(rBytes):size], rBytes)
+		copy(signature[2*size-len(sBytes):], sBytes)
+
+		return &key.PublicKey, signature
+	}
+
+	key, signature := sign(elliptic.P256())
+	assert.NoError(t, verifySignature("ES256", key, payload, signature))
+
+	// Keys on another curve than the one of the algorithm are rejected.
+	key, signature = sign(elliptic.P384())
+	assert.EqualError(t, verifySignature("ES256", key, payload, signature), `Key doesn't match signing algorithm "ES256"`)
+}
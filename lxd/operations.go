@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
@@ -13,10 +16,14 @@ import (
 	"github.com/lxc/lxd/lxd/node"
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/task"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
+
+	log "github.com/lxc/lxd/shared/log15"
 )
 
 var operationCmd = APIEndpoint{
@@ -58,26 +65,36 @@ func operationGet(d *Daemon, r *http.Request) response.Response {
 			return response.SmartError(err)
 		}
 
+		if !operationUserCanView(d, r, op.Project(), body) {
+			return response.Forbidden(nil)
+		}
+
 		return response.SyncResponse(true, body)
 	}
 
 	// Then check if the query is from an operation on another node, and, if so, forward it
-	var address string
+	var operation db.Operation
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
-		operation, err := tx.OperationByUUID(id)
+		var err error
+		operation, err = tx.OperationByUUID(id)
+		return err
+	})
+	if err == db.ErrNoSuchObject {
+		// Finally check if the operation is a completed one
+		body, err = operationRecordGet(d, r, id)
 		if err != nil {
-			return err
+			return response.SmartError(err)
 		}
 
-		address = operation.NodeAddress
-		return nil
-	})
+		return response.SyncResponse(true, body)
+	}
+
 	if err != nil {
 		return response.SmartError(err)
 	}
 
 	cert := d.endpoints.NetworkCert()
-	client, err := cluster.Connect(address, cert, false)
+	client, err := cluster.Connect(operation.NodeAddress, cert, false)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -87,6 +104,10 @@ func operationGet(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	if !operationUserCanView(d, r, operation.Project, body) {
+		return response.Forbidden(nil)
+	}
+
 	return response.SyncResponse(true, body)
 }
 
@@ -147,6 +168,16 @@ func operationDelete(d *Daemon, r *http.Request) response.Response {
 func operationsGet(d *Daemon, r *http.Request) response.Response {
 	project := projectParam(r)
 	recursion := util.IsRecursionRequest(r)
+	allHistory := shared.IsTrue(queryParam(r, "all-history"))
+
+	params, err := listParamsParse(r)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if !d.userIsClusterMember(r) && !d.userHasPermission(r, project, "view") {
+		return response.Forbidden(nil)
+	}
 
 	localOperationURLs := func() (shared.Jmap, error) {
 		// Get all the operations
@@ -161,6 +192,16 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 			if v.Project() != "" && v.Project() != project {
 				continue
 			}
+
+			_, op, err := v.Render()
+			if err != nil {
+				return nil, err
+			}
+
+			if !operationUserCanView(d, r, v.Project(), op) {
+				continue
+			}
+
 			status := strings.ToLower(v.Status().String())
 			_, ok := body[status]
 			if !ok {
@@ -186,17 +227,22 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 			if v.Project() != "" && v.Project() != project {
 				continue
 			}
-			status := strings.ToLower(v.Status().String())
-			_, ok := body[status]
-			if !ok {
-				body[status] = make([]*api.Operation, 0)
-			}
 
 			_, op, err := v.Render()
 			if err != nil {
 				return nil, err
 			}
 
+			if !operationUserCanView(d, r, v.Project(), op) {
+				continue
+			}
+
+			status := strings.ToLower(v.Status().String())
+			_, ok := body[status]
+			if !ok {
+				body[status] = make([]*api.Operation, 0)
+			}
+
 			body[status] = append(body[status].([]*api.Operation), op)
 		}
 
@@ -225,9 +271,12 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 		return response.SyncResponse(true, body)
 	}
 
+	if params.enabled() || allHistory {
+		return operationsGetList(d, r, project, recursion, params, allHistory)
+	}
+
 	// Start with local operations
 	var md shared.Jmap
-	var err error
 
 	if recursion {
 		md, err = localOperations()
@@ -252,9 +301,42 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 		return response.SyncResponse(true, md)
 	}
 
+	ops, err := operationsGetRemote(d, r, project)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Merge with existing data
+	for i := range ops {
+		op := ops[i]
+		status := strings.ToLower(op.Status)
+
+		_, ok := md[status]
+		if !ok {
+			if recursion {
+				md[status] = make([]*api.Operation, 0)
+			} else {
+				md[status] = make([]string, 0)
+			}
+		}
+
+		if recursion {
+			md[status] = append(md[status].([]*api.Operation), &op)
+		} else {
+			md[status] = append(md[status].([]string), fmt.Sprintf("/1.0/operations/%s", op.ID))
+		}
+	}
+
+	return response.SyncResponse(true, md)
+}
+
+// Fetch the operations of the project running on the other cluster members,
+// which the user can view.
+func operationsGetRemote(d *Daemon, r *http.Request, project string) ([]api.Operation, error) {
 	// Get all nodes with running operations in this project.
 	var nodes []string
-	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+	projects := map[string]string{}
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		var err error
 
 		nodes, err = tx.OperationNodes(project)
@@ -262,18 +344,29 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 			return err
 		}
 
+		operations, err := tx.OperationsByProject(project)
+		if err != nil {
+			return err
+		}
+
+		for _, operation := range operations {
+			projects[operation.UUID] = operation.Project
+		}
+
 		return nil
 	})
 	if err != nil {
-		return response.SmartError(err)
+		return nil, err
 	}
 
 	// Get local address
 	localAddress, err := node.HTTPSAddress(d.db)
 	if err != nil {
-		return response.InternalError(err)
+		return nil, err
 	}
 
+	result := []api.Operation{}
+
 	cert := d.endpoints.NetworkCert()
 	for _, node := range nodes {
 		if node == localAddress {
@@ -283,37 +376,220 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 		// Connect to the remote server
 		client, err := cluster.Connect(node, cert, true)
 		if err != nil {
-			return response.SmartError(err)
+			return nil, err
 		}
 
 		// Get operation data
-		ops, err := client.GetOperations()
+		ops, err := client.UseProject(project).GetOperations()
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range ops {
+			opProject, ok := projects[ops[i].ID]
+			if !ok || !operationUserCanView(d, r, opProject, &ops[i]) {
+				continue
+			}
+
+			result = append(result, ops[i])
+		}
+	}
+
+	return result, nil
+}
+
+// Return the filtered, paginated and projected operations of the project,
+// grouped by status. Operations are sorted by creation date. The completed
+// operations which are still recorded are included if allHistory is true.
+func operationsGetList(d *Daemon, r *http.Request, project string, recursion bool, params *listParams, allHistory bool) response.Response {
+	entries := []listEntry{}
+	addEntry := func(op *api.Operation) {
+		entries = append(entries, listEntry{
+			key:    fmt.Sprintf("%s/%s", op.CreatedAt.UTC().Format("2006-01-02T15:04:05.000000000Z"), op.ID),
+			url:    fmt.Sprintf("/%s/operations/%s", version.APIVersion, op.ID),
+			object: op,
+		})
+	}
+
+	// Start with local operations
+	operations.Lock()
+	localOps := operations.Operations()
+	operations.Unlock()
+
+	for _, v := range localOps {
+		if v.Project() != "" && v.Project() != project {
+			continue
+		}
+
+		_, op, err := v.Render()
+		if err != nil {
+			return response.InternalError(err)
+		}
+
+		if !operationUserCanView(d, r, v.Project(), op) {
+			continue
+		}
+
+		addEntry(op)
+	}
+
+	// Then the ones of the other cluster members
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	if clustered {
+		ops, err := operationsGetRemote(d, r, project)
 		if err != nil {
 			return response.SmartError(err)
 		}
 
-		// Merge with existing data
-		for _, op := range ops {
-			status := strings.ToLower(op.Status)
+		for i := range ops {
+			addEntry(&ops[i])
+		}
+	}
 
-			_, ok := md[status]
-			if !ok {
-				if recursion {
-					md[status] = make([]*api.Operation, 0)
-				} else {
-					md[status] = make([]string, 0)
-				}
+	// And finally the completed ones, unless they're still around
+	if allHistory {
+		seen := map[string]bool{}
+		for _, entry := range entries {
+			seen[entry.object.(*api.Operation).ID] = true
+		}
+
+		var records []db.OperationRecord
+		err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			var err error
+			records, err = tx.OperationRecords(project)
+			return err
+		})
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		for _, record := range records {
+			if seen[record.UUID] {
+				continue
 			}
 
-			if recursion {
-				md[status] = append(md[status].([]*api.Operation), &op)
-			} else {
-				md[status] = append(md[status].([]string), fmt.Sprintf("/1.0/operations/%s", op.ID))
+			op := operationRecordToAPI(record)
+			if !operationUserCanView(d, r, record.Project, op) {
+				continue
 			}
+
+			addEntry(op)
 		}
 	}
 
-	return response.SyncResponse(true, md)
+	entries, next, err := params.apply(entries)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	md := shared.Jmap{}
+	for _, entry := range entries {
+		status := strings.ToLower(entry.object.(*api.Operation).Status)
+
+		value, err := params.render(entry, recursion)
+		if err != nil {
+			return response.InternalError(err)
+		}
+
+		_, ok := md[status]
+		if !ok {
+			md[status] = []interface{}{}
+		}
+
+		md[status] = append(md[status].([]interface{}), value)
+	}
+
+	if next == "" {
+		return response.SyncResponse(true, md)
+	}
+
+	return response.SyncResponseHeaders(true, md, map[string]string{listNextPageTokenHeader: next})
+}
+
+// Return the record of the completed operation with the given UUID, if the
+// user can view it.
+func operationRecordGet(d *Daemon, r *http.Request, id string) (*api.Operation, error) {
+	var record db.OperationRecord
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		record, err = tx.OperationRecordByUUID(id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	op := operationRecordToAPI(record)
+	if !operationUserCanView(d, r, record.Project, op) {
+		return nil, os.ErrPermission
+	}
+
+	return op, nil
+}
+
+// Return whether the user can view the given operation of the given project.
+// Users can view the operations they requested, along with the ones of the
+// projects they can view. Operations not tied to any project are considered
+// part of the default project, as when cancelling them.
+func operationUserCanView(d *Daemon, r *http.Request, project string, op *api.Operation) bool {
+	if d.userIsClusterMember(r) {
+		return true
+	}
+
+	if op.Requestor != nil {
+		username, _ := r.Context().Value("username").(string)
+		protocol, _ := r.Context().Value("protocol").(string)
+		if username != "" && op.Requestor.Username == username && op.Requestor.Protocol == protocol {
+			return true
+		}
+	}
+
+	if project == "" {
+		project = "default"
+	}
+
+	return d.userHasPermission(r, project, "view")
+}
+
+// Convert the record of a completed operation to its API representation.
+func operationRecordToAPI(record db.OperationRecord) *api.Operation {
+	resources := map[string][]string{}
+	for key, values := range record.Resources {
+		urls := []string{}
+		for _, value := range values {
+			urls = append(urls, fmt.Sprintf("/%s/%s/%s", version.APIVersion, key, value))
+		}
+
+		resources[key] = urls
+	}
+
+	status := api.StatusCode(record.StatusCode)
+	op := &api.Operation{
+		ID:          record.UUID,
+		Class:       record.Class,
+		Description: record.Type.Description(),
+		CreatedAt:   record.CreatedAt,
+		UpdatedAt:   record.UpdatedAt,
+		Status:      status.String(),
+		StatusCode:  status,
+		Resources:   resources,
+		Metadata:    map[string]interface{}{},
+		Err:         record.Error,
+		Location:    record.Location,
+	}
+
+	if record.RequestorUsername != "" || record.RequestorProtocol != "" {
+		op.Requestor = &api.OperationRequestor{
+			Username: record.RequestorUsername,
+			Protocol: record.RequestorProtocol,
+		}
+	}
+
+	return op
 }
 
 func operationWaitGet(d *Daemon, r *http.Request) response.Response {
@@ -327,12 +603,21 @@ func operationWaitGet(d *Daemon, r *http.Request) response.Response {
 	// First check if the query is for a local operation from this node
 	op, err := operations.OperationGetInternal(id)
 	if err == nil {
+		_, body, err := op.Render()
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		if !operationUserCanView(d, r, op.Project(), body) {
+			return response.Forbidden(nil)
+		}
+
 		_, err = op.WaitFinal(timeout)
 		if err != nil {
 			return response.InternalError(err)
 		}
 
-		_, body, err := op.Render()
+		_, body, err = op.Render()
 		if err != nil {
 			return response.SmartError(err)
 		}
@@ -341,27 +626,42 @@ func operationWaitGet(d *Daemon, r *http.Request) response.Response {
 	}
 
 	// Then check if the query is from an operation on another node, and, if so, forward it
-	var address string
+	var operation db.Operation
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
-		operation, err := tx.OperationByUUID(id)
+		var err error
+		operation, err = tx.OperationByUUID(id)
+		return err
+	})
+	if err == db.ErrNoSuchObject {
+		// Finally check if the operation is a completed one
+		body, err := operationRecordGet(d, r, id)
 		if err != nil {
-			return err
+			return response.SmartError(err)
 		}
 
-		address = operation.NodeAddress
-		return nil
-	})
+		return response.SyncResponse(true, body)
+	}
+
 	if err != nil {
 		return response.SmartError(err)
 	}
 
 	cert := d.endpoints.NetworkCert()
-	client, err := cluster.Connect(address, cert, false)
+	client, err := cluster.Connect(operation.NodeAddress, cert, false)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	apiOp, _, err := client.GetOperation(id)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
-	apiOp, _, err := client.GetOperationWait(id, timeout)
+	if !operationUserCanView(d, r, operation.Project, apiOp) {
+		return response.Forbidden(nil)
+	}
+
+	apiOp, _, err = client.GetOperationWait(id, timeout)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -455,3 +755,29 @@ func operationWebsocketGet(d *Daemon, r *http.Request) response.Response {
 
 	return &forwardedOperationWebSocket{r, id, source}
 }
+
+// This task function removes the records of the operations which completed
+// longer ago than operations.history_expiry allows, or all of them if the
+// history is disabled. It's started by the Daemon and will run hourly.
+func pruneOperationRecordsTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			config, err := cluster.ConfigLoad(tx)
+			if err != nil {
+				return err
+			}
+
+			expiry := config.OperationsHistoryExpiry()
+			if expiry < 0 {
+				expiry = 0
+			}
+
+			return tx.OperationRecordsPrune(time.Now().UTC().Add(-time.Duration(expiry) * 24 * time.Hour))
+		})
+		if err != nil {
+			logger.Error("Failed to remove expired operation records", log.Ctx{"err": err})
+		}
+	}
+
+	return f, task.Every(time.Hour)
+}
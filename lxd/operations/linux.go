@@ -3,22 +3,92 @@
 package operations
 
 import (
+	"strconv"
+	"sync"
+
 	"github.com/pkg/errors"
 
+	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
 )
 
-func registerDBOperation(op *Operation, opType db.OperationType) error {
+// Register the operation in the database, checking the operations limit of
+// its project, if any. The returned function must be called once the
+// operation is tracked, so that it gets counted by concurrent requests.
+func registerDBOperation(op *Operation, opType db.OperationType) (func(), error) {
+	unlock := func() {}
 	if op.state == nil {
-		return nil
+		return unlock, nil
 	}
 
+	var lock *sync.Mutex
 	err := op.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		if op.project != "" {
+			max, err := operationsLimit(tx, op.project)
+			if err != nil {
+				return err
+			}
+
+			// Projects without a limit aren't locked. The
+			// transaction may be retried, so the project is
+			// only locked once.
+			if max > 0 {
+				if lock == nil {
+					lock = projectLock(op.project)
+					lock.Lock()
+					unlock = lock.Unlock
+				}
+
+				err = operationsLimitCheck(tx, op.project, max)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
 		_, err := tx.OperationAdd(op.project, op.id, opType)
 		return err
 	})
 	if err != nil {
-		return errors.Wrapf(err, "failed to add Operation %s to database", op.id)
+		unlock()
+
+		if errors.Cause(err) == db.ErrTooManyOperations {
+			return nil, err
+		}
+
+		return nil, errors.Wrapf(err, "failed to add Operation %s to database", op.id)
+	}
+
+	return unlock, nil
+}
+
+// Return the limits.operations of the given project, or zero if it has none.
+func operationsLimit(tx *db.ClusterTx, projectName string) (int, error) {
+	project, err := tx.ProjectGet(projectName)
+	if err != nil {
+		return 0, err
+	}
+
+	limit := project.Config["limits.operations"]
+	if limit == "" {
+		return 0, nil
+	}
+
+	return strconv.Atoi(limit)
+}
+
+// Check whether the project already has as many pending or running operations
+// across the cluster as the given limit allows. The caller must hold the lock
+// of the project.
+func operationsLimitCheck(tx *db.ClusterTx, projectName string, max int) error {
+	count, err := tx.OperationsCountElsewhere(projectName)
+	if err != nil {
+		return err
+	}
+
+	count += projectCount(projectName)
+	if count >= max {
+		return errors.Wrapf(db.ErrTooManyOperations, "Project %q has reached its limit of %d", projectName, max)
 	}
 
 	return nil
@@ -36,6 +106,56 @@ func removeDBOperation(op *Operation) error {
 	return err
 }
 
+func recordDBOperation(op *Operation) error {
+	if op.state == nil {
+		return nil
+	}
+
+	op.lock.Lock()
+	record := db.OperationRecord{
+		UUID:       op.id,
+		Project:    op.project,
+		Type:       op.opType,
+		Class:      op.class.String(),
+		StatusCode: int(op.status),
+		Error:      op.err,
+		Resources:  op.resources,
+		CreatedAt:  op.createdAt.UTC(),
+		UpdatedAt:  op.updatedAt.UTC(),
+	}
+
+	if op.requestor != nil {
+		record.RequestorUsername = op.requestor.Username
+		record.RequestorProtocol = op.requestor.Protocol
+	}
+	op.lock.Unlock()
+
+	err := op.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		config, err := cluster.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		// Expired records are dropped by a periodic task.
+		if config.OperationsHistoryExpiry() <= 0 {
+			return nil
+		}
+
+		record.Location, err = tx.NodeName()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.OperationRecordAdd(record)
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to add record of Operation %s to database", op.id)
+	}
+
+	return nil
+}
+
 func getServerName(op *Operation) (string, error) {
 	if op.state == nil {
 		return "", nil
@@ -8,12 +8,12 @@ import (
 	"github.com/lxc/lxd/lxd/db"
 )
 
-func registerDBOperation(op *Operation, opType db.OperationType) error {
+func registerDBOperation(op *Operation, opType db.OperationType) (func(), error) {
 	if op.state != nil {
-		return fmt.Errorf("registerDBOperation not supported on this platform")
+		return nil, fmt.Errorf("registerDBOperation not supported on this platform")
 	}
 
-	return nil
+	return func() {}, nil
 }
 
 func removeDBOperation(op *Operation) error {
@@ -24,6 +24,14 @@ func removeDBOperation(op *Operation) error {
 	return nil
 }
 
+func recordDBOperation(op *Operation) error {
+	if op.state != nil {
+		return fmt.Errorf("recordDBOperation not supported on this platform")
+	}
+
+	return nil
+}
+
 func getServerName(op *Operation) (string, error) {
 	if op.state != nil {
 		return "", fmt.Errorf("registerDBOperation not supported on this platform")
@@ -24,6 +24,27 @@ var debug bool
 var operationsLock sync.Mutex
 var operations = make(map[string]*Operation)
 
+// Per-project locks serializing the creation of operations in the projects
+// with an operations limit, so that concurrent requests on this node can't
+// exceed it.
+var operationsProjectLocks = make(map[string]*sync.Mutex)
+var operationsProjectLocksLock sync.Mutex
+
+// Return the lock serializing the creation of operations in the given
+// project.
+func projectLock(project string) *sync.Mutex {
+	operationsProjectLocksLock.Lock()
+	defer operationsProjectLocksLock.Unlock()
+
+	lock, ok := operationsProjectLocks[project]
+	if !ok {
+		lock = &sync.Mutex{}
+		operationsProjectLocks[project] = lock
+	}
+
+	return lock
+}
+
 type operationClass int
 
 const (
@@ -77,6 +98,30 @@ func OperationGetInternal(id string) (*Operation, error) {
 	return op, nil
 }
 
+// Return the number of pending or running operations of the given project on
+// this node.
+func projectCount(project string) int {
+	operationsLock.Lock()
+	defer operationsLock.Unlock()
+
+	count := 0
+	for _, op := range operations {
+		if op.project != project {
+			continue
+		}
+
+		op.lock.Lock()
+		status := op.status
+		op.lock.Unlock()
+
+		if status == api.Pending || status == api.Running {
+			count++
+		}
+	}
+
+	return count
+}
+
 // Operation represents an operation.
 type Operation struct {
 	project     string
@@ -93,6 +138,8 @@ type Operation struct {
 	canceler    *cancel.Canceler
 	description string
 	permission  string
+	opType      db.OperationType
+	requestor   *api.OperationRequestor
 
 	// Those functions are called at various points in the Operation lifecycle
 	onRun     func(*Operation) error
@@ -118,6 +165,7 @@ func OperationCreate(s *state.State, project string, opClass operationClass, opT
 	op.id = uuid.NewRandom().String()
 	op.description = opType.Description()
 	op.permission = opType.Permission()
+	op.opType = opType
 	op.class = opClass
 	op.createdAt = time.Now()
 	op.updatedAt = op.createdAt
@@ -159,15 +207,19 @@ func OperationCreate(s *state.State, project string, opClass operationClass, opT
 		return nil, fmt.Errorf("Token operations can't have a Cancel hook")
 	}
 
-	operationsLock.Lock()
-	operations[op.id] = &op
-	operationsLock.Unlock()
-
-	err = registerDBOperation(&op, opType)
+	// Check the operations limit of the project, if any, and register the
+	// operation. The project stays locked until the operation is tracked,
+	// so that it's counted by concurrent requests.
+	unlock, err := registerDBOperation(&op, opType)
 	if err != nil {
 		return nil, err
 	}
 
+	operationsLock.Lock()
+	operations[op.id] = &op
+	operationsLock.Unlock()
+	unlock()
+
 	logger.Debugf("New %s Operation: %s", op.class.String(), op.id)
 	_, md, _ := op.Render()
 	op.sendEvent(md)
@@ -187,12 +239,24 @@ func (op *Operation) done() {
 
 	op.lock.Lock()
 	op.readonly = true
+	op.updatedAt = time.Now()
 	op.onRun = nil
 	op.onCancel = nil
 	op.onConnect = nil
-	close(op.chanDone)
 	op.lock.Unlock()
 
+	close(op.chanDone)
+
+	// Keep a record of the operation once it's gone, without holding
+	// back the caller. The operation itself stays around long enough for
+	// the record to be there once it's gone.
+	go func() {
+		err := recordDBOperation(op)
+		if err != nil {
+			logger.Warnf("Failed to record operation %s: %s", op.id, err)
+		}
+	}()
+
 	time.AfterFunc(time.Second*5, func() {
 		operationsLock.Lock()
 		_, ok := operations[op.id]
@@ -424,6 +488,7 @@ func (op *Operation) Render() (string, *api.Operation, error) {
 		MayCancel:   op.mayCancel(),
 		Err:         op.err,
 		Location:    serverName,
+		Requestor:   op.requestor,
 	}, nil
 }
 
@@ -532,6 +597,14 @@ func (op *Operation) SetCanceler(canceler *cancel.Canceler) {
 	op.canceler = canceler
 }
 
+// SetRequestor sets the user who requested the operation, along with the
+// protocol they were authenticated with.
+func (op *Operation) SetRequestor(username string, protocol string) {
+	op.lock.Lock()
+	op.requestor = &api.OperationRequestor{Username: username, Protocol: protocol}
+	op.lock.Unlock()
+}
+
 // Permission returns the operation permission.
 func (op *Operation) Permission() string {
 	return op.permission
@@ -20,6 +20,17 @@ func OperationResponse(op *Operation) response.Response {
 	return &operationResponse{op}
 }
 
+// ResponseOperation returns the operation of the given response, or nil if it
+// isn't an operation response.
+func ResponseOperation(resp response.Response) *Operation {
+	opResp, ok := resp.(*operationResponse)
+	if !ok {
+		return nil
+	}
+
+	return opResp.op
+}
+
 func (r *operationResponse) Render(w http.ResponseWriter) error {
 	_, err := r.op.Run()
 	if err != nil {
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Now()
+	l := NewLimiter(2, 3)
+	l.now = func() time.Time { return now }
+
+	// The burst is allowed right away.
+	for i := 0; i < 3; i++ {
+		ok, _ := l.Allow("alice")
+		assert.True(t, ok)
+	}
+
+	ok, wait := l.Allow("alice")
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	// Other clients have their own bucket.
+	ok, _ = l.Allow("bob")
+	assert.True(t, ok)
+
+	// Tokens are added back over time.
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = l.Allow("alice")
+	assert.True(t, ok)
+
+	ok, _ = l.Allow("alice")
+	assert.False(t, ok)
+
+	// Buckets never hold more than the burst.
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		ok, _ := l.Allow("alice")
+		assert.True(t, ok)
+	}
+
+	ok, _ = l.Allow("alice")
+	assert.False(t, ok)
+}
+
+func TestLimiter_DefaultBurst(t *testing.T) {
+	l := NewLimiter(1.5, 0)
+	assert.Equal(t, float64(2), l.burst)
+}
+
+func TestLimiter_Prune(t *testing.T) {
+	now := time.Now()
+	l := NewLimiter(1, 1)
+	l.now = func() time.Time { return now }
+
+	l.Allow("alice")
+	now = now.Add(pruneInterval)
+	l.Allow("bob")
+
+	assert.Len(t, l.buckets, 1)
+}
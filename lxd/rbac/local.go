@@ -0,0 +1,120 @@
+package rbac
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// Roles of the local RBAC backend.
+const (
+	RoleAdmin    = "admin"
+	RoleOperator = "operator"
+	RoleViewer   = "viewer"
+)
+
+// Permissions granted by each role on a project.
+var rolePermissions = map[string][]string{
+	RoleAdmin:    {"view", "operate-containers", "manage-containers", "manage-images", "manage-profiles", "manage-projects"},
+	RoleOperator: {"view", "operate-containers", "manage-containers", "manage-images", "manage-profiles"},
+	RoleViewer:   {"view"},
+}
+
+// ValidRole returns whether the given role is known to the local RBAC backend.
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// Authentication protocols whose users can be members of groups.
+var identityProtocols = []string{"tls", "oidc", "candid"}
+
+var identityFingerprintRegexp = regexp.MustCompile(`^[a-f0-9]{64}$`)
+
+// Identity returns the identity of the user with the given name, as
+// authenticated with the given protocol. Identities are namespaced by protocol
+// so that, for example, an OpenID Connect subject can't impersonate a
+// certificate fingerprint.
+func Identity(protocol string, username string) string {
+	return fmt.Sprintf("%s:%s", protocol, username)
+}
+
+// ValidIdentity returns an error if the given identity isn't one of
+// tls:<certificate fingerprint>, oidc:<subject> or candid:<username>.
+func ValidIdentity(identity string) error {
+	fields := strings.SplitN(identity, ":", 2)
+	if len(fields) != 2 || !shared.StringInSlice(fields[0], identityProtocols) {
+		return fmt.Errorf("Identity %q must start with one of %s followed by a colon", identity, strings.Join(identityProtocols, ", "))
+	}
+
+	if fields[1] == "" {
+		return fmt.Errorf("Identity %q has an empty name", identity)
+	}
+
+	if fields[0] == "tls" && !identityFingerprintRegexp.MatchString(fields[1]) {
+		return fmt.Errorf("Identity %q isn't a certificate fingerprint", identity)
+	}
+
+	return nil
+}
+
+// Binding grants a role on a project, or on all projects if the project is
+// empty.
+type Binding struct {
+	Role    string
+	Project string
+}
+
+// Local is an RBAC backend whose groups and role bindings are stored locally,
+// rather than by an external RBAC service.
+type Local struct {
+	// Returns the role bindings of all the groups the identity is a
+	// member of.
+	BindingsFunc func(identity string) ([]Binding, error)
+}
+
+// NewLocal returns a new local RBAC backend.
+func NewLocal(bindingsFunc func(identity string) ([]Binding, error)) *Local {
+	return &Local{BindingsFunc: bindingsFunc}
+}
+
+func (l *Local) bindings(identity string) []Binding {
+	bindings, err := l.BindingsFunc(identity)
+	if err != nil {
+		logger.Warnf("Failed to load RBAC role bindings of %q: %v", identity, err)
+		return nil
+	}
+
+	return bindings
+}
+
+// IsAdmin returns whether or not the provided identity is an admin, that is
+// whether it has the admin role on all projects.
+func (l *Local) IsAdmin(identity string) bool {
+	for _, binding := range l.bindings(identity) {
+		if binding.Role == RoleAdmin && binding.Project == "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasPermission returns whether or not the identity has the permission to
+// perform a certain task on the project.
+func (l *Local) HasPermission(identity, project, permission string) bool {
+	for _, binding := range l.bindings(identity) {
+		if binding.Project != "" && binding.Project != project {
+			continue
+		}
+
+		if shared.StringInSlice(permission, rolePermissions[binding.Role]) {
+			return true
+		}
+	}
+
+	return false
+}
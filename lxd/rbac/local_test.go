@@ -0,0 +1,61 @@
+package rbac_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lxc/lxd/lxd/rbac"
+)
+
+func TestLocal(t *testing.T) {
+	bindings := map[string][]rbac.Binding{
+		"root":  {{Role: rbac.RoleAdmin}},
+		"alice": {{Role: rbac.RoleAdmin, Project: "foo"}, {Role: rbac.RoleViewer}},
+		"bob":   {{Role: rbac.RoleOperator, Project: "foo"}},
+	}
+
+	local := rbac.NewLocal(func(identity string) ([]rbac.Binding, error) {
+		return bindings[identity], nil
+	})
+
+	assert.True(t, local.IsAdmin("root"))
+	assert.False(t, local.IsAdmin("alice"))
+	assert.False(t, local.IsAdmin("eve"))
+
+	cases := []struct {
+		identity   string
+		project    string
+		permission string
+		allowed    bool
+	}{
+		{"root", "bar", "manage-projects", true},
+		{"alice", "foo", "manage-projects", true},
+		{"alice", "bar", "view", true},
+		{"alice", "bar", "manage-containers", false},
+		{"bob", "foo", "manage-containers", true},
+		{"bob", "foo", "manage-projects", false},
+		{"bob", "bar", "view", false},
+		{"eve", "foo", "view", false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.allowed, local.HasPermission(c.identity, c.project, c.permission), "%s %s %s", c.identity, c.project, c.permission)
+	}
+}
+
+func TestValidIdentity(t *testing.T) {
+	fingerprint := strings.Repeat("ab", 32)
+	assert.Equal(t, "tls:"+fingerprint, rbac.Identity("tls", fingerprint))
+
+	valid := []string{"tls:" + fingerprint, "oidc:alice", "candid:bob@example.com"}
+	for _, identity := range valid {
+		assert.NoError(t, rbac.ValidIdentity(identity), identity)
+	}
+
+	invalid := []string{"", fingerprint, "alice", "tls:alice", "token:" + fingerprint, "oidc:", ":alice"}
+	for _, identity := range invalid {
+		assert.Error(t, rbac.ValidIdentity(identity), identity)
+	}
+}
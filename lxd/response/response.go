@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"mime/multipart"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	lxd "github.com/lxc/lxd/client"
@@ -18,6 +20,10 @@ import (
 
 var debug bool
 
+// Delay clients are asked to wait before retrying requests rejected because
+// of the operations limit of their project.
+const operationsLimitRetryAfter = 5 * time.Second
+
 // Init sets the debug variable to the provided value.
 func Init(d bool) {
 	debug = d
@@ -189,6 +195,27 @@ func Unavailable(err error) Response {
 	return &errorResponse{http.StatusServiceUnavailable, message}
 }
 
+// TooManyRequests returns a too many requests response (429) with the given
+// error, telling the client how long to wait before retrying.
+func TooManyRequests(err error, retryAfter time.Duration) Response {
+	return &tooManyRequestsResponse{errorResponse{http.StatusTooManyRequests, err.Error()}, retryAfter}
+}
+
+type tooManyRequestsResponse struct {
+	errorResponse
+	retryAfter time.Duration
+}
+
+func (r *tooManyRequestsResponse) Render(w http.ResponseWriter) error {
+	seconds := int64(math.Ceil(r.retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	return r.errorResponse.Render(w)
+}
+
 func (r *errorResponse) String() string {
 	return r.msg
 }
@@ -386,3 +413,21 @@ func (r *forwardedResponse) Render(w http.ResponseWriter) error {
 func (r *forwardedResponse) String() string {
 	return fmt.Sprintf("request to %s", r.request.URL)
 }
+
+type manualResponse struct {
+	hook func(w http.ResponseWriter) error
+}
+
+// ManualResponse creates a new manual response responder, the given hook
+// being in charge of writing the whole response.
+func ManualResponse(hook func(w http.ResponseWriter) error) Response {
+	return &manualResponse{hook: hook}
+}
+
+func (r *manualResponse) Render(w http.ResponseWriter) error {
+	return r.hook(w)
+}
+
+func (r *manualResponse) String() string {
+	return "unknown"
+}
@@ -36,6 +36,8 @@ func SmartError(err error) Response {
 		}
 
 		return Conflict(nil)
+	case db.ErrTooManyOperations:
+		return TooManyRequests(err, operationsLimitRetryAfter)
 	default:
 		return InternalError(err)
 	}
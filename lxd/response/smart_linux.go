@@ -37,6 +37,8 @@ func SmartError(err error) Response {
 		}
 
 		return Conflict(nil)
+	case db.ErrTooManyOperations:
+		return TooManyRequests(err, operationsLimitRetryAfter)
 	case driver.ErrNoAvailableLeader:
 		return Unavailable(err)
 	default:
@@ -19,6 +19,7 @@ import (
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/logger"
 	"github.com/lxc/lxd/shared/version"
 )
 
@@ -140,14 +141,28 @@ func storagePoolsPost(d *Daemon, r *http.Request) response.Response {
 		}
 
 		if count == 1 {
+			// A single node can only carry its own node-specific
+			// config, which can just be merged into the global one.
+			if len(req.Members) > 0 {
+				err = storagePoolMergeMembersConfig(d, &req)
+				if err != nil {
+					return response.BadRequest(err)
+				}
+			}
+
 			// No targetNode was specified and we're either a single-node
 			// cluster or not clustered at all, so create the storage
 			// pool immediately.
 			err = storagePoolCreateGlobal(d.State(), req)
+		} else if len(req.Members) > 0 {
+			// No targetNode was specified but node-specific configs
+			// were given, so define the pool on all nodes and create
+			// it in one go.
+			err = storagePoolsPostMembers(d, req)
 		} else {
 			// No targetNode was specified and we're clustered, so finalize the
 			// config in the db and actually create the pool on all nodes.
-			err = storagePoolsPostCluster(d, req)
+			err = storagePoolsPostCluster(d, req, false)
 		}
 		if err != nil {
 			return response.InternalError(err)
@@ -156,6 +171,10 @@ func storagePoolsPost(d *Daemon, r *http.Request) response.Response {
 		return resp
 	}
 
+	if len(req.Members) > 0 {
+		return response.BadRequest(fmt.Errorf("Node-specific configs can't be combined with a target node"))
+	}
+
 	// A targetNode was specified, let's just define the node's storage
 	// without actually creating it. The only legal key values for the
 	// storage config are the ones in StoragePoolNodeConfigKeys.
@@ -184,10 +203,127 @@ func storagePoolsPost(d *Daemon, r *http.Request) response.Response {
 	return resp
 }
 
-func storagePoolsPostCluster(d *Daemon, req api.StoragePoolsPost) error {
+// Define the pool as pending on all nodes using the node-specific configs
+// passed in the request, then create it everywhere. If creation fails on any
+// node, the pool is removed again from all nodes.
+func storagePoolsPostMembers(d *Daemon, req api.StoragePoolsPost) error {
+	for nodeName, config := range req.Members {
+		for key := range config {
+			if !shared.StringInSlice(key, db.StoragePoolNodeConfigKeys) {
+				return fmt.Errorf("Config key '%s' of node %s may not be used as node-specific key", key, nodeName)
+			}
+		}
+
+		err := storagePoolValidate(req.Name, req.Driver, config)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		// Check that all the given nodes exist.
+		for nodeName := range req.Members {
+			_, err := tx.NodeByName(nodeName)
+			if err != nil {
+				if err == db.ErrNoSuchObject {
+					return fmt.Errorf("Node %s is not a member of the cluster", nodeName)
+				}
+				return err
+			}
+		}
+
+		_, err := tx.StoragePoolID(req.Name)
+		if err == nil {
+			return fmt.Errorf("The storage pool is already defined")
+		}
+		if err != db.ErrNoSuchObject {
+			return err
+		}
+
+		nodes, err := tx.Nodes()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			config, ok := req.Members[node.Name]
+			if !ok {
+				config = map[string]string{}
+			}
+
+			err := tx.StoragePoolCreatePending(node.Name, req.Name, req.Driver, config)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	req.Members = nil
+
+	return storagePoolsPostCluster(d, req, true)
+}
+
+// Merge the node-specific config of the local node into the global config.
+func storagePoolMergeMembersConfig(d *Daemon, req *api.StoragePoolsPost) error {
+	var nodeName string
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		nodeName, err = tx.NodeName()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	for name, config := range req.Members {
+		if name != nodeName {
+			return fmt.Errorf("Node %s is not a member of the cluster", name)
+		}
+
+		for key, value := range config {
+			if !shared.StringInSlice(key, db.StoragePoolNodeConfigKeys) {
+				return fmt.Errorf("Config key '%s' of node %s may not be used as node-specific key", key, name)
+			}
+
+			req.Config[key] = value
+		}
+	}
+
+	req.Members = nil
+
+	return nil
+}
+
+// Create a pool pending on all nodes. If revert is true, any failure will
+// cause the pool to be deleted from the nodes it was created on and from the
+// database, otherwise the pool will be marked as errored.
+func storagePoolsPostCluster(d *Daemon, req api.StoragePoolsPost, revert bool) error {
+	// Remove the pending pool from the database if it fails to get created
+	// on this node.
+	revertPending := func() {
+		if !revert {
+			return
+		}
+
+		err := dbStoragePoolDeleteAndUpdateCache(d.cluster, req.Name)
+		if err != nil {
+			logger.Errorf("Failed to delete storage pool %s from the database: %v", req.Name, err)
+		}
+	}
+
 	// Check that no node-specific config key has been defined.
 	for key := range req.Config {
 		if shared.StringInSlice(key, db.StoragePoolNodeConfigKeys) {
+			revertPending()
 			return fmt.Errorf("Config key '%s' is node-specific", key)
 		}
 	}
@@ -222,6 +358,7 @@ func storagePoolsPostCluster(d *Daemon, req api.StoragePoolsPost) error {
 		return tx.StoragePoolConfigAdd(poolID, 0, req.Config)
 	})
 	if err != nil {
+		revertPending()
 		if err == db.ErrNoSuchObject {
 			return fmt.Errorf("Pool not pending on any node (use --target <node> first)")
 		}
@@ -236,19 +373,27 @@ func storagePoolsPostCluster(d *Daemon, req api.StoragePoolsPost) error {
 
 	err = storagePoolValidate(req.Name, req.Driver, req.Config)
 	if err != nil {
+		revertPending()
 		return err
 	}
 
 	err = storagePoolCreateLocal(d.State(), poolID, req, false)
 	if err != nil {
+		revertPending()
 		return err
 	}
 
 	// Notify all other nodes to create the pool.
 	notifier, err := cluster.NewNotifier(d.State(), d.endpoints.NetworkCert(), cluster.NotifyAll)
 	if err != nil {
+		if revert {
+			storagePoolsPostClusterRevert(d, req.Name, nil)
+		}
 		return err
 	}
+
+	created := []string{}
+	createdLock := sync.Mutex{}
 	notifyErr := notifier(func(client lxd.InstanceServer) error {
 		server, _, err := client.GetServer()
 		if err != nil {
@@ -260,7 +405,16 @@ func storagePoolsPostCluster(d *Daemon, req api.StoragePoolsPost) error {
 			nodeReq.Config[key] = value
 		}
 
-		return client.CreateStoragePool(nodeReq)
+		err = client.CreateStoragePool(nodeReq)
+		if err != nil {
+			return err
+		}
+
+		createdLock.Lock()
+		created = append(created, server.Environment.ServerName)
+		createdLock.Unlock()
+
+		return nil
 	})
 
 	errored := notifyErr != nil
@@ -273,12 +427,68 @@ func storagePoolsPostCluster(d *Daemon, req api.StoragePoolsPost) error {
 		return tx.StoragePoolCreated(req.Name)
 	})
 	if err != nil {
+		if revert {
+			storagePoolsPostClusterRevert(d, req.Name, created)
+		}
 		return err
 	}
 
+	if errored && revert {
+		storagePoolsPostClusterRevert(d, req.Name, created)
+	}
+
 	return notifyErr
 }
 
+// Delete the pool from this node and from the given other nodes, and then
+// remove it from the database. The pool must not be in the pending state
+// anymore, otherwise the other nodes would only drop the database entry.
+func storagePoolsPostClusterRevert(d *Daemon, poolName string, nodes []string) {
+	if len(nodes) > 0 {
+		notifier, err := cluster.NewNotifier(d.State(), d.endpoints.NetworkCert(), cluster.NotifyAlive)
+		if err != nil {
+			logger.Errorf("Failed to revert creation of storage pool %s on other nodes: %v", poolName, err)
+		} else {
+			err = notifier(func(client lxd.InstanceServer) error {
+				server, _, err := client.GetServer()
+				if err != nil {
+					return err
+				}
+
+				if !shared.StringInSlice(server.Environment.ServerName, nodes) {
+					return nil
+				}
+
+				return client.DeleteStoragePool(poolName)
+			})
+			if err != nil {
+				logger.Errorf("Failed to revert creation of storage pool %s on other nodes: %v", poolName, err)
+			}
+		}
+	}
+
+	pool, err := storagePools.GetPoolByName(d.State(), poolName)
+	if err != storageDrivers.ErrUnknownDriver {
+		if err == nil {
+			err = pool.Delete(false, nil)
+		}
+	} else {
+		var s storage
+		s, err = storagePoolInit(d.State(), poolName)
+		if err == nil {
+			err = s.StoragePoolDelete()
+		}
+	}
+	if err != nil {
+		logger.Errorf("Failed to revert creation of storage pool %s: %v", poolName, err)
+	}
+
+	err = dbStoragePoolDeleteAndUpdateCache(d.cluster, poolName)
+	if err != nil {
+		logger.Errorf("Failed to delete storage pool %s from the database: %v", poolName, err)
+	}
+}
+
 // /1.0/storage-pools/{name}
 // Get a single storage pool.
 func storagePoolGet(d *Daemon, r *http.Request) response.Response {
@@ -123,6 +123,12 @@ func storagePoolVolumesGet(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
+	params, err := listParamsParse(r)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	entries := []listEntry{}
 	resultString := []string{}
 	for _, volume := range volumes {
 		apiEndpoint, err := storagePoolVolumeTypeNameToAPIEndpoint(volume.Type)
@@ -138,24 +144,35 @@ func storagePoolVolumesGet(d *Daemon, r *http.Request) response.Response {
 			apiEndpoint = "image"
 		}
 
-		if !recursion {
-			volName, snapName, ok := shared.InstanceGetParentAndSnapshotName(volume.Name)
-			if ok {
-				resultString = append(resultString,
-					fmt.Sprintf("/%s/storage-pools/%s/volumes/%s/%s/snapshots/%s",
-						version.APIVersion, poolName, apiEndpoint, volName, snapName))
-			} else {
-				resultString = append(resultString,
-					fmt.Sprintf("/%s/storage-pools/%s/volumes/%s/%s",
-						version.APIVersion, poolName, apiEndpoint, volume.Name))
-			}
+		var url string
+		volName, snapName, ok := shared.InstanceGetParentAndSnapshotName(volume.Name)
+		if ok {
+			url = fmt.Sprintf("/%s/storage-pools/%s/volumes/%s/%s/snapshots/%s",
+				version.APIVersion, poolName, apiEndpoint, volName, snapName)
 		} else {
+			url = fmt.Sprintf("/%s/storage-pools/%s/volumes/%s/%s",
+				version.APIVersion, poolName, apiEndpoint, volume.Name)
+		}
+
+		if recursion || params.filter != nil {
 			volumeUsedBy, err := storagePoolVolumeUsedByGet(d.State(), project, poolName, volume.Name, volume.Type)
 			if err != nil {
 				return response.InternalError(err)
 			}
 			volume.UsedBy = volumeUsedBy
 		}
+
+		resultString = append(resultString, url)
+		entries = append(entries, listEntry{key: fmt.Sprintf("%s/%s", apiEndpoint, volume.Name), url: url, object: volume})
+	}
+
+	if params.enabled() {
+		entries, next, err := params.apply(entries)
+		if err != nil {
+			return response.InternalError(err)
+		}
+
+		return params.response(entries, next, recursion)
 	}
 
 	if !recursion {
@@ -344,7 +361,7 @@ func doVolumeCreateOrCopy(d *Daemon, poolName string, req *api.StorageVolumesPos
 	// Volume copy operations potentially take a long time, so run as an async operation.
 	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationVolumeCopy, nil, nil, run, nil, nil)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
@@ -484,12 +501,12 @@ func doVolumeMigration(d *Daemon, poolName string, req *api.StorageVolumesPost)
 	if push {
 		op, err = operations.OperationCreate(d.State(), "", operations.OperationClassWebsocket, db.OperationVolumeCreate, resources, sink.Metadata(), run, nil, sink.Connect)
 		if err != nil {
-			return response.InternalError(err)
+			return response.SmartError(err)
 		}
 	} else {
 		op, err = operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationVolumeCopy, resources, nil, run, nil, nil)
 		if err != nil {
-			return response.InternalError(err)
+			return response.SmartError(err)
 		}
 	}
 
@@ -637,7 +654,7 @@ func storagePoolVolumeTypePostMigration(state *state.State, poolName string, vol
 
 		op, err := operations.OperationCreate(state, "", operations.OperationClassTask, db.OperationVolumeMigrate, resources, nil, run, nil, nil)
 		if err != nil {
-			return response.InternalError(err)
+			return response.SmartError(err)
 		}
 
 		return operations.OperationResponse(op)
@@ -646,7 +663,7 @@ func storagePoolVolumeTypePostMigration(state *state.State, poolName string, vol
 	// Pull mode
 	op, err := operations.OperationCreate(state, "", operations.OperationClassWebsocket, db.OperationVolumeMigrate, resources, ws.Metadata(), run, nil, ws.Connect)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
@@ -790,7 +807,7 @@ func storagePoolVolumeTypePostMove(d *Daemon, poolName string, volumeName string
 
 	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationVolumeMove, nil, nil, run, nil, nil)
 	if err != nil {
-		return response.InternalError(err)
+		return response.SmartError(err)
 	}
 
 	return operations.OperationResponse(op)
@@ -0,0 +1,173 @@
+package util
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/ed25519"
+)
+
+// SignatureLegacyMaxSize is the size of the largest file whose legacy, not
+// prehashed, signature is verified, as such signatures require the whole file
+// to be loaded in memory.
+const SignatureLegacyMaxSize = 64 * 1024 * 1024
+
+// SignatureKey is a minisign-style Ed25519 public key trusted to sign images.
+type SignatureKey struct {
+	ID        [8]byte
+	PublicKey ed25519.PublicKey
+}
+
+// ParseSignatureKey parses a base64 encoded minisign public key.
+func ParseSignatureKey(value string) (*SignatureKey, error) {
+	buf, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid public key %q", value)
+	}
+
+	if len(buf) != 2+8+ed25519.PublicKeySize || string(buf[0:2]) != "Ed" {
+		return nil, fmt.Errorf("Invalid public key %q: not an Ed25519 public key", value)
+	}
+
+	key := &SignatureKey{}
+	copy(key.ID[:], buf[2:10])
+	key.PublicKey = ed25519.PublicKey(buf[10:])
+
+	return key, nil
+}
+
+// ParseSignatureKeys parses a comma or space separated list of base64 encoded
+// minisign public keys, as found in the images.trusted_keys setting.
+func ParseSignatureKeys(value string) ([]SignatureKey, error) {
+	keys := []SignatureKey{}
+
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\t'
+	})
+
+	for _, field := range fields {
+		key, err := ParseSignatureKey(field)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, *key)
+	}
+
+	return keys, nil
+}
+
+// VerifySignature checks that the given minisign-style detached signature was
+// produced for the file at path by one of the given keys.
+func VerifySignature(keys []SignatureKey, path string, signature []byte) error {
+	lines := strings.Split(strings.TrimSpace(string(signature)), "\n")
+	if len(lines) < 4 {
+		return fmt.Errorf("Invalid signature: expected 4 lines, got %d", len(lines))
+	}
+
+	buf, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil {
+		return errors.Wrap(err, "Invalid signature")
+	}
+
+	if len(buf) != 2+8+ed25519.SignatureSize {
+		return fmt.Errorf("Invalid signature: bad length")
+	}
+
+	algorithm := string(buf[0:2])
+	if algorithm != "Ed" && algorithm != "ED" {
+		return fmt.Errorf("Invalid signature: unsupported algorithm %q", algorithm)
+	}
+
+	trustedComment := strings.TrimSpace(lines[2])
+	if !strings.HasPrefix(trustedComment, "trusted comment: ") {
+		return fmt.Errorf("Invalid signature: missing trusted comment")
+	}
+	trustedComment = strings.TrimPrefix(trustedComment, "trusted comment: ")
+
+	globalSignature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil {
+		return errors.Wrap(err, "Invalid signature")
+	}
+
+	var key *SignatureKey
+	for i := range keys {
+		if bytes.Equal(keys[i].ID[:], buf[2:10]) {
+			key = &keys[i]
+			break
+		}
+	}
+
+	if key == nil {
+		return fmt.Errorf("Signature made with untrusted key %X", reverseBytes(buf[2:10]))
+	}
+
+	message, err := signatureMessage(path, algorithm == "ED")
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(key.PublicKey, message, buf[10:]) {
+		return fmt.Errorf("Signature verification failed")
+	}
+
+	// The global signature covers the signature and the trusted comment.
+	global := append(append([]byte{}, buf[10:]...), []byte(trustedComment)...)
+	if !ed25519.Verify(key.PublicKey, global, globalSignature) {
+		return fmt.Errorf("Trusted comment verification failed")
+	}
+
+	return nil
+}
+
+// Return the message covered by the signature of the given file, either its
+// content or its BLAKE2b-512 hash.
+func signatureMessage(path string, prehashed bool) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if !prehashed {
+		fi, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+
+		if fi.Size() > SignatureLegacyMaxSize {
+			return nil, fmt.Errorf("Legacy signatures aren't supported for files larger than %d bytes, sign them prehashed instead", SignatureLegacyMaxSize)
+		}
+
+		return ioutil.ReadAll(io.LimitReader(f, SignatureLegacyMaxSize))
+	}
+
+	hash, err := blake2b.New512(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return nil, err
+	}
+
+	return hash.Sum(nil), nil
+}
+
+// Key IDs are stored little endian but displayed big endian by minisign.
+func reverseBytes(buf []byte) []byte {
+	out := make([]byte, len(buf))
+	for i := range buf {
+		out[len(buf)-1-i] = buf[i]
+	}
+
+	return out
+}
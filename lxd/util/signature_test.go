@@ -0,0 +1,116 @@
+package util_test
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/lxc/lxd/lxd/util"
+)
+
+// Generate a minisign-style key pair, returning the encoded public key.
+func signatureKey(t *testing.T, id byte) (string, []byte, ed25519.PrivateKey) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	keyID := []byte{id, 0, 0, 0, 0, 0, 0, 0}
+	encoded := base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), public...))
+
+	return encoded, keyID, private
+}
+
+// Produce a minisign-style prehashed signature of the given content.
+func signatureSign(keyID []byte, private ed25519.PrivateKey, content []byte) []byte {
+	hash := blake2b.Sum512(content)
+
+	return signatureSignMessage("ED", keyID, private, hash[:])
+}
+
+// Produce a minisign-style signature of the given message with the given
+// algorithm.
+func signatureSignMessage(algorithm string, keyID []byte, private ed25519.PrivateKey, message []byte) []byte {
+	sig := ed25519.Sign(private, message)
+	comment := "timestamp:1577836800"
+	global := ed25519.Sign(private, append(append([]byte{}, sig...), []byte(comment)...))
+
+	return []byte(fmt.Sprintf("untrusted comment: test\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(append(append([]byte(algorithm), keyID...), sig...)),
+		comment,
+		base64.StdEncoding.EncodeToString(global)))
+}
+
+func TestVerifySignature(t *testing.T) {
+	f, err := ioutil.TempFile("", "lxd-signature-test-")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = f.Write([]byte("image content"))
+	require.NoError(t, err)
+	f.Close()
+
+	key1, id1, private1 := signatureKey(t, 1)
+	key2, id2, private2 := signatureKey(t, 2)
+
+	keys, err := util.ParseSignatureKeys(fmt.Sprintf("%s, %s", key1, key2))
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+
+	// Signatures from any of the trusted keys are accepted.
+	assert.NoError(t, util.VerifySignature(keys, f.Name(), signatureSign(id1, private1, []byte("image content"))))
+	assert.NoError(t, util.VerifySignature(keys, f.Name(), signatureSign(id2, private2, []byte("image content"))))
+
+	// Signatures of other content are rejected.
+	err = util.VerifySignature(keys, f.Name(), signatureSign(id1, private1, []byte("other content")))
+	assert.EqualError(t, err, "Signature verification failed")
+
+	// Signatures from untrusted keys are rejected.
+	_, id3, private3 := signatureKey(t, 3)
+	err = util.VerifySignature(keys, f.Name(), signatureSign(id3, private3, []byte("image content")))
+	assert.EqualError(t, err, "Signature made with untrusted key 0000000000000003")
+
+	// Garbage is rejected.
+	err = util.VerifySignature(keys, f.Name(), []byte("not a signature"))
+	assert.EqualError(t, err, "Invalid signature: expected 4 lines, got 1")
+}
+
+func TestVerifySignature_Legacy(t *testing.T) {
+	f, err := ioutil.TempFile("", "lxd-signature-test-")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = f.Write([]byte("image content"))
+	require.NoError(t, err)
+
+	key, id, private := signatureKey(t, 1)
+	keys, err := util.ParseSignatureKeys(key)
+	require.NoError(t, err)
+
+	// Legacy signatures of small files are accepted.
+	signature := signatureSignMessage("Ed", id, private, []byte("image content"))
+	assert.NoError(t, util.VerifySignature(keys, f.Name(), signature))
+
+	// Large files are rejected without being read.
+	require.NoError(t, f.Truncate(util.SignatureLegacyMaxSize+1))
+	f.Close()
+
+	err = util.VerifySignature(keys, f.Name(), signature)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Legacy signatures aren't supported")
+}
+
+func TestParseSignatureKeys_Invalid(t *testing.T) {
+	_, err := util.ParseSignatureKeys("Zm9v")
+	assert.EqualError(t, err, `Invalid public key "Zm9v": not an Ed25519 public key`)
+
+	keys, err := util.ParseSignatureKeys("")
+	require.NoError(t, err)
+	assert.Len(t, keys, 0)
+}
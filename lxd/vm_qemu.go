@@ -32,6 +32,7 @@ import (
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/maas"
+	"github.com/lxc/lxd/lxd/metrics"
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/state"
@@ -2097,7 +2098,7 @@ func (vm *vmQemu) deviceRemove(deviceName string, rawConfig deviceConfig.Device)
 	return nil
 }
 
-func (vm *vmQemu) Export(w io.Writer, properties map[string]string) error {
+func (vm *vmQemu) Export(w io.Writer, properties map[string]string, creationDate time.Time) error {
 	return fmt.Errorf("Export Not implemented")
 }
 
@@ -2634,6 +2635,86 @@ func (vm *vmQemu) RenderState() (*api.InstanceState, error) {
 	}, nil
 }
 
+// Metrics returns the resource usage metrics of the running VM, as reported
+// by its agent and by QEMU.
+func (vm *vmQemu) Metrics() (*metrics.MetricSet, error) {
+	if !vm.IsRunning() {
+		return nil, fmt.Errorf("Instance is not running")
+	}
+
+	state, err := vm.RenderState()
+	if err != nil {
+		return nil, err
+	}
+
+	set := instanceStateMetrics(state)
+
+	disks, err := vm.blockStats()
+	if err != nil {
+		return nil, err
+	}
+
+	instanceDiskIOMetrics(set, disks)
+
+	return set, nil
+}
+
+// blockStats returns the I/O counters of the disks of the VM, as seen by
+// QEMU.
+func (vm *vmQemu) blockStats() (map[string]*instanceDiskIO, error) {
+	// Connect to the monitor.
+	monitor, err := qmp.NewSocketMonitor("unix", vm.getMonitorPath(), vmVsockTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	err = monitor.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer monitor.Disconnect()
+
+	// Send the block statistics command.
+	respRaw, err := monitor.Run([]byte("{'execute': 'query-blockstats'}"))
+	if err != nil {
+		return nil, err
+	}
+
+	var respDecoded struct {
+		Return []struct {
+			Device string `json:"device"`
+			Stats  struct {
+				ReadBytes       int64 `json:"rd_bytes"`
+				WrittenBytes    int64 `json:"wr_bytes"`
+				ReadOperations  int64 `json:"rd_operations"`
+				WriteOperations int64 `json:"wr_operations"`
+			} `json:"stats"`
+		} `json:"return"`
+	}
+
+	err = json.Unmarshal(respRaw, &respDecoded)
+	if err != nil {
+		return nil, err
+	}
+
+	disks := map[string]*instanceDiskIO{}
+	for _, stats := range respDecoded.Return {
+		// Only report the drives managed by LXD.
+		if !strings.HasPrefix(stats.Device, "lxd_") {
+			continue
+		}
+
+		disks[strings.TrimPrefix(stats.Device, "lxd_")] = &instanceDiskIO{
+			ReadBytes:       stats.Stats.ReadBytes,
+			WrittenBytes:    stats.Stats.WrittenBytes,
+			ReadsCompleted:  stats.Stats.ReadOperations,
+			WritesCompleted: stats.Stats.WriteOperations,
+		}
+	}
+
+	return disks, nil
+}
+
 // agentGetState connects to the agent inside of the VM and does
 // an API call to get the current state.
 func (vm *vmQemu) agentGetState() (*api.InstanceState, error) {
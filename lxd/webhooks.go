@@ -0,0 +1,354 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/lxd/webhooks"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+// Timeout of the requests sent to the webhooks.
+const webhookTimeout = 10 * time.Second
+
+var webhooksCmd = APIEndpoint{
+	Path: "webhooks",
+
+	Get:  APIEndpointAction{Handler: webhooksGet},
+	Post: APIEndpointAction{Handler: webhooksPost},
+}
+
+var webhookCmd = APIEndpoint{
+	Path: "webhooks/{name}",
+
+	Delete: APIEndpointAction{Handler: webhookDelete},
+	Get:    APIEndpointAction{Handler: webhookGet},
+	Put:    APIEndpointAction{Handler: webhookPut},
+}
+
+// Configuration keys of the webhooks.
+var webhookConfigKeys = map[string]func(value string) error{
+	"retry.count":   shared.IsUint32,
+	"retry.backoff": shared.IsUint32,
+}
+
+func webhooksGet(d *Daemon, r *http.Request) response.Response {
+	recursion := util.IsRecursionRequest(r)
+
+	var hooks []db.Webhook
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		hooks, err = tx.Webhooks()
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if recursion {
+		result := []api.Webhook{}
+		for _, webhook := range hooks {
+			result = append(result, webhookToAPI(webhook))
+		}
+
+		return response.SyncResponse(true, result)
+	}
+
+	urls := []string{}
+	for _, webhook := range hooks {
+		urls = append(urls, fmt.Sprintf("/%s/webhooks/%s", version.APIVersion, webhook.Name))
+	}
+
+	return response.SyncResponse(true, urls)
+}
+
+func webhooksPost(d *Daemon, r *http.Request) response.Response {
+	req := api.WebhooksPost{}
+	err := shared.ReadToJSON(r.Body, &req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.Name == "" {
+		return response.BadRequest(fmt.Errorf("No name provided"))
+	}
+
+	if strings.Contains(req.Name, "/") {
+		return response.BadRequest(fmt.Errorf("Webhook names may not contain slashes"))
+	}
+
+	webhook, err := webhookFromAPI(d, req.WebhookPut)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	webhook.Name = req.Name
+
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		_, err := tx.WebhookByName(webhook.Name)
+		if err == nil {
+			return db.ErrAlreadyDefined
+		}
+
+		if err != db.ErrNoSuchObject {
+			return err
+		}
+
+		_, err = tx.WebhookAdd(webhook)
+		return err
+	})
+	if err != nil {
+		if err == db.ErrAlreadyDefined {
+			return response.Conflict(fmt.Errorf("Webhook %q already exists", webhook.Name))
+		}
+
+		return response.SmartError(err)
+	}
+
+	d.webhooks.Invalidate()
+
+	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/webhooks/%s", version.APIVersion, webhook.Name))
+}
+
+func webhookGet(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	var webhook db.Webhook
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		webhook, err = tx.WebhookByName(name)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	result := webhookToAPI(webhook)
+
+	return response.SyncResponseETag(true, result, result.Writable())
+}
+
+func webhookPut(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	var current db.Webhook
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		current, err = tx.WebhookByName(name)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Validate ETag
+	etag := webhookToAPI(current)
+	err = util.EtagCheck(r, etag.Writable())
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	req := api.WebhookPut{}
+	err = shared.ReadToJSON(r.Body, &req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	webhook, err := webhookFromAPI(d, req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// The secret is never returned, so keep it unless a new one is given.
+	if webhook.Secret == "" {
+		webhook.Secret = current.Secret
+	}
+
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.WebhookUpdate(name, webhook)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	d.webhooks.Invalidate()
+
+	return response.EmptySyncResponse
+}
+
+func webhookDelete(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.WebhookRemove(name)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	d.webhooks.Invalidate()
+
+	return response.EmptySyncResponse
+}
+
+func webhookToAPI(webhook db.Webhook) api.Webhook {
+	result := api.Webhook{
+		Name: webhook.Name,
+	}
+
+	result.Description = webhook.Description
+	result.URL = webhook.URL
+	result.Events = webhook.Events
+	result.Projects = webhook.Projects
+	result.Config = webhook.Config
+
+	return result
+}
+
+// Validate the given webhook fields and convert them to a database entry.
+func webhookFromAPI(d *Daemon, req api.WebhookPut) (db.Webhook, error) {
+	webhook := db.Webhook{
+		Description: req.Description,
+		URL:         req.URL,
+		Secret:      req.Secret,
+		Config:      map[string]string{},
+		Events:      []string{},
+		Projects:    []string{},
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return webhook, fmt.Errorf("Invalid webhook URL %q", req.URL)
+	}
+
+	for key, value := range req.Config {
+		if value == "" {
+			continue
+		}
+
+		validator, ok := webhookConfigKeys[key]
+		if !ok {
+			return webhook, fmt.Errorf("Invalid webhook configuration key: %s", key)
+		}
+
+		err := validator(value)
+		if err != nil {
+			return webhook, fmt.Errorf("Invalid value for webhook configuration key %s: %v", key, err)
+		}
+
+		webhook.Config[key] = value
+	}
+
+	for _, event := range req.Events {
+		if !webhooks.ValidEvent(event) {
+			return webhook, fmt.Errorf("Invalid webhook event %q", event)
+		}
+
+		if !shared.StringInSlice(event, webhook.Events) {
+			webhook.Events = append(webhook.Events, event)
+		}
+	}
+
+	for _, project := range req.Projects {
+		if !shared.StringInSlice(project, webhook.Projects) {
+			webhook.Projects = append(webhook.Projects, project)
+		}
+	}
+
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		for _, name := range webhook.Projects {
+			exists, err := tx.ProjectExists(name)
+			if err != nil {
+				return err
+			}
+
+			if !exists {
+				return fmt.Errorf("Project %q doesn't exist", name)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return webhook, err
+	}
+
+	return webhook, nil
+}
+
+// Setup the delivery of the events originating from this member to the
+// webhooks.
+func (d *Daemon) setupWebhooks() {
+	client := &http.Client{
+		Timeout: webhookTimeout,
+		Transport: &http.Transport{
+			Proxy: func(req *http.Request) (*url.URL, error) {
+				if d.proxy == nil {
+					return nil, nil
+				}
+
+				return d.proxy(req)
+			},
+		},
+	}
+
+	d.webhooks = webhooks.NewDispatcher(d.webhooksLoad, client, shared.LogPath("webhooks-dead-letter.log"))
+	d.events.AddHandler(d.webhooks.Dispatch)
+}
+
+// Return the webhooks stored in the database.
+func (d *Daemon) webhooksLoad() ([]webhooks.Webhook, error) {
+	var hooks []db.Webhook
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		hooks, err = tx.Webhooks()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := []webhooks.Webhook{}
+	for _, hook := range hooks {
+		webhook := webhooks.Webhook{
+			Name:         hook.Name,
+			URL:          hook.URL,
+			Secret:       hook.Secret,
+			Events:       hook.Events,
+			Projects:     hook.Projects,
+			RetryCount:   webhooks.DefaultRetryCount,
+			RetryBackoff: webhooks.DefaultRetryBackoff,
+		}
+
+		if hook.Config["retry.count"] != "" {
+			webhook.RetryCount, err = strconv.Atoi(hook.Config["retry.count"])
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if hook.Config["retry.backoff"] != "" {
+			seconds, err := strconv.Atoi(hook.Config["retry.backoff"])
+			if err != nil {
+				return nil, err
+			}
+
+			webhook.RetryBackoff = time.Duration(seconds) * time.Second
+		}
+
+		result = append(result, webhook)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,392 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// Default delivery settings of the webhooks.
+const (
+	DefaultRetryCount   = 5
+	DefaultRetryBackoff = time.Second
+)
+
+// Delays between delivery attempts double up to this value.
+const maxRetryBackoff = 5 * time.Minute
+
+// How long the list of webhooks is cached, changes made through other cluster
+// members being taken into account once it expires.
+const cacheExpiry = 30 * time.Second
+
+// Number of events waiting to be delivered to a webhook, further ones going
+// straight to the dead letter file.
+const queueSize = 1000
+
+// Headers of the requests sent to the webhooks.
+const (
+	HeaderWebhook   = "X-LXD-Webhook"
+	HeaderProject   = "X-LXD-Project"
+	HeaderDelivery  = "X-LXD-Delivery"
+	HeaderSignature = "X-LXD-Signature"
+)
+
+// EventTypes are the types of the events which can be delivered to webhooks.
+var EventTypes = []string{"lifecycle", "operation"}
+
+// Webhook is an HTTP endpoint events are delivered to.
+type Webhook struct {
+	Name   string
+	URL    string
+	Secret string // Key used to sign the deliveries, if any
+
+	// Events to deliver, either as "<type>" or "<type>/<action>" where the
+	// action is the one of lifecycle events or the status of operation
+	// events. All events are delivered if empty.
+	Events []string
+
+	// Projects whose events are delivered (all if empty).
+	Projects []string
+
+	// Number of times failed deliveries are retried, and delay before the
+	// first retry.
+	RetryCount   int
+	RetryBackoff time.Duration
+}
+
+// DeadLetter records an event which couldn't be delivered to a webhook.
+type DeadLetter struct {
+	Timestamp time.Time `json:"timestamp"`
+	Webhook   string    `json:"webhook"`
+	URL       string    `json:"url"`
+	Project   string    `json:"project"`
+	Delivery  string    `json:"delivery"`
+	Attempts  int       `json:"attempts"`
+	Error     string    `json:"error"`
+	Event     api.Event `json:"event"`
+}
+
+// ValidEvent returns whether the given event filter is valid.
+func ValidEvent(event string) bool {
+	fields := strings.SplitN(event, "/", 2)
+	if len(fields) == 2 && fields[1] == "" {
+		return false
+	}
+
+	return shared.StringInSlice(fields[0], EventTypes)
+}
+
+// Matches returns whether the given event of the given project is to be
+// delivered to the webhook.
+func (w Webhook) Matches(project string, event api.Event) bool {
+	if !shared.StringInSlice(event.Type, EventTypes) {
+		return false
+	}
+
+	if len(w.Projects) > 0 && !shared.StringInSlice(project, w.Projects) {
+		return false
+	}
+
+	if len(w.Events) == 0 {
+		return true
+	}
+
+	action := fmt.Sprintf("%s/%s", event.Type, eventAction(event))
+	for _, filter := range w.Events {
+		if filter == event.Type || filter == action {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Return the action of a lifecycle event, or the status of an operation event.
+func eventAction(event api.Event) string {
+	switch event.Type {
+	case "lifecycle":
+		lifecycle := api.EventLifecycle{}
+		err := json.Unmarshal(event.Metadata, &lifecycle)
+		if err != nil {
+			return ""
+		}
+
+		return lifecycle.Action
+	case "operation":
+		op := api.Operation{}
+		err := json.Unmarshal(event.Metadata, &op)
+		if err != nil {
+			return ""
+		}
+
+		return strings.ToLower(op.Status)
+	}
+
+	return ""
+}
+
+// Sign returns the signature of the given request body, as sent in the
+// X-LXD-Signature header.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return fmt.Sprintf("sha256=%s", hex.EncodeToString(mac.Sum(nil)))
+}
+
+// Dispatcher delivers events to the webhooks they match.
+type Dispatcher struct {
+	// Returns all the webhooks.
+	WebhooksFunc func() ([]Webhook, error)
+
+	client         *http.Client
+	deadLetterPath string
+
+	mu       sync.Mutex
+	webhooks []Webhook
+	expiry   time.Time
+
+	// Events waiting to be delivered to each webhook, by name, each queue
+	// having its own worker.
+	queues    map[string]chan queuedEvent
+	queueSize int
+
+	deadLetterMu sync.Mutex
+}
+
+// An event waiting to be delivered to a webhook.
+type queuedEvent struct {
+	webhook Webhook
+	project string
+	event   api.Event
+}
+
+// NewDispatcher returns a dispatcher sending events with the given HTTP
+// client, and appending the events which couldn't be delivered to the file
+// at the given path.
+func NewDispatcher(webhooksFunc func() ([]Webhook, error), client *http.Client, deadLetterPath string) *Dispatcher {
+	return &Dispatcher{
+		WebhooksFunc:   webhooksFunc,
+		client:         client,
+		deadLetterPath: deadLetterPath,
+		queues:         map[string]chan queuedEvent{},
+		queueSize:      queueSize,
+	}
+}
+
+// Invalidate drops the cached list of webhooks, for changes to be taken into
+// account by the next event.
+func (d *Dispatcher) Invalidate() {
+	d.mu.Lock()
+	d.expiry = time.Time{}
+	d.mu.Unlock()
+}
+
+// Must be called with the dispatcher lock held.
+func (d *Dispatcher) load() []Webhook {
+	if time.Now().Before(d.expiry) {
+		return d.webhooks
+	}
+
+	webhooks, err := d.WebhooksFunc()
+	if err != nil {
+		// Keep using the previous list until the next attempt.
+		logger.Warn("Failed to load webhooks", log.Ctx{"err": err})
+	} else {
+		d.webhooks = webhooks
+	}
+
+	d.expiry = time.Now().Add(cacheExpiry)
+
+	// Stop the workers of the webhooks which are gone, once they're done
+	// with their queue.
+	for name, queue := range d.queues {
+		found := false
+		for _, webhook := range d.webhooks {
+			if webhook.Name == name {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			close(queue)
+			delete(d.queues, name)
+		}
+	}
+
+	return d.webhooks
+}
+
+// Dispatch queues the given event of the given project for delivery to the
+// webhooks it matches. Events which don't fit in the queue of a webhook are
+// recorded in the dead letter file instead.
+func (d *Dispatcher) Dispatch(project string, event api.Event) {
+	overflow := []Webhook{}
+
+	d.mu.Lock()
+	for _, webhook := range d.load() {
+		if !webhook.Matches(project, event) {
+			continue
+		}
+
+		queue, ok := d.queues[webhook.Name]
+		if !ok {
+			queue = make(chan queuedEvent, d.queueSize)
+			d.queues[webhook.Name] = queue
+			go d.worker(queue)
+		}
+
+		select {
+		case queue <- queuedEvent{webhook: webhook, project: project, event: event}:
+		default:
+			overflow = append(overflow, webhook)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, webhook := range overflow {
+		logger.Warn("Webhook delivery queue is full", log.Ctx{"webhook": webhook.Name})
+
+		err := d.deadLetter(DeadLetter{
+			Timestamp: time.Now().UTC(),
+			Webhook:   webhook.Name,
+			URL:       webhook.URL,
+			Project:   project,
+			Delivery:  uuid.NewRandom().String(),
+			Error:     "Delivery queue is full",
+			Event:     event,
+		})
+		if err != nil {
+			logger.Warn("Failed to record undelivered webhook event", log.Ctx{"webhook": webhook.Name, "err": err})
+		}
+	}
+}
+
+// Deliver the events of a webhook queue one at a time, until it's closed.
+func (d *Dispatcher) worker(queue chan queuedEvent) {
+	for entry := range queue {
+		d.deliver(entry.webhook, entry.project, entry.event)
+	}
+}
+
+// Deliver the event, retrying with exponential backoff, and record it in the
+// dead letter file if all attempts fail.
+func (d *Dispatcher) deliver(webhook Webhook, project string, event api.Event) {
+	delivery := uuid.NewRandom().String()
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		logger.Warn("Failed to encode webhook event", log.Ctx{"webhook": webhook.Name, "err": err})
+		return
+	}
+
+	backoff := webhook.RetryBackoff
+	attempts := 0
+	for {
+		attempts++
+
+		err = d.post(webhook, project, delivery, body)
+		if err == nil {
+			return
+		}
+
+		if attempts > webhook.RetryCount {
+			break
+		}
+
+		logger.Debug("Retrying webhook delivery", log.Ctx{"webhook": webhook.Name, "delivery": delivery, "err": err})
+
+		time.Sleep(backoff)
+
+		backoff *= 2
+		if backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+
+	logger.Warn("Failed to deliver event to webhook", log.Ctx{"webhook": webhook.Name, "delivery": delivery, "attempts": attempts, "err": err})
+
+	err = d.deadLetter(DeadLetter{
+		Timestamp: time.Now().UTC(),
+		Webhook:   webhook.Name,
+		URL:       webhook.URL,
+		Project:   project,
+		Delivery:  delivery,
+		Attempts:  attempts,
+		Error:     err.Error(),
+		Event:     event,
+	})
+	if err != nil {
+		logger.Warn("Failed to record undelivered webhook event", log.Ctx{"webhook": webhook.Name, "err": err})
+	}
+}
+
+func (d *Dispatcher) post(webhook Webhook, project string, delivery string, body []byte) error {
+	req, err := http.NewRequest("POST", webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderWebhook, webhook.Name)
+	req.Header.Set(HeaderProject, project)
+	req.Header.Set(HeaderDelivery, delivery)
+
+	if webhook.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(webhook.Secret, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Allow the connection to be reused.
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Unexpected response status %q", resp.Status)
+	}
+
+	return nil
+}
+
+// Append the given entry to the dead letter file.
+func (d *Dispatcher) deadLetter(entry DeadLetter) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	d.deadLetterMu.Lock()
+	defer d.deadLetterMu.Unlock()
+
+	f, err := os.OpenFile(d.deadLetterPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return errors.Wrap(err, "Failed to open webhooks dead letter file")
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,185 @@
+package webhooks
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+func lifecycleEvent(t *testing.T, action string) api.Event {
+	metadata, err := json.Marshal(api.EventLifecycle{Action: action, Source: "/1.0/instances/c1"})
+	require.NoError(t, err)
+
+	return api.Event{Type: "lifecycle", Timestamp: time.Now(), Metadata: metadata}
+}
+
+func operationEvent(t *testing.T, status string) api.Event {
+	metadata, err := json.Marshal(api.Operation{ID: "1234", Status: status})
+	require.NoError(t, err)
+
+	return api.Event{Type: "operation", Timestamp: time.Now(), Metadata: metadata}
+}
+
+func TestWebhook_Matches(t *testing.T) {
+	started := lifecycleEvent(t, "container-started")
+	failure := operationEvent(t, "Failure")
+	success := operationEvent(t, "Success")
+
+	all := Webhook{}
+	assert.True(t, all.Matches("default", started))
+	assert.True(t, all.Matches("default", success))
+	assert.False(t, all.Matches("default", api.Event{Type: "logging"}))
+
+	filtered := Webhook{Events: []string{"lifecycle/container-started", "operation/failure"}, Projects: []string{"web"}}
+	assert.True(t, filtered.Matches("web", started))
+	assert.True(t, filtered.Matches("web", failure))
+	assert.False(t, filtered.Matches("web", success))
+	assert.False(t, filtered.Matches("web", lifecycleEvent(t, "container-deleted")))
+	assert.False(t, filtered.Matches("default", started))
+}
+
+func TestValidEvent(t *testing.T) {
+	assert.True(t, ValidEvent("lifecycle"))
+	assert.True(t, ValidEvent("operation/failure"))
+	assert.False(t, ValidEvent("logging"))
+	assert.False(t, ValidEvent("lifecycle/"))
+	assert.False(t, ValidEvent(""))
+}
+
+// Events are signed and retried until they're accepted.
+func TestDispatcher_Deliver(t *testing.T) {
+	requests := make(chan *http.Request, 10)
+	bodies := make(chan []byte, 10)
+	failures := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		requests <- r
+		bodies <- body
+	}))
+	defer server.Close()
+
+	webhook := Webhook{Name: "chatops", URL: server.URL, Secret: "s3cr3t", RetryCount: 2, RetryBackoff: time.Millisecond}
+	d := NewDispatcher(func() ([]Webhook, error) { return []Webhook{webhook}, nil }, server.Client(), "")
+
+	event := lifecycleEvent(t, "container-started")
+	d.Dispatch("default", event)
+
+	r := <-requests
+	body := <-bodies
+	assert.Equal(t, "chatops", r.Header.Get(HeaderWebhook))
+	assert.Equal(t, "default", r.Header.Get(HeaderProject))
+	assert.NotEmpty(t, r.Header.Get(HeaderDelivery))
+	assert.Equal(t, Sign("s3cr3t", body), r.Header.Get(HeaderSignature))
+
+	received := api.Event{}
+	require.NoError(t, json.Unmarshal(body, &received))
+	assert.Equal(t, "lifecycle", received.Type)
+}
+
+// Events which can't be delivered end up in the dead letter file.
+func TestDispatcher_DeadLetter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "lxd-webhooks-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "dead-letter.log")
+	webhook := Webhook{Name: "chatops", URL: server.URL, RetryCount: 1, RetryBackoff: time.Millisecond}
+	d := NewDispatcher(func() ([]Webhook, error) { return []Webhook{webhook}, nil }, server.Client(), path)
+
+	d.deliver(webhook, "default", operationEvent(t, "Failure"))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	require.True(t, scanner.Scan())
+
+	entry := DeadLetter{}
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+	assert.Equal(t, "chatops", entry.Webhook)
+	assert.Equal(t, "default", entry.Project)
+	assert.Equal(t, 2, entry.Attempts)
+	assert.Equal(t, "operation", entry.Event.Type)
+	assert.False(t, scanner.Scan())
+}
+
+// Events which don't fit in the queue of a webhook go to the dead letter file
+// right away.
+func TestDispatcher_QueueFull(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	dir, err := ioutil.TempDir("", "lxd-webhooks-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "dead-letter.log")
+	webhook := Webhook{Name: "chatops", URL: server.URL}
+	d := NewDispatcher(func() ([]Webhook, error) { return []Webhook{webhook}, nil }, server.Client(), path)
+	d.queueSize = 1
+
+	// The first event is held up by the server, the second one waits in
+	// the queue and the third one doesn't fit.
+	d.Dispatch("default", lifecycleEvent(t, "container-started"))
+	time.Sleep(100 * time.Millisecond)
+	d.Dispatch("default", lifecycleEvent(t, "container-started"))
+	d.Dispatch("default", lifecycleEvent(t, "container-stopped"))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	require.True(t, scanner.Scan())
+
+	entry := DeadLetter{}
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+	assert.Equal(t, "chatops", entry.Webhook)
+	assert.Equal(t, 0, entry.Attempts)
+	assert.Equal(t, "Delivery queue is full", entry.Error)
+	assert.False(t, scanner.Scan())
+}
+
+// The list of webhooks is cached until invalidated.
+func TestDispatcher_Invalidate(t *testing.T) {
+	loads := 0
+	d := NewDispatcher(func() ([]Webhook, error) {
+		loads++
+		return []Webhook{}, nil
+	}, http.DefaultClient, "")
+
+	d.Dispatch("default", lifecycleEvent(t, "container-started"))
+	d.Dispatch("default", lifecycleEvent(t, "container-started"))
+	assert.Equal(t, 1, loads)
+
+	d.Invalidate()
+	d.Dispatch("default", lifecycleEvent(t, "container-started"))
+	assert.Equal(t, 2, loads)
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"time"
+)
+
+// AuditEntry represents an API request recorded in the audit log
+//
+// API extension: audit
+type AuditEntry struct {
+	Timestamp time.Time `json:"timestamp" yaml:"timestamp"`
+
+	// Certificate fingerprint or external username of the caller
+	User     string `json:"user" yaml:"user"`
+	Protocol string `json:"protocol" yaml:"protocol"`
+	Address  string `json:"address" yaml:"address"`
+
+	Method  string `json:"method" yaml:"method"`
+	URL     string `json:"url" yaml:"url"`
+	Project string `json:"project" yaml:"project"`
+
+	// SHA256 digest of the request body
+	BodyDigest string `json:"body_digest" yaml:"body_digest"`
+
+	Operation  string `json:"operation" yaml:"operation"`
+	StatusCode int    `json:"status_code" yaml:"status_code"`
+}
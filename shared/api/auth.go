@@ -0,0 +1,85 @@
+package api
+
+import (
+	"time"
+)
+
+// AuthTokensPost represents the fields of a new API token
+//
+// API extension: auth_tokens
+type AuthTokensPost struct {
+	Description string `json:"description" yaml:"description"`
+
+	// One of "read-only", "operate" or "manage"
+	Scope string `json:"scope" yaml:"scope"`
+
+	// Projects the token is restricted to (all projects if empty)
+	Projects []string `json:"projects" yaml:"projects"`
+
+	ExpiresAt time.Time `json:"expires_at" yaml:"expires_at"`
+}
+
+// AuthToken represents an API token
+//
+// API extension: auth_tokens
+type AuthToken struct {
+	AuthTokensPost `yaml:",inline"`
+
+	ID        string    `json:"id" yaml:"id"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+
+	// Bearer token to pass in the Authorization header, only returned on
+	// creation
+	Token string `json:"token,omitempty" yaml:"token,omitempty"`
+}
+
+// AuthGroupsPost represents the fields of a new group of the built-in RBAC
+// backend
+//
+// API extension: auth_groups
+type AuthGroupsPost struct {
+	AuthGroupPut `yaml:",inline"`
+
+	Name string `json:"name" yaml:"name"`
+}
+
+// AuthGroupPut represents the modifiable fields of a group of the built-in
+// RBAC backend
+//
+// API extension: auth_groups
+type AuthGroupPut struct {
+	Description string `json:"description" yaml:"description"`
+
+	// Certificate fingerprints or usernames of the members
+	Identities []string `json:"identities" yaml:"identities"`
+
+	// Roles granted to the members
+	Roles []AuthGroupRole `json:"roles" yaml:"roles"`
+}
+
+// AuthGroupRole represents a role granted on a project
+//
+// API extension: auth_groups
+type AuthGroupRole struct {
+	// One of "admin", "operator" or "viewer"
+	Role string `json:"role" yaml:"role"`
+
+	// Project the role applies to (all projects if empty)
+	Project string `json:"project" yaml:"project"`
+}
+
+// AuthGroup represents a group of the built-in RBAC backend
+//
+// API extension: auth_groups
+type AuthGroup struct {
+	AuthGroupPut `yaml:",inline"`
+
+	Name string `json:"name" yaml:"name"`
+}
+
+// Writable converts a full AuthGroup struct into a AuthGroupPut struct (filters read-only fields)
+//
+// API extension: auth_groups
+func (group *AuthGroup) Writable() AuthGroupPut {
+	return group.AuthGroupPut
+}
@@ -1,5 +1,11 @@
 package api
 
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
 // Cluster represents high-level information about a LXD cluster.
 //
 // API extension: clustering
@@ -39,6 +45,48 @@ type ClusterPut struct {
 	// API extension: clustering_join
 	ServerAddress   string `json:"server_address" yaml:"server_address"`
 	ClusterPassword string `json:"cluster_password" yaml:"cluster_password"`
+
+	// API extension: clustering_join_token
+	ClusterToken string `json:"cluster_token" yaml:"cluster_token"`
+}
+
+// ClusterMembersPost represents the fields required to issue a join token for
+// a new LXD node.
+//
+// API extension: clustering_join_token
+type ClusterMembersPost struct {
+	ServerName string `json:"server_name" yaml:"server_name"`
+}
+
+// ClusterMemberJoinToken represents the information a new LXD node needs in
+// order to join the cluster.
+//
+// API extension: clustering_join_token
+type ClusterMemberJoinToken struct {
+	ServerName  string    `json:"server_name" yaml:"server_name"`
+	Fingerprint string    `json:"fingerprint" yaml:"fingerprint"`
+	Addresses   []string  `json:"addresses" yaml:"addresses"`
+	Secret      string    `json:"secret" yaml:"secret"`
+	ExpiresAt   time.Time `json:"expires_at" yaml:"expires_at"`
+}
+
+// String encodes the join token as a base64 string, suitable to be passed to
+// the joining node.
+func (t *ClusterMemberJoinToken) String() string {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return ""
+	}
+
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// ClusterCertificatePut represents the certificate and key pair for all members in a LXD cluster.
+//
+// API extension: clustering_update_cert
+type ClusterCertificatePut struct {
+	ClusterCertificate    string `json:"cluster_certificate" yaml:"cluster_certificate"`
+	ClusterCertificateKey string `json:"cluster_certificate_key" yaml:"cluster_certificate_key"`
 }
 
 // ClusterMemberPost represents the fields required to rename a LXD node.
@@ -13,6 +13,13 @@ type Event struct {
 
 	// API extension: event_location
 	Location string `yaml:"location,omitempty" json:"location,omitempty"`
+
+	// API extension: event_sequence
+	Epoch    string `yaml:"epoch,omitempty" json:"epoch,omitempty"`
+	Sequence int64  `yaml:"sequence,omitempty" json:"sequence,omitempty"`
+
+	// API extension: event_project
+	Project string `yaml:"project,omitempty" json:"project,omitempty"`
 }
 
 // EventLogging represents a logging type event entry (admin only)
@@ -16,6 +16,9 @@ type ImagesPost struct {
 
 	// API extension: image_create_aliases
 	Aliases []ImageAlias `json:"aliases" yaml:"aliases"`
+
+	// API extension: image_reproducible_publish
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
 }
 
 // ImagesPostSource represents the source of a new LXD image
@@ -44,6 +47,26 @@ type ImagePut struct {
 
 	// API extension: images_expiry
 	ExpiresAt time.Time `json:"expires_at" yaml:"expires_at"`
+
+	// API extension: image_auto_update_policies
+	AutoUpdatePolicy ImageAutoUpdatePolicy `json:"auto_update_policy" yaml:"auto_update_policy"`
+}
+
+// ImageAutoUpdatePolicy represents when and how an image gets auto-updated
+//
+// API extension: image_auto_update_policies
+type ImageAutoUpdatePolicy struct {
+	// Cron expression of the times at which the image may be updated
+	Window string `json:"window" yaml:"window"`
+
+	// Fingerprint (or prefix) the image may only be updated to
+	Pin string `json:"pin" yaml:"pin"`
+
+	// Age after which the image is updated regardless of the window
+	MaxAge string `json:"max_age" yaml:"max_age"`
+
+	// Only emit an event when an update is available
+	NotifyOnly bool `json:"notify_only" yaml:"notify_only"`
 }
 
 // Image represents a LXD image
@@ -64,6 +87,9 @@ type Image struct {
 	CreatedAt  time.Time `json:"created_at" yaml:"created_at"`
 	LastUsedAt time.Time `json:"last_used_at" yaml:"last_used_at"`
 	UploadedAt time.Time `json:"uploaded_at" yaml:"uploaded_at"`
+
+	// API extension: image_used_by
+	UsedBy []string `json:"used_by,omitempty" yaml:"used_by,omitempty"`
 }
 
 // Writable converts a full Image struct into a ImagePut struct (filters read-only fields)
@@ -86,6 +112,9 @@ type ImageSource struct {
 
 	// API extension: image_types
 	ImageType string `json:"image_type" yaml:"image_type"`
+
+	// API extension: image_signatures
+	RequireSignature bool `json:"require_signature,omitempty" yaml:"require_signature,omitempty"`
 }
 
 // ImageAliasesPost represents a new LXD image alias
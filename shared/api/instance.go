@@ -134,4 +134,10 @@ type InstanceSource struct {
 	ContainerOnly bool              `json:"container_only,omitempty" yaml:"container_only,omitempty"` // Deprecated, use InstanceOnly.
 	Refresh       bool              `json:"refresh,omitempty" yaml:"refresh,omitempty"`
 	Project       string            `json:"project,omitempty" yaml:"project,omitempty"`
+
+	// API extension: image_signatures
+	RequireSignature bool `json:"require_signature,omitempty" yaml:"require_signature,omitempty"`
+
+	// API extension: image_import_disk
+	URL string `json:"url,omitempty" yaml:"url,omitempty"`
 }
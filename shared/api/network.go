@@ -9,6 +9,9 @@ type NetworksPost struct {
 	Managed bool   `json:"managed" yaml:"managed"`
 	Name    string `json:"name" yaml:"name"`
 	Type    string `json:"type" yaml:"type"`
+
+	// API extension: clustering_member_config
+	Members map[string]map[string]string `json:"members,omitempty" yaml:"members,omitempty"`
 }
 
 // NetworkPost represents the fields required to rename a LXD network
@@ -20,4 +20,15 @@ type Operation struct {
 
 	// API extension: operation_location
 	Location string `json:"location" yaml:"location"`
+
+	// API extension: operations_history
+	Requestor *OperationRequestor `json:"requestor,omitempty" yaml:"requestor,omitempty"`
+}
+
+// OperationRequestor represents the client which requested an operation
+//
+// API extension: operations_history
+type OperationRequestor struct {
+	Username string `json:"username" yaml:"username"`
+	Protocol string `json:"protocol" yaml:"protocol"`
 }
@@ -8,6 +8,9 @@ type StoragePoolsPost struct {
 
 	Name   string `json:"name" yaml:"name"`
 	Driver string `json:"driver" yaml:"driver"`
+
+	// API extension: clustering_member_config
+	Members map[string]map[string]string `json:"members,omitempty" yaml:"members,omitempty"`
 }
 
 // StoragePool represents the fields of a LXD storage pool.
@@ -0,0 +1,46 @@
+package api
+
+// WebhooksPost represents the fields of a new webhook
+//
+// API extension: webhooks
+type WebhooksPost struct {
+	WebhookPut `yaml:",inline"`
+
+	Name string `json:"name" yaml:"name"`
+}
+
+// WebhookPut represents the modifiable fields of a webhook
+//
+// API extension: webhooks
+type WebhookPut struct {
+	Description string `json:"description" yaml:"description"`
+
+	// URL the events are sent to with POST requests
+	URL string `json:"url" yaml:"url"`
+
+	// Key used to sign the requests with HMAC-SHA256. It's never returned
+	// by the server and is left unchanged by updates if empty.
+	Secret string `json:"secret,omitempty" yaml:"secret,omitempty"`
+
+	// Events to deliver, as "<type>" or "<type>/<action>" (all if empty)
+	Events []string `json:"events" yaml:"events"`
+
+	// Projects whose events are delivered (all if empty)
+	Projects []string `json:"projects" yaml:"projects"`
+
+	Config map[string]string `json:"config" yaml:"config"`
+}
+
+// Webhook represents a webhook events are delivered to
+//
+// API extension: webhooks
+type Webhook struct {
+	WebhookPut `yaml:",inline"`
+
+	Name string `json:"name" yaml:"name"`
+}
+
+// Writable converts a full Webhook struct into a WebhookPut struct (filters read-only fields)
+func (webhook *Webhook) Writable() WebhookPut {
+	return webhook.WebhookPut
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/idmap"
@@ -15,6 +16,9 @@ type ContainerTarWriter struct {
 	tarWriter *tar.Writer
 	idmapSet  *idmap.IdmapSet
 	linkMap   map[uint64]string
+
+	// Modification time of all the files, if not zero
+	modTime time.Time
 }
 
 func NewContainerTarWriter(writer io.Writer, idmapSet *idmap.IdmapSet) *ContainerTarWriter {
@@ -25,6 +29,12 @@ func NewContainerTarWriter(writer io.Writer, idmapSet *idmap.IdmapSet) *Containe
 	return ctw
 }
 
+// SetModTime makes the tarball reproducible, giving all the files the given
+// modification time and leaving out the names of their owners.
+func (ctw *ContainerTarWriter) SetModTime(modTime time.Time) {
+	ctw.modTime = modTime
+}
+
 func (ctw *ContainerTarWriter) WriteFile(offset int, path string, fi os.FileInfo) error {
 	var err error
 	var major, minor uint32
@@ -76,6 +86,14 @@ func (ctw *ContainerTarWriter) WriteFile(offset int, path string, fi os.FileInfo
 	hdr.Devmajor = int64(major)
 	hdr.Devminor = int64(minor)
 
+	if !ctw.modTime.IsZero() {
+		hdr.ModTime = ctw.modTime
+		hdr.AccessTime = time.Time{}
+		hdr.ChangeTime = time.Time{}
+		hdr.Uname = ""
+		hdr.Gname = ""
+	}
+
 	// If it's a hardlink we've already seen use the old name
 	if fi.Mode().IsRegular() && nlink > 1 {
 		if firstPath, found := ctw.linkMap[ino]; found {
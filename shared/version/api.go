@@ -179,6 +179,30 @@ var APIExtensions = []string{
 	"container_syscall_intercept_mount_fuse",
 	"container_disk_ceph",
 	"virtual-machines",
+	"clustering_member_config",
+	"clustering_join_token",
+	"event_sequence",
+	"clustering_update_cert",
+	"image_import_oci",
+	"images_simplestreams",
+	"image_signatures",
+	"image_deltas",
+	"image_auto_update_policies",
+	"image_used_by",
+	"image_import_disk",
+	"image_chunked_store",
+	"certificate_project",
+	"metrics",
+	"audit",
+	"oidc",
+	"auth_tokens",
+	"auth_groups",
+	"rate_limit",
+	"list_filters",
+	"webhooks",
+	"operations_history",
+	"event_project",
+	"image_reproducible_publish",
 }
 
 // APIExtensionsCount returns the number of available API extensions.
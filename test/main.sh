@@ -171,12 +171,15 @@ run_test test_clustering_network "clustering network"
 run_test test_clustering_publish "clustering publish"
 run_test test_clustering_profiles "clustering profiles"
 run_test test_clustering_join_api "clustering join api"
+run_test test_clustering_join_token "clustering join token"
 run_test test_clustering_shutdown_nodes "clustering shutdown"
 run_test test_clustering_projects "clustering projects"
 run_test test_clustering_address "clustering address"
 run_test test_clustering_image_replication "clustering image replication"
 run_test test_clustering_dns "clustering DNS"
 run_test test_clustering_recover "clustering recovery"
+run_test test_clustering_database_backup "clustering database backup"
+run_test test_clustering_update_cert "clustering update certificate"
 #run_test test_clustering_upgrade "clustering upgrade"
 run_test test_projects_default "default project"
 run_test test_projects_crud "projects CRUD operations"
@@ -189,6 +192,7 @@ run_test test_projects_images "images inside projects"
 run_test test_projects_images_default "images from the global default project"
 run_test test_projects_storage "projects and storage pools"
 run_test test_projects_network "projects and networks"
+run_test test_projects_restricted_certificates "certificates restricted to projects"
 run_test test_container_devices_disk "container devices - disk"
 run_test test_container_devices_nic_p2p "container devices - nic - p2p"
 run_test test_container_devices_nic_bridged "container devices - nic - bridged"
@@ -209,8 +213,18 @@ run_test test_security_protection "container protection"
 run_test test_image_expiry "image expiry"
 run_test test_image_list_all_aliases "image list all aliases"
 run_test test_image_auto_update "image auto-update"
+run_test test_image_auto_update_policy "image auto-update policies"
 run_test test_image_prefer_cached "image prefer cached"
 run_test test_image_import_dir "import image from directory"
+run_test test_image_import_oci "import OCI image"
+run_test test_image_import_disk "import disk image"
+run_test test_image_simplestreams "image simplestreams feed"
+run_test test_image_signatures "image signatures"
+run_test test_image_deltas "image deltas"
+run_test test_image_chunked_store "image chunked store"
+run_test test_image_mirror "image mirroring"
+run_test test_image_build "image building"
+run_test test_image_prune "image pruning"
 run_test test_concurrent_exec "concurrent exec"
 run_test test_concurrent "concurrent startup"
 run_test test_snapshots "container snapshots"
@@ -246,6 +260,14 @@ run_test test_kernel_limits "kernel limits"
 run_test test_macaroon_auth "macaroon authentication"
 run_test test_console "console"
 run_test test_query "query"
+run_test test_metrics "metrics"
+run_test test_audit "audit log"
+run_test test_auth_tokens "API tokens"
+run_test test_auth_groups "built-in RBAC groups"
+run_test test_rate_limit "API rate limiting"
+run_test test_list_filters "list filtering and pagination"
+run_test test_webhooks "webhooks"
+run_test test_operations_history "operations history"
 run_test test_storage_local_volume_handling "storage local volume handling"
 run_test test_backup_import "backup import"
 run_test test_backup_export "backup export"
@@ -0,0 +1,18 @@
+test_audit() {
+  ensure_import_testimage
+
+  lxc init testimage audit-c1
+  lxc delete audit-c1
+
+  # Mutations are recorded, reads aren't.
+  [ -s "${LXD_DIR}/logs/audit.log" ]
+  lxc query "/1.0/audit?limit=0" | jq -r '.[] | select(.method == "DELETE") | .url' | grep -q 'audit-c1'
+  lxc query "/1.0/audit?limit=0" | jq -r '.[] | select(.method == "POST" and .status_code == 202) | .operation' | grep -q .
+  ! lxc query "/1.0/audit?limit=0" | jq -r '.[].method' | grep -q GET || false
+
+  # Filtering.
+  [ "$(lxc query "/1.0/audit?limit=1" | jq length)" = "1" ]
+  [ "$(lxc query "/1.0/audit?project=nonexistent" | jq length)" = "0" ]
+  [ "$(lxc query "/1.0/audit?since=2999-01-01T00:00:00Z" | jq length)" = "0" ]
+  ! lxc query "/1.0/audit?since=yesterday" || false
+}
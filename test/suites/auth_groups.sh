@@ -0,0 +1,55 @@
+test_auth_groups() {
+  ensure_import_testimage
+  ensure_has_localhost_remote "${LXD_ADDR}"
+
+  fingerprint="$(openssl x509 -in "${LXD_CONF}/client.crt" -outform der | sha256sum | cut -d' ' -f1)"
+
+  lxc project create foo -c features.images=false -c features.profiles=false
+
+  # Manage groups.
+  lxc auth group create ops --description="Operators"
+  lxc auth group grant ops viewer
+  lxc auth group grant ops operator foo
+  ! lxc auth group grant ops operator foo || false
+  ! lxc auth group grant ops superuser || false
+  ! lxc auth group grant ops viewer bar || false
+  ! lxc auth group add-identity ops "${fingerprint}" || false
+  ! lxc auth group add-identity ops "oidc:" || false
+  lxc auth group add-identity ops "tls:${fingerprint}"
+  lxc auth group show ops | grep -q "description: Operators"
+  lxc auth group list --format csv | grep -q "^ops,Operators,"
+  ! lxc auth group create ops || false
+
+  # The built-in and external RBAC backends are mutually exclusive.
+  ! lxc query -X PATCH -d '{"config": {"rbac.local": "true", "rbac.api.url": "https://rbac.example.com"}}' /1.0 || false
+
+  lxc config set rbac.local true
+
+  # Groups grant the roles of their members.
+  lxc list localhost:
+  ! lxc init testimage localhost:c1 || false
+  lxc init testimage localhost:c1 --project foo
+  lxc delete localhost:c1 --project foo
+  ! lxc config trust list localhost: || false
+
+  # The unix socket isn't restricted.
+  lxc config trust list
+
+  # Members without any admin role don't get to manage the groups.
+  ! lxc auth group list localhost: || false
+
+  lxc auth group grant ops admin
+  lxc auth group list localhost: --format csv | grep -q "^ops,"
+
+  # Removed members lose their roles.
+  lxc auth group remove-identity ops "tls:${fingerprint}"
+  ! lxc list localhost: || false
+
+  lxc config unset rbac.local
+  lxc list localhost:
+
+  # Groups are removed along with their roles.
+  lxc auth group delete ops
+  ! lxc auth group show ops || false
+  lxc project delete foo
+}
@@ -0,0 +1,64 @@
+test_auth_tokens() {
+  ensure_import_testimage
+  ensure_has_localhost_remote "${LXD_ADDR}"
+
+  lxc init testimage c1
+  lxc project create foo -c features.images=false -c features.profiles=false
+
+  token_curl() {
+    token="${1}"
+    shift
+    curl -k -s -H "Authorization: Bearer ${token}" "$@"
+  }
+
+  # Read-only tokens can only look.
+  ro="$(lxc auth token add --quiet --scope=read-only --description=ci)"
+  [ "$(token_curl "${ro}" "https://${LXD_ADDR}/1.0" | jq -r .metadata.auth)" = "trusted" ]
+  token_curl "${ro}" "https://${LXD_ADDR}/1.0/instances" | jq -r '.metadata[]' | grep -q /1.0/instances/c1
+  [ "$(token_curl "${ro}" -X PUT -d '{"action": "start"}' "https://${LXD_ADDR}/1.0/instances/c1/state" | jq -r .error_code)" = "403" ]
+  [ "$(token_curl "${ro}" "https://${LXD_ADDR}/1.0/certificates?recursion=1" | jq -r .error_code)" = "403" ]
+
+  # Project restricted tokens only see their projects.
+  foo="$(lxc auth token add --quiet --scope=manage --projects=foo)"
+  [ "$(token_curl "${foo}" "https://${LXD_ADDR}/1.0/instances" | jq -r .error_code)" = "403" ]
+  token_curl "${foo}" "https://${LXD_ADDR}/1.0/instances?project=foo" | jq -r .status_code | grep -qx 200
+  [ "$(token_curl "${foo}" "https://${LXD_ADDR}/1.0/auth/tokens" | jq -r .error_code)" = "403" ]
+  [ "$(token_curl "${foo}" "https://${LXD_ADDR}/1.0/operations" | jq -r .error_code)" = "403" ]
+  token_curl "${foo}" "https://${LXD_ADDR}/1.0/operations?project=foo" | jq -r .status_code | grep -qx 200
+  [ "$(token_curl "${foo}" "https://${LXD_ADDR}/1.0/events?project=foo&type=logging" | jq -r .error_code)" = "403" ]
+
+  # Nor the operations of other projects, even once completed.
+  lxc init testimage c2
+  uuid="$(lxc query "/1.0/operations?all-history=true&recursion=1" | jq -r '.success[] | select(.resources.instances[0] == "/1.0/instances/c2") | .id' | tail -n1)"
+  [ -n "${uuid}" ]
+  [ "$(token_curl "${foo}" "https://${LXD_ADDR}/1.0/operations/${uuid}" | jq -r .error_code)" = "403" ]
+  [ "$(token_curl "${foo}" "https://${LXD_ADDR}/1.0/operations/${uuid}/wait" | jq -r .error_code)" = "403" ]
+  lxc delete c2
+
+  # Tokens can't manage tokens, even with the manage scope.
+  admin="$(lxc auth token add --quiet --scope=manage)"
+  token_curl "${admin}" "https://${LXD_ADDR}/1.0/certificates" | jq -r .status_code | grep -qx 200
+  [ "$(token_curl "${admin}" "https://${LXD_ADDR}/1.0/auth/tokens" | jq -r .error_code)" = "403" ]
+  [ "$(token_curl "${admin}" -X POST -d '{"scope": "manage"}' "https://${LXD_ADDR}/1.0/auth/tokens" | jq -r .error_code)" = "403" ]
+
+  # Tokens are listed without their secret.
+  lxc auth token list --format csv | grep -q ",ci,read-only,"
+  ! lxc query /1.0/auth/tokens?recursion=1 | grep -q "lxd_" || false
+
+  # Revoked and unknown tokens aren't trusted.
+  id="$(lxc auth token list --format csv | grep ",ci," | cut -d, -f1)"
+  lxc auth token show "${id}" | grep -q "scope: read-only"
+  lxc auth token revoke "${id}"
+  [ "$(token_curl "${ro}" "https://${LXD_ADDR}/1.0" | jq -r .metadata.auth)" = "untrusted" ]
+  [ "$(token_curl "lxd_invalid" "https://${LXD_ADDR}/1.0" | jq -r .metadata.auth)" = "untrusted" ]
+
+  # Past expiry dates are rejected.
+  ! lxc auth token add --expiry=-1h || false
+
+  for id in $(lxc auth token list --format csv | cut -d, -f1); do
+    lxc auth token revoke "${id}"
+  done
+
+  lxc project delete foo
+  lxc delete c1
+}
@@ -428,6 +428,20 @@ test_clustering_storage() {
     ! LXD_DIR="${LXD_ONE_DIR}" lxc storage show pool1 | grep -q rsync.bwlimit || false
   fi
 
+  # Define and create a storage pool in a single request
+  if [ "${driver}" = "dir" ]; then
+    # Failed requests don't leave a pending pool behind
+    ! LXD_DIR="${LXD_ONE_DIR}" lxc query -X POST -d '{"name": "pool2", "driver": "dir", "config": {"source": "/foo"}, "members": {"node1": {}}}' /1.0/storage-pools || false
+    ! LXD_DIR="${LXD_ONE_DIR}" lxc storage show pool2 || false
+    ! LXD_DIR="${LXD_ONE_DIR}" lxc query -X POST -d '{"name": "pool2", "driver": "dir", "config": {"rsync.bwlimit": "foo"}, "members": {"node1": {}}}' /1.0/storage-pools || false
+    ! LXD_DIR="${LXD_ONE_DIR}" lxc storage show pool2 || false
+
+    LXD_DIR="${LXD_ONE_DIR}" lxc query -X POST -d '{"name": "pool2", "driver": "dir", "members": {"node1": {}, "node2": {}}}' /1.0/storage-pools
+    LXD_DIR="${LXD_ONE_DIR}" lxc storage show pool2 | grep status: | grep -q Created
+    LXD_DIR="${LXD_ONE_DIR}" lxc storage show pool2 --target node2 | grep -q source
+    LXD_DIR="${LXD_TWO_DIR}" lxc storage delete pool2
+  fi
+
   if [ "${driver}" = "ceph" ]; then
     # Test migration of ceph-based containers
     LXD_DIR="${LXD_TWO_DIR}" ensure_import_testimage
@@ -658,6 +672,20 @@ test_clustering_network() {
   # FIXME: rename the network is not supported with clustering
   ! LXD_DIR="${LXD_TWO_DIR}" lxc network rename "${net}" "${net}-foo" || false
 
+  # Define and create a network in a single request
+  net2="${bridge}y"
+  ! LXD_DIR="${LXD_ONE_DIR}" lxc query -X POST -d "{\"name\": \"${net2}\", \"members\": {\"node1\": {\"ipv4.address\": \"auto\"}}}" /1.0/networks || false
+  ! LXD_DIR="${LXD_ONE_DIR}" lxc query -X POST -d "{\"name\": \"${net2}\", \"members\": {\"node3\": {}}}" /1.0/networks || false
+  ! LXD_DIR="${LXD_ONE_DIR}" lxc network show "${net2}" || false
+
+  # Failed requests don't leave a pending network behind
+  ! LXD_DIR="${LXD_ONE_DIR}" lxc query -X POST -d "{\"name\": \"${net2}\", \"config\": {\"bridge.external_interfaces\": \"foo\"}, \"members\": {\"node1\": {}}}" /1.0/networks || false
+  ! LXD_DIR="${LXD_ONE_DIR}" lxc network show "${net2}" || false
+  LXD_DIR="${LXD_ONE_DIR}" lxc query -X POST -d "{\"name\": \"${net2}\", \"members\": {\"node1\": {}, \"node2\": {}}}" /1.0/networks
+  LXD_DIR="${LXD_ONE_DIR}" lxc network show "${net2}" | grep status: | grep -q Created
+  LXD_DIR="${LXD_ONE_DIR}" lxc network show "${net2}" --target node2 | grep status: | grep -q Created
+  LXD_DIR="${LXD_TWO_DIR}" lxc network delete "${net2}"
+
   # Delete the networks
   LXD_DIR="${LXD_TWO_DIR}" lxc network delete "${net}"
   LXD_DIR="${LXD_TWO_DIR}" lxc network delete "${bridge}"
@@ -936,6 +964,59 @@ test_clustering_join_api() {
   kill_lxd "${LXD_ONE_DIR}"
 }
 
+test_clustering_join_token() {
+  # shellcheck disable=2039,2034
+  local LXD_DIR LXD_NETNS
+
+  setup_clustering_bridge
+  prefix="lxd$$"
+  bridge="${prefix}"
+
+  setup_clustering_netns 1
+  LXD_ONE_DIR=$(mktemp -d -p "${TEST_DIR}" XXX)
+  chmod +x "${LXD_ONE_DIR}"
+  ns1="${prefix}1"
+  spawn_lxd_and_bootstrap_cluster "${ns1}" "${bridge}" "${LXD_ONE_DIR}"
+
+  # Tokens can't be issued for existing members
+  ! LXD_DIR="${LXD_ONE_DIR}" lxc cluster add node1 || false
+
+  token=$(LXD_DIR="${LXD_ONE_DIR}" lxc cluster add node2 --quiet)
+
+  setup_clustering_netns 2
+  LXD_TWO_DIR=$(mktemp -d -p "${TEST_DIR}" XXX)
+  chmod +x "${LXD_TWO_DIR}"
+  ns2="${prefix}2"
+  LXD_ALT_CERT=1 LXD_NETNS="${ns2}" spawn_lxd "${LXD_TWO_DIR}" false
+
+  # A token issued for another name is rejected
+  ! curl --unix-socket "${LXD_TWO_DIR}/unix.socket" -X PUT "lxd/1.0/cluster" -d "{\"server_name\":\"node3\",\"enabled\":true,\"server_address\":\"10.1.1.102:8443\",\"cluster_token\":\"${token}\"}" | jq -r .error | grep -q "^null$" || false
+
+  op=$(curl --unix-socket "${LXD_TWO_DIR}/unix.socket" -X PUT "lxd/1.0/cluster" -d "{\"enabled\":true,\"member_config\":[{\"entity\": \"storage-pool\",\"name\":\"data\",\"key\":\"source\",\"value\":\"\"}],\"server_address\":\"10.1.1.102:8443\",\"cluster_token\":\"${token}\"}" | jq -r .operation)
+  curl --unix-socket "${LXD_TWO_DIR}/unix.socket" "lxd${op}/wait"
+
+  LXD_DIR="${LXD_ONE_DIR}" lxc cluster show node2 | grep -q "message: fully operational"
+
+  # Used tokens can't be used again in place of the trust password
+  secret=$(echo "${token}" | base64 -d | jq -r .secret)
+  ! lxc remote add cluster 10.1.1.101:8443 --accept-certificate --password="${secret}" || false
+
+  # Tokens must expire after a positive number of hours
+  ! LXD_DIR="${LXD_ONE_DIR}" lxc config set cluster.join_token_expiry 0 || false
+
+  LXD_DIR="${LXD_TWO_DIR}" lxd shutdown
+  LXD_DIR="${LXD_ONE_DIR}" lxd shutdown
+  sleep 0.5
+  rm -f "${LXD_TWO_DIR}/unix.socket"
+  rm -f "${LXD_ONE_DIR}/unix.socket"
+
+  teardown_clustering_netns
+  teardown_clustering_bridge
+
+  kill_lxd "${LXD_TWO_DIR}"
+  kill_lxd "${LXD_ONE_DIR}"
+}
+
 test_clustering_shutdown_nodes() {
   # shellcheck disable=2039
   local LXD_DIR
@@ -1454,3 +1535,138 @@ test_clustering_recover() {
   kill_lxd "${LXD_TWO_DIR}"
   kill_lxd "${LXD_THREE_DIR}"
 }
+
+test_clustering_database_backup() {
+  # shellcheck disable=2039
+  local LXD_DIR
+
+  setup_clustering_bridge
+  prefix="lxd$$"
+  bridge="${prefix}"
+
+  setup_clustering_netns 1
+  LXD_ONE_DIR=$(mktemp -d -p "${TEST_DIR}" XXX)
+  chmod +x "${LXD_ONE_DIR}"
+  ns1="${prefix}1"
+  spawn_lxd_and_bootstrap_cluster "${ns1}" "${bridge}" "${LXD_ONE_DIR}"
+
+  # Add a newline at the end of each line. YAML as weird rules..
+  cert=$(sed ':a;N;$!ba;s/\n/\n\n/g' "${LXD_ONE_DIR}/server.crt")
+
+  # Spawn a second node
+  setup_clustering_netns 2
+  LXD_TWO_DIR=$(mktemp -d -p "${TEST_DIR}" XXX)
+  chmod +x "${LXD_TWO_DIR}"
+  ns2="${prefix}2"
+  spawn_lxd_and_join_cluster "${ns2}" "${bridge}" "${cert}" 2 1 "${LXD_TWO_DIR}"
+
+  # Create a test project, just to insert something in the database.
+  LXD_DIR="${LXD_ONE_DIR}" lxc project create p1
+
+  # Take a backup from the non-leader node.
+  LXD_DIR="${LXD_TWO_DIR}" lxd cluster backup-database "${TEST_DIR}/cluster.db"
+  [ "$(stat -c %a "${TEST_DIR}/cluster.db")" = "600" ]
+
+  # Restoring onto a node of an existing cluster fails.
+  ! LXD_DIR="${LXD_ONE_DIR}" lxd cluster restore-database -q "${TEST_DIR}/cluster.db" || false
+
+  # Shutdown all nodes.
+  LXD_DIR="${LXD_TWO_DIR}" lxd shutdown
+  LXD_DIR="${LXD_ONE_DIR}" lxd shutdown
+  sleep 0.5
+
+  # Bootstrap a brand new cluster with the same name as the first node and
+  # restore the backup onto it.
+  LXD_THREE_DIR=$(mktemp -d -p "${TEST_DIR}" XXX)
+  chmod +x "${LXD_THREE_DIR}"
+  spawn_lxd_and_bootstrap_cluster "${ns1}" "${bridge}" "${LXD_THREE_DIR}"
+  ! LXD_DIR="${LXD_THREE_DIR}" lxc project list | grep -q p1 || false
+
+  LXD_DIR="${LXD_THREE_DIR}" lxd cluster restore-database -q "${TEST_DIR}/cluster.db"
+  LXD_DIR="${LXD_THREE_DIR}" lxd shutdown
+  sleep 0.5
+  respawn_lxd_cluster_member "${ns1}" "${LXD_THREE_DIR}"
+
+  # The project we had created is back, and the other node is known.
+  LXD_DIR="${LXD_THREE_DIR}" lxc project list | grep -q p1
+  LXD_DIR="${LXD_THREE_DIR}" lxc cluster list | grep -q node2
+
+  # Cleanup the dead node.
+  LXD_DIR="${LXD_THREE_DIR}" lxc cluster remove node2 -q --force
+
+  LXD_DIR="${LXD_THREE_DIR}" lxd shutdown
+  sleep 0.5
+  rm -f "${LXD_THREE_DIR}/unix.socket"
+  rm -f "${LXD_TWO_DIR}/unix.socket"
+  rm -f "${LXD_ONE_DIR}/unix.socket"
+  rm -f "${TEST_DIR}/cluster.db"
+
+  teardown_clustering_netns
+  teardown_clustering_bridge
+
+  kill_lxd "${LXD_ONE_DIR}"
+  kill_lxd "${LXD_TWO_DIR}"
+  kill_lxd "${LXD_THREE_DIR}"
+}
+
+test_clustering_update_cert() {
+  # shellcheck disable=2039
+  local LXD_DIR
+
+  setup_clustering_bridge
+  prefix="lxd$$"
+  bridge="${prefix}"
+
+  setup_clustering_netns 1
+  LXD_ONE_DIR=$(mktemp -d -p "${TEST_DIR}" XXX)
+  chmod +x "${LXD_ONE_DIR}"
+  ns1="${prefix}1"
+  spawn_lxd_and_bootstrap_cluster "${ns1}" "${bridge}" "${LXD_ONE_DIR}"
+
+  # Add a newline at the end of each line. YAML as weird rules..
+  cert=$(sed ':a;N;$!ba;s/\n/\n\n/g' "${LXD_ONE_DIR}/server.crt")
+
+  # Spawn a second node
+  setup_clustering_netns 2
+  LXD_TWO_DIR=$(mktemp -d -p "${TEST_DIR}" XXX)
+  chmod +x "${LXD_TWO_DIR}"
+  ns2="${prefix}2"
+  spawn_lxd_and_join_cluster "${ns2}" "${bridge}" "${cert}" 2 1 "${LXD_TWO_DIR}"
+
+  # An invalid keypair is rejected.
+  ! LXD_DIR="${LXD_ONE_DIR}" lxc cluster update-certificate "deps/server-alt.crt" "deps/server.key" || false
+
+  # Replace the cluster certificate on all nodes.
+  LXD_DIR="${LXD_ONE_DIR}" lxc cluster update-certificate "deps/server-alt.crt" "deps/server-alt.key" -q
+  cmp -s "deps/server-alt.crt" "${LXD_ONE_DIR}/cluster.crt"
+  cmp -s "deps/server-alt.crt" "${LXD_TWO_DIR}/cluster.crt"
+  cmp -s "deps/server-alt.key" "${LXD_TWO_DIR}/cluster.key"
+
+  # The nodes can still talk to each other without a restart.
+  LXD_DIR="${LXD_TWO_DIR}" lxc project create p1
+  LXD_DIR="${LXD_ONE_DIR}" lxc project list | grep -q p1
+  LXD_DIR="${LXD_ONE_DIR}" lxc cluster show node2 | grep -q "message: fully operational"
+
+  # A member failing to stage the new certificate leaves all of them unchanged.
+  mkdir "${LXD_TWO_DIR}/cluster.new.crt"
+  touch "${LXD_TWO_DIR}/cluster.new.crt/blocker"
+  ! LXD_DIR="${LXD_ONE_DIR}" lxc cluster update-certificate "${LXD_ONE_DIR}/server.crt" "${LXD_ONE_DIR}/server.key" -q || false
+  rm -rf "${LXD_TWO_DIR}/cluster.new.crt"
+  cmp -s "deps/server-alt.crt" "${LXD_ONE_DIR}/cluster.crt"
+  cmp -s "deps/server-alt.crt" "${LXD_TWO_DIR}/cluster.crt"
+  [ ! -e "${LXD_ONE_DIR}/cluster.new.crt" ]
+  [ ! -e "${LXD_TWO_DIR}/cluster.new.key" ]
+  LXD_DIR="${LXD_ONE_DIR}" lxc cluster show node2 | grep -q "message: fully operational"
+
+  LXD_DIR="${LXD_TWO_DIR}" lxd shutdown
+  LXD_DIR="${LXD_ONE_DIR}" lxd shutdown
+  sleep 0.5
+  rm -f "${LXD_TWO_DIR}/unix.socket"
+  rm -f "${LXD_ONE_DIR}/unix.socket"
+
+  teardown_clustering_netns
+  teardown_clustering_bridge
+
+  kill_lxd "${LXD_ONE_DIR}"
+  kill_lxd "${LXD_TWO_DIR}"
+}
@@ -79,6 +79,420 @@ test_image_import_dir() {
     rm "$exported"
 }
 
+test_image_import_oci() {
+    ensure_import_testimage
+    lxc image export testimage
+    # shellcheck disable=2039,2034,2155
+    local image=$(ls -1 -- *.tar.xz)
+
+    # Build a Docker image archive with the testimage rootfs as first layer
+    # and a second layer hiding one of its files.
+    mkdir -p unpacked oci/layer1 oci/layer2 layer2/root
+    tar -C unpacked -xf "$image"
+    echo hello > layer2/root/hello
+    touch layer2/root/.wh.testfile
+    mkdir -p unpacked/rootfs/root
+    echo testfile > unpacked/rootfs/root/testfile
+    tar -C unpacked/rootfs -cf oci/layer1/layer.tar .
+    tar -C layer2 -cf oci/layer2/layer.tar .
+    echo '{"architecture": "'"$(uname -m)"'", "os": "linux", "config": {"Entrypoint": ["/bin/sh"]}}' > oci/config.json
+    echo '[{"Config": "config.json", "RepoTags": ["testoci:latest"], "Layers": ["layer1/layer.tar", "layer2/layer.tar"]}]' > oci/manifest.json
+    tar -C oci -cf oci.tar .
+    rm -rf "$image" unpacked oci layer2
+
+    lxc image import --oci oci.tar --alias testoci
+    rm oci.tar
+    lxc image show testoci | grep -q 'description: testoci:latest'
+    lxc image show testoci | grep -q 'user.entrypoint'
+
+    lxc launch testoci c-oci
+    [ "$(lxc exec c-oci -- cat /root/hello)" = "hello" ]
+    ! lxc exec c-oci -- test -e /root/testfile || false
+
+    lxc delete -f c-oci
+    lxc image delete testoci
+}
+
+test_image_import_disk() {
+    if ! which qemu-img >/dev/null 2>&1; then
+        echo "==> SKIP: qemu-img is required to import disk images"
+        return
+    fi
+
+    qemu-img create -f qcow2 disk.qcow2 16M
+    qemu-img create -f raw disk.raw 16M
+    qemu-img create -f qcow2 -b disk.qcow2 -F qcow2 backed.qcow2
+    qemu-img create -f qcow2 -o data_file=disk.raw external.qcow2 16M
+    qemu-img create -f vmdk disk.vmdk 16M
+    qemu-img create -f vmdk -o subformat=twoGbMaxExtentSparse split.vmdk 16M
+
+    # A VMDK descriptor whose extent is a host file
+    cat > hostile.vmdk << EOF
+# Disk DescriptorFile
+version=1
+CID=fffffffe
+parentCID=ffffffff
+createType="monolithicFlat"
+
+RW 2048 FLAT "${LXD_DIR}/server.key" 0
+EOF
+
+    lxc image import --type=virtual-machine disk.qcow2 --alias testdisk
+    lxc image info testdisk | grep -q "Type: virtual-machine"
+    lxc image show testdisk | grep -q "description: Imported qcow2 disk image disk.qcow2"
+
+    lxc image import --type=virtual-machine disk.raw architecture="$(uname -m)" --alias testdisk-raw
+    lxc image info testdisk-raw | grep -q "Type: virtual-machine"
+    lxc image show testdisk-raw | grep -q "description: Imported raw disk image disk.raw"
+
+    lxc image import --type=virtual-machine disk.vmdk --alias testdisk-vmdk
+    lxc image show testdisk-vmdk | grep -q "description: Imported vmdk disk image disk.vmdk"
+
+    # Disks referring to other files, other formats and unknown architectures are refused
+    ! lxc image import --type=virtual-machine backed.qcow2 || false
+    ! lxc image import --type=virtual-machine external.qcow2 || false
+    ! lxc image import --type=virtual-machine hostile.vmdk || false
+    ! lxc image import --type=virtual-machine split.vmdk || false
+    ! lxc image import --type=virtual-machine disk.raw architecture=foo || false
+
+    lxc image delete testdisk testdisk-raw testdisk-vmdk
+    rm disk.qcow2 disk.raw backed.qcow2 external.qcow2 disk.vmdk split*.vmdk hostile.vmdk
+}
+
+test_image_chunked_store() {
+    ensure_import_testimage
+    lxc image export testimage chunked
+    lxc image delete testimage
+    lxc config set images.chunked_store true
+
+    # Importing a copy of the image stores it as chunks
+    lxc image import chunked* --alias testchunked
+    # shellcheck disable=2039,2034,2155
+    local fingerprint=$(lxc image info testchunked | grep ^Fingerprint | cut -d' ' -f2)
+    [ ! -e "${LXD_DIR}/images/${fingerprint}" ]
+    [ -n "$(find "${LXD_DIR}/images/chunks" -type f)" ]
+
+    # The image is reassembled as needed
+    curl -s --unix-socket "${LXD_DIR}/unix.socket" "lxd/1.0/images/${fingerprint}/export?chunks=true" | jq -e '.metadata.metadata | length > 0'
+    lxc image export testchunked exported
+    [ "$(sha256sum exported* | cut -d' ' -f1)" = "${fingerprint}" ]
+    [ ! -e "${LXD_DIR}/images/${fingerprint}" ]
+
+    lxc launch testchunked c-chunked
+    lxc delete -f c-chunked
+
+    # Deleting the image removes its chunks
+    lxc image delete testchunked
+    [ -z "$(find "${LXD_DIR}/images/chunks" -type f)" ]
+
+    lxc config unset images.chunked_store
+    rm -f chunked* exported*
+}
+
+test_image_simplestreams() {
+    ensure_import_testimage
+    # shellcheck disable=2039,2034,2155
+    local fingerprint=$(lxc image info testimage | grep ^Fingerprint | cut -d' ' -f2)
+
+    # Nothing is served by default
+    [ "$(curl -s --unix-socket "${LXD_DIR}/unix.socket" lxd/streams/v1/index.json | jq -r '.index | length')" = "0" ]
+
+    # Private images aren't served
+    lxc project set default images.simplestreams true
+    [ "$(curl -s --unix-socket "${LXD_DIR}/unix.socket" lxd/streams/v1/index.json | jq -r '.index | length')" = "0" ]
+
+    lxc query -X PATCH -d '{"public": true}' "/1.0/images/${fingerprint}"
+    curl -s --unix-socket "${LXD_DIR}/unix.socket" lxd/streams/v1/index.json | jq -r '.index["images:default"].products[]' | grep -q "${fingerprint}"
+
+    # The served files match the listed hashes
+    # shellcheck disable=2039,2034,2155
+    local products=$(curl -s --unix-socket "${LXD_DIR}/unix.socket" lxd/streams/v1/images/default.json)
+    echo "${products}" | jq -r ".products[\"${fingerprint}\"].aliases" | grep -q testimage
+    for item in $(echo "${products}" | jq -r ".products[\"${fingerprint}\"].versions[].items | keys[]"); do
+        path=$(echo "${products}" | jq -r ".products[\"${fingerprint}\"].versions[].items[\"${item}\"].path")
+        sha256=$(echo "${products}" | jq -r ".products[\"${fingerprint}\"].versions[].items[\"${item}\"].sha256")
+        [ "$(curl -s --unix-socket "${LXD_DIR}/unix.socket" "lxd/${path}" | sha256sum | cut -d' ' -f1)" = "${sha256}" ]
+    done
+
+    lxc query -X PATCH -d '{"public": false}' "/1.0/images/${fingerprint}"
+    lxc project unset default images.simplestreams
+}
+
+test_image_signatures() {
+    # shellcheck disable=2039
+    local LXD2_DIR LXD2_ADDR
+    LXD2_DIR=$(mktemp -d -p "${TEST_DIR}" XXX)
+    chmod +x "${LXD2_DIR}"
+    spawn_lxd "${LXD2_DIR}" true
+    LXD2_ADDR=$(cat "${LXD2_DIR}/lxd.addr")
+
+    ensure_import_testimage
+    ensure_has_localhost_remote "${LXD_ADDR}"
+    # shellcheck disable=2039,2034,2155
+    local fingerprint=$(lxc image info testimage | grep ^Fingerprint | cut -d' ' -f2)
+
+    lxc_remote remote add lxd2 "${LXD2_ADDR}" --accept-certificate --password foo
+    lxc_remote remote add signed "${LXD_ADDR}" --accept-certificate --password foo --require-signature
+
+    # Invalid keys are rejected
+    ! lxc config set images.trusted_keys invalid || false
+    lxc config set images.trusted_keys RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3
+    lxc config unset images.trusted_keys
+
+    # Imported images aren't signed
+    [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" "lxd/1.0/images/${fingerprint}/export?signature=true")" = "404" ]
+
+    # Unsigned images are rejected when a signature is required
+    lxc_remote image copy signed:testimage lxd2: 2>&1 | grep -q "isn't signed but a signature is required"
+    ! lxc_remote image info "lxd2:${fingerprint}" || false
+
+    # And accepted otherwise
+    lxc_remote image copy localhost:testimage lxd2:
+    lxc_remote image delete "lxd2:${fingerprint}"
+
+    if command -v minisign >/dev/null 2>&1; then
+        # Sign the image files in place
+        minisign -G -W -p "${TEST_DIR}/trusted.pub" -s "${TEST_DIR}/trusted.key"
+        minisign -G -W -p "${TEST_DIR}/other.pub" -s "${TEST_DIR}/other.key"
+        for file in "${LXD_DIR}/images/${fingerprint}" "${LXD_DIR}/images/${fingerprint}.rootfs"; do
+            [ -e "${file}" ] || continue
+            minisign -S -s "${TEST_DIR}/trusted.key" -m "${file}" -x "${file}.sig"
+        done
+        [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" "lxd/1.0/images/${fingerprint}/export?signature=true")" = "200" ]
+
+        # Signatures need trusted keys to be verified
+        lxc_remote image copy signed:testimage lxd2: 2>&1 | grep -q "images.trusted_keys isn't set"
+
+        # Only signatures from trusted keys are accepted
+        LXD_DIR="${LXD2_DIR}" lxc config set images.trusted_keys "$(tail -n1 "${TEST_DIR}/other.pub")"
+        ! lxc_remote image copy localhost:testimage lxd2: || false
+
+        LXD_DIR="${LXD2_DIR}" lxc config set images.trusted_keys "$(tail -n1 "${TEST_DIR}/other.pub"),$(tail -n1 "${TEST_DIR}/trusted.pub")"
+        lxc_remote image copy signed:testimage lxd2:
+        [ -e "${LXD2_DIR}/images/${fingerprint}.sig" ]
+
+        lxc_remote image delete "lxd2:${fingerprint}"
+        [ ! -e "${LXD2_DIR}/images/${fingerprint}.sig" ]
+        rm -f "${LXD_DIR}/images/${fingerprint}"*.sig "${TEST_DIR}"/trusted.* "${TEST_DIR}"/other.*
+    fi
+
+    lxc_remote remote remove lxd2
+    lxc_remote remote remove signed
+
+    kill_lxd "${LXD2_DIR}"
+}
+
+test_image_deltas() {
+    if ! command -v xdelta3 >/dev/null 2>&1; then
+        echo "==> SKIP: image deltas (missing xdelta3)"
+        return
+    fi
+
+    lxc config set images.delta_generations 2
+
+    deps/import-busybox --split --alias deltaimage
+    # shellcheck disable=2039,2034,2155
+    local fp1=$(lxc image info deltaimage | grep ^Fingerprint | cut -d' ' -f2)
+
+    # Build a new generation of the image
+    mkdir -p "${LXD_DIR}/delta"
+    lxc image export deltaimage "${LXD_DIR}/delta"
+    xz -d "${LXD_DIR}/delta/${fp1}.tar.xz"
+    echo "new generation" > "${LXD_DIR}/delta/generation"
+    tar --append -f "${LXD_DIR}/delta/${fp1}.tar" -C "${LXD_DIR}/delta" --transform 's,^,rootfs/,' generation
+    xz "${LXD_DIR}/delta/${fp1}.tar"
+    lxc image import "${LXD_DIR}/delta/meta-${fp1}.tar.xz" "${LXD_DIR}/delta/${fp1}.tar.xz"
+    # shellcheck disable=2039,2034,2155
+    local fp2=$(cat "${LXD_DIR}/delta/meta-${fp1}.tar.xz" "${LXD_DIR}/delta/${fp1}.tar.xz" | sha256sum | cut -d' ' -f1)
+
+    # Moving the alias generates the delta from the previous generation
+    lxc query -X PATCH -d "{\"target\": \"${fp2}\"}" /1.0/images/aliases/deltaimage
+    for _ in $(seq 10); do
+        lxc query "/1.0/images/${fp2}/export?deltas=true" | jq -r '.[]' | grep -q "${fp1}" && break
+        sleep 1
+    done
+    lxc query "/1.0/images/${fp2}/export?deltas=true" | jq -r '.[]' | grep -q "${fp1}"
+
+    # The delta applies to the previous rootfs
+    [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" "lxd/1.0/images/${fp2}/export?delta=${fp1}")" = "200" ]
+    [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" "lxd/1.0/images/${fp1}/export?delta=${fp2}")" = "404" ]
+
+    # Deltas are removed along with the image
+    lxc image delete "${fp2}"
+    [ -z "$(find "${LXD_DIR}/images" -name "${fp2}.rootfs.delta-*")" ]
+
+    lxc image delete "${fp1}"
+    rm -rf "${LXD_DIR}/delta"
+    lxc config unset images.delta_generations
+}
+
+test_image_mirror() {
+  # shellcheck disable=2039
+  local LXD2_DIR LXD2_ADDR
+  LXD2_DIR=$(mktemp -d -p "${TEST_DIR}" XXX)
+  chmod +x "${LXD2_DIR}"
+  spawn_lxd "${LXD2_DIR}" true
+  LXD2_ADDR=$(cat "${LXD2_DIR}/lxd.addr")
+
+  ensure_import_testimage
+  fp=$(lxc image info testimage | awk '/^Fingerprint/ { print $2 }')
+
+  # shellcheck disable=2153
+  lxc_remote remote add l1 "${LXD_ADDR}" --accept-certificate --password foo
+  lxc_remote remote add l2 "${LXD2_ADDR}" --accept-certificate --password foo
+
+  # An image which isn't on the source server
+  LXD_DIR="${LXD2_DIR}" deps/import-busybox --alias testimage-old
+  oldfp=$(lxc_remote image info l2:testimage-old | awk '/^Fingerprint/ { print $2 }')
+
+  # Filters which don't match anything
+  lxc_remote image mirror l1: l2: "alias=testimage*" type=virtual-machine --dry-run | grep -q "^+" && false
+  lxc_remote image mirror l1: l2: "alias=testimage*" architecture=nonexistent --dry-run | grep -q "^+" && false
+
+  # Pruning requires filters
+  ! lxc_remote image mirror l1: l2: --prune --dry-run || false
+
+  # Dry run doesn't change anything
+  lxc_remote image mirror l1: l2: "alias=testimage*" --prune --dry-run | grep -q "^+ $(echo "${fp}" | cut -c1-12) (testimage)"
+  lxc_remote image mirror l1: l2: "alias=testimage*" --prune --dry-run | grep -q "^- $(echo "${oldfp}" | cut -c1-12) (testimage-old)"
+  ! lxc_remote image info "l2:${fp}" || false
+  lxc_remote image info "l2:${oldfp}"
+
+  # Copy the missing images with their aliases and prune the others
+  lxc_remote image mirror l1: l2: "testimage*" type=container --prune
+  lxc_remote image info l2:testimage | grep -q "^Fingerprint: ${fp}"
+  ! lxc_remote image info "l2:${oldfp}" || false
+
+  # Nothing left to do
+  [ -z "$(lxc_remote image mirror l1: l2: "testimage*" --prune --dry-run)" ]
+
+  # Aliases are moved to the mirrored images
+  LXD_DIR="${LXD2_DIR}" deps/import-busybox --alias other
+  lxc_remote image alias create l1:other "${fp}"
+  lxc_remote image mirror l1: l2: "other" --dry-run | grep -q "^~ other: "
+  lxc_remote image mirror l1: l2: "other"
+  lxc_remote image info l2:other | grep -q "^Fingerprint: ${fp}"
+
+  # Images whose aliases moved to a newer image on the source are pruned
+  lxc_remote image mirror l1: l2: "testimage*" --prune
+  deps/import-busybox --alias testimage-new
+  newfp=$(lxc_remote image info l1:testimage-new | awk '/^Fingerprint/ { print $2 }')
+  lxc_remote image alias delete l1:testimage-new
+  lxc_remote image alias delete l1:testimage
+  lxc_remote image alias create l1:testimage "${newfp}"
+  lxc_remote image mirror l1: l2: "testimage*" --prune
+  lxc_remote image info l2:testimage | grep -q "^Fingerprint: ${newfp}"
+  ! lxc_remote image info "l2:${fp}" || false
+
+  lxc_remote image alias delete l1:testimage
+  lxc_remote image alias create l1:testimage "${fp}"
+  lxc_remote image delete "l1:${newfp}"
+  lxc_remote image alias delete l1:other
+  lxc_remote remote remove l1
+  lxc_remote remote remove l2
+  kill_lxd "${LXD2_DIR}"
+}
+
+test_image_build() {
+  ensure_import_testimage
+
+  mkdir -p "${LXD_DIR}/build"
+  echo "pushed file" > "${LXD_DIR}/build/motd"
+  echo "{{ container.name }}" > "${LXD_DIR}/build/hostname.tpl"
+  cat > "${LXD_DIR}/build/recipe.yaml" << EOF
+base: testimage
+environment:
+  GREETING: hello
+files:
+- path: /etc/build/motd
+  source: motd
+  mode: "0600"
+- path: /etc/build/inline
+  content: inline file
+commands:
+- echo "\${GREETING} from the build" > /etc/build/greeting
+- cat /etc/build/greeting
+templates:
+  /etc/build/hostname:
+    when: [create, copy]
+    template: hostname.tpl
+aliases:
+- builtimage
+properties:
+  description: Built image
+EOF
+
+  lxc image build "${LXD_DIR}/build/recipe.yaml" --log "${LXD_DIR}/build/build.log"
+  grep -q "hello from the build" "${LXD_DIR}/build/build.log"
+  lxc image info builtimage | grep -q "description: Built image"
+
+  # The build container is gone
+  ! lxc list --format csv | grep -q . || false
+
+  # Builds are reproducible
+  fp=$(lxc image info builtimage | awk '/^Fingerprint/ { print $2 }')
+  lxc image build "${LXD_DIR}/build/recipe.yaml"
+  [ "$(lxc image info builtimage | awk '/^Fingerprint/ { print $2 }')" = "${fp}" ]
+  SOURCE_DATE_EPOCH=1 lxc image build "${LXD_DIR}/build/recipe.yaml"
+  [ "$(lxc image info builtimage | awk '/^Fingerprint/ { print $2 }')" != "${fp}" ]
+  lxc image delete "${fp}"
+
+  # The image has the content and templates of the recipe
+  lxc launch builtimage c1
+  [ "$(lxc exec c1 -- cat /etc/build/motd)" = "pushed file" ]
+  [ "$(lxc exec c1 -- stat -c %a /etc/build/motd)" = "600" ]
+  [ "$(lxc exec c1 -- cat /etc/build/inline)" = "inline file" ]
+  [ "$(lxc exec c1 -- cat /etc/build/greeting)" = "hello from the build" ]
+  [ "$(lxc exec c1 -- cat /etc/build/hostname)" = "c1" ]
+  lxc delete -f c1
+
+  # Failing commands abort the build and clean up
+  printf "base: testimage\ncommands:\n- \"false\"\n" > "${LXD_DIR}/build/failing.yaml"
+  ! lxc image build "${LXD_DIR}/build/failing.yaml" || false
+  ! lxc list --format csv | grep -q . || false
+
+  lxc image delete builtimage
+  rm -rf "${LXD_DIR}/build"
+}
+
+test_image_prune() {
+  ensure_import_testimage
+  fp=$(lxc image info testimage | awk '/^Fingerprint/ { print $2 }')
+
+  # An image without aliases nothing uses
+  deps/import-busybox
+  unused=$(lxc image list --format csv -c f | grep -v "$(echo "${fp}" | cut -c1-12)" | head -n1)
+  [ -n "${unused}" ]
+
+  # Instances show up as users of their image
+  lxc init testimage c1
+  lxc query "/1.0/images/${fp}" | jq -r '.used_by[]' | grep -q "^/1.0/instances/c1$"
+  lxc image info testimage | grep -q "/1.0/instances/c1"
+
+  # Dry run lists the unused image only
+  lxc image prune --include-aliased --dry-run | grep -q "^- ${unused}"
+  ! lxc image prune --include-aliased --dry-run | grep -q "^- $(echo "${fp}" | cut -c1-12)" || false
+  lxc image info "${unused}"
+
+  # Recently used images are kept
+  [ -z "$(lxc image prune --unused-for 1d --dry-run)" ]
+
+  lxc image prune
+  ! lxc image info "${unused}" || false
+  lxc image info testimage
+
+  # The server refuses to delete images instances were created from
+  ! lxc image delete testimage || false
+  lxc image info testimage
+
+  # Images without users are pruned, unless aliased
+  lxc delete c1
+  lxc image prune
+  lxc image info testimage
+  lxc image prune --include-aliased
+  ! lxc image info testimage || false
+}
+
 test_image_import_existing_alias() {
     ensure_import_testimage
     lxc init testimage c
@@ -62,3 +62,48 @@ test_image_auto_update() {
   lxc image delete "${fp2}"
   kill_lxd "$LXD2_DIR"
 }
+
+test_image_auto_update_policy() {
+  # shellcheck disable=2039
+  local LXD2_DIR LXD2_ADDR
+  LXD2_DIR=$(mktemp -d -p "${TEST_DIR}" XXX)
+  chmod +x "${LXD2_DIR}"
+  spawn_lxd "${LXD2_DIR}" true
+  LXD2_ADDR=$(cat "${LXD2_DIR}/lxd.addr")
+
+  (LXD_DIR=${LXD2_DIR} deps/import-busybox --alias policyimage --public)
+  fp1=$(LXD_DIR=${LXD2_DIR} lxc image info policyimage | awk -F: '/^Fingerprint/ { print $2 }' | awk '{ print $1 }')
+
+  lxc remote add l2 "${LXD2_ADDR}" --accept-certificate --password foo
+  lxc image copy l2:policyimage local: --auto-update --copy-aliases
+
+  # Invalid policies are rejected
+  ! lxc query -X PATCH -d '{"auto_update_policy": {"window": "* * *"}}' "/1.0/images/${fp1}" || false
+  ! lxc query -X PATCH -d '{"auto_update_policy": {"pin": "not-a-fingerprint"}}' "/1.0/images/${fp1}" || false
+  ! lxc query -X PATCH -d '{"auto_update_policy": {"max_age": "forever"}}' "/1.0/images/${fp1}" || false
+
+  # Pin the image to its current fingerprint
+  lxc query -X PATCH -d "{\"auto_update_policy\": {\"window\": \"* 0-5 * * *\", \"pin\": \"${fp1}\", \"max_age\": \"4w\"}}" "/1.0/images/${fp1}"
+  lxc image show "${fp1}" | grep -q "pin: ${fp1}"
+  lxc image info "${fp1}" | grep -q "Window: \\* 0-5 \\* \\* \\*"
+
+  # Publish a new version of the image upstream
+  (LXD_DIR=${LXD2_DIR} lxc image delete policyimage)
+  (LXD_DIR=${LXD2_DIR} deps/import-busybox --alias policyimage --public --template create)
+  fp2=$(LXD_DIR=${LXD2_DIR} lxc image info policyimage | awk -F: '/^Fingerprint/ { print $2 }' | awk '{ print $1 }')
+  [ "${fp1}" != "${fp2}" ]
+
+  # The pinned image doesn't get updated
+  lxc image refresh policyimage
+  lxc image info policyimage | grep -q "^Fingerprint: ${fp1}"
+
+  # Moving the pin to the new version lets it through, along with the policy
+  lxc query -X PATCH -d "{\"auto_update_policy\": {\"pin\": \"${fp2}\"}}" "/1.0/images/${fp1}"
+  lxc image refresh policyimage
+  lxc image info policyimage | grep -q "^Fingerprint: ${fp2}"
+  lxc image show "${fp2}" | grep -q "pin: ${fp2}"
+
+  lxc remote remove l2
+  lxc image delete "${fp2}"
+  kill_lxd "$LXD2_DIR"
+}
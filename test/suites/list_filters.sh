@@ -0,0 +1,58 @@
+test_list_filters() {
+  ensure_import_testimage
+
+  lxc init testimage c1
+  lxc init testimage c2
+  lxc init testimage c3
+  lxc config set c1 user.team web
+  lxc config set c2 user.team web
+  lxc config set c3 user.team db
+
+  unix_curl() {
+    curl -s --unix-socket "${LXD_DIR}/unix.socket" "$@"
+  }
+
+  # Filter on the name only.
+  [ "$(lxc query "/1.0/instances?filter=name+eq+c1" | jq -r '. | join(",")')" = "/1.0/instances/c1" ]
+  [ "$(lxc query "/1.0/instances?filter=name+ne+c1" | jq -r '. | length')" = "2" ]
+
+  # Filter on the configuration.
+  [ "$(lxc query "/1.0/instances?recursion=1&filter=config.user.team+eq+web" | jq -r '[.[].name] | join(",")')" = "c1,c2" ]
+  [ "$(lxc query "/1.0/instances?recursion=1&filter=config.user.team+eq+web+and+not+name+eq+c1" | jq -r '[.[].name] | join(",")')" = "c2" ]
+  [ "$(lxc query "/1.0/instances?recursion=1&filter=(name+eq+c1+or+name+eq+c3)+and+status+eq+Stopped" | jq -r '[.[].name] | join(",")')" = "c1,c3" ]
+  ! lxc query "/1.0/instances?filter=name+is+c1" || false
+
+  # Filter on the project and state, the state only being returned with recursion=2.
+  [ "$(lxc query "/1.0/instances?recursion=1&filter=project+eq+default" | jq -r '. | length')" = "3" ]
+  [ "$(lxc query "/1.0/instances?recursion=1&filter=project+eq+foo" | jq -r '. | length')" = "0" ]
+  [ "$(lxc query "/1.0/instances?recursion=1&filter=state.status+eq+Stopped+and+name+eq+c1" | jq -c '[.[].name, .[].state]')" = '["c1",null]' ]
+  [ "$(lxc query "/1.0/instances?recursion=2&filter=config.user.team+eq+db" | jq -r '.[].state.status')" = "Stopped" ]
+  [ "$(lxc query "/1.0/instances?recursion=2&filter=config.user.team+eq+db&fields=name" | jq -c .)" = '[{"name":"c3"}]' ]
+
+  # Field selection.
+  [ "$(lxc query "/1.0/instances?recursion=1&fields=name,config.user.team&filter=name+eq+c3" | jq -c .)" = '[{"config":{"user.team":"db"},"name":"c3"}]' ]
+
+  # Pagination.
+  unix_curl -D headers -o page "lxd/1.0/instances?limit=2"
+  [ "$(jq -r '.metadata | join(",")' page)" = "/1.0/instances/c1,/1.0/instances/c2" ]
+  token="$(grep -i "^X-LXD-Next-Page-Token:" headers | cut -d' ' -f2 | tr -d '\r')"
+  [ -n "${token}" ]
+  unix_curl -D headers -o page "lxd/1.0/instances?limit=2&page_token=${token}"
+  [ "$(jq -r '.metadata | join(",")' page)" = "/1.0/instances/c3" ]
+  ! grep -qi "^X-LXD-Next-Page-Token:" headers || false
+  rm -f headers page
+
+  # Other collections.
+  fingerprint="$(lxc config get c1 volatile.base_image)"
+  [ "$(lxc query "/1.0/images?recursion=1&filter=fingerprint+eq+${fingerprint}&fields=fingerprint" | jq -c .)" = "[{\"fingerprint\":\"${fingerprint}\"}]" ]
+  [ "$(lxc query "/1.0/images?filter=public+eq+true" | jq -r '. | length')" = "0" ]
+  pool="$(lxc profile device get default root pool)"
+  [ "$(lxc query "/1.0/storage-pools/${pool}/volumes?filter=name+eq+c2" | jq -r '. | join(",")')" = "/1.0/storage-pools/${pool}/volumes/container/c2" ]
+  [ "$(lxc query "/1.0/operations?filter=status+eq+Running" | jq -r '. | length')" = "0" ]
+
+  # Client support.
+  [ "$(lxc list --filter "config.user.team eq web" --format csv -c n | tr '\n' ',')" = "c1,c2," ]
+  [ "$(lxc list --filter "config.user.team eq web" --format csv -c n c2)" = "c2" ]
+
+  lxc delete c1 c2 c3
+}
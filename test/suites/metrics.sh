@@ -0,0 +1,39 @@
+test_metrics() {
+  ensure_import_testimage
+  ensure_has_localhost_remote "${LXD_ADDR}"
+
+  lxc launch testimage c1
+  lxc project create foo -c features.images=false -c features.profiles=false
+  lxc launch testimage c2 --project foo
+
+  # All projects are reported by default.
+  my_curl "https://${LXD_ADDR}/1.0/metrics" | grep -q '^lxd_procs{name="c1",project="default",type="container"}'
+  my_curl "https://${LXD_ADDR}/1.0/metrics" | grep -q '^lxd_cpu_seconds_total{name="c2",project="foo",type="container"}'
+  my_curl "https://${LXD_ADDR}/1.0/metrics" | grep -q '^lxd_go_goroutines '
+  my_curl "https://${LXD_ADDR}/1.0/metrics" | grep -q '^lxd_api_request_duration_seconds_count{endpoint="/1.0/metrics",method="GET"}'
+  my_curl "https://${LXD_ADDR}/1.0/metrics" | tail -n1 | grep -qx '# EOF'
+
+  # Filtering by project.
+  my_curl "https://${LXD_ADDR}/1.0/metrics?project=foo" | grep -q 'name="c2"'
+  ! my_curl "https://${LXD_ADDR}/1.0/metrics?project=foo" | grep -q 'name="c1"' || false
+
+  # Metrics certificates are only trusted for the metrics.
+  gen_cert metrics
+  lxc config trust add "${LXD_CONF}/metrics.crt" --type=metrics
+  fingerprint="$(openssl x509 -in "${LXD_CONF}/metrics.crt" -noout -fingerprint -sha256 | cut -d= -f2 | tr -d : | tr '[:upper:]' '[:lower:]')"
+  lxc query "/1.0/certificates/${fingerprint}" | jq -r .type | grep -qx metrics
+
+  metrics_curl() {
+    curl -k -s --cert "${LXD_CONF}/metrics.crt" --key "${LXD_CONF}/metrics.key" "$@"
+  }
+
+  metrics_curl "https://${LXD_ADDR}/1.0/metrics" | grep -q 'name="c1"'
+  [ "$(metrics_curl "https://${LXD_ADDR}/1.0/instances" | jq -r .error_code)" = "403" ]
+  [ "$(metrics_curl "https://${LXD_ADDR}/1.0" | jq -r .metadata.auth)" = "untrusted" ]
+
+  lxc config trust remove "${fingerprint}"
+
+  lxc delete -f c2 --project foo
+  lxc project delete foo
+  lxc delete -f c1
+}
@@ -0,0 +1,42 @@
+test_operations_history() {
+  LXD_HISTORY_DIR=$(mktemp -d -p "${TEST_DIR}" XXX)
+  chmod +x "${LXD_HISTORY_DIR}"
+  spawn_lxd "${LXD_HISTORY_DIR}" true
+  (
+    set -e
+    # shellcheck disable=SC2030
+    LXD_DIR=${LXD_HISTORY_DIR}
+    ensure_import_testimage
+
+    # Completed operations are recorded along with who requested them.
+    lxc init testimage c1
+    op="$(lxc query "/1.0/operations?all-history=true&recursion=1" | jq -r '.success[] | select(.description == "Creating container") | .id')"
+    [ -n "${op}" ]
+    [ "$(lxc query "/1.0/operations/${op}" | jq -r .requestor.protocol)" = "unix" ]
+
+    # Records survive restarts, while the operations themselves don't.
+    shutdown_lxd "${LXD_DIR}"
+    respawn_lxd "${LXD_DIR}" true
+    [ "$(lxc query "/1.0/operations/${op}" | jq -r .status)" = "Success" ]
+    [ "$(lxc query "/1.0/operations/${op}/wait?timeout=1" | jq -r '.resources.instances[0]')" = "/1.0/instances/c1" ]
+    lxc operation list --all-history --format csv | grep -q "^${op},TASK,Creating container,SUCCESS,"
+    ! lxc operation list --format csv | grep -q "^${op}," || false
+
+    # Records are dropped once disabled, and no new ones are kept.
+    lxc config set operations.history_expiry 0
+    for _ in $(seq 10); do
+      lxc query "/1.0/operations/${op}" > /dev/null 2>&1 || break
+      sleep 1
+    done
+    ! lxc query "/1.0/operations/${op}" || false
+    ! lxc operation list --all-history --format csv | grep -q "^${op}," || false
+    lxc start c1
+    sleep 6
+    [ "$(lxc query "/1.0/operations?all-history=true" | jq -r '.success | length')" = "0" ]
+    lxc config unset operations.history_expiry
+
+    lxc delete -f c1
+  )
+  # shellcheck disable=SC2031
+  kill_lxd "${LXD_HISTORY_DIR}"
+}
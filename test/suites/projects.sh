@@ -468,3 +468,46 @@ test_projects_network() {
 
   lxc network delete "${network}"
 }
+
+# Certificates restricted to projects.
+test_projects_restricted_certificates() {
+  gen_cert restricted
+
+  lxc project create foo
+  lxc project create bar
+  lxc config trust add "${LXD_CONF}/restricted.crt" --restricted --projects=foo
+
+  fingerprint="$(openssl x509 -in "${LXD_CONF}/restricted.crt" -noout -fingerprint -sha256 | cut -d= -f2 | tr -d : | tr '[:upper:]' '[:lower:]')"
+  lxc query "/1.0/certificates/${fingerprint}" | jq -r .restricted | grep -q true
+  lxc query "/1.0/certificates/${fingerprint}" | jq -r '.projects[]' | grep -qx foo
+
+  restricted_curl() {
+    curl -k -s --cert "${LXD_CONF}/restricted.crt" --key "${LXD_CONF}/restricted.key" "$@"
+  }
+
+  # The listed projects are accessible.
+  [ "$(restricted_curl "https://${LXD_ADDR}/1.0/instances?project=foo" | jq -r .status_code)" = "200" ]
+  [ "$(restricted_curl "https://${LXD_ADDR}/1.0/profiles?project=foo" | jq -r .status_code)" = "200" ]
+
+  # Other projects aren't.
+  [ "$(restricted_curl "https://${LXD_ADDR}/1.0/instances?project=bar" | jq -r .error_code)" = "403" ]
+  [ "$(restricted_curl "https://${LXD_ADDR}/1.0/instances" | jq -r .error_code)" = "403" ]
+
+  # Neither are the projects, server configuration, cluster and certificates.
+  [ "$(restricted_curl -X POST -d '{"name": "baz"}' "https://${LXD_ADDR}/1.0/projects" | jq -r .error_code)" = "403" ]
+  [ "$(restricted_curl -X PATCH -d '{"config": {"core.trust_password": "bar"}}' "https://${LXD_ADDR}/1.0" | jq -r .error_code)" = "403" ]
+  [ "$(restricted_curl "https://${LXD_ADDR}/1.0" | jq -r '.metadata.config | length')" = "0" ]
+  [ "$(restricted_curl -X PUT -d '{"enabled": false}' "https://${LXD_ADDR}/1.0/cluster" | jq -r .error_code)" = "403" ]
+  [ "$(restricted_curl -X DELETE "https://${LXD_ADDR}/1.0/certificates/${fingerprint}" | jq -r .error_code)" = "403" ]
+
+  # Restricted certificates only see themselves.
+  [ "$(restricted_curl "https://${LXD_ADDR}/1.0/certificates" | jq -r '.metadata | length')" = "1" ]
+
+  # Lifting the restriction gives full access.
+  lxc query -X PATCH -d '{"restricted": false, "projects": []}' "/1.0/certificates/${fingerprint}"
+  [ "$(restricted_curl "https://${LXD_ADDR}/1.0/instances?project=bar" | jq -r .status_code)" = "200" ]
+
+  lxc config trust remove "${fingerprint}"
+  lxc project delete foo
+  lxc project delete bar
+}
@@ -0,0 +1,50 @@
+test_rate_limit() {
+  ensure_has_localhost_remote "${LXD_ADDR}"
+  ensure_import_testimage
+
+  token="$(lxc auth token add --quiet --scope=manage)"
+
+  token_curl() {
+    curl -k -s -H "Authorization: Bearer ${token}" "$@"
+  }
+
+  # Clients over their rate limit are told to retry later.
+  lxc config set core.rate_limit 1
+  lxc config set core.rate_limit_burst 2
+  token_curl "https://${LXD_ADDR}/1.0" | jq -r .status_code | grep -qx 200
+  token_curl "https://${LXD_ADDR}/1.0" | jq -r .status_code | grep -qx 200
+  [ "$(token_curl "https://${LXD_ADDR}/1.0" | jq -r .error_code)" = "429" ]
+  token_curl -D - -o /dev/null "https://${LXD_ADDR}/1.0" | grep -qi "^Retry-After: 1"
+
+  # The unix socket isn't limited.
+  for _ in $(seq 5); do
+    lxc query /1.0 > /dev/null
+  done
+
+  # Clients are let through again once their bucket refills.
+  sleep 2
+  token_curl "https://${LXD_ADDR}/1.0" | jq -r .status_code | grep -qx 200
+
+  lxc config unset core.rate_limit
+  lxc config unset core.rate_limit_burst
+  for _ in $(seq 5); do
+    token_curl "https://${LXD_ADDR}/1.0" | jq -r .status_code | grep -qx 200
+  done
+
+  # Projects can limit their number of concurrent operations.
+  ! lxc project set default limits.operations foo || false
+  lxc project set default limits.operations 0
+  [ "$(token_curl -X POST -d '{"name": "c1", "source": {"type": "none"}}' "https://${LXD_ADDR}/1.0/instances" | jq -r .error_code)" = "429" ]
+  token_curl -D - -o /dev/null -X POST -d '{"name": "c1", "source": {"type": "none"}}' "https://${LXD_ADDR}/1.0/instances" | grep -qi "^Retry-After: 5"
+  ! lxc init testimage c1 || false
+
+  # Requests which don't create operations aren't limited.
+  token_curl "https://${LXD_ADDR}/1.0/instances" | jq -r .status_code | grep -qx 200
+  token_curl -X POST -d '{"name": "limited"}' "https://${LXD_ADDR}/1.0/profiles" | jq -r .status_code | grep -qx 200
+  lxc profile delete limited
+  lxc project unset default limits.operations
+
+  for id in $(lxc auth token list --format csv | cut -d, -f1); do
+    lxc auth token revoke "${id}"
+  done
+}
@@ -23,8 +23,8 @@ test_template() {
   lxc file pull template1/template - | grep "^name: template$"
 
   # Cleanup
-  lxc image delete template-test
   lxc delete template template1 --force
+  lxc image delete template-test
 
 
   # Import a template which only triggers on copy
@@ -48,8 +48,8 @@ test_template() {
   lxc file pull template1/template - | grep "^name: template1$"
 
   # Cleanup
-  lxc image delete template-test
   lxc delete template template1 --force
+  lxc image delete template-test
 
 
   # Import a template which only triggers on start
@@ -66,8 +66,8 @@ test_template() {
   lxc file pull template/template - | grep "^user.foo: bar$"
 
   # Cleanup
-  lxc image delete template-test
   lxc delete template --force
+  lxc image delete template-test
 
 
   # Import a template which triggers on both create and copy
@@ -93,6 +93,6 @@ test_template() {
   lxc file pull template1/template - | grep "^user.foo: _unset_$"
 
   # Cleanup
-  lxc image delete template-test
   lxc delete template template1 --force
+  lxc image delete template-test
 }
@@ -0,0 +1,49 @@
+test_webhooks() {
+  ensure_import_testimage
+
+  dead_letter="${LXD_DIR}/logs/webhooks-dead-letter.log"
+  rm -f "${dead_letter}"
+
+  # Webhooks are validated.
+  lxc webhook create hook1 http://127.0.0.1:1/ --description "Unreachable" --event lifecycle/container-created --project default
+  lxc webhook list --format csv | grep -q "^hook1,Unreachable,http://127.0.0.1:1/,lifecycle/container-created,default$"
+  ! lxc webhook create hook1 http://127.0.0.1:1/ || false
+  ! lxc webhook create hook2 ftp://127.0.0.1/ || false
+  ! lxc webhook create hook2 http://127.0.0.1:1/ --event logging || false
+  ! lxc webhook create hook2 http://127.0.0.1:1/ --project foo || false
+
+  # Undeliverable events end up in the dead letter log.
+  lxc webhook show hook1 | sed 's/^config: {}$/config:\n  retry.count: "0"/' | lxc webhook edit hook1
+  [ "$(lxc webhook show hook1 | grep retry.count)" = '  retry.count: "0"' ]
+  lxc init testimage c1
+  for _ in $(seq 20); do
+    [ -s "${dead_letter}" ] && break
+    sleep 0.5
+  done
+  [ "$(jq -r .webhook "${dead_letter}")" = "hook1" ]
+  [ "$(jq -r .attempts "${dead_letter}")" = "1" ]
+  [ "$(jq -r .event.metadata.action "${dead_letter}")" = "container-created" ]
+  lxc webhook delete hook1
+
+  # Deliveries are signed, the secret not being returned.
+  port="$(local_tcp_port)"
+  socat -u "TCP-LISTEN:${port},reuseaddr" "OPEN:${TEST_DIR}/webhook.request,creat" &
+  socat_pid=$!
+  sleep 0.5
+  lxc webhook create hook2 "http://127.0.0.1:${port}/lxd" --secret s3cr3t --event lifecycle/container-started
+  ! lxc webhook show hook2 | grep -q s3cr3t || false
+  lxc start c1
+  for _ in $(seq 20); do
+    grep -q "container-started" "${TEST_DIR}/webhook.request" && break
+    sleep 0.5
+  done
+  grep -q "^POST /lxd " "${TEST_DIR}/webhook.request"
+  grep -qi "^X-Lxd-Webhook: hook2" "${TEST_DIR}/webhook.request"
+  grep -qi "^X-Lxd-Signature: sha256=" "${TEST_DIR}/webhook.request"
+  kill -9 "${socat_pid}" || true
+  rm -f "${TEST_DIR}/webhook.request"
+
+  lxc webhook delete hook2
+  ! lxc webhook show hook2 || false
+  lxc delete --force c1
+}